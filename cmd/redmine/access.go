@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/access"
+)
+
+// accessReport lists the effective roles and permissions of the users in
+// the projects, through groups and parent projects.
+func accessReport(a *app, args []string) error {
+	fs := newFlagSet("access report", "")
+	user := fs.String("user", "", "login of the user to report")
+	project := fs.String("project", "", "identifier of the project to report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := access.Load(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	memberships := []access.Membership{}
+	for _, m := range r.Memberships {
+		if (*user == "" || strings.EqualFold(m.Login, *user)) && (*project == "" || m.Project == *project) {
+			memberships = append(memberships, m)
+		}
+	}
+
+	t := &table{header: []string{"LOGIN", "PROJECT", "ROLES", "PERMISSIONS"}}
+	for _, m := range memberships {
+		grants := make([]string, len(m.Grants))
+		for i, g := range m.Grants {
+			grants[i] = g.String()
+		}
+		t.add(m.Login, m.Project, strings.Join(grants, ", "), truncate(strings.Join(m.Permissions, " "), 60))
+	}
+	return a.render(memberships, t)
+}
+
+// accessCan tells whether a user has a permission in a project, and by
+// which roles.
+func accessCan(a *app, args []string) error {
+	fs := newFlagSet("access can", "<login> <project> <permission>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return errors.New("login, project and permission required")
+	}
+
+	r, err := access.Load(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return err
+	}
+	ok, grants, err := r.Can(fs.Arg(0), fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Allowed bool           `json:"allowed"`
+		Grants  []access.Grant `json:"grants"`
+	}{ok, grants}
+	t := &table{header: []string{"ALLOWED", "BY"}}
+	if len(grants) == 0 {
+		t.add(strconv.FormatBool(ok), "")
+	}
+	for _, g := range grants {
+		t.add(strconv.FormatBool(ok), g.String())
+	}
+	return a.render(result, t)
+}
+
+// accessMatrix prints the matrix of the roles by permissions, or of the
+// users by projects with -members, as CSV or HTML.
+func accessMatrix(a *app, args []string) error {
+	fs := newFlagSet("access matrix", "")
+	members := fs.Bool("members", false, "print the roles of the users by project instead of the permissions of the roles")
+	asHTML := fs.Bool("html", false, "print an HTML page instead of CSV")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := access.Load(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return err
+	}
+	m := r.RoleMatrix()
+	if *members {
+		m = r.MembershipMatrix()
+	}
+
+	if a.format != formatTable && a.format != "" {
+		return a.render(m, nil)
+	}
+	if *asHTML {
+		return m.WriteHTML(a.out)
+	}
+	return m.WriteCSV(a.out)
+}
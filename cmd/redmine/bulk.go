@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/bulk"
+)
+
+// issuesBulkUpdate applies the -set-* changes and the note to the issues
+// selected by the filter flags of `issues list`. In dry-run mode the
+// changes are only listed.
+func issuesBulkUpdate(a *app, args []string) error {
+	fs := newFlagSet("issues bulk-update", "")
+	filter := issueFilter{}
+	filter.register(fs)
+	status := fs.String("set-status", "", "new status ID or name")
+	assignee := fs.String("set-assignee", "", "new assignee ID, login or me, 0 to unassign")
+	version := fs.String("set-version", "", "new fixed version ID or name, requires -project for a name, 0 to clear")
+	var custom multiFlag
+	fs.Var(&custom, "set-cf", "new custom field `id=value`, may be repeated")
+	note := fs.String("note", "", "note to add, @file reads it from a file")
+	private := fs.Bool("private", false, "make the note private")
+	dryRun := fs.Bool("dry-run", false, "only list the changes")
+	concurrency := fs.Int("concurrency", 4, "maximum number of concurrent updates")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q, err := filter.query()
+	if err != nil {
+		return err
+	}
+
+	change := bulk.Change{PrivateNotes: *private}
+	resolve := func(name, v string, fn func(string) (int, error)) *int {
+		if err != nil || v == "" {
+			return nil
+		}
+		var id int
+		id, err = fn(v)
+		if err != nil {
+			err = fmt.Errorf("-%s: %w", name, err)
+		}
+		return &id
+	}
+	change.StatusId = resolve("set-status", *status, a.resolveStatus)
+	change.AssignedToId = resolve("set-assignee", *assignee, a.resolveUser)
+	change.FixedVersionId = resolve("set-version", *version, func(v string) (int, error) { return a.resolveVersion(filter.project, v) })
+	if err != nil {
+		return err
+	}
+	if change.CustomFields, err = parseCustomFields(custom); err != nil {
+		return err
+	}
+	if *note != "" {
+		text, err := textArg(*note)
+		if err != nil {
+			return err
+		}
+		change.Notes = &text
+	}
+	if change.StatusId == nil && change.AssignedToId == nil && change.FixedVersionId == nil && len(change.CustomFields) == 0 && change.Notes == nil {
+		fs.Usage()
+		return errors.New("no change given")
+	}
+
+	u := &bulk.Updater{Client: a.client, Auth: a.auth, Concurrency: *concurrency, DryRun: *dryRun}
+	results, err := u.Update(a.ctx, q, change)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "RESULT", "CHANGES", "SUBJECT"}}
+	failed := 0
+	for _, r := range results {
+		changes := []string{}
+		for _, c := range r.Changes {
+			if c.Field == bulk.FieldNotes {
+				changes = append(changes, c.Field)
+			} else {
+				changes = append(changes, fmt.Sprintf("%s: %q -> %q", c.Field, c.From, c.To))
+			}
+		}
+
+		result := "unchanged"
+		switch {
+		case r.Error != "":
+			result = "failed: " + r.Error
+			failed++
+		case r.Updated:
+			result = "updated"
+		case len(r.Changes) > 0:
+			result = "would update"
+		}
+		t.add(strconv.Itoa(r.Id), result, strings.Join(changes, ", "), truncate(r.Subject, 40))
+	}
+	if err := a.render(results, t); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d issues failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/burndown"
+)
+
+// versionsBurndown prints the daily work of a version as CSV, or draws its
+// burndown, or burnup with -burnup, as an SVG or PNG image with -chart.
+// The JSON and YAML outputs are the points of the chart.
+func versionsBurndown(a *app, args []string) error {
+	fs := newFlagSet("versions burndown", "<version>")
+	unit := fs.String("unit", burndown.UnitCount, "unit of the work: count, estimated_hours or spent_hours")
+	from := fs.String("from", "", "first day, YYYY-MM-DD or today, the creation of the version if empty")
+	to := fs.String("to", "", "last day, YYYY-MM-DD or today, the due date of the version if empty")
+	burnup := fs.Bool("burnup", false, "draw the scope and the completed work instead of the remaining work")
+	chart := fs.String("chart", "", "draw the chart as svg or png instead of printing CSV")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("version id required")
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid version id %q", fs.Arg(0))
+	}
+	if *chart != "" && *chart != "svg" && *chart != "png" {
+		return fmt.Errorf("unknown chart format %q", *chart)
+	}
+
+	opts := burndown.Options{Unit: *unit}
+	if d, err := parseDate(*from); err != nil {
+		return err
+	} else if d != nil {
+		opts.From = d.Time
+	}
+	if d, err := parseDate(*to); err != nil {
+		return err
+	} else if d != nil {
+		opts.To = d.Time
+	}
+
+	data, err := burndown.Load(a.ctx, a.client, id, a.auth...)
+	if err != nil {
+		return err
+	}
+	c, err := data.Chart(opts)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a.format != formatTable && a.format != "":
+		return a.render(c, nil)
+	case *chart == "svg":
+		_, err = io.WriteString(a.out, c.SVG(*burnup))
+		return err
+	case *chart == "png":
+		return c.PNG(a.out, *burnup)
+	}
+	return c.WriteCSV(a.out)
+}
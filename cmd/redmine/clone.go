@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/clone"
+)
+
+// projectsClone creates a project from another one, copying the parts
+// selected by -parts. The steps are printed to stderr as they complete.
+func projectsClone(a *app, args []string) error {
+	fs := newFlagSet("projects clone", "<source>")
+	name := fs.String("name", "", "name of the new project (required)")
+	identifier := fs.String("identifier", "", "identifier of the new project (required)")
+	parts := fs.String("parts", "settings,members,versions,categories,wiki", "parts to copy, separated by commas")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *name == "" || *identifier == "" {
+		fs.Usage()
+		return errors.New("source project, -name and -identifier required")
+	}
+
+	opts := clone.Options{Name: *name, Identifier: *identifier}
+	for _, p := range strings.Split(*parts, ",") {
+		switch strings.TrimSpace(p) {
+		case "settings":
+			opts.Settings = true
+		case clone.PartMembers:
+			opts.Members = true
+		case clone.PartVersions:
+			opts.Versions = true
+		case clone.PartCategories:
+			opts.Categories = true
+		case clone.PartWiki:
+			opts.Wiki = true
+		case "":
+		default:
+			return fmt.Errorf("unknown part %q", p)
+		}
+	}
+
+	cl := &clone.Cloner{Client: a.client, Auth: a.auth, Progress: func(s clone.Step) {
+		if s.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", s.Part, s.Item, s.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s %s: done\n", s.Part, s.Item)
+		}
+	}}
+	r, err := cl.Clone(a.ctx, fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PART", "ITEM", "RESULT"}}
+	for _, s := range r.Steps {
+		result := "done"
+		if s.Error != "" {
+			result = "failed: " + s.Error
+		}
+		t.add(s.Part, s.Item, result)
+	}
+	if err := a.render(r, t); err != nil {
+		return err
+	}
+	if r.Failed > 0 {
+		return fmt.Errorf("%d of %d steps failed", r.Failed, len(r.Steps))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Profile is a Redmine server and the credentials to access it.
+type Profile struct {
+	// URL The base URL of the Redmine server.
+	URL string `yaml:"url"`
+
+	// APIKey The API access key. It takes precedence over Username and Password.
+	APIKey string `yaml:"api_key,omitempty"`
+
+	// Username The login for basic authentication.
+	Username string `yaml:"username,omitempty"`
+
+	// Password The password for basic authentication.
+	Password string `yaml:"password,omitempty"`
+}
+
+// Config is the content of the configuration file.
+//
+//	default: work
+//	profiles:
+//	  work:
+//	    url: https://redmine.example.com
+//	    api_key: 0123456789abcdef
+//	  local:
+//	    url: http://127.0.0.1:3000
+//	    username: admin
+//	    password: admin
+type Config struct {
+	// Default The name of the profile used when none is selected.
+	Default string `yaml:"default,omitempty"`
+
+	// Profiles The profiles by name.
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/redmine/config.yml or its
+// platform equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "redmine", "config.yml")
+}
+
+// loadConfig reads the configuration file at path. A missing file yields an
+// empty configuration so that the environment variables alone can be used.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+
+	return cfg, nil
+}
+
+// Profile returns the profile with name, or the default profile if name is
+// empty. REDMINE_URL, REDMINE_API_KEY, REDMINE_USERNAME and REDMINE_PASSWORD
+// override the values of the selected profile.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+
+	p := Profile{}
+	if name != "" {
+		found, ok := c.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
+		p = found
+	}
+
+	if v := os.Getenv("REDMINE_URL"); v != "" {
+		p.URL = v
+	}
+	if v := os.Getenv("REDMINE_API_KEY"); v != "" {
+		p.APIKey = v
+	}
+	if v := os.Getenv("REDMINE_USERNAME"); v != "" {
+		p.Username = v
+	}
+	if v := os.Getenv("REDMINE_PASSWORD"); v != "" {
+		p.Password = v
+	}
+
+	if p.URL == "" {
+		return nil, errors.New("no Redmine URL configured, set a profile or REDMINE_URL")
+	}
+
+	return &p, nil
+}
+
+// Auth returns the request editors authenticating with the profile.
+func (p *Profile) Auth() []redmine.RequestEditorFn {
+	switch {
+	case p.APIKey != "":
+		return []redmine.RequestEditorFn{apiutil.APIKey(p.APIKey)}
+	case p.Username != "":
+		return []redmine.RequestEditorFn{apiutil.BasicAuth(p.Username, p.Password)}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/transfer"
+)
+
+// issuesCopy copies issues with their subtasks to the project of -project,
+// on the server of -to-profile if given.
+func issuesCopy(a *app, args []string) error {
+	return copyIssues(a, "issues copy", args, false)
+}
+
+// issuesMove moves issues to the project of -project. To another server, it
+// copies them as issuesCopy does and deletes the sources, which it refuses
+// when time is logged on them unless -delete-time is set.
+func issuesMove(a *app, args []string) error {
+	return copyIssues(a, "issues move", args, true)
+}
+
+func copyIssues(a *app, name string, args []string, move bool) error {
+	fs := newFlagSet(name, "<id>...")
+	project := fs.String("project", "", "target project ID or identifier (required)")
+	profile := fs.String("to-profile", "", "profile `name` of the target server, the current one if empty")
+	journals := fs.Bool("journals", false, "add the notes of the journals to the copies")
+	deleteTime := new(bool)
+	if move {
+		deleteTime = fs.Bool("delete-time", false, "with -to-profile, delete the issues even if time is logged on them, losing that time")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *project == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("-project and issue IDs required")
+	}
+	ids := []int{}
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid issue ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	cp := &transfer.Copier{Source: a.client, SourceAuth: a.auth, Project: *project, Journals: *journals, DeleteTimeEntries: *deleteTime}
+	if *profile != "" {
+		p, ok := a.config.Profiles[*profile]
+		if !ok {
+			return fmt.Errorf("profile %q not found", *profile)
+		}
+		c, err := redmine.NewClientWithResponses(p.URL, redmine.WithHTTPClient(a.http))
+		if err != nil {
+			return err
+		}
+		cp.Target, cp.TargetAuth = c, p.Auth()
+	}
+
+	reports := []*transfer.Report{}
+	t := &table{header: []string{"SOURCE", "TARGET", "PARENT", "SUBJECT", "DROPPED"}}
+	for _, id := range ids {
+		var r *transfer.Report
+		var err error
+		if move {
+			r, err = cp.Move(a.ctx, id)
+		} else {
+			r, err = cp.Copy(a.ctx, id)
+		}
+		if r != nil {
+			reports = append(reports, r)
+			addCopyRows(t, r)
+		}
+		if err != nil {
+			if rerr := a.render(reports, t); rerr != nil {
+				return rerr
+			}
+			return fmt.Errorf("#%d: %w", id, err)
+		}
+	}
+	return a.render(reports, t)
+}
+
+// addCopyRows adds a row per copied issue, listing the values dropped.
+func addCopyRows(t *table, r *transfer.Report) {
+	for _, c := range r.Issues {
+		dropped := ""
+		for _, d := range r.Dropped {
+			if d.Issue != c.Source {
+				continue
+			}
+			if dropped != "" {
+				dropped += ", "
+			}
+			dropped += d.Field + ": " + d.Value
+		}
+		parent := ""
+		if c.Parent != 0 {
+			parent = strconv.Itoa(c.Parent)
+		}
+		t.add(strconv.Itoa(c.Source), strconv.Itoa(c.Target), parent, truncate(c.Subject, 40), dropped)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/csvexport"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// csvFlags holds the flags common to the csv commands.
+type csvFlags struct {
+	file         string
+	project      string
+	charset      string
+	decimalComma bool
+	filters      multiFlag
+	headers      multiFlag
+}
+
+func (f *csvFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "file", "", "parse an export saved to a file instead of downloading it")
+	fs.StringVar(&f.project, "project", "", "project ID or identifier of the issues or time entries")
+	fs.StringVar(&f.charset, "charset", "", "charset of the export, detected if empty")
+	fs.BoolVar(&f.decimalComma, "decimal-comma", false, "numbers have a comma as decimal separator")
+	fs.Var(&f.filters, "f", "additional `key=value` filter, may be repeated")
+	fs.Var(&f.headers, "header", "additional `header=key` column mapping, key cf_<id> for a custom field, may be repeated")
+}
+
+func (f *csvFlags) options() (csvexport.Options, error) {
+	opts := csvexport.Options{Charset: f.charset, DecimalComma: f.decimalComma, Headers: map[string]string{}}
+	for _, h := range f.headers {
+		header, key, ok := strings.Cut(h, "=")
+		if !ok {
+			return opts, fmt.Errorf("invalid header mapping %q, expected header=key", h)
+		}
+		opts.Headers[header] = key
+	}
+	return opts, nil
+}
+
+// query sets the project and the filters with set.
+func (f *csvFlags) query(set func(k, v string)) error {
+	if f.project != "" {
+		set("project_id", f.project)
+	}
+	for _, e := range f.filters {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok {
+			return fmt.Errorf("invalid filter %q, expected key=value", e)
+		}
+		set(k, v)
+	}
+	return nil
+}
+
+// open returns the export of the file flag, or downloads it with export.
+func (f *csvFlags) open(a *app, export func(ctx context.Context, c redmine.ClientInterface) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if f.file != "" {
+		return os.Open(f.file)
+	}
+	return export(a.ctx, a.raw)
+}
+
+func fmtDay(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+// readExport parses the export of the flags of the command name and renders
+// its records, the table with the columns of header and row.
+func readExport[T any](a *app, name string, args []string, export func(ctx context.Context, c redmine.ClientInterface, f *csvFlags) (io.ReadCloser, error), newReader func(io.Reader, csvexport.Options) (*csvexport.Reader[T], error), header []string, row func(r *T) []string) error {
+	fs := newFlagSet(name, "")
+	f := csvFlags{}
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	body, err := f.open(a, func(ctx context.Context, c redmine.ClientInterface) (io.ReadCloser, error) { return export(ctx, c, &f) })
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	r, err := newReader(body, opts)
+	if err != nil {
+		return err
+	}
+	records, err := r.All()
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: header}
+	for i := range records {
+		t.add(row(&records[i])...)
+	}
+	return a.render(records, t)
+}
+
+// csvIssues prints the issues of a CSV export.
+func csvIssues(a *app, args []string) error {
+	export := func(ctx context.Context, c redmine.ClientInterface, f *csvFlags) (io.ReadCloser, error) {
+		q := &redmine.IssuesIndexCsvParams_Query{}
+		if err := f.query(q.Set); err != nil {
+			return nil, err
+		}
+		return csvexport.ExportIssues(ctx, c, &redmine.IssuesIndexCsvParams{Query: q}, a.auth...)
+	}
+	return readExport(a, "csv issues", args, export, csvexport.NewIssueReader,
+		[]string{"ID", "TRACKER", "STATUS", "ASSIGNEE", "DONE", "SUBJECT"},
+		func(i *csvexport.Issue) []string {
+			return []string{strconv.Itoa(i.Id), i.Tracker, i.Status, i.AssignedTo, strconv.Itoa(i.DoneRatio) + "%", truncate(i.Subject, 60)}
+		})
+}
+
+// csvTime prints the time entries of a CSV export.
+func csvTime(a *app, args []string) error {
+	export := func(ctx context.Context, c redmine.ClientInterface, f *csvFlags) (io.ReadCloser, error) {
+		q := &redmine.TimelogIndexCsvParams_Query{}
+		if err := f.query(q.Set); err != nil {
+			return nil, err
+		}
+		return csvexport.ExportTimeEntries(ctx, c, &redmine.TimelogIndexCsvParams{Query: q}, a.auth...)
+	}
+	return readExport(a, "csv time", args, export, csvexport.NewTimeEntryReader,
+		[]string{"DATE", "USER", "ACTIVITY", "HOURS", "ISSUE"},
+		func(e *csvexport.TimeEntry) []string {
+			return []string{fmtDay(e.SpentOn), e.User, e.Activity, fmtHours(&e.Hours), truncate(e.Issue, 60)}
+		})
+}
+
+// csvUsers prints the users of a CSV export.
+func csvUsers(a *app, args []string) error {
+	export := func(ctx context.Context, c redmine.ClientInterface, f *csvFlags) (io.ReadCloser, error) {
+		q := &redmine.UsersIndexCsvParams_Query{}
+		if err := f.query(q.Set); err != nil {
+			return nil, err
+		}
+		return csvexport.ExportUsers(ctx, c, &redmine.UsersIndexCsvParams{Query: q}, a.auth...)
+	}
+	return readExport(a, "csv users", args, export, csvexport.NewUserReader,
+		[]string{"LOGIN", "NAME", "MAIL", "ADMIN"},
+		func(u *csvexport.User) []string {
+			return []string{u.Login, strings.TrimSpace(u.Firstname + " " + u.Lastname), u.Mail, strconv.FormatBool(u.Admin)}
+		})
+}
+
+// csvProjects prints the projects of a CSV export.
+func csvProjects(a *app, args []string) error {
+	export := func(ctx context.Context, c redmine.ClientInterface, f *csvFlags) (io.ReadCloser, error) {
+		q := &redmine.ProjectsIndexCsvParams_Query{}
+		if err := f.query(q.Set); err != nil {
+			return nil, err
+		}
+		return csvexport.ExportProjects(ctx, c, &redmine.ProjectsIndexCsvParams{Query: q}, a.auth...)
+	}
+	return readExport(a, "csv projects", args, export, csvexport.NewProjectReader,
+		[]string{"IDENTIFIER", "NAME", "STATUS", "PARENT"},
+		func(p *csvexport.Project) []string {
+			return []string{p.Identifier, p.Name, p.Status, p.Parent}
+		})
+}
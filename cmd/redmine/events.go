@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/watch"
+)
+
+func logError(err error) {
+	fmt.Fprintf(os.Stderr, "redmine: %v\n", err)
+}
+
+// eventsWatch polls for issue changes until interrupted. The events are
+// printed as JSON lines, or POSTed to the -forward endpoints.
+func eventsWatch(a *app, args []string) error {
+	fs := newFlagSet("events watch", "")
+	var projects, endpoints multiFlag
+	fs.Var(&projects, "project", "watched project ID or identifier (repeatable)")
+	fs.Var(&endpoints, "forward", "endpoint `url` the events are POSTed to (repeatable)")
+	interval := fs.Duration("interval", watch.DefaultInterval, "time between two polls")
+	state := fs.String("state", "", "checkpoint file `path` of the watcher")
+	pending := fs.String("pending", "", "file `path` of the events waiting to be forwarded")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(a.ctx, os.Interrupt)
+	defer stop()
+
+	w := &watch.Watcher{
+		Client:    a.client,
+		Auth:      a.auth,
+		Projects:  projects,
+		Interval:  *interval,
+		StatePath: *state,
+		OnError:   logError,
+	}
+
+	events := make(chan watch.Event)
+	done := make(chan error, 1)
+	if len(endpoints) > 0 {
+		f := &watch.Forwarder{
+			Endpoints: endpoints,
+			StatePath: *pending,
+			OnError:   func(err *watch.DeliveryError) { logError(err) },
+		}
+		go func() { done <- f.Run(ctx, events) }()
+	} else {
+		go func() {
+			var err error
+			enc := json.NewEncoder(a.out)
+			for e := range events {
+				if err == nil {
+					err = enc.Encode(e)
+				}
+			}
+			done <- err
+		}()
+	}
+
+	err := w.Run(ctx, events)
+	close(events)
+	if ferr := <-done; ferr != nil && !errors.Is(ferr, context.Canceled) {
+		return ferr
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
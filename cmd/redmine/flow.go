@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/flow"
+)
+
+// flowFlags are the flags selecting and analyzing the issues of the flow
+// commands.
+type flowFlags struct {
+	filter issueFilter
+	start  multiFlag
+	group  *string
+}
+
+func (f *flowFlags) register(fs *flag.FlagSet) {
+	f.filter.register(fs)
+	fs.Var(&f.start, "start", "ID or name of a status starting the cycle time, may be repeated")
+	f.group = fs.String("group", "", "grouping of the issues: tracker, assignee or version")
+}
+
+// load fetches and analyzes the issues selected by the flags, of all
+// statuses unless -status is set.
+func (f *flowFlags) load(a *app) ([]flow.Issue, error) {
+	if !slices.Contains([]string{flow.GroupNone, flow.GroupTracker, flow.GroupAssignee, flow.GroupVersion}, *f.group) {
+		return nil, fmt.Errorf("unknown grouping %q", *f.group)
+	}
+	q, err := f.filter.query()
+	if err != nil {
+		return nil, err
+	}
+	return flow.Load(a.ctx, a.client, q, flow.Options{Start: f.start}, a.auth...)
+}
+
+// flowIssues prints the time in status, lead time and cycle time of each
+// issue as CSV, with their status periods in JSON or YAML.
+func flowIssues(a *app, args []string) error {
+	fs := newFlagSet("flow issues", "")
+	f := flowFlags{}
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	issues, err := f.load(a)
+	if err != nil {
+		return err
+	}
+	if a.format != formatTable && a.format != "" {
+		return a.render(issues, nil)
+	}
+	return flow.WriteIssuesCSV(a.out, issues)
+}
+
+// flowSummary prints the percentiles of the lead times, cycle times and
+// times in status by -group.
+func flowSummary(a *app, args []string) error {
+	fs := newFlagSet("flow summary", "")
+	f := flowFlags{}
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	issues, err := f.load(a)
+	if err != nil {
+		return err
+	}
+	summaries := flow.Summarize(issues, *f.group)
+	if a.format != formatTable && a.format != "" {
+		return a.render(summaries, nil)
+	}
+	return flow.WriteSummaryCSV(a.out, summaries)
+}
+
+// flowThroughput prints the number of issues closed per week by -group.
+func flowThroughput(a *app, args []string) error {
+	fs := newFlagSet("flow throughput", "")
+	f := flowFlags{}
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	issues, err := f.load(a)
+	if err != nil {
+		return err
+	}
+	weeks := flow.Throughput(issues, *f.group)
+	if a.format != formatTable && a.format != "" {
+		return a.render(weeks, nil)
+	}
+	return flow.WriteThroughputCSV(a.out, weeks)
+}
+
+// flowWIP prints the number of issues in progress per day by -group.
+func flowWIP(a *app, args []string) error {
+	fs := newFlagSet("flow wip", "")
+	f := flowFlags{}
+	f.register(fs)
+	from := fs.String("from", "", "first day, YYYY-MM-DD or today, the first creation day if empty")
+	to := fs.String("to", "today", "last day, YYYY-MM-DD or today")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	first, err := parseDate(*from)
+	if err != nil {
+		return err
+	}
+	last, err := parseDate(*to)
+	if err != nil {
+		return err
+	}
+
+	issues, err := f.load(a)
+	if err != nil {
+		return err
+	}
+	var start, end time.Time
+	if first != nil {
+		start = first.Time
+	} else {
+		for _, i := range issues {
+			if start.IsZero() || i.Created.Before(start) {
+				start = i.Created
+			}
+		}
+		y, m, d := start.Date()
+		start = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	if last != nil {
+		end = last.Time
+	}
+
+	samples := flow.WIP(issues, *f.group, start, end)
+	if a.format != formatTable && a.format != "" {
+		return a.render(samples, nil)
+	}
+	return flow.WriteWIPCSV(a.out, samples)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/gantt"
+)
+
+// issuesGantt prints the timeline of the issues of -project selected by the
+// filter flags of `issues list` as an SVG image or a Mermaid gantt chart.
+// The JSON and YAML outputs are the issues and versions drawn from.
+func issuesGantt(a *app, args []string) error {
+	fs := newFlagSet("issues gantt", "")
+	filter := issueFilter{}
+	filter.register(fs)
+	from := fs.String("from", "", "first day drawn, YYYY-MM-DD or today, the first day of the issues if empty")
+	to := fs.String("to", "", "last day drawn, YYYY-MM-DD or today, the last day of the issues if empty")
+	group := fs.String("group", "", "grouping of the issues: assignee or version")
+	relations := fs.Bool("relations", false, "draw the blocks and precedes relations as arrows")
+	milestones := fs.Bool("milestones", false, "draw the versions with a due date")
+	dayWidth := fs.Int("day-width", gantt.DefaultDayWidth, "width of a day in pixels")
+	mermaid := fs.Bool("mermaid", false, "print a Mermaid gantt chart instead of SVG")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if filter.project == "" {
+		fs.Usage()
+		return errors.New("-project required")
+	}
+	if !slices.Contains([]string{gantt.GroupNone, gantt.GroupAssignee, gantt.GroupVersion}, *group) {
+		return fmt.Errorf("unknown grouping %q", *group)
+	}
+	opts := gantt.Options{GroupBy: *group, Relations: *relations, Milestones: *milestones, DayWidth: *dayWidth}
+	if d, err := parseDate(*from); err != nil {
+		return err
+	} else if d != nil {
+		opts.From = d.Time
+	}
+	if d, err := parseDate(*to); err != nil {
+		return err
+	} else if d != nil {
+		opts.To = d.Time
+	}
+
+	q, err := filter.query()
+	if err != nil {
+		return err
+	}
+	c, err := gantt.Load(a.ctx, a.client, filter.project, q, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	if a.format != formatTable && a.format != "" {
+		return a.render(c, nil)
+	}
+	out := c.SVG(opts)
+	if *mermaid {
+		out = c.Mermaid(opts)
+	}
+	_, err = io.WriteString(a.out, out)
+	return err
+}
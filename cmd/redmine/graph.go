@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/depgraph"
+)
+
+// issuesGraph prints the dependencies between the issues selected by the
+// filter flags of `issues list`, of every status by default: the cycles,
+// the chains of open issues blocking each other and the critical path, or
+// the graph in DOT or Mermaid.
+func issuesGraph(a *app, args []string) error {
+	fs := newFlagSet("issues graph", "")
+	filter := issueFilter{}
+	filter.register(fs)
+	dot := fs.Bool("dot", false, "print the graph in Graphviz DOT")
+	mermaid := fs.Bool("mermaid", false, "print the graph as a Mermaid flowchart")
+	hours := fs.Float64("hours-per-day", depgraph.DefaultHoursPerDay, "hours of a day of the estimated hours")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if filter.status == "" {
+		filter.status = "*"
+	}
+	q, err := filter.query()
+	if err != nil {
+		return err
+	}
+
+	g, err := depgraph.Load(a.ctx, a.client, q, a.auth...)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *dot:
+		_, err = io.WriteString(a.out, g.DOT())
+		return err
+	case *mermaid:
+		_, err = io.WriteString(a.out, g.Mermaid())
+		return err
+	}
+
+	report := struct {
+		Edges         []depgraph.Edge `json:"edges"`
+		Cycles        [][]int         `json:"cycles"`
+		BlockedChains [][]int         `json:"blocked_chains"`
+		CriticalPath  *depgraph.Path  `json:"critical_path"`
+	}{Edges: g.Edges, Cycles: g.Cycles(), BlockedChains: g.BlockedChains()}
+	if len(report.Cycles) == 0 {
+		if report.CriticalPath, err = g.CriticalPath(*hours); err != nil {
+			return err
+		}
+	}
+	if a.format != formatTable && a.format != "" {
+		return a.render(report, nil)
+	}
+
+	chain := func(ids []int, sep string) string {
+		labels := []string{}
+		for _, id := range ids {
+			labels = append(labels, fmt.Sprintf("#%d %s", id, g.Issue(id).Subject))
+		}
+		return strings.Join(labels, sep)
+	}
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%d issues, %d relations\n", len(g.Issues), len(g.Edges))
+	fmt.Fprintf(&b, "\nCycles: %d\n", len(report.Cycles))
+	for _, c := range report.Cycles {
+		fmt.Fprintf(&b, "  %s\n", chain(c, ", "))
+	}
+	fmt.Fprintf(&b, "\nBlocked chains: %d\n", len(report.BlockedChains))
+	for _, c := range report.BlockedChains {
+		fmt.Fprintf(&b, "  %s\n", chain(c, " -> "))
+	}
+	if p := report.CriticalPath; p != nil {
+		fmt.Fprintf(&b, "\nCritical path: %s days\n", fmtHours(&p.Days))
+		if len(p.Issues) > 0 {
+			fmt.Fprintf(&b, "  %s\n", chain(p.Issues, " -> "))
+		}
+	}
+	_, err = io.WriteString(a.out, b.String())
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/csvimport"
+)
+
+// issuesImport creates and updates issues from the rows of a CSV file,
+// with the columns mapped to the fields by a YAML file.
+func issuesImport(a *app, args []string) error {
+	fs := newFlagSet("issues import", "<file>")
+	mapping := fs.String("mapping", "", "mapping file of the columns (required)")
+	project := fs.String("project", "", "project ID or identifier of the new issues, over the one of the mapping")
+	dryRun := fs.Bool("dry-run", false, "validate the rows without writing anything")
+	result := fs.String("result", "", "write the results as CSV to `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *mapping == "" {
+		fs.Usage()
+		return errors.New("file and -mapping required")
+	}
+
+	m, err := csvimport.LoadMapping(*mapping)
+	if err != nil {
+		return err
+	}
+	if *project != "" {
+		m.Project = *project
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	im := &csvimport.Importer{Client: a.client, Auth: a.auth, Mapping: m, DryRun: *dryRun}
+	results, err := im.Import(a.ctx, f)
+	if err != nil {
+		return err
+	}
+
+	if *result != "" {
+		out, err := os.Create(*result)
+		if err != nil {
+			return err
+		}
+		if err := csvimport.WriteResults(out, results); err != nil {
+			_ = out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+
+	tbl := &table{header: []string{"ROW", "UNIQUE ID", "ID", "ACTION", "ERROR"}}
+	failed := 0
+	for _, r := range results {
+		id := ""
+		if r.Id != 0 {
+			id = strconv.Itoa(r.Id)
+		}
+		if r.Action == csvimport.ActionFailed {
+			failed++
+		}
+		tbl.add(strconv.Itoa(r.Row), r.UniqueId, id, r.Action, r.Error)
+	}
+	if err := a.render(results, tbl); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(results))
+	}
+	return nil
+}
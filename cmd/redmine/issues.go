@@ -0,0 +1,535 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// issueFilter holds the filter flags of `issues list`. Each flag maps to a
+// field of IssuesIndexParams_Query and takes Redmine's "[operator]<values>"
+// expression, e.g. `-status closed`, `-assignee me`, `-updated ">=2025-01-01"`.
+type issueFilter struct {
+	project  string
+	status   string
+	tracker  string
+	assignee string
+	author   string
+	version  string
+	priority string
+	category string
+	parent   string
+	subject  string
+	created  string
+	updated  string
+	due      string
+	sort     string
+	extra    multiFlag
+}
+
+func (f *issueFilter) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.project, "project", "", "project ID or identifier")
+	fs.StringVar(&f.status, "status", "", "status: open, closed, * or IDs separated by |")
+	fs.StringVar(&f.tracker, "tracker", "", "tracker IDs separated by |")
+	fs.StringVar(&f.assignee, "assignee", "", "assignee IDs separated by |, or me")
+	fs.StringVar(&f.author, "author", "", "author IDs separated by |, or me")
+	fs.StringVar(&f.version, "version", "", "fixed version IDs separated by |")
+	fs.StringVar(&f.priority, "priority", "", "priority IDs separated by |")
+	fs.StringVar(&f.category, "category", "", "category IDs separated by |")
+	fs.StringVar(&f.parent, "parent", "", "parent issue ID")
+	fs.StringVar(&f.subject, "subject", "", "subject expression, e.g. ~text")
+	fs.StringVar(&f.created, "created", "", "created_on expression, e.g. >=2025-01-01")
+	fs.StringVar(&f.updated, "updated", "", "updated_on expression, e.g. >=2025-01-01")
+	fs.StringVar(&f.due, "due", "", "due_date expression, e.g. <=2025-12-31")
+	fs.StringVar(&f.sort, "sort", "", "sort order, e.g. updated_on:desc")
+	fs.Var(&f.extra, "f", "additional `key=value` filter, may be repeated")
+}
+
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func (f *issueFilter) query() (*redmine.IssuesIndexParams_Query, error) {
+	q := &redmine.IssuesIndexParams_Query{
+		ProjectId:      optional(f.project),
+		StatusId:       optional(f.status),
+		TrackerId:      optional(f.tracker),
+		AssignedToId:   optional(f.assignee),
+		AuthorId:       optional(f.author),
+		FixedVersionId: optional(f.version),
+		PriorityId:     optional(f.priority),
+		CategoryId:     optional(f.category),
+		ParentId:       optional(f.parent),
+		Subject:        optional(f.subject),
+		CreatedOn:      optional(f.created),
+		UpdatedOn:      optional(f.updated),
+		DueDate:        optional(f.due),
+	}
+
+	if f.sort != "" {
+		q.Set("sort", f.sort)
+	}
+
+	for _, e := range f.extra {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter %q, expected key=value", e)
+		}
+		q.Set(k, v)
+	}
+
+	return q, nil
+}
+
+func issuesList(a *app, args []string) error {
+	fs := newFlagSet("issues list", "")
+	filter := issueFilter{}
+	filter.register(fs)
+	limit := fs.Int("limit", 25, "maximum number of issues, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q, err := filter.query()
+	if err != nil {
+		return err
+	}
+
+	params := redmine.IssuesIndexParams{Query: q}
+	var issues []model.Issue
+	if *limit <= 0 {
+		issues, err = apiutil.Issues(a.ctx, a.client, &params, a.auth...)
+	} else {
+		issues, err = issuesPage(a, &params, *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "TRACKER", "STATUS", "PRIORITY", "ASSIGNEE", "UPDATED", "SUBJECT"}}
+	for _, i := range issues {
+		t.add(
+			strconv.Itoa(i.Id),
+			refName(i.Tracker),
+			statusName(i.Status),
+			refName(i.Priority),
+			refName(i.AssignedTo),
+			fmtTime(i.UpdatedOn),
+			truncate(i.Subject, 60))
+	}
+
+	return a.render(issues, t)
+}
+
+// issuesPage reads the first limit issues, page by page.
+func issuesPage(a *app, params *redmine.IssuesIndexParams, limit int) ([]model.Issue, error) {
+	issues := []model.Issue{}
+	for len(issues) < limit {
+		p := *params
+		p.Pagination = apiutil.Page(len(issues), min(limit-len(issues), apiutil.PageSize))
+		resp, err := a.client.IssuesIndexWithResponse(a.ctx, &p, a.auth...)
+		if err != nil {
+			return nil, err
+		}
+		if err := apiutil.Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Issues     []model.Issue `json:"issues"`
+			TotalCount int           `json:"total_count"`
+		}
+		if err := json.Unmarshal(resp.Body, &page); err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, page.Issues...)
+		if len(page.Issues) == 0 || len(issues) >= page.TotalCount {
+			break
+		}
+	}
+	return issues, nil
+}
+
+func issuesShow(a *app, args []string) error {
+	fs := newFlagSet("issues show", "<id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("issue id required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	include := []string{"attachments", "children", "journals", "relations", "watchers"}
+	issue, err := apiutil.Issue(a.ctx, a.client, id, include, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"FIELD", "VALUE"}}
+	t.add("Id", strconv.Itoa(issue.Id))
+	t.add("Project", refName(issue.Project))
+	t.add("Tracker", refName(issue.Tracker))
+	t.add("Status", statusName(issue.Status))
+	t.add("Priority", refName(issue.Priority))
+	t.add("Subject", issue.Subject)
+	t.add("Author", refName(issue.Author))
+	t.add("Assignee", refName(issue.AssignedTo))
+	t.add("Category", refName(issue.Category))
+	t.add("Version", refName(issue.FixedVersion))
+	if issue.Parent != nil {
+		t.add("Parent", "#"+strconv.Itoa(issue.Parent.Id))
+	}
+	t.add("Start date", fmtDate(issue.StartDate))
+	t.add("Due date", fmtDate(issue.DueDate))
+	t.add("Done", strconv.Itoa(issue.DoneRatio)+"%")
+	t.add("Estimated", fmtHours(issue.EstimatedHours))
+	t.add("Spent", fmtHours(issue.SpentHours))
+	t.add("Created", fmtTime(issue.CreatedOn))
+	t.add("Updated", fmtTime(issue.UpdatedOn))
+	for _, cf := range issue.CustomFields {
+		t.add(cf.Name, strings.Join(cf.Values(), ", "))
+	}
+	for _, w := range issue.Watchers {
+		t.add("Watcher", w.Name)
+	}
+	for _, r := range issue.Relations {
+		t.add("Relation", fmt.Sprintf("%s #%d", r.RelationType, otherIssue(r, issue.Id)))
+	}
+	for _, c := range issue.Children {
+		t.add("Subtask", fmt.Sprintf("#%d %s", c.Id, c.Subject))
+	}
+	for _, att := range issue.Attachments {
+		t.add("Attachment", fmt.Sprintf("%s (%d bytes)", att.Filename, att.Filesize))
+	}
+	t.add("Description", truncate(issue.Description, 200))
+	for _, j := range issue.Journals {
+		if j.Notes != "" {
+			t.add("Note", fmt.Sprintf("%s %s: %s", fmtTime(&j.CreatedOn), refName(j.User), truncate(j.Notes, 120)))
+		}
+	}
+
+	return a.render(issue, t)
+}
+
+func otherIssue(r model.Relation, id int) int {
+	if r.IssueId == id {
+		return r.IssueToId
+	}
+	return r.IssueId
+}
+
+// issueForm holds the field flags of `issues create` and `issues update`.
+type issueForm struct {
+	project     string
+	tracker     string
+	status      string
+	priority    string
+	assignee    string
+	version     string
+	category    int
+	parent      int
+	subject     string
+	description string
+	start       string
+	due         string
+	done        int
+	estimate    float64
+	private     bool
+	note        string
+	watchers    multiFlag
+	attach      multiFlag
+	custom      multiFlag
+}
+
+func (f *issueForm) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.project, "project", "", "project ID or identifier")
+	fs.StringVar(&f.tracker, "tracker", "", "tracker ID or name")
+	fs.StringVar(&f.status, "status", "", "status ID or name")
+	fs.StringVar(&f.priority, "priority", "", "priority ID or name")
+	fs.StringVar(&f.assignee, "assignee", "", "assignee ID, login or me")
+	fs.StringVar(&f.version, "version", "", "fixed version ID or name")
+	fs.IntVar(&f.category, "category", 0, "category ID")
+	fs.IntVar(&f.parent, "parent", 0, "parent issue ID")
+	fs.StringVar(&f.subject, "subject", "", "subject")
+	fs.StringVar(&f.description, "description", "", "description, @file reads it from a file")
+	fs.StringVar(&f.start, "start", "", "start date YYYY-MM-DD")
+	fs.StringVar(&f.due, "due", "", "due date YYYY-MM-DD")
+	fs.IntVar(&f.done, "done", -1, "done ratio in percent")
+	fs.Float64Var(&f.estimate, "estimate", -1, "estimated hours")
+	fs.BoolVar(&f.private, "private", false, "make the issue private")
+	fs.StringVar(&f.note, "note", "", "note to add, @file reads it from a file")
+	fs.Var(&f.watchers, "watcher", "watcher ID or login, may be repeated")
+	fs.Var(&f.attach, "attach", "`file` to attach, may be repeated")
+	fs.Var(&f.custom, "cf", "custom field `id=value`, may be repeated")
+}
+
+// textArg returns v, or the content of the file if v starts with @.
+func textArg(v string) (string, error) {
+	if !strings.HasPrefix(v, "@") {
+		return v, nil
+	}
+
+	data, err := os.ReadFile(v[1:])
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (f *issueForm) fields(a *app, fs *flag.FlagSet) (apiutil.IssueFields, error) {
+	fields := apiutil.IssueFields{ProjectId: f.project}
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	var err error
+	resolve := func(name, v string, fn func(string) (int, error)) *int {
+		if err != nil || v == "" {
+			return nil
+		}
+		var id int
+		id, err = fn(v)
+		if err != nil {
+			err = fmt.Errorf("-%s: %w", name, err)
+		}
+		return &id
+	}
+
+	fields.TrackerId = resolve("tracker", f.tracker, a.resolveTracker)
+	fields.StatusId = resolve("status", f.status, a.resolveStatus)
+	fields.PriorityId = resolve("priority", f.priority, a.resolvePriority)
+	fields.AssignedToId = resolve("assignee", f.assignee, a.resolveUser)
+	fields.FixedVersionId = resolve("version", f.version, func(v string) (int, error) { return a.resolveVersion(f.project, v) })
+	if err != nil {
+		return fields, err
+	}
+
+	if set["category"] {
+		fields.CategoryId = &f.category
+	}
+	if set["parent"] {
+		fields.ParentIssueId = &f.parent
+	}
+	if set["subject"] {
+		fields.Subject = &f.subject
+	}
+	if set["description"] {
+		text, err := textArg(f.description)
+		if err != nil {
+			return fields, err
+		}
+		fields.Description = &text
+	}
+	if set["note"] {
+		text, err := textArg(f.note)
+		if err != nil {
+			return fields, err
+		}
+		fields.Notes = &text
+	}
+	if set["done"] {
+		fields.DoneRatio = &f.done
+	}
+	if set["estimate"] {
+		fields.EstimatedHours = &f.estimate
+	}
+	if set["private"] {
+		fields.IsPrivate = &f.private
+	}
+
+	if fields.StartDate, err = parseDate(f.start); err != nil {
+		return fields, err
+	}
+	if fields.DueDate, err = parseDate(f.due); err != nil {
+		return fields, err
+	}
+
+	if fields.CustomFields, err = parseCustomFields(f.custom); err != nil {
+		return fields, err
+	}
+
+	if len(f.watchers) != 0 {
+		if fields.WatcherUserIds, err = a.resolveUsers(f.watchers); err != nil {
+			return fields, err
+		}
+	}
+
+	for _, path := range f.attach {
+		upload, err := uploadFile(a, path)
+		if err != nil {
+			return fields, err
+		}
+		fields.Uploads = append(fields.Uploads, *upload)
+	}
+
+	return fields, nil
+}
+
+func uploadFile(a *app, path string) (*apiutil.Upload, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	return apiutil.UploadFile(a.ctx, a.client, name, mime.TypeByExtension(filepath.Ext(name)), file, a.auth...)
+}
+
+func issuesCreate(a *app, args []string) error {
+	fs := newFlagSet("issues create", "")
+	form := issueForm{}
+	form.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if form.project == "" || form.subject == "" {
+		fs.Usage()
+		return errors.New("-project and -subject are required")
+	}
+
+	fields, err := form.fields(a, fs)
+	if err != nil {
+		return err
+	}
+
+	issue, err := apiutil.CreateIssue(a.ctx, a.client, fields, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "SUBJECT"}}
+	t.add(strconv.Itoa(issue.Id), issue.Subject)
+	return a.render(issue, t)
+}
+
+func issuesUpdate(a *app, args []string) error {
+	fs := newFlagSet("issues update", "<id>")
+	form := issueForm{}
+	form.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("issue id required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fields, err := form.fields(a, fs)
+	if err != nil {
+		return err
+	}
+
+	return updated(a, id, apiutil.UpdateIssue(a.ctx, a.client, id, fields, a.auth...))
+}
+
+func issuesClose(a *app, args []string) error {
+	fs := newFlagSet("issues close", "<id>")
+	status := fs.String("status", "", "closed status ID or name, defaults to the first closed status")
+	note := fs.String("note", "", "note to add")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("issue id required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	statusId := 0
+	if *status != "" {
+		if statusId, err = a.resolveStatus(*status); err != nil {
+			return err
+		}
+	} else {
+		statuses, err := apiutil.Statuses(a.ctx, a.client, a.auth...)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.IsClosed {
+				statusId = s.Id
+				break
+			}
+		}
+		if statusId == 0 {
+			return errors.New("no closed status defined")
+		}
+	}
+
+	fields := apiutil.IssueFields{StatusId: &statusId, Notes: optional(*note)}
+	return updated(a, id, apiutil.UpdateIssue(a.ctx, a.client, id, fields, a.auth...))
+}
+
+func issuesNote(a *app, args []string) error {
+	fs := newFlagSet("issues note", "<id> <text|@file>")
+	private := fs.Bool("private", false, "make the note private")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("issue id and note required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	text, err := textArg(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	fields := apiutil.IssueFields{Notes: &text}
+	if *private {
+		fields.PrivateNotes = private
+	}
+	return updated(a, id, apiutil.UpdateIssue(a.ctx, a.client, id, fields, a.auth...))
+}
+
+// updated reports the result of an update of the issue with id.
+func updated(a *app, id int, err error) error {
+	if err != nil {
+		return err
+	}
+
+	result := map[string]any{"id": id, "updated": true}
+	t := &table{}
+	t.add(fmt.Sprintf("Issue #%d updated.", id))
+	return a.render(result, t)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/issuetree"
+)
+
+// issuesTree prints an issue and its subtasks with the rolled up hours and
+// done ratio, as an indented tree or a Markdown task list. The JSON and
+// YAML outputs nest the subtasks under their parent.
+func issuesTree(a *app, args []string) error {
+	fs := newFlagSet("issues tree", "<id>")
+	markdown := fs.Bool("markdown", false, "print a Markdown task list instead of the indented tree")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("issue id required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	t, err := issuetree.Load(a.ctx, a.client, id, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	if a.format != formatTable && a.format != "" {
+		return a.render(t, nil)
+	}
+	text := t.Text()
+	if *markdown {
+		text = t.Markdown()
+	}
+	_, err = io.WriteString(a.out, text)
+	return err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+var projectStatuses = map[int]string{
+	model.ProjectStatusActive:   "active",
+	model.ProjectStatusClosed:   "closed",
+	model.ProjectStatusArchived: "archived",
+}
+
+var userStatuses = map[int]string{
+	model.UserStatusActive:     "active",
+	model.UserStatusRegistered: "registered",
+	model.UserStatusLocked:     "locked",
+}
+
+func projectsList(a *app, args []string) error {
+	fs := newFlagSet("projects list", "")
+	status := fs.String("status", "", "status IDs separated by |: 1 active, 5 closed, 9 archived")
+	parent := fs.String("parent", "", "parent project ID")
+	name := fs.String("name", "", "name expression, e.g. ~text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := redmine.ProjectsIndexParams_Query{
+		Status:   optional(*status),
+		ParentId: optional(*parent),
+		Name:     optional(*name),
+	}
+
+	projects, err := apiutil.Projects(a.ctx, a.client, &redmine.ProjectsIndexParams{Query: &q}, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "IDENTIFIER", "NAME", "PARENT", "STATUS", "PUBLIC"}}
+	for _, p := range projects {
+		t.add(
+			strconv.Itoa(p.Id),
+			p.Identifier,
+			p.Name,
+			refName(p.Parent),
+			projectStatuses[p.Status],
+			strconv.FormatBool(p.IsPublic))
+	}
+
+	return a.render(projects, t)
+}
+
+func versionsList(a *app, args []string) error {
+	fs := newFlagSet("versions list", "")
+	project := fs.String("project", "", "project ID or identifier")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *project == "" {
+		fs.Usage()
+		return errors.New("-project is required")
+	}
+
+	versions, err := apiutil.Versions(a.ctx, a.client, *project, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "NAME", "PROJECT", "STATUS", "DUE", "SHARING"}}
+	for _, v := range versions {
+		t.add(strconv.Itoa(v.Id), v.Name, refName(v.Project), v.Status, fmtDate(v.DueDate), v.Sharing)
+	}
+
+	return a.render(versions, t)
+}
+
+func usersList(a *app, args []string) error {
+	fs := newFlagSet("users list", "")
+	status := fs.String("status", "", "status: 1 active, 2 registered, 3 locked, * all")
+	name := fs.String("name", "", "login, name or mail expression, e.g. ~text")
+	group := fs.String("group", "", "group IDs separated by |")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := redmine.UsersIndexParams_Query{
+		Status:          optional(*status),
+		Name:            optional(*name),
+		IsMemberOfGroup: optional(*group),
+	}
+
+	users, err := apiutil.Users(a.ctx, a.client, &redmine.UsersIndexParams{Query: &q}, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "LOGIN", "NAME", "MAIL", "STATUS", "ADMIN"}}
+	for _, u := range users {
+		t.add(strconv.Itoa(u.Id), u.Login, u.Name(), u.Mail, userStatuses[u.Status], strconv.FormatBool(u.Admin))
+	}
+
+	return a.render(users, t)
+}
+
+func wikiList(a *app, args []string) error {
+	fs := newFlagSet("wiki list", "")
+	project := fs.String("project", "", "project ID or identifier")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *project == "" {
+		fs.Usage()
+		return errors.New("-project is required")
+	}
+
+	pages, err := apiutil.WikiPages(a.ctx, a.client, *project, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TITLE", "PARENT", "VERSION", "UPDATED"}}
+	for _, p := range pages {
+		t.add(p.Title, p.ParentTitle(), strconv.Itoa(p.Version), fmtTime(p.UpdatedOn))
+	}
+
+	return a.render(pages, t)
+}
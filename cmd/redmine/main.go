@@ -0,0 +1,250 @@
+// Command redmine is a command-line client for the Redmine REST API.
+//
+// Usage:
+//
+//	redmine [-profile name] [-config path] [-o table|json|yaml] <resource> <action> [flags] [args]
+//
+// Run `redmine help` for the list of resources and actions.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// app is the state shared by every command.
+type app struct {
+	ctx    context.Context
+	client redmine.ClientWithResponsesInterface
+	auth   []redmine.RequestEditorFn
+	out    io.Writer
+	format string
+
+	// config and http create the clients of other profiles.
+	config *Config
+	http   *http.Client
+
+	// raw streams the responses that client reads in memory.
+	raw redmine.ClientInterface
+}
+
+// render writes data in the selected output format.
+func (a *app) render(data any, t *table) error {
+	return render(a.out, a.format, data, t)
+}
+
+// command is an action on a resource.
+type command struct {
+	usage string
+	run   func(a *app, args []string) error
+}
+
+var commands = map[string]map[string]command{
+	"issues": {
+		"list":        {"list issues", issuesList},
+		"show":        {"show an issue with its history", issuesShow},
+		"create":      {"create an issue", issuesCreate},
+		"update":      {"update an issue", issuesUpdate},
+		"close":       {"close an issue", issuesClose},
+		"note":        {"add a note to an issue", issuesNote},
+		"template":    {"create the issues of a template file", issuesTemplate},
+		"import":      {"create and update issues from a CSV file", issuesImport},
+		"copy":        {"copy issues to another project or server", issuesCopy},
+		"move":        {"move issues to another project or server", issuesMove},
+		"bulk-update": {"change the issues matching filters", issuesBulkUpdate},
+		"tree":        {"print an issue with its subtasks and rollups", issuesTree},
+		"graph":       {"print the dependencies between issues", issuesGraph},
+		"gantt":       {"draw the timeline of issues in SVG or Mermaid", issuesGantt},
+	},
+	"time": {
+		"list": {"list time entries", timeList},
+		"log":  {"log time on an issue or a project", timeLog},
+	},
+	"watchers": {
+		"add":    {"add watchers to an issue", watchersAdd},
+		"remove": {"remove watchers from an issue", watchersRemove},
+	},
+	"relations": {
+		"list":   {"list the relations of an issue", relationsList},
+		"add":    {"relate an issue to another", relationsAdd},
+		"remove": {"delete a relation", relationsRemove},
+	},
+	"projects": {
+		"list":  {"list projects", projectsList},
+		"clone": {"create a project from another one", projectsClone},
+		"tree":  {"print the hierarchy of projects", projectsTree},
+	},
+	"versions": {
+		"list":     {"list the versions of a project", versionsList},
+		"burndown": {"compute the burndown or burnup of a version as CSV, SVG or PNG", versionsBurndown},
+	},
+	"users": {
+		"list": {"list users", usersList},
+		"sync": {"create, update and lock users and set group members from a roster", usersSync},
+	},
+	"access": {
+		"report": {"list the effective roles and permissions of the users", accessReport},
+		"can":    {"tell whether a user has a permission in a project", accessCan},
+		"matrix": {"print the roles by permissions or the users by projects", accessMatrix},
+	},
+	"csv": {
+		"issues":   {"parse the CSV export of issues", csvIssues},
+		"time":     {"parse the CSV export of time entries", csvTime},
+		"users":    {"parse the CSV export of users", csvUsers},
+		"projects": {"parse the CSV export of projects", csvProjects},
+	},
+	"flow": {
+		"issues":     {"print the time in status, lead time and cycle time of issues", flowIssues},
+		"summary":    {"print the percentiles of the flow metrics by group", flowSummary},
+		"throughput": {"print the number of issues closed per week", flowThroughput},
+		"wip":        {"print the number of issues in progress per day", flowWIP},
+	},
+	"rules": {
+		"run": {"run housekeeping rules over the issues, once or on a schedule", rulesRun},
+	},
+	"events": {
+		"watch": {"poll for issue changes and print or forward them", eventsWatch},
+	},
+	"wiki": {
+		"list":    {"list the wiki pages of a project", wikiList},
+		"export":  {"export the wiki of a project to a directory", wikiExport},
+		"publish": {"save a directory of pages to the wiki of a project", wikiPublish},
+	},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: redmine [-profile name] [-config path] [-o table|json|yaml] <resource> <action> [flags] [args]")
+	fmt.Fprintln(w)
+
+	resources := make([]string, 0, len(commands))
+	for r := range commands {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+
+	for _, r := range resources {
+		actions := make([]string, 0, len(commands[r]))
+		for a := range commands[r] {
+			actions = append(actions, a)
+		}
+		sort.Strings(actions)
+
+		for _, a := range actions {
+			fmt.Fprintf(w, "  %-20s %s\n", r+" "+a, commands[r][a].usage)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `redmine <resource> <action> -h` for the flags of an action.")
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "redmine: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("redmine", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr) }
+
+	profile := fs.String("profile", os.Getenv("REDMINE_PROFILE"), "profile `name` in the configuration file")
+	configPath := fs.String("config", defaultConfigPath(), "configuration file `path`")
+	format := fs.String("o", formatTable, "output `format`: table, json or yaml")
+	timeout := fs.Duration("timeout", 60*time.Second, "HTTP request timeout")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		usage(stdout)
+		return nil
+	}
+
+	if fs.NArg() < 2 {
+		usage(stderr)
+		return fmt.Errorf("missing action for %q", fs.Arg(0))
+	}
+
+	actions, ok := commands[fs.Arg(0)]
+	if !ok {
+		usage(stderr)
+		return fmt.Errorf("unknown resource %q", fs.Arg(0))
+	}
+
+	cmd, ok := actions[fs.Arg(1)]
+	if !ok {
+		usage(stderr)
+		return fmt.Errorf("unknown action %q for %q", fs.Arg(1), fs.Arg(0))
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	p, err := cfg.Profile(*profile)
+	if err != nil {
+		return err
+	}
+
+	hc := http.Client{Timeout: *timeout}
+	c, err := redmine.NewClientWithResponses(p.URL, redmine.WithHTTPClient(&hc))
+	if err != nil {
+		return err
+	}
+
+	a := &app{
+		ctx:    context.Background(),
+		client: c,
+		auth:   p.Auth(),
+		out:    stdout,
+		format: *format,
+		config: cfg,
+		http:   &hc,
+		raw:    c.ClientInterface,
+	}
+
+	err = cmd.run(a, fs.Args()[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// newFlagSet returns the flag set of an action.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: redmine %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// multiFlag is a flag that can be repeated.
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newServer(t *testing.T, h http.HandlerFunc) {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("REDMINE_URL", srv.URL)
+	t.Setenv("REDMINE_API_KEY", "secret")
+}
+
+func runCommand(t *testing.T, args ...string) string {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	args = append([]string{"-config", ""}, args...)
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestConfigProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := `
+default: work
+profiles:
+  work:
+    url: https://redmine.example.com
+    api_key: key
+  local:
+    url: http://127.0.0.1:3000
+    username: admin
+    password: admin
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := cfg.Profile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.URL != "https://redmine.example.com" || p.APIKey != "key" {
+		t.Errorf("default profile = %+v", p)
+	}
+
+	t.Setenv("REDMINE_URL", "http://override")
+	p, err = cfg.Profile("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.URL != "http://override" || p.Username != "admin" {
+		t.Errorf("local profile = %+v", p)
+	}
+
+	if _, err := cfg.Profile("missing"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestIssuesListFilters(t *testing.T) {
+	newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Redmine-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		if r.URL.Path != "/issues.json" || q.Get("status_id") != "open" || q.Get("assigned_to_id") != "me" || q.Get("sort") != "id" || q.Get("cf_1") != "x" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+
+		fmt.Fprint(w, `{"issues":[{"id":7,"subject":"Fix it","status":{"id":1,"name":"New"},"tracker":{"id":1,"name":"Bug"}}],"total_count":1}`)
+	})
+
+	out := runCommand(t, "issues", "list", "-status", "open", "-assignee", "me", "-sort", "id", "-f", "cf_1=x")
+	if !strings.Contains(out, "Fix it") || !strings.HasPrefix(out, "ID") {
+		t.Errorf("output = %q", out)
+	}
+
+	out = runCommand(t, "-o", "json", "issues", "list", "-status", "open", "-assignee", "me", "-sort", "id", "-f", "cf_1=x")
+	var issues []map[string]any
+	if err := json.Unmarshal([]byte(out), &issues); err != nil || len(issues) != 1 || issues[0]["id"].(float64) != 7 {
+		t.Errorf("output = %q, err = %v", out, err)
+	}
+
+	out = runCommand(t, "-o", "yaml", "issues", "list", "-status", "open", "-assignee", "me", "-sort", "id", "-f", "cf_1=x")
+	if !strings.Contains(out, "subject: Fix it") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestIssuesClose(t *testing.T) {
+	newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/issue_statuses.json":
+			fmt.Fprint(w, `{"issue_statuses":[{"id":1,"name":"New"},{"id":5,"name":"Closed","is_closed":true}]}`)
+		case r.URL.Path == "/issues/3.json" && r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"issue":{"status_id":5,"notes":"done"}}` {
+				t.Errorf("body = %s", body)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	})
+
+	out := runCommand(t, "issues", "close", "-note", "done", "3")
+	if !strings.Contains(out, "Issue #3 updated.") {
+		t.Errorf("output = %q", out)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// table is the tabular representation of a result.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// render writes data in format. t is only used for the table format.
+func render(w io.Writer, format string, data any, t *table) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case formatYAML:
+		// Going through JSON keeps the field names identical to the JSON output.
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		var v any
+		if err := json.Unmarshal(buf, &v); err != nil {
+			return err
+		}
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case formatTable, "":
+		return writeTable(w, t)
+	}
+
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func writeTable(w io.Writer, t *table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(t.header) != 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			// Tabs and newlines would break the alignment.
+			cells[i] = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ").Replace(c)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func refName(r *model.Ref) string {
+	if r == nil {
+		return ""
+	}
+	return r.Name
+}
+
+func statusName(s *model.Status) string {
+	if s == nil {
+		return ""
+	}
+	return s.Name
+}
+
+func fmtTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func fmtDate(d *openapi_types.Date) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func fmtHours(h *float64) string {
+	if h == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*h, 'f', 2, 64)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+func (a *app) resolveStatus(v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	statuses, err := apiutil.Statuses(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return 0, err
+	}
+	return apiutil.FindByName("status", v, statuses, func(s model.Status) (int, string) { return s.Id, s.Name })
+}
+
+func (a *app) resolveTracker(v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	trackers, err := apiutil.Trackers(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return 0, err
+	}
+	return apiutil.FindByName("tracker", v, trackers, func(t model.Tracker) (int, string) { return t.Id, t.Name })
+}
+
+func (a *app) resolvePriority(v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	priorities, err := apiutil.Priorities(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return 0, err
+	}
+	return apiutil.FindByName("priority", v, priorities, func(r model.Ref) (int, string) { return r.Id, r.Name })
+}
+
+func (a *app) resolveVersion(project, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	if project == "" {
+		return 0, fmt.Errorf("version %q given by name requires -project", v)
+	}
+
+	versions, err := apiutil.Versions(a.ctx, a.client, project, a.auth...)
+	if err != nil {
+		return 0, err
+	}
+	return apiutil.FindByName("version", v, versions, func(r model.Version) (int, string) { return r.Id, r.Name })
+}
+
+// resolveUser accepts a user ID, `me` or a login.
+func (a *app) resolveUser(v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	if v == "me" {
+		u, err := apiutil.CurrentUser(a.ctx, a.client, a.auth...)
+		if err != nil {
+			return 0, err
+		}
+		return u.Id, nil
+	}
+
+	users, err := apiutil.Users(a.ctx, a.client, nil, a.auth...)
+	if err != nil {
+		return 0, err
+	}
+	return apiutil.FindByName("user", v, users, func(u model.User) (int, string) { return u.Id, u.Login })
+}
+
+func (a *app) resolveUsers(values []string) ([]int, error) {
+	ids := []int{}
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			id, err := a.resolveUser(strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func parseDate(v string) (*openapi_types.Date, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	if v == "today" {
+		v = time.Now().Format(openapi_types.DateFormat)
+	}
+
+	t, err := time.Parse(openapi_types.DateFormat, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", v)
+	}
+
+	return &openapi_types.Date{Time: t}, nil
+}
+
+// parseCustomFields parses `id=value` pairs. Repeating an ID sets a list.
+func parseCustomFields(values []string) ([]apiutil.CustomFieldValue, error) {
+	order := []int{}
+	byId := map[int][]string{}
+	for _, v := range values {
+		k, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid custom field %q, expected id=value", v)
+		}
+
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom field id %q", k)
+		}
+
+		if _, ok := byId[id]; !ok {
+			order = append(order, id)
+		}
+		byId[id] = append(byId[id], value)
+	}
+
+	fields := []apiutil.CustomFieldValue{}
+	for _, id := range order {
+		if len(byId[id]) == 1 {
+			fields = append(fields, apiutil.CustomFieldValue{Id: id, Value: byId[id][0]})
+		} else {
+			fields = append(fields, apiutil.CustomFieldValue{Id: id, Value: byId[id]})
+		}
+	}
+
+	return fields, nil
+}
+
+func parseIssueId(v string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(v, "#"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid issue id %q", v)
+	}
+	return id, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/roster"
+)
+
+// usersSync prints the changes making the users and the groups match a
+// roster file, and applies them with -apply.
+func usersSync(a *app, args []string) error {
+	fs := newFlagSet("users sync", "<roster.yaml|roster.csv>")
+	apply := fs.Bool("apply", false, "apply the changes instead of printing the plan")
+	lockMissing := fs.Bool("lock-missing", false, "lock the users missing from the roster, except the administrators and yourself")
+	audit := fs.String("audit", "", "append the audit report of -apply to `file`")
+	var protected multiFlag
+	fs.Var(&protected, "protect", "`login` of an account never changed, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("roster file required")
+	}
+
+	r, err := roster.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	r.Protected = append(r.Protected, protected...)
+	r.LockMissing = r.LockMissing || *lockMissing
+
+	s := &roster.Syncer{Client: a.client, Auth: a.auth}
+	plan, err := s.Plan(a.ctx, r)
+	if err != nil {
+		return err
+	}
+
+	if !*apply {
+		t := &table{header: []string{"ACTION", "LOGIN", "GROUP", "CHANGES"}}
+		for _, act := range plan.Actions {
+			t.add(act.Kind, act.Login, act.Group, fmtChanges(act.Changes))
+		}
+		return a.render(plan, t)
+	}
+
+	report := s.Apply(a.ctx, plan)
+	if *audit != "" {
+		f, err := os.OpenFile(*audit, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		if err := report.Audit(f); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	t := &table{header: []string{"ACTION", "LOGIN", "GROUP", "CHANGES", "APPLIED", "ERROR"}}
+	for _, act := range report.Actions {
+		t.add(act.Kind, act.Login, act.Group, fmtChanges(act.Changes), strconv.FormatBool(act.Applied), act.Error)
+	}
+	if err := a.render(report, t); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d actions failed", report.Failed, len(report.Actions))
+	}
+	return nil
+}
+
+func fmtChanges(changes []roster.FieldChange) string {
+	s := ""
+	for i, c := range changes {
+		if i > 0 {
+			s += ", "
+		}
+		if c.From == "" {
+			s += c.Field + "=" + c.To
+		} else {
+			s += c.Field + ": " + c.From + " -> " + c.To
+		}
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/roster"
+	"github.com/9506hqwy/redmine-client-go/pkg/rules"
+)
+
+// rulesRun runs the rules of a file once, or every -every until
+// interrupted. With -simulate, the matching issues and their changes are
+// printed but not applied.
+func rulesRun(a *app, args []string) error {
+	fs := newFlagSet("rules run", "<rules.yaml>")
+	simulate := fs.Bool("simulate", false, "report the matching issues without changing them")
+	every := fs.Duration("every", 0, "run the rules again after each `interval` until interrupted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("rules file required")
+	}
+
+	c, err := rules.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	e := &rules.Engine{Client: a.client, Auth: a.auth, Simulate: *simulate}
+
+	if *every <= 0 {
+		results, err := e.Run(a.ctx, c)
+		if err != nil {
+			return err
+		}
+		if err := a.render(results, rulesTable(results)); err != nil {
+			return err
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d changes failed", failed, len(results))
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(a.ctx, os.Interrupt)
+	defer stop()
+
+	err = e.Schedule(ctx, c, *every, func(results []rules.Result, err error) {
+		if err != nil {
+			logError(err)
+			return
+		}
+		if err := a.render(results, rulesTable(results)); err != nil {
+			logError(err)
+		}
+	})
+	if errors.Is(err, ctx.Err()) && a.ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+func rulesTable(results []rules.Result) *table {
+	t := &table{header: []string{"RULE", "ID", "SUBJECT", "CHANGES", "APPLIED", "ERROR"}}
+	for _, r := range results {
+		changes := make([]roster.FieldChange, len(r.Changes))
+		for i, c := range r.Changes {
+			changes[i] = roster.FieldChange(c)
+		}
+		applied := strconv.FormatBool(r.Applied)
+		if r.FiredOn != nil {
+			applied = "fired " + r.FiredOn.Format("2006-01-02")
+		}
+		t.add(r.Rule, strconv.Itoa(r.Id), truncate(r.Subject, 40), truncate(fmtChanges(changes), 60), applied, r.Error)
+	}
+	return t
+}
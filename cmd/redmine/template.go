@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/template"
+)
+
+// issuesTemplate creates the issues of a template file, with the variables
+// given as -var name=value.
+func issuesTemplate(a *app, args []string) error {
+	fs := newFlagSet("issues template", "<file>")
+	project := fs.String("project", "", "project ID or identifier, over the one of the template")
+	var vars multiFlag
+	fs.Var(&vars, "var", "template variable `name=value`, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("template file required")
+	}
+
+	t, err := template.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	in := &template.Instantiator{Client: a.client, Auth: a.auth, Project: *project, Vars: map[string]string{}}
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("invalid variable %q, expected name=value", v)
+		}
+		in.Vars[name] = value
+	}
+
+	created, err := in.Instantiate(a.ctx, t)
+	if err != nil {
+		return err
+	}
+
+	tbl := &table{header: []string{"ID", "PARENT", "KEY", "SUBJECT"}}
+	for _, c := range created {
+		parent := ""
+		if c.Parent != 0 {
+			parent = strconv.Itoa(c.Parent)
+		}
+		tbl.add(strconv.Itoa(c.Id), parent, c.Key, c.Subject)
+	}
+	return a.render(created, tbl)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+func timeList(a *app, args []string) error {
+	fs := newFlagSet("time list", "")
+	project := fs.String("project", "", "project ID or identifier")
+	issue := fs.String("issue", "", "issue ID")
+	user := fs.String("user", "", "user IDs separated by |, or me")
+	activity := fs.String("activity", "", "activity IDs separated by |")
+	from := fs.String("from", "", "first day YYYY-MM-DD")
+	to := fs.String("to", "", "last day YYYY-MM-DD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := redmine.TimelogIndexParams_Query{
+		ProjectId:  optional(*project),
+		IssueId:    optional(*issue),
+		UserId:     optional(*user),
+		ActivityId: optional(*activity),
+	}
+
+	switch {
+	case *from != "" && *to != "":
+		q.SpentOn = optional("><" + *from + "|" + *to)
+	case *from != "":
+		q.SpentOn = optional(">=" + *from)
+	case *to != "":
+		q.SpentOn = optional("<=" + *to)
+	}
+
+	entries, err := apiutil.TimeEntries(a.ctx, a.client, &redmine.TimelogIndexParams{Query: &q}, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	total := 0.0
+	t := &table{header: []string{"ID", "DATE", "USER", "ACTIVITY", "PROJECT", "ISSUE", "HOURS", "COMMENTS"}}
+	for _, e := range entries {
+		issue := ""
+		if e.IssueId() != 0 {
+			issue = "#" + strconv.Itoa(e.IssueId())
+		}
+		t.add(
+			strconv.Itoa(e.Id),
+			fmtDate(e.SpentOn),
+			refName(e.User),
+			refName(e.Activity),
+			refName(e.Project),
+			issue,
+			fmtHours(&e.Hours),
+			truncate(e.Comments, 60))
+		total += e.Hours
+	}
+	t.add("", "", "", "", "", "Total", fmtHours(&total), "")
+
+	return a.render(entries, t)
+}
+
+func timeLog(a *app, args []string) error {
+	fs := newFlagSet("time log", "")
+	issue := fs.Int("issue", 0, "issue ID")
+	project := fs.String("project", "", "project ID or identifier, when not logging on an issue")
+	hours := fs.Float64("hours", 0, "spent hours")
+	activity := fs.Int("activity", 0, "activity ID, defaults to the default activity")
+	comment := fs.String("comment", "", "comment")
+	date := fs.String("date", "", "day YYYY-MM-DD, defaults to today")
+	user := fs.String("user", "", "user ID or login to log time for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *hours <= 0 || (*issue == 0 && *project == "") {
+		fs.Usage()
+		return errors.New("-hours and either -issue or -project are required")
+	}
+
+	fields := apiutil.TimeEntryFields{
+		ProjectId: *project,
+		Hours:     *hours,
+		Comments:  *comment,
+	}
+	if *issue != 0 {
+		fields.IssueId = issue
+	}
+	if *activity != 0 {
+		fields.ActivityId = activity
+	}
+
+	var err error
+	if fields.SpentOn, err = parseDate(*date); err != nil {
+		return err
+	}
+
+	if *user != "" {
+		id, err := a.resolveUser(*user)
+		if err != nil {
+			return err
+		}
+		fields.UserId = &id
+	}
+
+	entry, err := apiutil.CreateTimeEntry(a.ctx, a.client, fields, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "DATE", "HOURS"}}
+	t.add(strconv.Itoa(entry.Id), fmtDate(entry.SpentOn), fmtHours(&entry.Hours))
+	return a.render(entry, t)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/projecttree"
+)
+
+// projectsTree prints the hierarchy of projects, or of the subprojects of
+// -root, as an indented tree or a Mermaid flowchart. The JSON and YAML
+// outputs nest the subprojects under their parent.
+func projectsTree(a *app, args []string) error {
+	fs := newFlagSet("projects tree", "")
+	root := fs.String("root", "", "ID or identifier of the top project, every project if empty")
+	status := fs.String("status", "", "statuses kept, separated by commas: active, closed, archived")
+	mermaid := fs.Bool("mermaid", false, "print a Mermaid flowchart instead of the indented tree")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	t, err := projecttree.Load(a.ctx, a.client, a.auth...)
+	if err != nil {
+		return err
+	}
+	if *root != "" {
+		n := t.Find(*root)
+		if n == nil {
+			return fmt.Errorf("project %q not found", *root)
+		}
+		t = n.Subtree()
+	}
+	if *status != "" {
+		statuses := []int{}
+		for _, s := range strings.Split(*status, ",") {
+			id := 0
+			for k, v := range projectStatuses {
+				if v == strings.TrimSpace(s) {
+					id = k
+				}
+			}
+			if id == 0 {
+				return fmt.Errorf("unknown project status %q", s)
+			}
+			statuses = append(statuses, id)
+		}
+		t = t.Filter(statuses...)
+	}
+
+	if a.format != formatTable && a.format != "" {
+		return a.render(t, nil)
+	}
+	text := t.Text()
+	if *mermaid {
+		text = t.Mermaid()
+	}
+	_, err = io.WriteString(a.out, text)
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+)
+
+func watchersAdd(a *app, args []string) error {
+	fs := newFlagSet("watchers add", "<issue> <user>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("issue id and users required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	users, err := a.resolveUsers(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := apiutil.AddWatchers(a.ctx, a.client, id, users, a.auth...); err != nil {
+		return err
+	}
+
+	return updated(a, id, nil)
+}
+
+func watchersRemove(a *app, args []string) error {
+	fs := newFlagSet("watchers remove", "<issue> <user>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("issue id and users required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	users, err := a.resolveUsers(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := apiutil.RemoveWatcher(a.ctx, a.client, id, user, a.auth...); err != nil {
+			return err
+		}
+	}
+
+	return updated(a, id, nil)
+}
+
+func relationsList(a *app, args []string) error {
+	fs := newFlagSet("relations list", "<issue>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("issue id required")
+	}
+
+	id, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	relations, err := apiutil.Relations(a.ctx, a.client, id, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "ISSUE", "TYPE", "ISSUE TO", "DELAY"}}
+	for _, r := range relations {
+		delay := ""
+		if r.Delay != nil {
+			delay = strconv.Itoa(*r.Delay)
+		}
+		t.add(strconv.Itoa(r.Id), "#"+strconv.Itoa(r.IssueId), r.RelationType, "#"+strconv.Itoa(r.IssueToId), delay)
+	}
+
+	return a.render(relations, t)
+}
+
+func relationsAdd(a *app, args []string) error {
+	fs := newFlagSet("relations add", "<issue> <issue to>")
+	relationType := fs.String("type", "relates", "relation type: relates, duplicates, duplicated, blocks, blocked, precedes, follows, copied_to or copied_from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("two issue ids required")
+	}
+
+	from, err := parseIssueId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	to, err := parseIssueId(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	r, err := apiutil.CreateRelation(a.ctx, a.client, from, to, *relationType, a.auth...)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "ISSUE", "TYPE", "ISSUE TO"}}
+	t.add(strconv.Itoa(r.Id), "#"+strconv.Itoa(r.IssueId), r.RelationType, "#"+strconv.Itoa(r.IssueToId))
+	return a.render(r, t)
+}
+
+func relationsRemove(a *app, args []string) error {
+	fs := newFlagSet("relations remove", "<relation>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("relation id required")
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid relation id %q", fs.Arg(0))
+	}
+
+	if err := apiutil.DeleteRelation(a.ctx, a.client, id, a.auth...); err != nil {
+		return err
+	}
+
+	t := &table{}
+	t.add(fmt.Sprintf("Relation %d deleted.", id))
+	return a.render(map[string]any{"id": id, "deleted": true}, t)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/wiki"
+)
+
+// wikiExport writes the wiki of a project to a directory tree.
+func wikiExport(a *app, args []string) error {
+	fs := newFlagSet("wiki export", "")
+	project := fs.String("project", "", "project ID or identifier")
+	dir := fs.String("dir", ".", "destination directory `path`")
+	format := fs.String("format", string(wiki.FormatTextile), "text formatting of the wiki: textile, markdown or common_mark")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *project == "" {
+		fs.Usage()
+		return errors.New("-project is required")
+	}
+
+	e := &wiki.Exporter{Client: a.client, Auth: a.auth, Project: *project, Format: wiki.Format(*format)}
+	pages, err := e.Export(a.ctx, *dir)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TITLE", "DIR", "VERSION", "ATTACHMENTS"}}
+	for _, p := range pages {
+		t.add(p.Title, p.Dir, strconv.Itoa(p.Version), strconv.Itoa(len(p.Attachments)))
+	}
+
+	return a.render(pages, t)
+}
+
+// wikiPublish saves a directory tree of pages to the wiki of a project. In
+// dry-run mode the changes are printed as diffs after the table, and the
+// conflicting regions of the pages which could not be merged are listed.
+func wikiPublish(a *app, args []string) error {
+	fs := newFlagSet("wiki publish", "")
+	project := fs.String("project", "", "project ID or identifier")
+	dir := fs.String("dir", ".", "source directory `path`")
+	format := fs.String("format", string(wiki.FormatTextile), "text formatting of the wiki: textile, markdown or common_mark")
+	comments := fs.String("comments", "", "comment of the saved versions")
+	dryRun := fs.Bool("dry-run", false, "only show what would change")
+	merge := fs.Bool("merge", false, "merge the changes made on the server instead of reporting conflicts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *project == "" {
+		fs.Usage()
+		return errors.New("-project is required")
+	}
+
+	p := &wiki.Publisher{
+		Client:   a.client,
+		Auth:     a.auth,
+		Project:  *project,
+		Format:   wiki.Format(*format),
+		Comments: *comments,
+		DryRun:   *dryRun,
+		Merge:    *merge,
+	}
+	results, err := p.Publish(a.ctx, *dir)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TITLE", "ACTION", "VERSION", "UPLOADS"}}
+	conflicts := 0
+	for _, r := range results {
+		t.add(r.Title, string(r.Action), strconv.Itoa(r.Version), strings.Join(r.Uploads, ","))
+		if r.Action == wiki.ActionConflict {
+			conflicts++
+		}
+	}
+	if err := a.render(results, t); err != nil {
+		return err
+	}
+
+	if a.format == formatTable || a.format == "" {
+		for _, r := range results {
+			if *dryRun && r.Action != wiki.ActionUnchanged && r.Diff != "" {
+				fmt.Fprintf(a.out, "\n%s", r.Diff)
+			}
+			for _, c := range r.Conflicts {
+				fmt.Fprintf(a.out, "\n%s: conflict at line %d\n", r.Title, c.BaseLine)
+			}
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d pages changed on the server since the last sync", conflicts)
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+// Package access computes the effective roles and permissions of the users
+// in the projects, as Redmine grants them: through the memberships of the
+// users, of their groups, and of the parent projects whose members are
+// inherited. It answers whether a user has a permission in a project and
+// exports the matrices of the roles and of the memberships in CSV or HTML.
+//
+// The permissions of the built-in Non member and Anonymous roles on public
+// projects are not reported, as the API does not list them.
+package access
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Sources of grants.
+const (
+	SourceDirect = "direct"
+	SourceGroup  = "group"
+	SourceParent = "parent"
+	SourceAdmin  = "admin"
+)
+
+// Grant is a role of a user in a project with where it comes from.
+type Grant struct {
+	RoleId int    `json:"role_id,omitempty"`
+	Role   string `json:"role,omitempty"`
+
+	// Source How the role is granted: direct, group, parent, or admin for
+	// the administrators, which have every permission without role.
+	Source string `json:"source"`
+
+	// Via The name of the group or the identifier of the parent project
+	// granting the role.
+	Via string `json:"via,omitempty"`
+}
+
+// String returns the role with its source, such as "Developer (group
+// Devs)".
+func (g Grant) String() string {
+	switch g.Source {
+	case SourceDirect:
+		return g.Role
+	case SourceAdmin:
+		return "administrator"
+	}
+	return fmt.Sprintf("%s (%s %s)", g.Role, g.Source, g.Via)
+}
+
+// Membership is the effective membership of a user in a project.
+type Membership struct {
+	UserId    int    `json:"user_id"`
+	Login     string `json:"login"`
+	ProjectId int    `json:"project_id"`
+	Project   string `json:"project"`
+
+	Grants []Grant `json:"grants"`
+
+	// Permissions The permissions of the roles whose module is enabled in
+	// the project, sorted.
+	Permissions []string `json:"permissions"`
+}
+
+// Report is the effective memberships of the users in the projects.
+type Report struct {
+	Users    []model.User    `json:"users"`
+	Projects []model.Project `json:"projects"`
+	Roles    []model.Role    `json:"roles"`
+
+	// Memberships The effective memberships, sorted by login and project
+	// identifier.
+	Memberships []Membership `json:"memberships"`
+}
+
+// Load fetches the active users, the groups with their users, the projects
+// with their modules, the roles with their permissions and the memberships
+// of every project, and computes the report. It requires an administrator.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, auth ...redmine.RequestEditorFn) (*Report, error) {
+	users, err := apiutil.Users(ctx, c, nil, auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := apiutil.Groups(ctx, c, auth...)
+	if err != nil {
+		return nil, err
+	}
+	for i, g := range groups {
+		group, err := apiutil.Group(ctx, c, g.Id, []string{"users"}, auth...)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = *group
+	}
+
+	// The closed projects still grant the permissions that only read.
+	include := []string{"enabled_modules"}
+	notArchived := "!" + strconv.Itoa(model.ProjectStatusArchived)
+	q := redmine.ProjectsIndexParams_Query{Status: &notArchived}
+	projects, err := apiutil.Projects(ctx, c, &redmine.ProjectsIndexParams{Query: &q, Include: &include}, auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := apiutil.Roles(ctx, c, auth...)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]model.Role, len(refs))
+	for i, r := range refs {
+		role, err := apiutil.Role(ctx, c, r.Id, auth...)
+		if err != nil {
+			return nil, err
+		}
+		roles[i] = *role
+	}
+
+	memberships := []model.Membership{}
+	for _, p := range projects {
+		ms, err := apiutil.Memberships(ctx, c, strconv.Itoa(p.Id), auth...)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, ms...)
+	}
+
+	return New(users, groups, projects, roles, memberships), nil
+}
+
+// New computes the effective memberships of users from the memberships of
+// the projects. The groups must include their users. The roles inherited
+// by the memberships are ignored, as the groups and the parent projects are
+// expanded here. The archived projects and the unknown users are skipped.
+func New(users []model.User, groups []model.Group, projects []model.Project, roles []model.Role, memberships []model.Membership) *Report {
+	r := &Report{Users: users, Projects: projects, Roles: roles, Memberships: []Membership{}}
+
+	usersById := map[int]*model.User{}
+	for i := range users {
+		usersById[users[i].Id] = &users[i]
+	}
+	groupsById := map[int]*model.Group{}
+	for i := range groups {
+		groupsById[groups[i].Id] = &groups[i]
+	}
+	projectsById := map[int]*model.Project{}
+	for i := range projects {
+		projectsById[projects[i].Id] = &projects[i]
+	}
+
+	// grants by project and user.
+	grants := map[int]map[int][]Grant{}
+	add := func(projectId, userId int, g Grant) {
+		if usersById[userId] == nil {
+			return
+		}
+		if grants[projectId] == nil {
+			grants[projectId] = map[int][]Grant{}
+		}
+		if !slices.Contains(grants[projectId][userId], g) {
+			grants[projectId][userId] = append(grants[projectId][userId], g)
+		}
+	}
+
+	for _, m := range memberships {
+		if m.Project == nil {
+			continue
+		}
+		for _, mr := range m.Roles {
+			if mr.Inherited {
+				continue
+			}
+			g := Grant{RoleId: mr.Id, Role: r.roleName(mr)}
+			switch {
+			case m.Group != nil:
+				group := groupsById[m.Group.Id]
+				if group == nil {
+					continue
+				}
+				g.Source, g.Via = SourceGroup, group.Name
+				for _, u := range group.Users {
+					add(m.Project.Id, u.Id, g)
+				}
+			case m.User != nil:
+				g.Source = SourceDirect
+				add(m.Project.Id, m.User.Id, g)
+			}
+		}
+	}
+
+	// The members of a parent are inherited once its own are complete.
+	for _, p := range parentsFirst(projects, projectsById) {
+		if !p.InheritMembers || p.Parent == nil || projectsById[p.Parent.Id] == nil {
+			continue
+		}
+		parent := projectsById[p.Parent.Id]
+		for userId, gs := range grants[parent.Id] {
+			for _, g := range gs {
+				add(p.Id, userId, Grant{RoleId: g.RoleId, Role: g.Role, Source: SourceParent, Via: parent.Identifier})
+			}
+		}
+	}
+
+	for projectId, byUser := range grants {
+		p := projectsById[projectId]
+		if p == nil || p.Status == model.ProjectStatusArchived {
+			continue
+		}
+		for userId, gs := range byUser {
+			r.Memberships = append(r.Memberships, Membership{
+				UserId:      userId,
+				Login:       usersById[userId].Login,
+				ProjectId:   projectId,
+				Project:     p.Identifier,
+				Grants:      gs,
+				Permissions: r.permissions(p, gs),
+			})
+		}
+	}
+	slices.SortFunc(r.Memberships, func(a, b Membership) int {
+		return cmp.Or(cmp.Compare(a.Login, b.Login), cmp.Compare(a.Project, b.Project))
+	})
+	return r
+}
+
+// parentsFirst returns projects ordered so that parents precede their
+// children.
+func parentsFirst(projects []model.Project, byId map[int]*model.Project) []*model.Project {
+	depth := func(p *model.Project) int {
+		d := 0
+		for seen := map[int]bool{}; p.Parent != nil && byId[p.Parent.Id] != nil && !seen[p.Id]; d++ {
+			seen[p.Id] = true
+			p = byId[p.Parent.Id]
+		}
+		return d
+	}
+
+	ordered := make([]*model.Project, len(projects))
+	for i := range projects {
+		ordered[i] = &projects[i]
+	}
+	slices.SortStableFunc(ordered, func(a, b *model.Project) int { return cmp.Compare(depth(a), depth(b)) })
+	return ordered
+}
+
+func (r *Report) role(id int) *model.Role {
+	for i := range r.Roles {
+		if r.Roles[i].Id == id {
+			return &r.Roles[i]
+		}
+	}
+	return nil
+}
+
+func (r *Report) roleName(mr model.MembershipRole) string {
+	if role := r.role(mr.Id); role != nil {
+		return role.Name
+	}
+	return mr.Name
+}
+
+// permissions returns the permissions of the roles of grants whose module is
+// enabled in p, sorted. A closed project only grants those reading.
+func (r *Report) permissions(p *model.Project, grants []Grant) []string {
+	permissions := []string{}
+	for _, g := range grants {
+		role := r.role(g.RoleId)
+		if role == nil {
+			continue
+		}
+		for _, perm := range role.Permissions {
+			if moduleEnabled(p, perm) && (p.Status != model.ProjectStatusClosed || readOnly(perm)) && !slices.Contains(permissions, perm) {
+				permissions = append(permissions, perm)
+			}
+		}
+	}
+	slices.Sort(permissions)
+	return permissions
+}
+
+// moduleEnabled reports whether the module of permission is enabled in p,
+// true if the modules of p are unknown.
+func moduleEnabled(p *model.Project, permission string) bool {
+	module, ok := modules[permission]
+	if !ok || p.EnabledModules == nil {
+		return true
+	}
+	return slices.ContainsFunc(p.EnabledModules, func(m model.Ref) bool { return m.Name == module })
+}
+
+// Membership returns the effective membership of the user in the project,
+// nil if none.
+func (r *Report) Membership(userId, projectId int) *Membership {
+	for i, m := range r.Memberships {
+		if m.UserId == userId && m.ProjectId == projectId {
+			return &r.Memberships[i]
+		}
+	}
+	return nil
+}
+
+// Can reports whether the user with login has permission in the project
+// with the ID or identifier project, with the grants giving it. The error
+// is for an unknown user or project.
+func (r *Report) Can(login, project, permission string) (bool, []Grant, error) {
+	i := slices.IndexFunc(r.Users, func(u model.User) bool { return strings.EqualFold(u.Login, login) })
+	if i < 0 {
+		return false, nil, fmt.Errorf("user %q not found", login)
+	}
+	u := r.Users[i]
+	i = slices.IndexFunc(r.Projects, func(p model.Project) bool {
+		return p.Identifier == project || strconv.Itoa(p.Id) == project
+	})
+	if i < 0 {
+		return false, nil, fmt.Errorf("project %q not found", project)
+	}
+	p := r.Projects[i]
+
+	if p.Status == model.ProjectStatusArchived || !moduleEnabled(&p, permission) {
+		return false, nil, nil
+	}
+	// Even the administrators cannot change a closed project.
+	if p.Status == model.ProjectStatusClosed && !readOnly(permission) {
+		return false, nil, nil
+	}
+	if u.Admin {
+		return true, []Grant{{Source: SourceAdmin}}, nil
+	}
+
+	m := r.Membership(u.Id, p.Id)
+	if m == nil {
+		return false, nil, nil
+	}
+	grants := []Grant{}
+	for _, g := range m.Grants {
+		if role := r.role(g.RoleId); role != nil && slices.Contains(role.Permissions, permission) {
+			grants = append(grants, g)
+		}
+	}
+	return len(grants) > 0, grants, nil
+}
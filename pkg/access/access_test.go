@@ -0,0 +1,160 @@
+package access
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Users = append(f.Users,
+		redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith", Mail: "jsmith@example.net"}},
+		redminetest.User{User: model.User{Id: 3, Login: "alice", Firstname: "Alice", Lastname: "Doe", Mail: "alice@example.net"}},
+		redminetest.User{User: model.User{Id: 4, Login: "bob", Firstname: "Bob", Lastname: "Roe", Mail: "bob@example.net"}},
+	)
+	f.Groups = []model.Group{{Id: 10, Name: "Devs", Users: []model.Ref{{Id: 3}}}}
+	issues, wiki := model.Ref{Name: "issue_tracking"}, model.Ref{Name: "wiki"}
+	f.Projects = []model.Project{
+		{Id: 1, Name: "Web", Identifier: "web", Status: model.ProjectStatusActive, EnabledModules: []model.Ref{issues, wiki}},
+		{Id: 2, Name: "Web API", Identifier: "web-api", Parent: &model.Ref{Id: 1}, InheritMembers: true, Status: model.ProjectStatusActive, EnabledModules: []model.Ref{issues}},
+		{Id: 3, Name: "Ops", Identifier: "ops", Status: model.ProjectStatusActive, EnabledModules: []model.Ref{issues, wiki}},
+		{Id: 4, Name: "Legacy", Identifier: "legacy", Status: model.ProjectStatusClosed, EnabledModules: []model.Ref{issues}},
+	}
+	f.Memberships = []model.Membership{
+		{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 1}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Group: &model.Ref{Id: 10}, Roles: []model.MembershipRole{{Id: 2}}},
+		{Id: 3, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 3}, Roles: []model.MembershipRole{{Id: 2, Inherited: true}}},
+		{Id: 4, Project: &model.Ref{Id: 3}, User: &model.Ref{Id: 4}, Roles: []model.MembershipRole{{Id: 3}}},
+		{Id: 5, Project: &model.Ref{Id: 3}, User: &model.Ref{Id: 3}, Roles: []model.MembershipRole{{Id: 3}}},
+		{Id: 6, Project: &model.Ref{Id: 4}, User: &model.Ref{Id: 4}, Roles: []model.MembershipRole{{Id: 3}}},
+	}
+	return f
+}
+
+func TestLoad(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	r, err := Load(context.Background(), c, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, m := range r.Memberships {
+		grants := []string{}
+		for _, g := range m.Grants {
+			grants = append(grants, g.String())
+		}
+		got = append(got, m.Login+"@"+m.Project+": "+strings.Join(grants, ", "))
+	}
+	want := []string{
+		"alice@ops: Reporter",
+		"alice@web: Developer (group Devs)",
+		"alice@web-api: Developer (parent web)",
+		"bob@legacy: Reporter",
+		"bob@ops: Reporter",
+		"jsmith@web: Manager",
+		"jsmith@web-api: Manager (parent web)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("memberships =\n%s", strings.Join(got, "\n"))
+	}
+
+	m := r.Membership(3, 2)
+	if m == nil || !strings.Contains(strings.Join(m.Permissions, ","), "edit_issues") || strings.Contains(strings.Join(m.Permissions, ","), "wiki") {
+		t.Errorf("alice@web-api = %+v", m)
+	}
+	if m := r.Membership(4, 4); m == nil || strings.Join(m.Permissions, ",") != "view_issue_watchers,view_issues" {
+		t.Errorf("bob@legacy = %+v", m)
+	}
+}
+
+func TestCan(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	r, err := Load(context.Background(), c, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		login, project, permission string
+		want                       bool
+		grants                     string
+	}{
+		{"alice", "web-api", "edit_issues", true, "Developer (parent web)"},
+		{"alice", "web-api", "edit_wiki_pages", false, ""},
+		{"alice", "web", "edit_wiki_pages", true, "Developer (group Devs)"},
+		{"ALICE", "3", "edit_issues", false, ""},
+		{"bob", "web", "view_issues", false, ""},
+		{"jsmith", "web", "manage_members", true, "Manager"},
+		{"admin", "ops", "delete_issues", true, "administrator"},
+		{"bob", "legacy", "view_issues", true, "Reporter"},
+		{"bob", "legacy", "add_issues", false, ""},
+		{"admin", "legacy", "delete_issues", false, ""},
+	}
+	for _, tc := range cases {
+		ok, grants, err := r.Can(tc.login, tc.project, tc.permission)
+		s := []string{}
+		for _, g := range grants {
+			s = append(s, g.String())
+		}
+		if err != nil || ok != tc.want || strings.Join(s, ", ") != tc.grants {
+			t.Errorf("Can(%s, %s, %s) = %v, %v, %v", tc.login, tc.project, tc.permission, ok, s, err)
+		}
+	}
+
+	if _, _, err := r.Can("nobody", "web", "view_issues"); err == nil {
+		t.Error("unknown user")
+	}
+	if _, _, err := r.Can("alice", "nowhere", "view_issues"); err == nil {
+		t.Error("unknown project")
+	}
+}
+
+func TestMatrices(t *testing.T) {
+	r := New(
+		[]model.User{{Id: 1, Login: "admin", Admin: true}, {Id: 2, Login: "jsmith"}},
+		nil,
+		[]model.Project{{Id: 1, Identifier: "web"}, {Id: 2, Identifier: "old", Status: model.ProjectStatusArchived}},
+		[]model.Role{{Id: 1, Name: "Manager", Permissions: []string{"view_issues", "edit_project"}}, {Id: 2, Name: "R&D", Permissions: []string{"view_issues"}}},
+		[]model.Membership{
+			{Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 1}, {Id: 2}}},
+			{Project: &model.Ref{Id: 2}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 1}}},
+		},
+	)
+	if len(r.Memberships) != 1 {
+		t.Errorf("memberships = %+v", r.Memberships)
+	}
+
+	var buf bytes.Buffer
+	if err := r.RoleMatrix().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Permission,Manager,R&D\nedit_project,x,\nview_issues,x,x\n" {
+		t.Errorf("role matrix = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.MembershipMatrix().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "User,web\nadmin (admin),\njsmith,\"Manager, R&D\"\n" {
+		t.Errorf("membership matrix = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.RoleMatrix().WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<tr><th>Permission</th><th>Manager</th><th>R&amp;D</th></tr>\n") ||
+		!strings.Contains(buf.String(), "<tr><th scope=\"row\">view_issues</th><td>x</td><td>x</td></tr>\n") {
+		t.Errorf("html =\n%s", buf.String())
+	}
+}
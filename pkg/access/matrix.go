@@ -0,0 +1,125 @@
+package access
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// Matrix is a table with labeled rows and columns.
+type Matrix struct {
+	Title string `json:"title"`
+
+	// Corner The header of the labels of the rows.
+	Corner string `json:"corner"`
+
+	Columns []string   `json:"columns"`
+	Rows    []string   `json:"rows"`
+	Cells   [][]string `json:"cells"`
+}
+
+// RoleMatrix returns the permissions of the roles: a row per permission,
+// grouped by module, and a column per role, with "x" for the permissions
+// of the role.
+func (r *Report) RoleMatrix() *Matrix {
+	m := &Matrix{Title: "Roles and permissions", Corner: "Permission"}
+	for _, role := range r.Roles {
+		m.Columns = append(m.Columns, role.Name)
+		for _, perm := range role.Permissions {
+			if !slices.Contains(m.Rows, perm) {
+				m.Rows = append(m.Rows, perm)
+			}
+		}
+	}
+	slices.SortFunc(m.Rows, func(a, b string) int { return cmp.Or(cmp.Compare(modules[a], modules[b]), cmp.Compare(a, b)) })
+
+	for _, perm := range m.Rows {
+		row := make([]string, len(r.Roles))
+		for i, role := range r.Roles {
+			if slices.Contains(role.Permissions, perm) {
+				row[i] = "x"
+			}
+		}
+		m.Cells = append(m.Cells, row)
+	}
+	return m
+}
+
+// MembershipMatrix returns the effective roles of the users: a row per user,
+// the administrators marked, and a column per project identifier, with the
+// roles of the user in the project.
+func (r *Report) MembershipMatrix() *Matrix {
+	m := &Matrix{Title: "Effective memberships", Corner: "User"}
+	projects := []model.Project{}
+	for _, p := range r.Projects {
+		if p.Status != model.ProjectStatusArchived {
+			projects = append(projects, p)
+			m.Columns = append(m.Columns, p.Identifier)
+		}
+	}
+
+	for _, u := range r.Users {
+		label := u.Login
+		if u.Admin {
+			label += " (admin)"
+		}
+		m.Rows = append(m.Rows, label)
+
+		row := make([]string, len(projects))
+		for i, p := range projects {
+			ms := r.Membership(u.Id, p.Id)
+			if ms == nil {
+				continue
+			}
+			names := []string{}
+			for _, g := range ms.Grants {
+				if !slices.Contains(names, g.Role) {
+					names = append(names, g.Role)
+				}
+			}
+			row[i] = strings.Join(names, ", ")
+		}
+		m.Cells = append(m.Cells, row)
+	}
+	return m
+}
+
+// WriteCSV writes the matrix as CSV, with the columns in the header.
+func (m *Matrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(append([]string{m.Corner}, m.Columns...))
+	for i, row := range m.Rows {
+		_ = cw.Write(append([]string{row}, m.Cells[i]...))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHTML writes the matrix as a standalone HTML page.
+func (m *Matrix) WriteHTML(w io.Writer) error {
+	b := &strings.Builder{}
+	title := html.EscapeString(m.Title)
+	fmt.Fprintf(b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	b.WriteString("<style>table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:2px 6px}td{text-align:center}th[scope=row]{text-align:left}</style>\n")
+	fmt.Fprintf(b, "</head>\n<body>\n<h1>%s</h1>\n<table>\n<tr><th>%s</th>", title, html.EscapeString(m.Corner))
+	for _, c := range m.Columns {
+		fmt.Fprintf(b, "<th>%s</th>", html.EscapeString(c))
+	}
+	b.WriteString("</tr>\n")
+	for i, row := range m.Rows {
+		fmt.Fprintf(b, "<tr><th scope=\"row\">%s</th>", html.EscapeString(row))
+		for _, cell := range m.Cells[i] {
+			fmt.Fprintf(b, "<td>%s</td>", html.EscapeString(cell))
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
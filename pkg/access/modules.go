@@ -0,0 +1,93 @@
+package access
+
+import (
+	"slices"
+	"strings"
+)
+
+// modules maps the permissions of Redmine to the project module they belong
+// to. A permission missing from the map, such as edit_project or
+// manage_members, does not depend on a module.
+var modules = map[string]string{
+	"manage_categories":      "issue_tracking",
+	"view_issues":            "issue_tracking",
+	"add_issues":             "issue_tracking",
+	"edit_issues":            "issue_tracking",
+	"edit_own_issues":        "issue_tracking",
+	"copy_issues":            "issue_tracking",
+	"manage_issue_relations": "issue_tracking",
+	"manage_subtasks":        "issue_tracking",
+	"set_issues_private":     "issue_tracking",
+	"set_own_issues_private": "issue_tracking",
+	"add_issue_notes":        "issue_tracking",
+	"edit_issue_notes":       "issue_tracking",
+	"edit_own_issue_notes":   "issue_tracking",
+	"view_private_notes":     "issue_tracking",
+	"set_notes_private":      "issue_tracking",
+	"delete_issues":          "issue_tracking",
+	"view_issue_watchers":    "issue_tracking",
+	"add_issue_watchers":     "issue_tracking",
+	"delete_issue_watchers":  "issue_tracking",
+	"import_issues":          "issue_tracking",
+	"save_queries":           "issue_tracking",
+	"manage_public_queries":  "issue_tracking",
+
+	"view_time_entries":         "time_tracking",
+	"log_time":                  "time_tracking",
+	"edit_time_entries":         "time_tracking",
+	"edit_own_time_entries":     "time_tracking",
+	"manage_project_activities": "time_tracking",
+	"log_time_for_other_users":  "time_tracking",
+	"import_time_entries":       "time_tracking",
+
+	"view_news":    "news",
+	"manage_news":  "news",
+	"comment_news": "news",
+
+	"view_documents":   "documents",
+	"add_documents":    "documents",
+	"edit_documents":   "documents",
+	"delete_documents": "documents",
+
+	"view_files":   "files",
+	"manage_files": "files",
+
+	"view_wiki_pages":               "wiki",
+	"view_wiki_edits":               "wiki",
+	"export_wiki_pages":             "wiki",
+	"edit_wiki_pages":               "wiki",
+	"rename_wiki_pages":             "wiki",
+	"delete_wiki_pages":             "wiki",
+	"delete_wiki_pages_attachments": "wiki",
+	"view_wiki_page_watchers":       "wiki",
+	"add_wiki_page_watchers":        "wiki",
+	"delete_wiki_page_watchers":     "wiki",
+	"protect_wiki_pages":            "wiki",
+	"manage_wiki":                   "wiki",
+
+	"view_changesets":       "repository",
+	"browse_repository":     "repository",
+	"commit_access":         "repository",
+	"manage_related_issues": "repository",
+	"manage_repository":     "repository",
+
+	"view_messages":           "boards",
+	"add_messages":            "boards",
+	"edit_messages":           "boards",
+	"edit_own_messages":       "boards",
+	"delete_messages":         "boards",
+	"delete_own_messages":     "boards",
+	"view_message_watchers":   "boards",
+	"add_message_watchers":    "boards",
+	"delete_message_watchers": "boards",
+	"manage_boards":           "boards",
+
+	"view_calendar": "calendar",
+	"view_gantt":    "gantt",
+}
+
+// readOnly reports whether permission only reads, which is all a closed
+// project grants.
+func readOnly(permission string) bool {
+	return strings.HasPrefix(permission, "view_") || slices.Contains([]string{"browse_repository", "export_wiki_pages", "search_project"}, permission)
+}
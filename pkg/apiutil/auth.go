@@ -0,0 +1,35 @@
+// Package apiutil provides helpers shared by the tools built on the generated
+// Redmine client: authentication, error handling and paginated listing.
+package apiutil
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// BasicAuth returns a request editor that authenticates with a login and password.
+func BasicAuth(username, password string) redmine.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	}
+}
+
+// APIKey returns a request editor that authenticates with an API access key.
+func APIKey(key string) redmine.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("X-Redmine-API-Key", key)
+		return nil
+	}
+}
+
+// SwitchUser returns a request editor that impersonates the user with login.
+// This only works when authenticated as an administrator.
+func SwitchUser(login string) redmine.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("X-Redmine-Switch-User", login)
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Response is implemented by every response type of the generated client.
+type Response interface {
+	Status() string
+	StatusCode() int
+}
+
+// ResponseError is returned for responses with a non-2xx status code.
+type ResponseError struct {
+	// StatusCode The HTTP status code of the response.
+	StatusCode int
+
+	// Status The HTTP status line of the response.
+	Status string
+
+	// Errors The validation errors reported by the server (HTTP 422).
+	Errors []string
+}
+
+func (e *ResponseError) Error() string {
+	if len(e.Errors) != 0 {
+		return fmt.Sprintf("%s: %s", e.Status, strings.Join(e.Errors, ", "))
+	}
+	return e.Status
+}
+
+// Check returns a *ResponseError if resp has a non-2xx status code.
+// body is the raw response body used to extract validation errors.
+func Check(resp Response, body []byte) error {
+	code := resp.StatusCode()
+	if http.StatusOK <= code && code < http.StatusMultipleChoices {
+		return nil
+	}
+
+	e := &ResponseError{StatusCode: code, Status: resp.Status()}
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	}
+
+	var errs struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(body, &errs) == nil {
+		e.Errors = errs.Errors
+	}
+
+	return e
+}
+
+// IsNotFound reports whether err is a *ResponseError with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsForbidden reports whether err is a *ResponseError with status 403.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict reports whether err is a *ResponseError with status 409.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+func hasStatus(err error, code int) bool {
+	var e *ResponseError
+	return errors.As(err, &e) && e.StatusCode == code
+}
@@ -0,0 +1,315 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// PageSize is the number of items requested per page. Redmine caps it at 100.
+const PageSize = 100
+
+// Pagination is identical to the anonymous pagination struct of the
+// generated *Params types, so a *Pagination can be assigned to them.
+type Pagination = struct {
+	// Limit The number of items to be present in the response.
+	// If not specified, it defaults to 25.
+	Limit *int `json:"limit,omitempty"`
+
+	// Nometa If set to 1, the response will not include pagination information.
+	Nometa *int `json:"nometa,omitempty"`
+
+	// Offset The offset of the first object to retrieve
+	// If not specified, it defaults to 0.
+	Offset *int `json:"offset,omitempty"`
+}
+
+// Page returns the pagination parameters of a page.
+func Page(offset, limit int) *Pagination {
+	return &Pagination{Offset: &offset, Limit: &limit}
+}
+
+type page[T any] struct {
+	Items      []T
+	TotalCount int
+}
+
+// paginate calls fetch page by page until every item has been read.
+func paginate[T any](fetch func(p *Pagination) (*page[T], error)) ([]T, error) {
+	items := []T{}
+	for offset := 0; ; {
+		p, err := fetch(Page(offset, PageSize))
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, p.Items...)
+		offset += len(p.Items)
+		if len(p.Items) == 0 || offset >= p.TotalCount {
+			return items, nil
+		}
+	}
+}
+
+func decodePage[T any](body []byte, key string) (*page[T], error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	p := &page[T]{}
+	if v, ok := raw[key]; ok {
+		if err := json.Unmarshal(v, &p.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	p.TotalCount = len(p.Items)
+	if v, ok := raw["total_count"]; ok {
+		if err := json.Unmarshal(v, &p.TotalCount); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func decodeList[T any](body []byte, key string) ([]T, error) {
+	p, err := decodePage[T](body, key)
+	if err != nil {
+		return nil, err
+	}
+	return p.Items, nil
+}
+
+// Issues returns every issue matching params across all pages.
+func Issues(ctx context.Context, c redmine.ClientWithResponsesInterface, params *redmine.IssuesIndexParams, reqEditors ...redmine.RequestEditorFn) ([]model.Issue, error) {
+	p := redmine.IssuesIndexParams{}
+	if params != nil {
+		p = *params
+	}
+
+	return paginate(func(pagination *Pagination) (*page[model.Issue], error) {
+		p.Pagination = pagination
+		resp, err := c.IssuesIndexWithResponse(ctx, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.Issue](resp.Body, "issues")
+	})
+}
+
+// Projects returns every project matching params across all pages.
+func Projects(ctx context.Context, c redmine.ClientWithResponsesInterface, params *redmine.ProjectsIndexParams, reqEditors ...redmine.RequestEditorFn) ([]model.Project, error) {
+	p := redmine.ProjectsIndexParams{}
+	if params != nil {
+		p = *params
+	}
+
+	return paginate(func(pagination *Pagination) (*page[model.Project], error) {
+		p.Pagination = pagination
+		resp, err := c.ProjectsIndexWithResponse(ctx, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.Project](resp.Body, "projects")
+	})
+}
+
+// Users returns every user matching params across all pages.
+func Users(ctx context.Context, c redmine.ClientWithResponsesInterface, params *redmine.UsersIndexParams, reqEditors ...redmine.RequestEditorFn) ([]model.User, error) {
+	p := redmine.UsersIndexParams{}
+	if params != nil {
+		p = *params
+	}
+
+	return paginate(func(pagination *Pagination) (*page[model.User], error) {
+		p.Pagination = pagination
+		resp, err := c.UsersIndexWithResponse(ctx, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.User](resp.Body, "users")
+	})
+}
+
+// TimeEntries returns every time entry matching params across all pages.
+func TimeEntries(ctx context.Context, c redmine.ClientWithResponsesInterface, params *redmine.TimelogIndexParams, reqEditors ...redmine.RequestEditorFn) ([]model.TimeEntry, error) {
+	p := redmine.TimelogIndexParams{}
+	if params != nil {
+		p = *params
+	}
+
+	return paginate(func(pagination *Pagination) (*page[model.TimeEntry], error) {
+		p.Pagination = pagination
+		resp, err := c.TimelogIndexWithResponse(ctx, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.TimeEntry](resp.Body, "time_entries")
+	})
+}
+
+// Memberships returns every membership of the project across all pages.
+func Memberships(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, reqEditors ...redmine.RequestEditorFn) ([]model.Membership, error) {
+	return paginate(func(pagination *Pagination) (*page[model.Membership], error) {
+		p := redmine.MembersIndexParams{Pagination: pagination}
+		resp, err := c.MembersIndexWithResponse(ctx, projectId, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.Membership](resp.Body, "memberships")
+	})
+}
+
+// Groups returns every group across all pages.
+func Groups(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) ([]model.Group, error) {
+	return paginate(func(pagination *Pagination) (*page[model.Group], error) {
+		p := redmine.GroupsIndexParams{Pagination: pagination}
+		resp, err := c.GroupsIndexWithResponse(ctx, &p, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		if err := Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodePage[model.Group](resp.Body, "groups")
+	})
+}
+
+// Versions returns the versions available in the project.
+func Versions(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, reqEditors ...redmine.RequestEditorFn) ([]model.Version, error) {
+	resp, err := c.VersionsIndexWithResponse(ctx, projectId, &redmine.VersionsIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Version](resp.Body, "versions")
+}
+
+// Categories returns the issue categories of the project.
+func Categories(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, reqEditors ...redmine.RequestEditorFn) ([]model.Ref, error) {
+	resp, err := c.IssueCategoriesIndexWithResponse(ctx, projectId, &redmine.IssueCategoriesIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Ref](resp.Body, "issue_categories")
+}
+
+// WikiPages returns the index of the wiki pages of the project.
+func WikiPages(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, reqEditors ...redmine.RequestEditorFn) ([]model.WikiPage, error) {
+	resp, err := c.WikiIndexWithResponse(ctx, projectId, &redmine.WikiIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.WikiPage](resp.Body, "wiki_pages")
+}
+
+// Relations returns the relations of the issue.
+func Relations(ctx context.Context, c redmine.ClientWithResponsesInterface, issueId int, reqEditors ...redmine.RequestEditorFn) ([]model.Relation, error) {
+	resp, err := c.IssueRelationsIndexWithResponse(ctx, issueId, &redmine.IssueRelationsIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Relation](resp.Body, "relations")
+}
+
+// Statuses returns every issue status.
+func Statuses(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) ([]model.Status, error) {
+	resp, err := c.IssueStatusesIndexWithResponse(ctx, &redmine.IssueStatusesIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Status](resp.Body, "issue_statuses")
+}
+
+// Trackers returns every tracker.
+func Trackers(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) ([]model.Tracker, error) {
+	resp, err := c.TrackersIndexWithResponse(ctx, &redmine.TrackersIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Tracker](resp.Body, "trackers")
+}
+
+// Priorities returns every issue priority.
+func Priorities(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) ([]model.Ref, error) {
+	resp, err := c.EnumerationsIndexIssuePriorityWithResponse(ctx, &redmine.EnumerationsIndexIssuePriorityParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Ref](resp.Body, "issue_priorities")
+}
+
+// Roles returns every role without its permissions.
+func Roles(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) ([]model.Role, error) {
+	resp, err := c.RolesIndexWithResponse(ctx, &redmine.RolesIndexParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeList[model.Role](resp.Body, "roles")
+}
+
+// FindByName returns the ID of the item whose name, given by key, matches name
+// case-insensitively. The error of a missing item names it as a kind.
+func FindByName[T any](kind, name string, items []T, key func(T) (int, string)) (int, error) {
+	for _, item := range items {
+		id, n := key(item)
+		if strings.EqualFold(n, name) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("%s %q not found", kind, name)
+}
+
+// TimeEqual reports whether a and b are both nil or the same instant, as the
+// updated_on of a record listed twice.
+func TimeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,126 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *redmine.ClientWithResponses {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return c
+}
+
+func TestIssuesPaginate(t *testing.T) {
+	total := 250
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Redmine-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		fmt.Fprint(w, `{"issues":[`)
+		for i := offset; i < offset+limit && i < total; i++ {
+			if i != offset {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%d,"subject":"issue %d"}`, i+1, i+1)
+		}
+		fmt.Fprintf(w, `],"total_count":%d,"offset":%d,"limit":%d}`, total, offset, limit)
+	})
+
+	issues, err := Issues(context.TODO(), c, nil, APIKey("secret"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(issues) != total {
+		t.Fatalf("len(issues) = %d", len(issues))
+	}
+
+	if issues[total-1].Id != total || issues[total-1].Subject != "issue 250" {
+		t.Errorf("last issue = %+v", issues[total-1])
+	}
+}
+
+func TestCheckValidationError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"errors":["Subject cannot be blank"]}`)
+	})
+
+	_, err := CreateIssue(context.TODO(), c, IssueFields{ProjectId: "test"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	e, ok := err.(*ResponseError)
+	if !ok {
+		t.Fatalf("unexpected error type %T", err)
+	}
+
+	if e.StatusCode != http.StatusUnprocessableEntity || len(e.Errors) != 1 || e.Errors[0] != "Subject cannot be blank" {
+		t.Errorf("error = %+v", e)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := Issue(context.TODO(), c, 1, nil)
+	if !IsNotFound(err) {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestIssueFieldsClear(t *testing.T) {
+	id := 3
+	buf, err := json.Marshal(IssueFields{StatusId: &id, Clear: []string{"assigned_to_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `{"assigned_to_id":"","status_id":3}` {
+		t.Errorf("json = %s", buf)
+	}
+}
+
+func TestFindByName(t *testing.T) {
+	items := []model.Ref{{Id: 1, Name: "Bug"}, {Id: 2, Name: "2024"}}
+	key := func(r model.Ref) (int, string) { return r.Id, r.Name }
+	if id, err := FindByName("tracker", "bug", items, key); err != nil || id != 1 {
+		t.Errorf("bug = %d, %v", id, err)
+	}
+	if id, err := FindByName("tracker", "2024", items, key); err != nil || id != 2 {
+		t.Errorf("2024 = %d, %v", id, err)
+	}
+	if _, err := FindByName("tracker", "2", items, key); err == nil || err.Error() != `tracker "2" not found` {
+		t.Errorf("2 = %v", err)
+	}
+}
+
+func TestTimeEqual(t *testing.T) {
+	utc := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	local := utc.In(time.FixedZone("JST", 9*60*60))
+	if !TimeEqual(&utc, &local) || !TimeEqual(nil, nil) || TimeEqual(&utc, nil) {
+		t.Error("TimeEqual")
+	}
+}
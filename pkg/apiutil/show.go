@@ -0,0 +1,151 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+func decodeOne[T any](body []byte, key string) (*T, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	v := new(T)
+	if err := json.Unmarshal(raw[key], v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func includes(include []string) *[]string {
+	if len(include) == 0 {
+		return nil
+	}
+	return &include
+}
+
+// Issue returns the issue with id and the associated data in include.
+func Issue(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, include []string, reqEditors ...redmine.RequestEditorFn) (*model.Issue, error) {
+	resp, err := c.IssuesShowWithResponse(ctx, id, &redmine.IssuesShowParams{Include: includes(include)}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Issue](resp.Body, "issue")
+}
+
+// Project returns the project with id or identifier and the associated data in include.
+func Project(ctx context.Context, c redmine.ClientWithResponsesInterface, id string, include []string, reqEditors ...redmine.RequestEditorFn) (*model.Project, error) {
+	resp, err := c.ProjectsShowWithResponse(ctx, id, &redmine.ProjectsShowParams{Include: includes(include)}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Project](resp.Body, "project")
+}
+
+// User returns the user with id and the associated data in include.
+func User(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, include []string, reqEditors ...redmine.RequestEditorFn) (*model.User, error) {
+	resp, err := c.UsersShowWithResponse(ctx, strconv.Itoa(id), &redmine.UsersShowParams{Include: includes(include)}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.User](resp.Body, "user")
+}
+
+// Group returns the group with id and the associated data in include.
+func Group(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, include []string, reqEditors ...redmine.RequestEditorFn) (*model.Group, error) {
+	resp, err := c.GroupsShowWithResponse(ctx, id, &redmine.GroupsShowParams{Include: includes(include)}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Group](resp.Body, "group")
+}
+
+// Role returns the role with id including its permissions.
+func Role(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) (*model.Role, error) {
+	resp, err := c.RolesShowWithResponse(ctx, id, &redmine.RolesShowParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Role](resp.Body, "role")
+}
+
+// Version returns the version with id.
+func Version(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) (*model.Version, error) {
+	resp, err := c.VersionsShowWithResponse(ctx, id, &redmine.VersionsShowParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Version](resp.Body, "version")
+}
+
+// WikiPage returns the latest version of the wiki page with title.
+func WikiPage(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId, title string, include []string, reqEditors ...redmine.RequestEditorFn) (*model.WikiPage, error) {
+	resp, err := c.WikiShowWithResponse(ctx, projectId, title, &redmine.WikiShowParams{Include: includes(include)}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.WikiPage](resp.Body, "wiki_page")
+}
+
+// WikiPageVersion returns the given version of the wiki page with title.
+func WikiPageVersion(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId, title string, version int, reqEditors ...redmine.RequestEditorFn) (*model.WikiPage, error) {
+	resp, err := c.WikiShowVersionWithResponse(ctx, projectId, title, version, &redmine.WikiShowVersionParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.WikiPage](resp.Body, "wiki_page")
+}
+
+// CurrentUser returns the account of the authenticated user.
+func CurrentUser(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) (*model.User, error) {
+	resp, err := c.MyAccountWithResponse(ctx, &redmine.MyAccountParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.User](resp.Body, "user")
+}
+
+// AttachmentContent returns the content of the attachment with id.
+func AttachmentContent(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) ([]byte, error) {
+	resp, err := c.AttachmentsDownloadWithResponse(ctx, id, &redmine.AttachmentsDownloadParams{}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
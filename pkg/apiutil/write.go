@@ -0,0 +1,467 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const contentTypeJSON = "application/json"
+
+// Upload is a file uploaded with UploadFile, to be attached to a resource.
+type Upload struct {
+	// Token The token returned by the upload.
+	Token string `json:"token"`
+
+	// Filename The filename of the attachment.
+	Filename string `json:"filename,omitempty"`
+
+	// ContentType The content type of the attachment.
+	ContentType string `json:"content_type,omitempty"`
+
+	// Description The description of the attachment.
+	Description string `json:"description,omitempty"`
+}
+
+// CustomFieldValue is the value of a custom field to set.
+type CustomFieldValue struct {
+	// Id The ID of the custom field.
+	Id int `json:"id"`
+
+	// Value The value of the custom field, a string or a list of strings.
+	Value any `json:"value"`
+}
+
+// IssueFields is the set of issue fields sent on create or update.
+// Nil fields are left unchanged.
+//
+// It is a superset of the generated request bodies, which lack uploads.
+type IssueFields struct {
+	ProjectId      string              `json:"project_id,omitempty"`
+	TrackerId      *int                `json:"tracker_id,omitempty"`
+	StatusId       *int                `json:"status_id,omitempty"`
+	PriorityId     *int                `json:"priority_id,omitempty"`
+	AssignedToId   *int                `json:"assigned_to_id,omitempty"`
+	CategoryId     *int                `json:"category_id,omitempty"`
+	FixedVersionId *int                `json:"fixed_version_id,omitempty"`
+	ParentIssueId  *int                `json:"parent_issue_id,omitempty"`
+	Subject        *string             `json:"subject,omitempty"`
+	Description    *string             `json:"description,omitempty"`
+	StartDate      *openapi_types.Date `json:"start_date,omitempty"`
+	DueDate        *openapi_types.Date `json:"due_date,omitempty"`
+	DoneRatio      *int                `json:"done_ratio,omitempty"`
+	EstimatedHours *float64            `json:"estimated_hours,omitempty"`
+	IsPrivate      *bool               `json:"is_private,omitempty"`
+	Notes          *string             `json:"notes,omitempty"`
+	PrivateNotes   *bool               `json:"private_notes,omitempty"`
+	CustomFields   []CustomFieldValue  `json:"custom_fields,omitempty"`
+	WatcherUserIds []int               `json:"watcher_user_ids,omitempty"`
+	Uploads        []Upload            `json:"uploads,omitempty"`
+
+	// Clear The JSON names of the fields to clear, such as assigned_to_id.
+	Clear []string `json:"-"`
+}
+
+// MarshalJSON writes the fields of Clear as empty strings, which Redmine
+// takes as no value.
+func (f IssueFields) MarshalJSON() ([]byte, error) {
+	type fields IssueFields
+	buf, err := json.Marshal(fields(f))
+	if err != nil || len(f.Clear) == 0 {
+		return buf, err
+	}
+
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	for _, name := range f.Clear {
+		m[name] = json.RawMessage(`""`)
+	}
+	return json.Marshal(m)
+}
+
+func jsonBody(key string, v any) (io.Reader, error) {
+	buf, err := json.Marshal(map[string]any{key: v})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
+
+// CreateIssue creates an issue and returns it as stored by the server.
+func CreateIssue(ctx context.Context, c redmine.ClientWithResponsesInterface, fields IssueFields, reqEditors ...redmine.RequestEditorFn) (*model.Issue, error) {
+	body, err := jsonBody("issue", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.IssuesCreateWithBodyWithResponse(ctx, &redmine.IssuesCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Issue](resp.Body, "issue")
+}
+
+// CreateProjectIssue creates an issue in the project with projectId and
+// returns it as stored by the server.
+func CreateProjectIssue(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, fields IssueFields, reqEditors ...redmine.RequestEditorFn) (*model.Issue, error) {
+	body, err := jsonBody("issue", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.IssuesCreateProjectWithBodyWithResponse(ctx, projectId, &redmine.IssuesCreateProjectParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Issue](resp.Body, "issue")
+}
+
+// UpdateIssue updates the issue with id.
+func UpdateIssue(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, fields IssueFields, reqEditors ...redmine.RequestEditorFn) error {
+	body, err := jsonBody("issue", fields)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.IssuesUpdatePatchWithBodyWithResponse(ctx, id, &redmine.IssuesUpdatePatchParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// DeleteIssue deletes the issue with id, with its subtasks.
+func DeleteIssue(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) error {
+	resp, err := c.IssuesDestroyWithResponse(ctx, id, &redmine.IssuesDestroyParams{}, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// UploadFile uploads the content of r and returns the upload to attach.
+func UploadFile(ctx context.Context, c redmine.ClientWithResponsesInterface, filename, contentType string, r io.Reader, reqEditors ...redmine.RequestEditorFn) (*Upload, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	params := redmine.AttachmentsUploadParams{Filename: &filename}
+	resp, err := c.AttachmentsUploadWithBodyWithResponse(ctx, &params, "application/octet-stream", r, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+
+	upload, err := decodeOne[Upload](resp.Body, "upload")
+	if err != nil {
+		return nil, err
+	}
+
+	upload.Filename = filename
+	upload.ContentType = contentType
+	return upload, nil
+}
+
+// TimeEntryFields is the set of time entry fields sent on create.
+type TimeEntryFields struct {
+	IssueId      *int                `json:"issue_id,omitempty"`
+	ProjectId    string              `json:"project_id,omitempty"`
+	SpentOn      *openapi_types.Date `json:"spent_on,omitempty"`
+	Hours        float64             `json:"hours"`
+	ActivityId   *int                `json:"activity_id,omitempty"`
+	Comments     string              `json:"comments,omitempty"`
+	UserId       *int                `json:"user_id,omitempty"`
+	CustomFields []CustomFieldValue  `json:"custom_fields,omitempty"`
+}
+
+// CreateTimeEntry logs time and returns the time entry as stored by the server.
+func CreateTimeEntry(ctx context.Context, c redmine.ClientWithResponsesInterface, fields TimeEntryFields, reqEditors ...redmine.RequestEditorFn) (*model.TimeEntry, error) {
+	body, err := jsonBody("time_entry", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TimelogCreateWithBodyWithResponse(ctx, &redmine.TimelogCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.TimeEntry](resp.Body, "time_entry")
+}
+
+// AddWatchers adds the users to the watchers of the issue.
+func AddWatchers(ctx context.Context, c redmine.ClientWithResponsesInterface, issueId int, userIds []int, reqEditors ...redmine.RequestEditorFn) error {
+	body := redmine.WatchersCreateIssueJSONRequestBody{
+		Watcher: &struct {
+			UserId  *int   `json:"user_id,omitempty"`
+			UserIds *[]int `json:"user_ids,omitempty"`
+		}{
+			UserIds: &userIds,
+		},
+	}
+
+	resp, err := c.WatchersCreateIssueWithResponse(ctx, issueId, &redmine.WatchersCreateIssueParams{}, body, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// RemoveWatcher removes the user from the watchers of the issue.
+func RemoveWatcher(ctx context.Context, c redmine.ClientWithResponsesInterface, issueId, userId int, reqEditors ...redmine.RequestEditorFn) error {
+	resp, err := c.WatchersDestroyIssueWithResponse(ctx, issueId, userId, &redmine.WatchersDestroyIssueParams{}, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// CreateRelation relates the issue to the issue issueToId.
+func CreateRelation(ctx context.Context, c redmine.ClientWithResponsesInterface, issueId, issueToId int, relationType string, reqEditors ...redmine.RequestEditorFn) (*model.Relation, error) {
+	to := strconv.Itoa(issueToId)
+	body := redmine.IssueRelationsCreateJSONRequestBody{
+		Relation: &struct {
+			IssueToId    *string `json:"issue_to_id,omitempty"`
+			RelationType *string `json:"relation_type,omitempty"`
+		}{
+			IssueToId:    &to,
+			RelationType: &relationType,
+		},
+	}
+
+	resp, err := c.IssueRelationsCreateWithResponse(ctx, issueId, &redmine.IssueRelationsCreateParams{}, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Relation](resp.Body, "relation")
+}
+
+// DeleteRelation deletes the relation with id.
+func DeleteRelation(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) error {
+	resp, err := c.IssueRelationsDestroyWithResponse(ctx, id, &redmine.IssueRelationsDestroyParams{}, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// WikiPageFields is the set of wiki page fields sent on create or update.
+// Nil fields are left unchanged.
+//
+// It is a superset of the generated request bodies, which lack uploads.
+type WikiPageFields struct {
+	Text        *string  `json:"text,omitempty"`
+	Comments    *string  `json:"comments,omitempty"`
+	ParentTitle *string  `json:"parent_title,omitempty"`
+	Version     *int     `json:"version,omitempty"`
+	Uploads     []Upload `json:"uploads,omitempty"`
+}
+
+// SaveWikiPage creates or updates the wiki page with title. When Version
+// is set and the page has been changed since, the server answers with a
+// conflict, reported by IsConflict.
+func SaveWikiPage(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId, title string, fields WikiPageFields, reqEditors ...redmine.RequestEditorFn) error {
+	body, err := jsonBody("wiki_page", fields)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.WikiUpdatePutWithBodyWithResponse(ctx, projectId, title, &redmine.WikiUpdatePutParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// ProjectFields is the set of project fields sent on create or update.
+// Nil fields are left unchanged, or set to the defaults of the server on
+// create.
+type ProjectFields struct {
+	Name                string             `json:"name,omitempty"`
+	Identifier          string             `json:"identifier,omitempty"`
+	Description         *string            `json:"description,omitempty"`
+	Homepage            *string            `json:"homepage,omitempty"`
+	IsPublic            *bool              `json:"is_public,omitempty"`
+	ParentId            *int               `json:"parent_id,omitempty"`
+	InheritMembers      *bool              `json:"inherit_members,omitempty"`
+	TrackerIds          *[]int             `json:"tracker_ids,omitempty"`
+	EnabledModuleNames  *[]string          `json:"enabled_module_names,omitempty"`
+	IssueCustomFieldIds *[]int             `json:"issue_custom_field_ids,omitempty"`
+	CustomFields        []CustomFieldValue `json:"custom_fields,omitempty"`
+}
+
+// CreateProject creates a project and returns it as stored by the server.
+func CreateProject(ctx context.Context, c redmine.ClientWithResponsesInterface, fields ProjectFields, reqEditors ...redmine.RequestEditorFn) (*model.Project, error) {
+	body, err := jsonBody("project", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.ProjectsCreateWithBodyWithResponse(ctx, &redmine.ProjectsCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Project](resp.Body, "project")
+}
+
+// CreateMembership adds the user or group with principalId to the project
+// with the roles.
+func CreateMembership(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, principalId int, roleIds []int, reqEditors ...redmine.RequestEditorFn) (*model.Membership, error) {
+	body, err := jsonBody("membership", map[string]any{"user_id": principalId, "role_ids": roleIds})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.MembersCreateWithBodyWithResponse(ctx, projectId, &redmine.MembersCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Membership](resp.Body, "membership")
+}
+
+// VersionFields is the set of version fields sent on create or update.
+// Nil fields are left unchanged.
+type VersionFields struct {
+	Name          string              `json:"name,omitempty"`
+	Description   *string             `json:"description,omitempty"`
+	Status        *string             `json:"status,omitempty"`
+	Sharing       *string             `json:"sharing,omitempty"`
+	DueDate       *openapi_types.Date `json:"due_date,omitempty"`
+	WikiPageTitle *string             `json:"wiki_page_title,omitempty"`
+	CustomFields  []CustomFieldValue  `json:"custom_fields,omitempty"`
+}
+
+// CreateVersion creates a version of the project and returns it as stored
+// by the server.
+func CreateVersion(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId string, fields VersionFields, reqEditors ...redmine.RequestEditorFn) (*model.Version, error) {
+	body, err := jsonBody("version", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.VersionsCreateWithBodyWithResponse(ctx, projectId, &redmine.VersionsCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Version](resp.Body, "version")
+}
+
+// CreateCategory creates an issue category of the project, with the
+// default assignee assignedToId if not 0.
+func CreateCategory(ctx context.Context, c redmine.ClientWithResponsesInterface, projectId, name string, assignedToId int, reqEditors ...redmine.RequestEditorFn) (*model.Ref, error) {
+	category := map[string]any{"name": name}
+	if assignedToId != 0 {
+		category["assigned_to_id"] = assignedToId
+	}
+	body, err := jsonBody("issue_category", category)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.IssueCategoriesCreateWithBodyWithResponse(ctx, projectId, &redmine.IssueCategoriesCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.Ref](resp.Body, "issue_category")
+}
+
+// UserFields is the set of user fields sent on create or update. Nil
+// fields are left unchanged.
+type UserFields struct {
+	Login            string             `json:"login,omitempty"`
+	Firstname        *string            `json:"firstname,omitempty"`
+	Lastname         *string            `json:"lastname,omitempty"`
+	Mail             *string            `json:"mail,omitempty"`
+	Password         *string            `json:"password,omitempty"`
+	GeneratePassword *bool              `json:"generate_password,omitempty"`
+	MustChangePasswd *bool              `json:"must_change_passwd,omitempty"`
+	Admin            *bool              `json:"admin,omitempty"`
+	Status           *int               `json:"status,omitempty"`
+	GroupIds         *[]int             `json:"group_ids,omitempty"`
+	CustomFields     []CustomFieldValue `json:"custom_fields,omitempty"`
+}
+
+// CreateUser creates a user and returns it as stored by the server.
+func CreateUser(ctx context.Context, c redmine.ClientWithResponsesInterface, fields UserFields, reqEditors ...redmine.RequestEditorFn) (*model.User, error) {
+	body, err := jsonBody("user", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.UsersCreateWithBodyWithResponse(ctx, &redmine.UsersCreateParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(resp, resp.Body); err != nil {
+		return nil, err
+	}
+	return decodeOne[model.User](resp.Body, "user")
+}
+
+// UpdateUser applies fields to the user with id. A user is locked or
+// unlocked by its status.
+func UpdateUser(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, fields UserFields, reqEditors ...redmine.RequestEditorFn) error {
+	body, err := jsonBody("user", fields)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.UsersUpdatePatchWithBodyWithResponse(ctx, strconv.Itoa(id), &redmine.UsersUpdatePatchParams{}, contentTypeJSON, body, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// AddGroupUsers adds the users to the group.
+func AddGroupUsers(ctx context.Context, c redmine.ClientWithResponsesInterface, groupId int, userIds []int, reqEditors ...redmine.RequestEditorFn) error {
+	body := redmine.GroupsAddUsersJSONRequestBody{UserIds: &userIds}
+
+	resp, err := c.GroupsAddUsersWithResponse(ctx, groupId, &redmine.GroupsAddUsersParams{}, body, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
+
+// RemoveGroupUser removes the user from the group.
+func RemoveGroupUser(ctx context.Context, c redmine.ClientWithResponsesInterface, groupId, userId int, reqEditors ...redmine.RequestEditorFn) error {
+	resp, err := c.GroupsRemoveUserWithResponse(ctx, groupId, userId, &redmine.GroupsRemoveUserParams{}, reqEditors...)
+	if err != nil {
+		return err
+	}
+	return Check(resp, resp.Body)
+}
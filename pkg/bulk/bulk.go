@@ -0,0 +1,222 @@
+// Package bulk applies a set of changes to all the issues matching a
+// query, as the bulk edit of the Redmine web UI does, with one update per
+// issue.
+package bulk
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Change is the set of changes to apply to the issues. Nil fields are left
+// unchanged; the ID 0 unassigns the issues or clears their version.
+type Change struct {
+	StatusId       *int `json:"status_id,omitempty"`
+	AssignedToId   *int `json:"assigned_to_id,omitempty"`
+	FixedVersionId *int `json:"fixed_version_id,omitempty"`
+
+	CustomFields []apiutil.CustomFieldValue `json:"custom_fields,omitempty"`
+
+	// Notes The note added to the issues.
+	Notes *string `json:"notes,omitempty"`
+
+	// PrivateNotes Whether the note is private.
+	PrivateNotes bool `json:"private_notes,omitempty"`
+}
+
+// Field names of FieldChange. The custom fields are named cf_ followed by
+// their ID.
+const (
+	FieldStatus       = "status_id"
+	FieldAssignedTo   = "assigned_to_id"
+	FieldFixedVersion = "fixed_version_id"
+	FieldNotes        = "notes"
+)
+
+// FieldChange is the change of a field of an issue.
+type FieldChange struct {
+	Field string `json:"field"`
+
+	// From The current value: an ID for the status, assignee and version,
+	// the values separated by commas for a custom field, "" if none.
+	From string `json:"from"`
+
+	To string `json:"to"`
+}
+
+// Result is the outcome of the change of an issue.
+type Result struct {
+	Id      int    `json:"id"`
+	Subject string `json:"subject"`
+
+	// Changes The fields which change.
+	Changes []FieldChange `json:"changes,omitempty"`
+
+	// Updated Whether the changes were sent and accepted. It is false in
+	// dry-run mode and when nothing changes.
+	Updated bool `json:"updated"`
+
+	// Error The error of the update, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Updater applies changes to issues.
+type Updater struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Concurrency The maximum number of updates in flight, 4 if 0.
+	Concurrency int
+
+	// DryRun Whether to only report the changes, without sending them.
+	DryRun bool
+}
+
+// defaultConcurrency is the number of concurrent updates when not set.
+const defaultConcurrency = 4
+
+// Update applies change to the issues matching query and returns the result
+// of each issue, in the order of the query. The issues on which nothing
+// changes are not updated. A failed update does not stop the others: the
+// error is that of the query only.
+func (u *Updater) Update(ctx context.Context, query *redmine.IssuesIndexParams_Query, change Change) ([]Result, error) {
+	issues, err := apiutil.Issues(ctx, u.Client, &redmine.IssuesIndexParams{Query: query}, u.Auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(issues))
+	n := u.Concurrency
+	if n <= 0 {
+		n = defaultConcurrency
+	}
+	sem := make(chan struct{}, n)
+	wg := sync.WaitGroup{}
+	for i := range issues {
+		results[i] = Result{Id: issues[i].Id, Subject: issues[i].Subject, Changes: Diff(&issues[i], change)}
+		if u.DryRun || len(results[i].Changes) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				r.Error = ctx.Err().Error()
+				return
+			}
+
+			err := apiutil.UpdateIssue(ctx, u.Client, r.Id, fields(r.Changes, change), u.Auth...)
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			r.Updated = true
+		}(&results[i])
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Diff returns the fields of issue changed by change, custom fields last
+// in the order of change.
+func Diff(issue *model.Issue, change Change) []FieldChange {
+	changes := []FieldChange{}
+	ref := func(field string, id *int, current int) {
+		if id != nil && *id != current {
+			changes = append(changes, FieldChange{Field: field, From: refString(current), To: refString(*id)})
+		}
+	}
+	status := 0
+	if issue.Status != nil {
+		status = issue.Status.Id
+	}
+	ref(FieldStatus, change.StatusId, status)
+	ref(FieldAssignedTo, change.AssignedToId, refId(issue.AssignedTo))
+	ref(FieldFixedVersion, change.FixedVersionId, refId(issue.FixedVersion))
+
+	for _, cf := range change.CustomFields {
+		current := []string{}
+		if i := slices.IndexFunc(issue.CustomFields, func(c model.CustomField) bool { return c.Id == cf.Id }); i >= 0 {
+			current = issue.CustomFields[i].Values()
+		}
+		values := model.CustomField{Value: cf.Value}.Values()
+		if !slices.Equal(sorted(current), sorted(values)) {
+			changes = append(changes, FieldChange{Field: "cf_" + strconv.Itoa(cf.Id), From: strings.Join(current, ","), To: strings.Join(values, ",")})
+		}
+	}
+
+	if change.Notes != nil && *change.Notes != "" {
+		changes = append(changes, FieldChange{Field: FieldNotes, To: *change.Notes})
+	}
+	return changes
+}
+
+// fields returns the fields of change sent to apply changes.
+func fields(changes []FieldChange, change Change) apiutil.IssueFields {
+	f := apiutil.IssueFields{}
+	for _, c := range changes {
+		switch c.Field {
+		case FieldStatus:
+			f.StatusId = change.StatusId
+		// Redmine clears a field sent empty, and rejects the ID 0.
+		case FieldAssignedTo:
+			if *change.AssignedToId == 0 {
+				f.Clear = append(f.Clear, c.Field)
+			} else {
+				f.AssignedToId = change.AssignedToId
+			}
+		case FieldFixedVersion:
+			if *change.FixedVersionId == 0 {
+				f.Clear = append(f.Clear, c.Field)
+			} else {
+				f.FixedVersionId = change.FixedVersionId
+			}
+		case FieldNotes:
+			f.Notes = change.Notes
+			if change.PrivateNotes {
+				f.PrivateNotes = &change.PrivateNotes
+			}
+		default:
+			for _, cf := range change.CustomFields {
+				if c.Field == "cf_"+strconv.Itoa(cf.Id) {
+					f.CustomFields = append(f.CustomFields, cf)
+				}
+			}
+		}
+	}
+	return f
+}
+
+func refId(r *model.Ref) int {
+	if r == nil {
+		return 0
+	}
+	return r.Id
+}
+
+func refString(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func sorted(values []string) []string {
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return values
+}
@@ -0,0 +1,125 @@
+package bulk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{
+		{Id: 1, Name: "Web", Identifier: "web"},
+		{Id: 2, Name: "Mobile", Identifier: "mobile"},
+	}
+	f.Users = append(f.Users, redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith"}})
+	f.Memberships = []model.Membership{{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}}}
+	f.CustomFields = []redminetest.CustomField{{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"}}
+	f.Issues = []model.Issue{
+		{Id: 1, Project: &model.Ref{Id: 1}, Tracker: &model.Ref{Id: 1}, Status: &model.Status{Id: 1}, Priority: &model.Ref{Id: 2}, Author: &model.Ref{Id: 1}, Subject: "Login fails"},
+		{Id: 2, Project: &model.Ref{Id: 1}, Tracker: &model.Ref{Id: 1}, Status: &model.Status{Id: 2}, Priority: &model.Ref{Id: 2}, Author: &model.Ref{Id: 1}, AssignedTo: &model.Ref{Id: 2}, Subject: "Logout fails",
+			CustomFields: []model.CustomField{{Id: 1, Value: "web"}}},
+		{Id: 3, Project: &model.Ref{Id: 2}, Tracker: &model.Ref{Id: 1}, Status: &model.Status{Id: 1}, Priority: &model.Ref{Id: 2}, Author: &model.Ref{Id: 1}, Subject: "Crash on start"},
+	}
+	return f
+}
+
+func TestUpdate(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+
+	status, assignee, note := 2, 2, "Triaged"
+	change := Change{
+		StatusId:     &status,
+		AssignedToId: &assignee,
+		CustomFields: []apiutil.CustomFieldValue{{Id: 1, Value: "web"}},
+		Notes:        &note,
+	}
+	query := &redmine.IssuesIndexParams_Query{}
+	query.Set("sort", "id")
+
+	u := &Updater{Client: c, Auth: auth, DryRun: true, Concurrency: 2}
+	results, err := u.Update(ctx, query, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+	want := []FieldChange{
+		{Field: FieldStatus, From: "1", To: "2"},
+		{Field: FieldAssignedTo, From: "", To: "2"},
+		{Field: "cf_1", From: "", To: "web"},
+		{Field: FieldNotes, To: "Triaged"},
+	}
+	if got := results[0].Changes; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("changes of #1 = %+v", got)
+	}
+	if got := results[1].Changes; len(got) != 1 || got[0].Field != FieldNotes {
+		t.Errorf("changes of #2 = %+v", got)
+	}
+	for _, r := range results {
+		if r.Updated || r.Error != "" {
+			t.Errorf("dry run updated %+v", r)
+		}
+	}
+	if i, _ := apiutil.Issue(ctx, c, 1, nil, auth...); i.Status.Id != 1 {
+		t.Errorf("dry run changed the status of #1")
+	}
+
+	u.DryRun = false
+	if results, err = u.Update(ctx, query, change); err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Updated || !results[1].Updated || results[2].Updated || results[2].Error == "" {
+		t.Errorf("results = %+v", results)
+	}
+
+	i, err := apiutil.Issue(ctx, c, 1, []string{"journals"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Status.Id != 2 || i.AssignedTo == nil || i.AssignedTo.Id != 2 || len(i.CustomFields) != 1 || i.CustomFields[0].Value != "web" {
+		t.Errorf("issue #1 = %+v", i)
+	}
+	if i, _ := apiutil.Issue(ctx, c, 3, nil, auth...); i.Status.Id != 1 {
+		t.Errorf("failed update changed #3: %+v", i)
+	}
+}
+
+func TestDiffCustomFieldList(t *testing.T) {
+	issue := &model.Issue{CustomFields: []model.CustomField{{Id: 1, Multiple: true, Value: []any{"b", "a"}}}}
+	if got := Diff(issue, Change{CustomFields: []apiutil.CustomFieldValue{{Id: 1, Value: []string{"a", "b"}}}}); len(got) != 0 {
+		t.Errorf("same values: %+v", got)
+	}
+	got := Diff(issue, Change{CustomFields: []apiutil.CustomFieldValue{{Id: 1, Value: []string{"a"}}}})
+	if len(got) != 1 || got[0].From != "b,a" || got[0].To != "a" {
+		t.Errorf("changed values: %+v", got)
+	}
+}
+
+func TestUpdateUnassign(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+
+	none := 0
+	query := &redmine.IssuesIndexParams_Query{}
+	query.Set("issue_id", "2")
+	u := &Updater{Client: c, Auth: auth}
+	results, err := u.Update(ctx, query, Change{AssignedToId: &none})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Updated {
+		t.Fatalf("results = %+v", results)
+	}
+	if i, err := apiutil.Issue(ctx, c, 2, nil, auth...); err != nil || i.AssignedTo != nil {
+		t.Errorf("issue #2 = %+v, %v", i, err)
+	}
+}
@@ -0,0 +1,253 @@
+// Package burndown computes the daily burndown and burnup of a version, in
+// issue counts, estimated hours or spent hours, and renders them locally as
+// SVG or PNG charts with an ideal line.
+//
+// The state of the issues at the end of each day, their version, status
+// and estimated hours, is rebuilt from the current values by undoing the
+// changes of their journals made later. The spent hours come from the time
+// entries. Only the issues currently in the version are fetched: an issue
+// moved to another version counts for none of the days.
+package burndown
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Units of the work.
+const (
+	UnitCount     = "count"
+	UnitEstimated = "estimated_hours"
+	UnitSpent     = "spent_hours"
+)
+
+// Data is a version with its issues, their journals and time entries.
+type Data struct {
+	Version     model.Version     `json:"version"`
+	Issues      []model.Issue     `json:"issues"`
+	Statuses    []model.Status    `json:"statuses"`
+	TimeEntries []model.TimeEntry `json:"time_entries"`
+}
+
+// Point is the work of a version at the end of a day.
+type Point struct {
+	Date time.Time `json:"date"`
+
+	// Scope The total work of the issues in the version.
+	Scope float64 `json:"scope"`
+
+	// Completed The work of the closed issues, or the hours spent on the
+	// issues in spent hours.
+	Completed float64 `json:"completed"`
+
+	// Remaining The work left, never negative.
+	Remaining float64 `json:"remaining"`
+
+	// Ideal The work left on a steady pace from the first day to the last.
+	Ideal float64 `json:"ideal"`
+}
+
+// Chart is the daily work of a version.
+type Chart struct {
+	Version string `json:"version"`
+	Unit    string `json:"unit"`
+
+	// From The first day of the chart.
+	From time.Time `json:"from"`
+
+	// To The last day of the chart, the due date of the version by default.
+	To time.Time `json:"to"`
+
+	// Points The days from From to To, or to the day of the analysis if
+	// earlier.
+	Points []Point `json:"points"`
+}
+
+// Options selects the unit and the range of a chart.
+type Options struct {
+	// Unit The unit of the work, UnitCount by default.
+	Unit string
+
+	// From The first day, the creation of the version if zero.
+	From time.Time
+
+	// To The last day, the due date of the version if zero, or the day of
+	// the analysis if it has none.
+	To time.Time
+
+	// Now The time of the analysis, the current time if zero.
+	Now time.Time
+}
+
+// Load fetches the version with id, its issues of all statuses with their
+// journals, the statuses and the time entries of the issues, filtered on
+// the version of their issue.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) (*Data, error) {
+	v, err := apiutil.Version(ctx, c, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := apiutil.Statuses(ctx, c, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &redmine.IssuesIndexParams_Query{}
+	q.Set("status_id", "*")
+	q.Set("fixed_version_id", strconv.Itoa(id))
+	listed, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{Query: q}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Data{Version: *v, Statuses: statuses}
+	for _, i := range listed {
+		full, err := apiutil.Issue(ctx, c, i.Id, []string{"journals"}, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		d.Issues = append(d.Issues, *full)
+	}
+
+	version := strconv.Itoa(id)
+	d.TimeEntries, err = apiutil.TimeEntries(ctx, c, &redmine.TimelogIndexParams{Query: &redmine.TimelogIndexParams_Query{IssueFixedVersionId: &version}}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// day returns the start of the day of t, in UTC.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// valueAt returns the value of the attribute name of the issue at t: the
+// old value of its first change after t, or current if none.
+func valueAt(i *model.Issue, name string, t time.Time, current string) string {
+	for _, j := range i.Journals {
+		if j.CreatedOn.Before(t) {
+			continue
+		}
+		for _, d := range j.Details {
+			if d.Property == "attr" && d.Name == name {
+				if d.OldValue == nil {
+					return ""
+				}
+				return *d.OldValue
+			}
+		}
+	}
+	return current
+}
+
+func refId(r *model.Ref) string {
+	if r == nil {
+		return ""
+	}
+	return strconv.Itoa(r.Id)
+}
+
+func hours(h *float64) string {
+	if h == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*h, 'f', -1, 64)
+}
+
+// Chart computes the work of the version day by day.
+func (d *Data) Chart(opts Options) (*Chart, error) {
+	unit := opts.Unit
+	if unit == "" {
+		unit = UnitCount
+	}
+	if unit != UnitCount && unit != UnitEstimated && unit != UnitSpent {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	from, to := day(opts.From), day(opts.To)
+	if opts.From.IsZero() {
+		if d.Version.CreatedOn == nil {
+			return nil, fmt.Errorf("version %q has no creation date, the first day is required", d.Version.Name)
+		}
+		from = day(*d.Version.CreatedOn)
+	}
+	if opts.To.IsZero() {
+		to = day(now)
+		if d.Version.DueDate != nil {
+			to = day(d.Version.DueDate.Time)
+		}
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("last day %s before first day %s", to.Format(time.DateOnly), from.Format(time.DateOnly))
+	}
+
+	closed := map[string]bool{}
+	for _, s := range d.Statuses {
+		closed[strconv.Itoa(s.Id)] = s.IsClosed
+	}
+	version := strconv.Itoa(d.Version.Id)
+
+	c := &Chart{Version: d.Version.Name, Unit: unit, From: from, To: to, Points: []Point{}}
+	for t := from; !t.After(to) && !t.After(now); t = t.AddDate(0, 0, 1) {
+		end := t.AddDate(0, 0, 1)
+		p := Point{Date: t}
+		in := map[int]bool{}
+		for k := range d.Issues {
+			i := &d.Issues[k]
+			if i.CreatedOn != nil && !i.CreatedOn.Before(end) {
+				continue
+			}
+			if valueAt(i, "fixed_version_id", end, refId(i.FixedVersion)) != version {
+				continue
+			}
+			in[i.Id] = true
+
+			work := 1.0
+			if unit != UnitCount {
+				work, _ = strconv.ParseFloat(valueAt(i, "estimated_hours", end, hours(i.EstimatedHours)), 64)
+			}
+			p.Scope += work
+			status := ""
+			if i.Status != nil {
+				status = strconv.Itoa(i.Status.Id)
+			}
+			if unit != UnitSpent && closed[valueAt(i, "status_id", end, status)] {
+				p.Completed += work
+			}
+		}
+		if unit == UnitSpent {
+			for _, e := range d.TimeEntries {
+				if in[e.IssueId()] && e.SpentOn != nil && e.SpentOn.Time.Before(end) {
+					p.Completed += e.Hours
+				}
+			}
+		}
+		p.Remaining = max(p.Scope-p.Completed, 0)
+		c.Points = append(c.Points, p)
+	}
+
+	if len(c.Points) > 0 {
+		n := to.Sub(from).Hours() / 24
+		for k := range c.Points {
+			p := &c.Points[k]
+			if n == 0 {
+				p.Ideal = 0
+				continue
+			}
+			p.Ideal = c.Points[0].Remaining * (1 - p.Date.Sub(from).Hours()/24/n)
+		}
+	}
+	return c, nil
+}
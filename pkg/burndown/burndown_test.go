@@ -0,0 +1,170 @@
+package burndown
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+// date returns a time of January 2025.
+func date(d, h int) time.Time {
+	return time.Date(2025, 1, d, h, 0, 0, 0, time.UTC)
+}
+
+func changed(id int, at time.Time, name string, from, to *string) model.Journal {
+	return model.Journal{
+		Id:        id,
+		User:      &model.Ref{Id: 1},
+		CreatedOn: at,
+		Details:   []model.JournalDetail{{Property: "attr", Name: name, OldValue: from, NewValue: to}},
+	}
+}
+
+func str(s string) *string { return &s }
+
+func load(t *testing.T) *Data {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Web", Identifier: "web"}}
+	created, due := date(6, 9), openapi_types.Date{Time: date(10, 0)}
+	f.Versions = []model.Version{
+		{Id: 1, Project: &model.Ref{Id: 1}, Name: "Sprint 1", Status: "open", DueDate: &due, CreatedOn: &created},
+		{Id: 2, Project: &model.Ref{Id: 1}, Name: "Sprint 2", Status: "open"},
+	}
+	issue := func(id, status int, created time.Time, estimated float64, journals ...model.Journal) model.Issue {
+		return model.Issue{
+			Id:             id,
+			Project:        &model.Ref{Id: 1},
+			Tracker:        &model.Ref{Id: 1},
+			Status:         &model.Status{Id: status},
+			Priority:       &model.Ref{Id: 2},
+			Author:         &model.Ref{Id: 1},
+			FixedVersion:   &model.Ref{Id: 1},
+			Subject:        "Issue",
+			EstimatedHours: &estimated,
+			CreatedOn:      &created,
+			Journals:       journals,
+		}
+	}
+	f.Issues = []model.Issue{
+		issue(1, 5, date(5, 0), 4, changed(1, date(7, 10), "status_id", str("1"), str("5"))),
+		issue(2, 1, date(5, 0), 2, changed(2, date(8, 10), "estimated_hours", str("3"), str("2"))),
+		issue(3, 2, date(5, 0), 6, changed(3, date(8, 10), "fixed_version_id", nil, str("1"))),
+		issue(4, 5, date(9, 9), 1, changed(4, date(9, 12), "status_id", str("1"), str("5"))),
+		issue(5, 1, date(5, 0), 8),
+	}
+	f.Issues[4].FixedVersion = &model.Ref{Id: 2}
+	entry := func(id, issue int, d int, hours float64) model.TimeEntry {
+		return model.TimeEntry{
+			Id:      id,
+			Project: &model.Ref{Id: 1},
+			Issue: &struct {
+				Id int `json:"id"`
+			}{issue},
+			User:     &model.Ref{Id: 1},
+			Activity: &model.Ref{Id: 1},
+			Hours:    hours,
+			SpentOn:  &openapi_types.Date{Time: date(d, 0)},
+		}
+	}
+	f.TimeEntries = []model.TimeEntry{entry(1, 1, 6, 2), entry(2, 1, 7, 2), entry(3, 3, 7, 1), entry(4, 5, 7, 3)}
+	c := redminetest.Client(t, f)
+	d, err := Load(context.Background(), c, 1, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func csvOf(t *testing.T, c *Chart) string {
+	var buf bytes.Buffer
+	if err := c.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestChart(t *testing.T) {
+	d := load(t)
+	if len(d.Issues) != 4 || len(d.TimeEntries) != 3 {
+		t.Fatalf("data = %d issues, %d time entries", len(d.Issues), len(d.TimeEntries))
+	}
+	now := date(9, 18)
+
+	cases := []struct {
+		unit, want string
+	}{
+		{UnitCount, "2,0,2,2.00\n2,1,1,1.50\n3,1,2,1.00\n4,2,2,0.50\n"},
+		{UnitEstimated, "7,0,7,7.00\n7,4,3,5.25\n12,4,8,3.50\n13,5,8,1.75\n"},
+		{UnitSpent, "7,2,5,5.00\n7,4,3,3.75\n12,5,7,2.50\n13,5,8,1.25\n"},
+	}
+	for _, tc := range cases {
+		c, err := d.Chart(Options{Unit: tc.unit, Now: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.To.Equal(date(10, 0)) || len(c.Points) != 4 {
+			t.Errorf("%s: chart = %+v", tc.unit, c)
+		}
+		got := csvOf(t, c)
+		rows := strings.Split(got, "\n")
+		want := "date,scope,completed,remaining,ideal\n"
+		for k, row := range strings.SplitAfter(tc.want, "\n") {
+			if row != "" {
+				want += date(6+k, 0).Format(time.DateOnly) + "," + row
+			}
+		}
+		if got != want {
+			t.Errorf("%s: rows = %q", tc.unit, rows)
+		}
+	}
+
+	if _, err := d.Chart(Options{Unit: "points"}); err == nil {
+		t.Error("unknown unit")
+	}
+	if _, err := d.Chart(Options{From: date(10, 0), To: date(6, 0)}); err == nil {
+		t.Error("reversed range")
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, err := load(t).Chart(Options{Now: date(9, 18)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svg := c.SVG(false)
+	for _, s := range []string{"Burndown of Sprint 1 (count)", `<polyline points="56.0,32.0 704.0,320.0" fill="none" stroke="#999999" stroke-width="2" stroke-dasharray="6 4"/>`, ">Remaining</text>", ">2025-01-10</text>"} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("svg lacks %q:\n%s", s, svg)
+		}
+	}
+	if svg := c.SVG(true); !strings.Contains(svg, "Burnup of Sprint 1") || !strings.Contains(svg, ">Scope</text>") {
+		t.Errorf("burnup svg =\n%s", svg)
+	}
+
+	var buf bytes.Buffer
+	if err := c.PNG(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != width || img.Bounds().Dy() != height {
+		t.Errorf("png bounds = %v", img.Bounds())
+	}
+	if r, g, b, _ := img.At(marginLeft, marginTop).RGBA(); r>>8 != 0x99 || g>>8 != 0x99 || b>>8 != 0x99 {
+		t.Errorf("png axis color = %x %x %x", r, g, b)
+	}
+}
@@ -0,0 +1,206 @@
+package burndown
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sizes of the charts, in pixels.
+const (
+	width        = 720
+	height       = 360
+	marginLeft   = 56
+	marginRight  = 16
+	marginTop    = 32
+	marginBottom = 40
+)
+
+// series is a line of a chart, in data coordinates: days since the first
+// day and work.
+type series struct {
+	name   string
+	color  color.RGBA
+	dashed bool
+	points [][2]float64
+}
+
+var (
+	blue = color.RGBA{0x3f, 0x73, 0xc0, 0xff}
+	red  = color.RGBA{0xcc, 0x33, 0x33, 0xff}
+	gray = color.RGBA{0x99, 0x99, 0x99, 0xff}
+)
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// series returns the lines of the chart: the remaining work and the ideal
+// line to zero for a burndown, the scope, the completed work and the ideal
+// line to the last scope for a burnup.
+func (c *Chart) series(burnup bool) []series {
+	days := func(p Point) float64 { return p.Date.Sub(c.From).Hours() / 24 }
+	last := c.To.Sub(c.From).Hours() / 24
+	if len(c.Points) == 0 {
+		return nil
+	}
+	first, end := c.Points[0], c.Points[len(c.Points)-1]
+
+	if !burnup {
+		remaining := series{name: "Remaining", color: blue}
+		for _, p := range c.Points {
+			remaining.points = append(remaining.points, [2]float64{days(p), p.Remaining})
+		}
+		ideal := series{name: "Ideal", color: gray, dashed: true, points: [][2]float64{{0, first.Remaining}, {last, 0}}}
+		return []series{ideal, remaining}
+	}
+
+	scope := series{name: "Scope", color: red}
+	completed := series{name: "Completed", color: blue}
+	for _, p := range c.Points {
+		scope.points = append(scope.points, [2]float64{days(p), p.Scope})
+		completed.points = append(completed.points, [2]float64{days(p), p.Completed})
+	}
+	ideal := series{name: "Ideal", color: gray, dashed: true, points: [][2]float64{{0, first.Completed}, {last, end.Scope}}}
+	return []series{ideal, scope, completed}
+}
+
+// scale returns the projection of data coordinates to pixels, and the top
+// of the work axis.
+func (c *Chart) scale(lines []series) (func(p [2]float64) (float64, float64), float64) {
+	top := 0.0
+	for _, s := range lines {
+		for _, p := range s.points {
+			top = max(top, p[1])
+		}
+	}
+	top = math.Max(1, math.Ceil(top))
+	last := max(c.To.Sub(c.From).Hours()/24, 1)
+
+	plotW := float64(width - marginLeft - marginRight)
+	plotH := float64(height - marginTop - marginBottom)
+	return func(p [2]float64) (float64, float64) {
+		return marginLeft + p[0]/last*plotW, marginTop + (1-p[1]/top)*plotH
+	}, top
+}
+
+func (c *Chart) title(burnup bool) string {
+	kind := "Burndown"
+	if burnup {
+		kind = "Burnup"
+	}
+	return fmt.Sprintf("%s of %s (%s)", kind, c.Version, strings.ReplaceAll(c.Unit, "_", " "))
+}
+
+func formatWork(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// SVG renders the chart as a standalone SVG image, with the work on the
+// vertical axis, the days on the horizontal one and a legend.
+func (c *Chart) SVG(burnup bool) string {
+	lines := c.series(burnup)
+	xy, top := c.scale(lines)
+	bottom, right := height-marginBottom, width-marginRight
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-weight="bold">%s</text>`+"\n", marginLeft, html.EscapeString(c.title(burnup)))
+
+	for _, v := range []float64{0, top / 2, top} {
+		_, y := xy([2]float64{0, v})
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", marginLeft, y, right, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", marginLeft-6, y+4, formatWork(v))
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", marginLeft, marginTop, marginLeft, bottom)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", marginLeft, bottom, right, bottom)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", marginLeft, bottom+16, c.From.Format(time.DateOnly))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", right, bottom+16, c.To.Format(time.DateOnly))
+
+	for k, s := range lines {
+		points := make([]string, len(s.points))
+		for i, p := range s.points {
+			x, y := xy(p)
+			points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+		}
+		dash := ""
+		if s.dashed {
+			dash = ` stroke-dasharray="6 4"`
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"%s/>`+"\n", strings.Join(points, " "), hex(s.color), dash)
+
+		lx := marginLeft + k*120
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"%s/>`, lx, height-10, lx+20, height-10, hex(s.color), dash)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", lx+26, height-6, s.name)
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// PNG renders the chart as a PNG image with the axes, the grid and the
+// lines of the SVG rendering, without text.
+func (c *Chart) PNG(w io.Writer, burnup bool) error {
+	lines := c.series(burnup)
+	xy, top := c.scale(lines)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	light := color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	for _, v := range []float64{top / 2, top} {
+		_, y := xy([2]float64{0, v})
+		line(img, marginLeft, y, width-marginRight, y, light, 1, false)
+	}
+	line(img, marginLeft, marginTop, marginLeft, height-marginBottom, gray, 1, false)
+	line(img, marginLeft, height-marginBottom, width-marginRight, height-marginBottom, gray, 1, false)
+
+	for _, s := range lines {
+		for i := 1; i < len(s.points); i++ {
+			x1, y1 := xy(s.points[i-1])
+			x2, y2 := xy(s.points[i])
+			line(img, x1, y1, x2, y2, s.color, 2, s.dashed)
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// line draws a line of width pixels, dashed every 6 pixels.
+func line(img *image.RGBA, x1, y1, x2, y2 float64, c color.RGBA, width int, dashed bool) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1)))
+	for i := 0; i <= steps; i++ {
+		if dashed && (i/6)%2 == 1 {
+			continue
+		}
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		x, y := int(math.Round(x1+(x2-x1)*t)), int(math.Round(y1+(y2-y1)*t))
+		for dx := range width {
+			for dy := range width {
+				img.SetRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+}
+
+// WriteCSV writes the points of the chart as CSV, a row per day.
+func (c *Chart) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "scope", "completed", "remaining", "ideal"})
+	for _, p := range c.Points {
+		_ = cw.Write([]string{p.Date.Format(time.DateOnly), formatWork(p.Scope), formatWork(p.Completed),
+			formatWork(p.Remaining), strconv.FormatFloat(p.Ideal, 'f', 2, 64)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
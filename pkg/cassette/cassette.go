@@ -0,0 +1,154 @@
+// Package cassette provides an HTTP request doer that records the
+// interactions with a Redmine server into cassette files and replays them,
+// so that tests run offline through the generated client.
+//
+// Credentials are redacted before an interaction is recorded, and the
+// requests are matched with the recorded ones by method, path, normalized
+// query and body as configured by a Matcher.
+package cassette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Interaction is a request with its response.
+type Interaction struct {
+	Request  Request  `json:"request" yaml:"request"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Body is the body of a request or a response. It is saved as text, or
+// base64-encoded when it is not valid UTF-8.
+type Body []byte
+
+// body is the saved form of a Body.
+type body struct {
+	Text   string `json:"text,omitempty" yaml:"text,omitempty"`
+	Base64 string `json:"base64,omitempty" yaml:"base64,omitempty"`
+}
+
+func (b Body) saved() body {
+	if utf8.Valid(b) {
+		return body{Text: string(b)}
+	}
+	return body{Base64: base64.StdEncoding.EncodeToString(b)}
+}
+
+func (b *Body) load(v body) error {
+	if v.Base64 == "" {
+		*b = Body(v.Text)
+		return nil
+	}
+	buf, err := base64.StdEncoding.DecodeString(v.Base64)
+	if err != nil {
+		return err
+	}
+	*b = buf
+	return nil
+}
+
+// IsZero reports whether the body is empty, for omitempty in YAML.
+func (b Body) IsZero() bool {
+	return len(b) == 0
+}
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.saved())
+}
+
+func (b *Body) UnmarshalJSON(buf []byte) error {
+	var v body
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	return b.load(v)
+}
+
+func (b Body) MarshalYAML() (any, error) {
+	return b.saved(), nil
+}
+
+func (b *Body) UnmarshalYAML(n *yaml.Node) error {
+	var v body
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	return b.load(v)
+}
+
+// isYAML reports whether the file at path is saved as YAML rather than JSON.
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return true
+	}
+	return false
+}
+
+// Load reads a cassette file, in YAML when its extension is .yml or .yaml
+// and in JSON otherwise.
+func Load(path string) (*Cassette, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cassette{}
+	if isYAML(path) {
+		err = yaml.Unmarshal(buf, c)
+	} else {
+		err = json.Unmarshal(buf, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to a file, creating its directory.
+func (c *Cassette) Save(path string) error {
+	var buf []byte
+	var err error
+	if isYAML(path) {
+		buf, err = yaml.Marshal(c)
+	} else {
+		buf, err = json.MarshalIndent(c, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
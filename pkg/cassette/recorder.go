@@ -0,0 +1,196 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// ErrNoInteraction is returned when a replayed request matches no recorded
+// interaction left.
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// Mode is how a Recorder handles the requests.
+type Mode int
+
+const (
+	// ModeReplay serves the requests from the cassette, in the recorded
+	// order, and never sends them.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends the requests and records them into a new cassette.
+	ModeRecord
+
+	// ModeAuto replays the cassette when the file exists and records it
+	// otherwise.
+	ModeAuto
+)
+
+// Recorder is a redmine.HttpRequestDoer recording or replaying the
+// interactions of a cassette file.
+type Recorder struct {
+	// Doer sends the recorded requests. Nil means http.DefaultClient.
+	Doer redmine.HttpRequestDoer
+
+	// Matcher selects the recorded interaction replayed for a request. Nil
+	// means DefaultMatcher.
+	Matcher *Matcher
+
+	// Redaction The credentials removed from the recorded interactions. Nil
+	// means DefaultRedaction.
+	Redaction *Redaction
+
+	path     string
+	mode     Mode
+	mu       sync.Mutex
+	cassette *Cassette
+	replayed []bool
+}
+
+// Open returns a Recorder of the cassette file at path. In replay mode the
+// file is read at once.
+func Open(path string, mode Mode) (*Recorder, error) {
+	if mode == ModeAuto {
+		mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = ModeReplay
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	r := &Recorder{path: path, mode: mode, cassette: &Cassette{}}
+	if mode == ModeReplay {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+		r.replayed = make([]bool, len(c.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns the mode of the recorder, ModeAuto being resolved by Open.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+func (r *Recorder) redaction() *Redaction {
+	if r.Redaction != nil {
+		return r.Redaction
+	}
+	return &DefaultRedaction
+}
+
+func (r *Recorder) matcher() *Matcher {
+	if r.Matcher != nil {
+		return r.Matcher
+	}
+	return &DefaultMatcher
+}
+
+// Do sends or replays a request.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	redaction := r.redaction()
+	recorded := Request{
+		Method: req.Method,
+		URL:    redaction.url(req.URL).String(),
+		Header: redaction.header(req.Header),
+		Body:   redaction.body(body),
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, &recorded)
+	}
+	return r.record(req, recorded)
+}
+
+func (r *Recorder) replay(req *http.Request, recorded *Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.matcher()
+	for i := range r.cassette.Interactions {
+		it := &r.cassette.Interactions[i]
+		if r.replayed[i] || !m.Match(recorded, &it.Request) {
+			continue
+		}
+		r.replayed[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Response.StatusCode, http.StatusText(it.Response.StatusCode)),
+			StatusCode:    it.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        it.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(it.Response.Body)),
+			ContentLength: int64(len(it.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	doer := r.Doer
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	redaction := r.redaction()
+	header := redaction.header(resp.Header)
+	header.Del("Content-Length")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       redaction.body(body),
+		},
+	})
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
@@ -0,0 +1,150 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+// session runs the same calls against a server, recorded or replayed, and
+// returns the subjects of issue 1 before and after its update.
+func session(t *testing.T, server string, doer redmine.HttpRequestDoer) (string, string) {
+	t.Helper()
+
+	c, err := redmine.NewClientWithResponses(server, redmine.WithHTTPClient(doer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	auth := apiutil.APIKey("admin")
+
+	subject := func() string {
+		resp, err := c.IssuesShowWithResponse(ctx, 1, &redmine.IssuesShowParams{}, auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.JSON200 == nil {
+			t.Fatalf("show: %s", resp.Status())
+		}
+		return *resp.JSON200.Issue.Subject
+	}
+
+	before := subject()
+	body := `{"issue": {"subject": "changed"}}`
+	if resp, err := c.IssuesUpdatePutWithBodyWithResponse(ctx, 1, &redmine.IssuesUpdatePutParams{}, "application/json", strings.NewReader(body), auth); err != nil || resp.StatusCode() != http.StatusNoContent {
+		t.Fatalf("update: %v %v", resp, err)
+	}
+	body = `{"user": {"login": "jsmith", "firstname": "John", "lastname": "Smith", "mail": "jsmith@example.net", "password": "secret-password"}}`
+	if resp, err := c.UsersCreateWithBodyWithResponse(ctx, &redmine.UsersCreateParams{}, "application/json", strings.NewReader(body), auth); err != nil || resp.JSON201 == nil {
+		t.Fatalf("create user: %v %v", resp, err)
+	}
+	return before, subject()
+}
+
+func TestRecordReplay(t *testing.T) {
+	for _, name := range []string{"session.json", "session.yml"} {
+		path := filepath.Join(t.TempDir(), "cassettes", name)
+
+		f := redminetest.DefaultFixtures()
+		f.Projects = []model.Project{{Id: 1, Name: "Project", Identifier: "project"}}
+		f.Issues = []model.Issue{{Id: 1, Project: &model.Ref{Id: 1}, Subject: "original"}}
+		srv := redminetest.NewServer(f)
+		rec, err := Open(path, ModeAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Mode() != ModeRecord {
+			t.Fatalf("%s: mode = %d", name, rec.Mode())
+		}
+		before, after := session(t, srv.URL, rec)
+		srv.Close()
+		if err := rec.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(buf), "secret-password") || !strings.Contains(string(buf), Redacted) {
+			t.Errorf("%s: credentials not redacted:\n%s", name, buf)
+		}
+		c, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key := c.Interactions[0].Request.Header.Get("X-Redmine-API-Key"); key != Redacted {
+			t.Errorf("%s: api key = %q", name, key)
+		}
+
+		rec, err = Open(path, ModeAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Mode() != ModeReplay {
+			t.Fatalf("%s: mode = %d", name, rec.Mode())
+		}
+		if b, a := session(t, "http://replay.invalid", rec); b != before || a != after || b != "original" || a != "changed" {
+			t.Errorf("%s: replayed %q %q, recorded %q %q", name, b, a, before, after)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	recorded := &Request{Method: "PUT", URL: "http://a/issues/1.json?b=2&a=1&key=REDACTED", Body: Body(`{"issue":{"subject":"s","notes":"n"}}`)}
+
+	cases := []struct {
+		name    string
+		matcher Matcher
+		req     Request
+		want    bool
+	}{
+		{"same", DefaultMatcher, Request{Method: "PUT", URL: "http://b/issues/1.json?a=1&key=REDACTED&b=2", Body: Body(`{"issue": {"notes": "n", "subject": "s"}}`)}, true},
+		{"method", DefaultMatcher, Request{Method: "PATCH", URL: recorded.URL, Body: recorded.Body}, false},
+		{"path", DefaultMatcher, Request{Method: "PUT", URL: "http://a/issues/2.json?b=2&a=1&key=REDACTED", Body: recorded.Body}, false},
+		{"query", DefaultMatcher, Request{Method: "PUT", URL: "http://a/issues/1.json?a=1&key=REDACTED", Body: recorded.Body}, false},
+		{"ignored param", Matcher{Method: true, Path: true, Query: true, IgnoreParams: []string{"b"}}, Request{Method: "PUT", URL: "http://a/issues/1.json?a=1&key=REDACTED"}, true},
+		{"body", DefaultMatcher, Request{Method: "PUT", URL: recorded.URL, Body: Body(`{"issue":{"subject":"t"}}`)}, false},
+		{"body ignored", Matcher{Method: true, Path: true}, Request{Method: "PUT", URL: "http://a/issues/1.json"}, true},
+	}
+	for _, c := range cases {
+		if got := c.matcher.Match(&c.req, recorded); got != c.want {
+			t.Errorf("%s: got %v", c.name, got)
+		}
+	}
+}
+
+func TestReplayExhausted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	c := &Cassette{Interactions: []Interaction{{
+		Request:  Request{Method: "GET", URL: "http://a/issues.json"},
+		Response: Response{StatusCode: http.StatusOK, Body: Body(`{"issues":[]}`)},
+	}}}
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []error{nil, ErrNoInteraction} {
+		req, _ := http.NewRequest(http.MethodGet, "http://b/issues.json", nil)
+		resp, err := rec.Do(req)
+		if !errors.Is(err, want) {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
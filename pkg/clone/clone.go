@@ -0,0 +1,350 @@
+// Package clone creates a project from an existing one, such as a template
+// project, with its settings, members, versions, issue categories and wiki
+// pages.
+//
+// Each part is copied item by item: a failed item is reported and does not
+// stop the others. The issues, time entries and news are not copied.
+package clone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Parts of a project, as reported in Step.
+const (
+	PartProject    = "project"
+	PartMembers    = "members"
+	PartVersions   = "versions"
+	PartCategories = "categories"
+	PartWiki       = "wiki"
+)
+
+// Options selects the new project and the parts to copy.
+type Options struct {
+	// Name The name of the new project.
+	Name string
+
+	// Identifier The identifier of the new project.
+	Identifier string
+
+	// Settings Whether to copy the description, homepage, visibility,
+	// parent, modules, trackers and custom fields. The new project has the
+	// defaults of the server otherwise.
+	Settings bool
+
+	// Members Whether to copy the memberships given to users and groups
+	// directly, not those inherited.
+	Members bool
+
+	// Versions Whether to copy the versions of the project, not those
+	// shared by other projects.
+	Versions bool
+
+	// Categories Whether to copy the issue categories, without their
+	// default assignee.
+	Categories bool
+
+	// Wiki Whether to copy the last version of the wiki pages, with their
+	// attachments.
+	Wiki bool
+}
+
+// Step is the copy of an item of a part.
+type Step struct {
+	Part string `json:"part"`
+
+	// Item The name of the item: a project identifier, a user or group
+	// name, a version or category name, or a page title.
+	Item string `json:"item"`
+
+	// Error The error of the copy, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the outcome of a clone.
+type Report struct {
+	// Project The new project.
+	Project *model.Project `json:"project"`
+
+	// Steps The items copied or not, in order.
+	Steps []Step `json:"steps"`
+
+	// Failed The number of steps which failed.
+	Failed int `json:"failed"`
+}
+
+// Cloner copies projects.
+type Cloner struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Progress If set, is called after each step.
+	Progress func(Step)
+}
+
+// Clone creates the project of opts from the project source and copies the
+// parts selected. The error is that of reading the source or of creating
+// the project; the failures of the other steps are in the report.
+func (cl *Cloner) Clone(ctx context.Context, source string, opts Options) (*Report, error) {
+	if opts.Name == "" || opts.Identifier == "" {
+		return nil, errors.New("no name or identifier")
+	}
+
+	src, err := apiutil.Project(ctx, cl.Client, source, []string{"trackers", "enabled_modules", "issue_custom_fields"}, cl.Auth...)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", source, err)
+	}
+
+	p, err := apiutil.CreateProject(ctx, cl.Client, projectFields(src, opts), cl.Auth...)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", opts.Identifier, err)
+	}
+	r := &Report{Project: p}
+	cl.step(r, PartProject, p.Identifier, nil)
+
+	type part struct {
+		name string
+		copy func(ctx context.Context, r *Report, src *model.Project) error
+	}
+	parts := []part{}
+	if opts.Members {
+		parts = append(parts, part{PartMembers, cl.members})
+	}
+	if opts.Versions {
+		parts = append(parts, part{PartVersions, cl.versions})
+	}
+	if opts.Categories {
+		parts = append(parts, part{PartCategories, cl.categories})
+	}
+	if opts.Wiki {
+		parts = append(parts, part{PartWiki, cl.wiki})
+	}
+	for _, pt := range parts {
+		if err := pt.copy(ctx, r, src); err != nil {
+			cl.step(r, pt.name, "", err)
+		}
+	}
+	return r, nil
+}
+
+// projectFields returns the fields of the new project.
+func projectFields(src *model.Project, opts Options) apiutil.ProjectFields {
+	f := apiutil.ProjectFields{Name: opts.Name, Identifier: opts.Identifier}
+	if !opts.Settings {
+		return f
+	}
+
+	f.Description = &src.Description
+	f.Homepage = &src.Homepage
+	f.IsPublic = &src.IsPublic
+	f.InheritMembers = &src.InheritMembers
+	if src.Parent != nil {
+		f.ParentId = &src.Parent.Id
+	}
+
+	trackers, modules, fields := []int{}, []string{}, []int{}
+	for _, t := range src.Trackers {
+		trackers = append(trackers, t.Id)
+	}
+	for _, m := range src.EnabledModules {
+		modules = append(modules, m.Name)
+	}
+	for _, cf := range src.IssueCustomFields {
+		fields = append(fields, cf.Id)
+	}
+	f.TrackerIds, f.EnabledModuleNames, f.IssueCustomFieldIds = &trackers, &modules, &fields
+
+	for _, cf := range src.CustomFields {
+		f.CustomFields = append(f.CustomFields, apiutil.CustomFieldValue{Id: cf.Id, Value: cf.Value})
+	}
+	return f
+}
+
+// members copies the roles given directly to users and groups. The
+// principals already members of the new project, such as its creator, are
+// skipped.
+func (cl *Cloner) members(ctx context.Context, r *Report, src *model.Project) error {
+	members, err := apiutil.Memberships(ctx, cl.Client, src.Identifier, cl.Auth...)
+	if err != nil {
+		return err
+	}
+	existing, err := apiutil.Memberships(ctx, cl.Client, r.Project.Identifier, cl.Auth...)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		principal := m.User
+		if principal == nil {
+			principal = m.Group
+		}
+		if principal == nil || slices.ContainsFunc(existing, func(e model.Membership) bool { return principalId(e) == principal.Id }) {
+			continue
+		}
+		roles := []int{}
+		for _, role := range m.Roles {
+			if !role.Inherited {
+				roles = append(roles, role.Id)
+			}
+		}
+		if len(roles) == 0 {
+			continue
+		}
+
+		_, err := apiutil.CreateMembership(ctx, cl.Client, r.Project.Identifier, principal.Id, roles, cl.Auth...)
+		cl.step(r, PartMembers, principal.Name, err)
+	}
+	return nil
+}
+
+func principalId(m model.Membership) int {
+	switch {
+	case m.User != nil:
+		return m.User.Id
+	case m.Group != nil:
+		return m.Group.Id
+	}
+	return 0
+}
+
+// versions copies the versions owned by the source project.
+func (cl *Cloner) versions(ctx context.Context, r *Report, src *model.Project) error {
+	versions, err := apiutil.Versions(ctx, cl.Client, src.Identifier, cl.Auth...)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		if v.Project == nil || v.Project.Id != src.Id {
+			continue
+		}
+		f := apiutil.VersionFields{
+			Name:          v.Name,
+			Description:   &v.Description,
+			Status:        &v.Status,
+			Sharing:       &v.Sharing,
+			DueDate:       v.DueDate,
+			WikiPageTitle: &v.WikiPageTitle,
+		}
+		for _, cf := range v.CustomFields {
+			f.CustomFields = append(f.CustomFields, apiutil.CustomFieldValue{Id: cf.Id, Value: cf.Value})
+		}
+
+		_, err := apiutil.CreateVersion(ctx, cl.Client, r.Project.Identifier, f, cl.Auth...)
+		cl.step(r, PartVersions, v.Name, err)
+	}
+	return nil
+}
+
+// categories copies the issue categories.
+func (cl *Cloner) categories(ctx context.Context, r *Report, src *model.Project) error {
+	categories, err := apiutil.Categories(ctx, cl.Client, src.Identifier, cl.Auth...)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range categories {
+		_, err := apiutil.CreateCategory(ctx, cl.Client, r.Project.Identifier, c.Name, 0, cl.Auth...)
+		cl.step(r, PartCategories, c.Name, err)
+	}
+	return nil
+}
+
+// wiki copies the wiki pages, parents first. A page whose parent failed
+// is copied at the top level.
+func (cl *Cloner) wiki(ctx context.Context, r *Report, src *model.Project) error {
+	pages, err := apiutil.WikiPages(ctx, cl.Client, src.Identifier, cl.Auth...)
+	if err != nil {
+		return err
+	}
+
+	saved := map[string]bool{}
+	for _, title := range parentsFirst(pages) {
+		err := cl.wikiPage(ctx, src, r.Project, title, saved)
+		if err == nil {
+			saved[title] = true
+		}
+		cl.step(r, PartWiki, title, err)
+	}
+	return nil
+}
+
+func (cl *Cloner) wikiPage(ctx context.Context, src, dst *model.Project, title string, saved map[string]bool) error {
+	page, err := apiutil.WikiPage(ctx, cl.Client, src.Identifier, title, []string{"attachments"}, cl.Auth...)
+	if err != nil {
+		return err
+	}
+
+	comments := "Copied from " + src.Identifier
+	f := apiutil.WikiPageFields{Text: &page.Text, Comments: &comments}
+	if parent := page.ParentTitle(); saved[parent] {
+		f.ParentTitle = &parent
+	}
+	for _, a := range page.Attachments {
+		content, err := apiutil.AttachmentContent(ctx, cl.Client, a.Id, cl.Auth...)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %w", a.Filename, err)
+		}
+		u, err := apiutil.UploadFile(ctx, cl.Client, a.Filename, a.ContentType, bytes.NewReader(content), cl.Auth...)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %w", a.Filename, err)
+		}
+		u.Description = a.Description
+		f.Uploads = append(f.Uploads, *u)
+	}
+	return apiutil.SaveWikiPage(ctx, cl.Client, dst.Identifier, title, f, cl.Auth...)
+}
+
+// parentsFirst returns the titles of pages, each after its parent.
+func parentsFirst(pages []model.WikiPage) []string {
+	children := map[string][]string{}
+	titles := map[string]bool{}
+	for _, p := range pages {
+		titles[p.Title] = true
+	}
+	roots := []string{}
+	for _, p := range pages {
+		if parent := p.ParentTitle(); parent != "" && titles[parent] {
+			children[parent] = append(children[parent], p.Title)
+		} else {
+			roots = append(roots, p.Title)
+		}
+	}
+
+	titlesInOrder := []string{}
+	var add func(title string)
+	add = func(title string) {
+		titlesInOrder = append(titlesInOrder, title)
+		for _, c := range children[title] {
+			add(c)
+		}
+	}
+	for _, title := range roots {
+		add(title)
+	}
+	return titlesInOrder
+}
+
+// step records the outcome of a step and reports the progress.
+func (cl *Cloner) step(r *Report, part, item string, err error) {
+	s := Step{Part: part, Item: item}
+	if err != nil {
+		s.Error = err.Error()
+		r.Failed++
+	}
+	r.Steps = append(r.Steps, s)
+	if cl.Progress != nil {
+		cl.Progress(s)
+	}
+}
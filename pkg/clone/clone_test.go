@@ -0,0 +1,154 @@
+package clone
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{
+		{Id: 1, Name: "Template", Identifier: "tmpl", Description: "Engagement template.",
+			Trackers:          []model.Ref{{Id: 1}, {Id: 3}},
+			EnabledModules:    []model.Ref{{Name: "issue_tracking"}, {Name: "wiki"}},
+			IssueCustomFields: []model.Ref{{Id: 1}}},
+		{Id: 2, Name: "Shared", Identifier: "shared"},
+	}
+	f.Users = append(f.Users, redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith"}})
+	f.Groups = []model.Group{{Id: 10, Name: "Consultants"}}
+	f.Memberships = []model.Membership{
+		{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Group: &model.Ref{Id: 10}, Roles: []model.MembershipRole{{Id: 3}}},
+	}
+	f.CustomFields = []redminetest.CustomField{
+		{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"},
+		{Id: 2, Name: "Owner", CustomizedType: "version", FieldFormat: "string", IsRequired: true},
+	}
+	f.Versions = []model.Version{
+		{Id: 1, Project: &model.Ref{Id: 1}, Name: "Kickoff", Description: "First week", CustomFields: []model.CustomField{{Id: 2, Value: "jsmith"}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Name: "Handover"},
+		{Id: 3, Project: &model.Ref{Id: 2}, Name: "Company", Sharing: "system", CustomFields: []model.CustomField{{Id: 2, Value: "admin"}}},
+	}
+	f.Categories = []redminetest.Category{{Id: 1, Project: &model.Ref{Id: 1}, Name: "Backend"}, {Id: 2, Project: &model.Ref{Id: 1}, Name: "Frontend"}}
+	f.WikiPages = []redminetest.WikiPage{
+		{Project: "tmpl", WikiPage: model.WikiPage{Title: "Setup", Text: "h1. Setup", Parent: &model.WikiParent{Title: "Wiki"}}},
+		{Project: "tmpl", WikiPage: model.WikiPage{Title: "Wiki", Text: "h1. Engagement"}},
+	}
+	return f
+}
+
+func TestClone(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+
+	u, err := apiutil.UploadFile(ctx, c, "plan.txt", "text/plain", strings.NewReader("plan"), auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiutil.SaveWikiPage(ctx, c, "tmpl", "Setup", apiutil.WikiPageFields{Uploads: []apiutil.Upload{*u}}, auth...); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := []Step{}
+	cl := &Cloner{Client: c, Auth: auth, Progress: func(s Step) { progress = append(progress, s) }}
+	opts := Options{Name: "Acme", Identifier: "acme", Settings: true, Members: true, Versions: true, Categories: true, Wiki: true}
+	r, err := cl.Clone(ctx, "tmpl", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []string{}
+	for _, s := range r.Steps {
+		items = append(items, s.Part+":"+s.Item)
+	}
+	want := []string{
+		"project:acme",
+		"members:John Smith", "members:Consultants",
+		"versions:Kickoff", "versions:Handover",
+		"categories:Backend", "categories:Frontend",
+		"wiki:Wiki", "wiki:Setup",
+	}
+	if !slices.Equal(items, want) || !slices.Equal(progress, r.Steps) {
+		t.Fatalf("steps = %v", items)
+	}
+	if r.Failed != 1 || !strings.Contains(r.Steps[4].Error, "Owner cannot be blank") {
+		t.Errorf("failures = %d, %+v", r.Failed, r.Steps[4])
+	}
+
+	p, err := apiutil.Project(ctx, c, "acme", []string{"trackers", "enabled_modules", "issue_custom_fields", "issue_categories"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Description != "Engagement template." || len(p.Trackers) != 2 || p.Trackers[1].Id != 3 || len(p.EnabledModules) != 2 || len(p.IssueCustomFields) != 1 || len(p.IssueCategories) != 2 {
+		t.Errorf("project = %+v", p)
+	}
+
+	members, err := apiutil.Memberships(ctx, c, "acme", auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].User.Id != 2 || members[0].Roles[0].Id != 2 || members[1].Group.Id != 10 {
+		t.Errorf("members = %+v", members)
+	}
+
+	versions, err := apiutil.Versions(ctx, c, "acme", auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, v := range versions {
+		names = append(names, v.Name)
+	}
+	if !slices.Contains(names, "Kickoff") || slices.Contains(names, "Handover") || len(names) != 2 {
+		t.Errorf("versions = %v", names)
+	}
+
+	page, err := apiutil.WikiPage(ctx, c, "acme", "Setup", []string{"attachments"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Text != "h1. Setup" || page.ParentTitle() != "Wiki" || len(page.Attachments) != 1 || page.Attachments[0].Filename != "plan.txt" {
+		t.Errorf("page = %+v", page)
+	}
+}
+
+func TestCloneParts(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+
+	cl := &Cloner{Client: c, Auth: auth}
+	r, err := cl.Clone(ctx, "tmpl", Options{Name: "Bare", Identifier: "bare", Categories: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Steps) != 3 || r.Failed != 0 {
+		t.Errorf("steps = %+v", r.Steps)
+	}
+
+	p, err := apiutil.Project(ctx, c, "bare", []string{"trackers"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Description != "" || len(p.Trackers) != 3 {
+		t.Errorf("project = %+v", p)
+	}
+	if members, _ := apiutil.Memberships(ctx, c, "bare", auth...); len(members) != 0 {
+		t.Errorf("members = %+v", members)
+	}
+
+	if _, err := cl.Clone(ctx, "tmpl", Options{Name: "Bare", Identifier: "bare"}); err == nil || !strings.Contains(err.Error(), "Identifier has already been taken") {
+		t.Errorf("duplicate identifier: %v", err)
+	}
+	if _, err := cl.Clone(ctx, "unknown", Options{Name: "X", Identifier: "x"}); !apiutil.IsNotFound(err) {
+		t.Errorf("unknown source: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package contract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/cassette"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Report is the violations found per operation, each listed once.
+type Report map[string][]Violation
+
+func (r Report) add(violations ...Violation) {
+	for _, v := range violations {
+		if !slices.Contains(r[v.Operation], v) {
+			r[v.Operation] = append(r[v.Operation], v)
+		}
+	}
+}
+
+// Err returns the violations as an error, sorted by operation, or nil when
+// there are none.
+func (r Report) Err() error {
+	if len(r) == 0 {
+		return nil
+	}
+
+	operations := make([]string, 0, len(r))
+	for op := range r {
+		operations = append(operations, op)
+	}
+	slices.Sort(operations)
+
+	lines := []string{}
+	for _, op := range operations {
+		for _, v := range r[op] {
+			lines = append(lines, v.String())
+		}
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// Checker is a redmine.HttpRequestDoer validating the responses of the
+// requests it sends.
+type Checker struct {
+	// Doer sends the requests. Nil means http.DefaultClient.
+	Doer redmine.HttpRequestDoer
+
+	Validator *Validator
+
+	// OnViolation is called with the violations of each response, if not
+	// nil.
+	OnViolation func([]Violation)
+
+	mu     sync.Mutex
+	report Report
+}
+
+// Do sends a request and validates its response. The response is returned
+// as is, whatever its violations.
+func (c *Checker) Do(req *http.Request) (*http.Response, error) {
+	doer := c.Doer
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	violations := c.Validator.Validate(req.Method, req.URL, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if len(violations) > 0 {
+		c.mu.Lock()
+		if c.report == nil {
+			c.report = Report{}
+		}
+		c.report.add(violations...)
+		c.mu.Unlock()
+
+		if c.OnViolation != nil {
+			c.OnViolation(violations)
+		}
+	}
+	return resp, nil
+}
+
+// Report returns the violations found so far.
+func (c *Checker) Report() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := Report{}
+	for _, violations := range c.report {
+		r.add(violations...)
+	}
+	return r
+}
+
+// Verify returns a Checker of the responses of doer which fails the test
+// with the violations found when it ends.
+func Verify(t testing.TB, v *Validator, doer redmine.HttpRequestDoer) *Checker {
+	c := &Checker{Doer: doer, Validator: v}
+	t.Cleanup(func() {
+		if err := c.Report().Err(); err != nil {
+			t.Errorf("responses not matching the specification:\n%v", err)
+		}
+	})
+	return c
+}
+
+// ValidateCassette validates the responses recorded in a cassette.
+func (v *Validator) ValidateCassette(c *cassette.Cassette) (Report, error) {
+	r := Report{}
+	for i, it := range c.Interactions {
+		u, err := url.Parse(it.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("interaction %d: %w", i, err)
+		}
+		r.add(v.Validate(it.Request.Method, u, it.Response.StatusCode, it.Response.Header.Get("Content-Type"), it.Response.Body)...)
+	}
+	return r, nil
+}
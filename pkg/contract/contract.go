@@ -0,0 +1,214 @@
+// Package contract validates the responses of a Redmine server against the
+// full OpenAPI specification, enums included, which the generated client
+// does not enforce.
+//
+// A Validator reports the unknown fields, the type mismatches, the enum
+// violations and the missing required fields of a response per operation.
+// A Checker validates the live responses going through it, and
+// ValidateCassette the responses recorded by package cassette.
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Kind is the kind of a violation.
+type Kind string
+
+const (
+	// KindUnknownField is a field the schema does not declare.
+	KindUnknownField Kind = "unknown field"
+
+	// KindType is a value of a type other than the declared one.
+	KindType Kind = "type mismatch"
+
+	// KindEnum is a value outside the declared enum.
+	KindEnum Kind = "enum violation"
+
+	// KindMissingField is a required field missing from an object.
+	KindMissingField Kind = "missing field"
+
+	// KindStatus is a successful status the operation does not declare.
+	KindStatus Kind = "undocumented status"
+
+	// KindOperation is a request matching no operation.
+	KindOperation Kind = "undocumented operation"
+
+	// KindBody is a body which is not valid JSON.
+	KindBody Kind = "invalid body"
+)
+
+// Violation is a difference between a response and the specification.
+type Violation struct {
+	// Operation The operation ID, or the method and the path of the request
+	// when it matches no operation.
+	Operation string `json:"operation"`
+
+	Kind Kind `json:"kind"`
+
+	// Path The location of the value in the body, such as
+	// `$.issues[].status.name`.
+	Path string `json:"path,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("%s: %s: %s", v.Operation, v.Kind, v.Message)
+	}
+	return fmt.Sprintf("%s: %s at %s: %s", v.Operation, v.Kind, v.Path, v.Message)
+}
+
+// route is a path of the specification.
+type route struct {
+	template string
+	pattern  *regexp.Regexp
+	literals int
+	item     *openapi3.PathItem
+}
+
+var paramPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+func newRoute(template string, item *openapi3.PathItem) route {
+	literals := paramPattern.Split(template, -1)
+	expr := strings.Builder{}
+	n := 0
+	for i, lit := range literals {
+		if i > 0 {
+			expr.WriteString(`[^/]+`)
+		}
+		expr.WriteString(regexp.QuoteMeta(lit))
+		n += len(lit)
+	}
+	return route{template: template, pattern: regexp.MustCompile("^" + expr.String() + "$"), literals: n, item: item}
+}
+
+// Validator validates responses against an OpenAPI specification.
+type Validator struct {
+	// BasePath The path of the Redmine server, removed from the request
+	// paths before they are matched with the specification.
+	BasePath string
+
+	routes []route
+}
+
+// Load returns a Validator of the specification file at path, in JSON or
+// YAML. scripts/gen.sh saves the full specification to openapi.yml at the
+// root of the repository.
+func Load(path string) (*Validator, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := New(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+// New returns a Validator of a specification in JSON or YAML.
+func New(spec []byte) (*Validator, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{}
+	for template, item := range doc.Paths.Map() {
+		v.routes = append(v.routes, newRoute(template, item))
+	}
+	// The most specific paths first, so that /issues/{id}.json does not
+	// hide a literal path such as /issues/new.json.
+	slices.SortFunc(v.routes, func(a, b route) int {
+		if a.literals != b.literals {
+			return b.literals - a.literals
+		}
+		return strings.Compare(a.template, b.template)
+	})
+	return v, nil
+}
+
+// operation returns the operation of a request and its name.
+func (v *Validator) operation(method string, u *url.URL) (*openapi3.Operation, string) {
+	p := strings.TrimPrefix(u.Path, strings.TrimSuffix(v.BasePath, "/"))
+	for _, r := range v.routes {
+		if !r.pattern.MatchString(p) {
+			continue
+		}
+		if op := r.item.GetOperation(strings.ToUpper(method)); op != nil {
+			name := op.OperationID
+			if name == "" {
+				name = strings.ToUpper(method) + " " + r.template
+			}
+			return op, name
+		}
+	}
+	return nil, strings.ToUpper(method) + " " + p
+}
+
+// Validate validates a response to a request. Only the JSON bodies are
+// validated; the responses with an error status the operation does not
+// declare are ignored.
+func (v *Validator) Validate(method string, u *url.URL, status int, contentType string, body []byte) []Violation {
+	op, name := v.operation(method, u)
+	if op == nil {
+		return []Violation{{Operation: name, Kind: KindOperation, Message: "no operation in the specification"}}
+	}
+
+	var resp *openapi3.ResponseRef
+	if op.Responses != nil {
+		resp = op.Responses.Status(status)
+		if resp == nil {
+			resp = op.Responses.Default()
+		}
+	}
+	if resp == nil || resp.Value == nil {
+		if status >= 200 && status < 300 {
+			return []Violation{{Operation: name, Kind: KindStatus, Message: fmt.Sprintf("status %d", status)}}
+		}
+		return nil
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "application/json" {
+		return nil
+	}
+	media := resp.Value.Content.Get("application/json")
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []Violation{{Operation: name, Kind: KindBody, Message: err.Error()}}
+	}
+	// The items of the arrays share their path, so a violation is listed
+	// once however many items have it.
+	var violations []Violation
+	for _, violation := range check("$", media.Schema.Value, value) {
+		violation.Operation = name
+		if !slices.Contains(violations, violation) {
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// ValidateResponse validates a response with the body read by the caller.
+func (v *Validator) ValidateResponse(resp *http.Response, body []byte) []Violation {
+	return v.Validate(resp.Request.Method, resp.Request.URL, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+}
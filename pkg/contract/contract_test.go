@@ -0,0 +1,248 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/cassette"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// schema is the part of a specification exercising each kind of violation.
+// The enums and the required fields are the tests' own, not Redmine's.
+const schema = `
+openapi: 3.0.3
+info:
+  title: Redmine API
+  version: 5.1.0
+paths:
+  /issues.json:
+    get:
+      operationId: issues_index
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  issues:
+                    type: array
+                    items:
+                      $ref: "#/components/schemas/issue"
+                  total_count:
+                    type: integer
+  /issues/{id}.json:
+    get:
+      operationId: issues_show
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  issue:
+                    $ref: "#/components/schemas/issue"
+        "404":
+          description: Not Found
+    put:
+      operationId: issues_update
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "204":
+          description: No Content
+components:
+  schemas:
+    ref:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+    issue:
+      allOf:
+        - type: object
+          required: [id, subject]
+          properties:
+            id:
+              type: integer
+            subject:
+              type: string
+            status:
+              allOf:
+                - $ref: "#/components/schemas/ref"
+                - properties:
+                    is_closed:
+                      type: boolean
+            priority:
+              type: object
+              properties:
+                id:
+                  type: integer
+                  enum: [1, 2, 3]
+                name:
+                  type: string
+            done_ratio:
+              type: number
+              nullable: true
+        - type: object
+          properties:
+            custom_fields:
+              type: array
+              items:
+                type: object
+                properties:
+                  id:
+                    type: integer
+                  value:
+                    oneOf:
+                      - type: string
+                      - type: array
+                        items:
+                          type: string
+`
+
+func newValidator(t *testing.T) *Validator {
+	v, err := New([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// TestSpec checks the routes of the client against the specification saved
+// by scripts/gen.sh.
+func TestSpec(t *testing.T) {
+	v, err := Load("openapi.yml")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("run scripts/gen.sh to save the specification")
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	server := "http://redmine.example.net"
+	must := func(req *http.Request, err error) *http.Request {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	requests := []*http.Request{
+		must(redmine.NewIssuesIndexRequest(server, &redmine.IssuesIndexParams{})),
+		must(redmine.NewIssuesShowRequest(server, 1, &redmine.IssuesShowParams{})),
+		must(redmine.NewProjectsIndexRequest(server, &redmine.ProjectsIndexParams{})),
+		must(redmine.NewTimelogIndexRequest(server, &redmine.TimelogIndexParams{})),
+		must(redmine.NewNewsIndexRequest(server, &redmine.NewsIndexParams{})),
+		must(redmine.NewSearchIndexRequest(server, &redmine.SearchIndexParams{})),
+		must(redmine.NewMyAccountRequest(server, &redmine.MyAccountParams{})),
+	}
+	for _, req := range requests {
+		// The errors not documented are not violations, unlike the routes.
+		for _, violation := range v.Validate(req.Method, req.URL, http.StatusForbidden, "", nil) {
+			t.Error(violation)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	v := newValidator(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		want   []string
+	}{
+		{"valid", "GET", "/issues/1.json", 200, `{"issue":{"id":1,"subject":"s","status":{"id":1,"name":"New","is_closed":false},"priority":{"id":2},"done_ratio":null,"custom_fields":[{"id":1,"value":["a"]}]}}`, nil},
+		{"unknown field", "GET", "/issues/1.json", 200, `{"issue":{"id":1,"subject":"s","status":{"id":1,"color":"red"}}}`, []string{"issues_show: unknown field at $.issue.status.color: string"}},
+		{"type mismatch", "GET", "/issues/1.json", 200, `{"issue":{"id":"1","subject":"s","done_ratio":1.5}}`, []string{"issues_show: type mismatch at $.issue.id: string, want integer"}},
+		{"enum", "GET", "/issues/1.json", 200, `{"issue":{"id":1,"subject":"s","priority":{"id":5}}}`, []string{"issues_show: enum violation at $.issue.priority.id: 5 not in [1,2,3]"}},
+		{"missing field", "GET", "/issues.json", 200, `{"issues":[{"id":1},{"id":2}],"total_count":2}`, []string{"issues_index: missing field at $.issues[].subject: required"}},
+		{"one of", "GET", "/issues/1.json", 200, `{"issue":{"id":1,"subject":"s","custom_fields":[{"id":1,"value":1}]}}`, []string{"issues_show: type mismatch at $.issue.custom_fields[].value: integer, want string"}},
+		{"undocumented error", "GET", "/issues/1.json", 403, `{"errors":[]}`, nil},
+		{"undocumented status", "PUT", "/issues/1.json", 200, ``, []string{"issues_update: undocumented status: status 200"}},
+		{"undocumented operation", "DELETE", "/issues/1.json", 204, ``, []string{"DELETE /issues/1.json: undocumented operation: no operation in the specification"}},
+	}
+	for _, c := range cases {
+		u, _ := url.Parse("http://redmine.example.net" + c.path)
+		got := []string{}
+		for _, violation := range v.Validate(c.method, u, c.status, "application/json; charset=utf-8", []byte(c.body)) {
+			got = append(got, violation.String())
+		}
+		if !slices.Equal(got, append([]string{}, c.want...)) {
+			t.Errorf("%s: got %q", c.name, got)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"issue":{"id":1,"subject":"s","tracker":{"id":1}}}`)
+	}))
+	defer srv.Close()
+
+	chk := &Checker{Validator: newValidator(t)}
+	c, err := redmine.NewClientWithResponses(srv.URL, redmine.WithHTTPClient(chk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		resp, err := c.IssuesShowWithResponse(context.Background(), 1, &redmine.IssuesShowParams{})
+		if err != nil || resp.JSON200 == nil {
+			t.Fatalf("show: %v %v", resp, err)
+		}
+	}
+
+	want := `issues_show: unknown field at $.issue.tracker: object`
+	if err := chk.Report().Err(); err == nil || err.Error() != want {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestValidateCassette(t *testing.T) {
+	c := &cassette.Cassette{Interactions: []cassette.Interaction{
+		{
+			Request:  cassette.Request{Method: "GET", URL: "http://a/issues.json?key=REDACTED"},
+			Response: cassette.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/json"}}, Body: cassette.Body(`{"issues":[{"id":1,"subject":"s","priority":{"id":4}}]}`)},
+		},
+		{
+			Request:  cassette.Request{Method: "GET", URL: "http://a/issues/1.json"},
+			Response: cassette.Response{StatusCode: 404},
+		},
+	}}
+
+	r, err := newValidator(t).ValidateCassette(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || len(r["issues_index"]) != 1 || !strings.Contains(r["issues_index"][0].Message, "4 not in") {
+		t.Errorf("got %v", r)
+	}
+}
@@ -0,0 +1,200 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// typeOf returns the JSON schema type of a decoded JSON value.
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// permits reports whether schema s allows values of type typ. A schema
+// without type allows any value.
+func permits(s *openapi3.Schema, typ string) bool {
+	switch {
+	case s.Type == nil || len(s.Type.Slice()) == 0:
+		return true
+	case typ == "null":
+		return s.Nullable || s.Type.Includes("null")
+	case typ == "integer":
+		return s.Type.Includes("integer") || s.Type.Includes("number")
+	}
+	return s.Type.Includes(typ)
+}
+
+// merge returns schema s with the schemas of its allOf merged into it, so
+// that the fields declared by any of them are known.
+func merge(s *openapi3.Schema) *openapi3.Schema {
+	if len(s.AllOf) == 0 {
+		return s
+	}
+
+	out := *s
+	out.AllOf = nil
+	out.Properties = openapi3.Schemas{}
+	out.Required = slices.Clone(s.Required)
+	for name, p := range s.Properties {
+		out.Properties[name] = p
+	}
+	for _, ref := range s.AllOf {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		sub := merge(ref.Value)
+		if out.Type == nil {
+			out.Type = sub.Type
+		}
+		out.Nullable = out.Nullable || sub.Nullable
+		if out.Items == nil {
+			out.Items = sub.Items
+		}
+		if len(out.Enum) == 0 {
+			out.Enum = sub.Enum
+		}
+		if out.AdditionalProperties.Has == nil && out.AdditionalProperties.Schema == nil {
+			out.AdditionalProperties = sub.AdditionalProperties
+		}
+		out.OneOf = append(out.OneOf, sub.OneOf...)
+		out.AnyOf = append(out.AnyOf, sub.AnyOf...)
+		out.Required = append(out.Required, sub.Required...)
+		for name, p := range sub.Properties {
+			if _, ok := out.Properties[name]; !ok {
+				out.Properties[name] = p
+			}
+		}
+	}
+	return &out
+}
+
+// check returns the violations of value v of the schema s, at path.
+func check(path string, s *openapi3.Schema, v any) []Violation {
+	if s == nil {
+		return nil
+	}
+	s = merge(s)
+
+	if alternatives := append(slices.Clone(s.OneOf), s.AnyOf...); len(alternatives) > 0 {
+		return checkAlternatives(path, alternatives, v)
+	}
+
+	typ := typeOf(v)
+	if !permits(s, typ) {
+		return []Violation{{Kind: KindType, Path: path, Message: fmt.Sprintf("%s, want %s", typ, strings.Join(s.Type.Slice(), " or "))}}
+	}
+	if typ != "null" && len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return []Violation{{Kind: KindEnum, Path: path, Message: fmt.Sprintf("%s not in %s", marshal(v), marshal(s.Enum))}}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return checkObject(path, s, v)
+	case []any:
+		var out []Violation
+		if s.Items != nil {
+			for _, e := range v {
+				out = append(out, check(path+"[]", s.Items.Value, e)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// checkAlternatives returns nil when value v is valid for any of the
+// schemas, and the violations of the closest one otherwise.
+func checkAlternatives(path string, alternatives openapi3.SchemaRefs, v any) []Violation {
+	var best []Violation
+	for i, ref := range alternatives {
+		if ref == nil {
+			continue
+		}
+		out := check(path, ref.Value, v)
+		if len(out) == 0 {
+			return nil
+		}
+		if i == 0 || len(out) < len(best) {
+			best = out
+		}
+	}
+	return best
+}
+
+func checkObject(path string, s *openapi3.Schema, v map[string]any) []Violation {
+	var out []Violation
+
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// An object without properties is free-form unless it forbids them.
+	open := len(s.Properties) == 0 && s.AdditionalProperties.Has == nil
+	for _, name := range names {
+		p := path + "." + name
+		switch prop, ok := s.Properties[name]; {
+		case ok && prop != nil:
+			out = append(out, check(p, prop.Value, v[name])...)
+		case s.AdditionalProperties.Schema != nil:
+			out = append(out, check(p, s.AdditionalProperties.Schema.Value, v[name])...)
+		case open || s.AdditionalProperties.Has != nil && *s.AdditionalProperties.Has:
+		default:
+			out = append(out, Violation{Kind: KindUnknownField, Path: p, Message: typeOf(v[name])})
+		}
+	}
+
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			out = append(out, Violation{Kind: KindMissingField, Path: path + "." + name, Message: "required"})
+		}
+	}
+	return out
+}
+
+// inEnum reports whether value v is one of the values of enum. The values
+// are compared as JSON, so that the numbers of the specification match
+// the decoded ones.
+func inEnum(enum []any, v any) bool {
+	m, _ := json.Marshal(v)
+	return slices.ContainsFunc(enum, func(e any) bool {
+		buf, _ := json.Marshal(e)
+		return string(buf) == string(m)
+	})
+}
+
+// marshal returns value v as JSON, shortened for the messages.
+func marshal(v any) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(buf) > 80 {
+		return string(buf[:77]) + "..."
+	}
+	return string(buf)
+}
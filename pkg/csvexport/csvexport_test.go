@@ -0,0 +1,189 @@
+package csvexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+const issuesJa = "#,トラッカー,ステータス,題名,担当者,開始日,予定工数,進捗率,作成日,プライベート,重要度,備考\r\n" +
+	"12,バグ,新規,ログインできない,山田 太郎,2025/01/06,1.5,30,2025/01/05 10:30,いいえ,高,\r\n" +
+	"13,機能,進行中,\"検索\n改善\",,,,0,2025/01/07 08:00,はい,,要確認\r\n"
+
+func shiftJIS(t *testing.T, s string) string {
+	encoded, err := japanese.ShiftJIS.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func windows1252(t *testing.T, s string) string {
+	encoded, err := charmap.Windows1252.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{"\xef\xbb\xbf#,Subject\n", CharsetUTF8},
+		{"#,Subject\n1,Café\n", CharsetUTF8},
+		{shiftJIS(t, issuesJa), CharsetShiftJIS},
+		{windows1252(t, "#,Subject\n1,Café déjà vu\n"), CharsetWindows1252},
+	}
+	for _, c := range cases {
+		if got := Detect([]byte(c.content)); got != c.want {
+			t.Errorf("Detect(%q) = %s", c.content, got)
+		}
+	}
+}
+
+func TestIssueReader(t *testing.T) {
+	r, err := NewIssueReader(strings.NewReader(shiftJIS(t, issuesJa)), Options{Headers: map[string]string{"重要度": "cf_3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Charset != CharsetShiftJIS || strings.Join(r.Keys(), ",") != "id,tracker,status,subject,assigned_to,start_date,estimated_hours,done_ratio,created_on,is_private,cf_3,備考" {
+		t.Errorf("charset = %s, keys = %v", r.Charset, r.Keys())
+	}
+
+	issues, err := r.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	i := issues[0]
+	if i.Id != 12 || i.Tracker != "バグ" || i.Subject != "ログインできない" || i.AssignedTo != "山田 太郎" ||
+		!i.StartDate.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) || *i.EstimatedHours != 1.5 || i.DoneRatio != 30 ||
+		!i.CreatedOn.Equal(time.Date(2025, 1, 5, 10, 30, 0, 0, time.UTC)) || i.IsPrivate || i.CustomFields[3] != "高" || i.Other != nil {
+		t.Errorf("issue 12 = %+v", i)
+	}
+	i = issues[1]
+	if i.Subject != "検索\n改善" || i.AssignedTo != "" || i.StartDate != nil || i.EstimatedHours != nil || !i.IsPrivate || i.CustomFields != nil || i.Other["備考"] != "要確認" {
+		t.Errorf("issue 13 = %+v", i)
+	}
+}
+
+func TestTimeEntryReader(t *testing.T) {
+	export := windows1252(t, "Date;User;Activity;Project;Issue;Comment;Hours\n"+
+		"01/06/2025;José;Design;Web;Bug #12: Handle #34 crash;Café;1,5\n"+
+		"01/07/2025;José;Meeting;Web;;;0,25\n")
+	r, err := NewTimeEntryReader(strings.NewReader(export), Options{DecimalComma: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Charset != CharsetWindows1252 || len(entries) != 2 {
+		t.Fatalf("charset = %s, entries = %+v", r.Charset, entries)
+	}
+	e := entries[0]
+	if !e.SpentOn.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) || e.User != "José" || e.IssueId != 12 || e.Issue != "Bug #12: Handle #34 crash" || e.Comments != "Café" || e.Hours != 1.5 {
+		t.Errorf("entry = %+v", e)
+	}
+	if e := entries[1]; e.IssueId != 0 || e.Hours != 0.25 {
+		t.Errorf("entry = %+v", e)
+	}
+
+	r, err = NewTimeEntryReader(strings.NewReader("Date,Hours\n2025-01-06,1\n2025-01-07,x\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.All(); err == nil || !strings.Contains(err.Error(), "line 3, Hours") {
+		t.Errorf("invalid hours: %v", err)
+	}
+}
+
+func TestUserAndProjectReaders(t *testing.T) {
+	users, err := NewUserReader(strings.NewReader("\xef\xbb\xbfLogin,First name,Last name,Email,Administrator,Created,Last connection\n"+
+		"jsmith,John,Smith,jsmith@example.net,Yes,01/02/2025 09:15 AM,\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := users.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Login != "jsmith" || !u.Admin || !u.CreatedOn.Equal(time.Date(2025, 1, 2, 9, 15, 0, 0, time.UTC)) || u.LastLoginOn != nil {
+		t.Errorf("user = %+v", u)
+	}
+	if _, err := users.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("end: %v", err)
+	}
+
+	projects, err := NewProjectReader(strings.NewReader("Name,Identifier,Public,Subproject of\nWeb,web,No,Company\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := projects.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Web" || p.Identifier != "web" || p.IsPublic || p.Parent != "Company" {
+		t.Errorf("project = %+v", p)
+	}
+
+	if _, err := NewProjectReader(strings.NewReader(""), Options{}); err == nil {
+		t.Error("empty export")
+	}
+	if _, err := NewProjectReader(strings.NewReader("Name\n"), Options{Charset: "klingon"}); err == nil {
+		t.Error("unknown charset")
+	}
+}
+
+func TestExportIssues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("project_id") {
+		case "secret":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Header().Set("Content-Type", "text/csv; header=present")
+			_, _ = io.WriteString(w, shiftJIS(t, issuesJa))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := redmine.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	body, err := ExportIssues(ctx, c, &redmine.IssuesIndexCsvParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	r, err := NewIssueReader(body, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, err := r.Read(); err != nil || i.Id != 12 || i.Subject != "ログインできない" {
+		t.Errorf("issue = %+v, %v", i, err)
+	}
+
+	project := "secret"
+	if _, err := ExportIssues(ctx, c, &redmine.IssuesIndexCsvParams{Query: &redmine.IssuesIndexCsvParams_Query{ProjectId: &project}}); !apiutil.IsForbidden(err) {
+		t.Errorf("forbidden: %v", err)
+	}
+}
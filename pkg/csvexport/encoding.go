@@ -0,0 +1,104 @@
+package csvexport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// Charsets detected by Detect.
+const (
+	CharsetUTF8        = "UTF-8"
+	CharsetShiftJIS    = "Shift_JIS"
+	CharsetWindows1252 = "windows-1252"
+)
+
+// sniffSize is the length of the beginning of an export read to detect its
+// charset.
+const sniffSize = 64 << 10
+
+var bom = []byte("\xef\xbb\xbf")
+
+// Decode returns a reader of the content of r converted to UTF-8, without
+// BOM, and the charset of r: charset if not empty, else the one detected
+// from the beginning of r.
+func Decode(r io.Reader, charset string) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+	if charset == "" {
+		charset = Detect(head)
+	}
+
+	enc, err := encodingOf(charset)
+	if err != nil {
+		return nil, "", err
+	}
+	if enc == nil {
+		if bytes.HasPrefix(head, bom) {
+			_, _ = br.Discard(len(bom))
+		}
+		return br, charset, nil
+	}
+	return transform.NewReader(br, enc.NewDecoder()), charset, nil
+}
+
+// Detect returns the charset of the beginning of an export: UTF-8 if it
+// starts with a BOM or is valid UTF-8, Shift_JIS if it is valid Shift_JIS
+// with kana, windows-1252 otherwise. Other charsets must be given to
+// Decode.
+func Detect(head []byte) string {
+	if bytes.HasPrefix(head, bom) {
+		return CharsetUTF8
+	}
+	if len(head) == sniffSize {
+		// Do not judge a character cut at the end.
+		if i := bytes.LastIndexByte(head, '\n'); i > 0 {
+			head = head[:i]
+		}
+	}
+
+	if utf8.Valid(head) {
+		return CharsetUTF8
+	}
+	s, err := japanese.ShiftJIS.NewDecoder().Bytes(head)
+	if err == nil && !bytes.ContainsRune(s, utf8.RuneError) && bytes.ContainsFunc(s, isKana) {
+		return CharsetShiftJIS
+	}
+	return CharsetWindows1252
+}
+
+func isKana(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana)
+}
+
+// encodingOf returns the encoding of charset, nil for UTF-8. The charsets
+// are named as in HTML, with the names of Code Page 932 for Shift_JIS.
+func encodingOf(charset string) (encoding.Encoding, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return nil, nil
+	case "cp932", "windows-31j", "shift_jis", "sjis":
+		return japanese.ShiftJIS, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unknown charset %q", charset)
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return nil, nil
+	}
+	return enc, nil
+}
@@ -0,0 +1,62 @@
+package csvexport
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// maxErrorBody is the length of the body of an error response read for its
+// validation errors.
+const maxErrorBody = 64 << 10
+
+// response is the status of an HTTP response as apiutil.Check expects.
+type response struct {
+	*http.Response
+}
+
+func (r response) Status() string {
+	return r.Response.Status
+}
+
+func (r response) StatusCode() int {
+	return r.Response.StatusCode
+}
+
+// body returns the body of a successful export response, to be closed.
+func body(resp *http.Response, err error) (io.ReadCloser, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || http.StatusMultipleChoices <= resp.StatusCode {
+		defer resp.Body.Close()
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, apiutil.Check(response{resp}, buf)
+	}
+	return resp.Body, nil
+}
+
+// ExportIssues returns the CSV export of the issues matching params. The
+// client is the raw one, so that the export is not read in memory.
+func ExportIssues(ctx context.Context, c redmine.ClientInterface, params *redmine.IssuesIndexCsvParams, reqEditors ...redmine.RequestEditorFn) (io.ReadCloser, error) {
+	return body(c.IssuesIndexCsv(ctx, params, reqEditors...))
+}
+
+// ExportTimeEntries returns the CSV export of the time entries matching
+// params.
+func ExportTimeEntries(ctx context.Context, c redmine.ClientInterface, params *redmine.TimelogIndexCsvParams, reqEditors ...redmine.RequestEditorFn) (io.ReadCloser, error) {
+	return body(c.TimelogIndexCsv(ctx, params, reqEditors...))
+}
+
+// ExportUsers returns the CSV export of the users matching params.
+func ExportUsers(ctx context.Context, c redmine.ClientInterface, params *redmine.UsersIndexCsvParams, reqEditors ...redmine.RequestEditorFn) (io.ReadCloser, error) {
+	return body(c.UsersIndexCsv(ctx, params, reqEditors...))
+}
+
+// ExportProjects returns the CSV export of the projects matching params.
+func ExportProjects(ctx context.Context, c redmine.ClientInterface, params *redmine.ProjectsIndexCsvParams, reqEditors ...redmine.RequestEditorFn) (io.ReadCloser, error) {
+	return body(c.ProjectsIndexCsv(ctx, params, reqEditors...))
+}
@@ -0,0 +1,102 @@
+package csvexport
+
+// headers maps the column headers of the exports in English and Japanese to
+// the keys of the fields, as in the c[] parameter of the exports. The
+// headers of the other languages are given with Options.Headers.
+var headers = map[string]string{
+	"#": "id",
+
+	"Project":              "project",
+	"Tracker":              "tracker",
+	"Parent task":          "parent",
+	"Status":               "status",
+	"Priority":             "priority",
+	"Subject":              "subject",
+	"Author":               "author",
+	"Assignee":             "assigned_to",
+	"Updated":              "updated_on",
+	"Category":             "category",
+	"Target version":       "fixed_version",
+	"Start date":           "start_date",
+	"Due date":             "due_date",
+	"Estimated time":       "estimated_hours",
+	"Total estimated time": "total_estimated_hours",
+	"Spent time":           "spent_hours",
+	"Total spent time":     "total_spent_hours",
+	"% Done":               "done_ratio",
+	"Created":              "created_on",
+	"Closed":               "closed_on",
+	"Last updated by":      "last_updated_by",
+	"Related issues":       "relations",
+	"Description":          "description",
+	"Last notes":           "last_notes",
+	"Private":              "is_private",
+	"Date":                 "spent_on",
+	"User":                 "user",
+	"Activity":             "activity",
+	"Issue":                "issue",
+	"Comment":              "comments",
+	"Hours":                "hours",
+	"Login":                "login",
+	"First name":           "firstname",
+	"Last name":            "lastname",
+	"Email":                "mail",
+	"Administrator":        "admin",
+	"Last connection":      "last_login_on",
+	"Name":                 "name",
+	"Identifier":           "identifier",
+	"Homepage":             "homepage",
+	"Public":               "is_public",
+	"Subproject of":        "parent",
+
+	"プロジェクト":   "project",
+	"トラッカー":    "tracker",
+	"親チケット":    "parent",
+	"ステータス":    "status",
+	"優先度":      "priority",
+	"題名":       "subject",
+	"作成者":      "author",
+	"担当者":      "assigned_to",
+	"更新日":      "updated_on",
+	"カテゴリ":     "category",
+	"対象バージョン":  "fixed_version",
+	"開始日":      "start_date",
+	"期日":       "due_date",
+	"予定工数":     "estimated_hours",
+	"合計予定工数":   "total_estimated_hours",
+	"作業時間":     "spent_hours",
+	"合計作業時間":   "total_spent_hours",
+	"進捗率":      "done_ratio",
+	"作成日":      "created_on",
+	"終了日":      "closed_on",
+	"最終更新者":    "last_updated_by",
+	"関連するチケット": "relations",
+	"説明":       "description",
+	"最新の注記":    "last_notes",
+	"プライベート":   "is_private",
+	"日付":       "spent_on",
+	"ユーザー":     "user",
+	"作業分類":     "activity",
+	"チケット":     "issue",
+	"コメント":     "comments",
+	"時間":       "hours",
+	"ログインID":   "login",
+	"名":        "firstname",
+	"姓":        "lastname",
+	"メールアドレス":  "mail",
+	"システム管理者":  "admin",
+	"最終接続日":    "last_login_on",
+	"名称":       "name",
+	"識別子":      "identifier",
+	"ホームページ":   "homepage",
+	"公開":       "is_public",
+	"親プロジェクト名": "parent",
+}
+
+// booleans maps the localized values of the boolean columns.
+var booleans = map[string]bool{
+	"Yes": true, "No": false,
+	"はい": true, "いいえ": false,
+	"true": true, "false": false,
+	"1": true, "0": false,
+}
@@ -0,0 +1,548 @@
+// Package csvexport parses the CSV exports of issues, time entries, users
+// and projects into typed records.
+//
+// The exports are encoded in the charset of the server, often Shift_JIS or
+// windows-1252, with a BOM for UTF-8, and their headers are in the
+// language of the user. A Reader converts the charset, maps the headers
+// back to the keys of the fields and decodes the rows one at a time, so
+// that large exports are streamed. Only the English and Japanese headers
+// are known; those of the other languages are mapped with Options.Headers.
+package csvexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDateLayouts are the layouts of the dates tried in order: ISO 8601
+// and the date formats of the server settings, month first for slashes
+// with the year last.
+var DefaultDateLayouts = []string{"2006-01-02", "2006/01/02", "01/02/2006", "02.01.2006", "02-01-2006"}
+
+// timeLayouts are the layouts of the times following the date of a
+// timestamp.
+var timeLayouts = []string{"", " 15:04", " 03:04 PM", " 3:04 PM", " 15:04:05"}
+
+// Options configures a Reader.
+type Options struct {
+	// Charset The charset of the export, detected if empty.
+	Charset string
+
+	// Comma The field separator, detected from the header line if zero: a
+	// semicolon if it has more semicolons than commas, a comma otherwise.
+	Comma rune
+
+	// DecimalComma Whether the numbers have a comma as decimal separator.
+	DecimalComma bool
+
+	// DateLayouts The layouts of the dates, DefaultDateLayouts if empty.
+	DateLayouts []string
+
+	// Headers Additional headers mapped to keys, for the languages other
+	// than English and Japanese or for custom fields: a key "cf_<id>" is
+	// the custom field with the ID.
+	Headers map[string]string
+}
+
+// Extra holds the columns of a record without a typed field.
+type Extra struct {
+	// CustomFields The values of the columns mapped to custom fields, by ID.
+	CustomFields map[int]string `json:"custom_fields,omitempty"`
+
+	// Other The values of the other columns, by header.
+	Other map[string]string `json:"other,omitempty"`
+}
+
+// Issue is a row of an issue export.
+type Issue struct {
+	// Id The ID of the issue.
+	Id int `json:"id"`
+
+	// Project The name of the project.
+	Project string `json:"project,omitempty"`
+
+	// Tracker The name of the tracker.
+	Tracker string `json:"tracker,omitempty"`
+
+	// ParentId The ID of the parent issue.
+	ParentId int `json:"parent_id,omitempty"`
+
+	// Status The name of the status.
+	Status string `json:"status,omitempty"`
+
+	// Priority The name of the priority.
+	Priority string `json:"priority,omitempty"`
+
+	// Subject The subject of the issue.
+	Subject string `json:"subject,omitempty"`
+
+	// Author The name of the author.
+	Author string `json:"author,omitempty"`
+
+	// AssignedTo The name of the assignee.
+	AssignedTo string `json:"assigned_to,omitempty"`
+
+	// Category The name of the category.
+	Category string `json:"category,omitempty"`
+
+	// FixedVersion The name of the target version.
+	FixedVersion string `json:"fixed_version,omitempty"`
+
+	// StartDate The start date of the issue.
+	StartDate *time.Time `json:"start_date,omitempty"`
+
+	// DueDate The due date of the issue.
+	DueDate *time.Time `json:"due_date,omitempty"`
+
+	// EstimatedHours The estimated hours of the issue.
+	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
+
+	// TotalEstimatedHours The estimated hours of the issue and its subtasks.
+	TotalEstimatedHours *float64 `json:"total_estimated_hours,omitempty"`
+
+	// SpentHours The hours logged on the issue.
+	SpentHours *float64 `json:"spent_hours,omitempty"`
+
+	// TotalSpentHours The hours logged on the issue and its subtasks.
+	TotalSpentHours *float64 `json:"total_spent_hours,omitempty"`
+
+	// DoneRatio The done ratio of the issue.
+	DoneRatio int `json:"done_ratio"`
+
+	// CreatedOn The time the issue was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The time the issue was last updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+
+	// ClosedOn The time the issue was closed.
+	ClosedOn *time.Time `json:"closed_on,omitempty"`
+
+	// LastUpdatedBy The name of the user who last updated the issue.
+	LastUpdatedBy string `json:"last_updated_by,omitempty"`
+
+	// Relations The relations of the issue, as exported.
+	Relations string `json:"relations,omitempty"`
+
+	// Description The description of the issue.
+	Description string `json:"description,omitempty"`
+
+	// LastNotes The last notes added to the issue.
+	LastNotes string `json:"last_notes,omitempty"`
+
+	// IsPrivate Whether the issue is private.
+	IsPrivate bool `json:"is_private,omitempty"`
+
+	Extra
+}
+
+// TimeEntry is a row of a time entry export.
+type TimeEntry struct {
+	// SpentOn The date of the time entry.
+	SpentOn *time.Time `json:"spent_on,omitempty"`
+
+	// User The name of the user.
+	User string `json:"user,omitempty"`
+
+	// Activity The name of the activity.
+	Activity string `json:"activity,omitempty"`
+
+	// Project The name of the project.
+	Project string `json:"project,omitempty"`
+
+	// IssueId The ID of the issue, zero for a time entry on the project.
+	IssueId int `json:"issue_id,omitempty"`
+
+	// Issue The issue as exported, "Tracker #ID: Subject".
+	Issue string `json:"issue,omitempty"`
+
+	// Comments The comments of the time entry.
+	Comments string `json:"comments,omitempty"`
+
+	// Hours The hours of the time entry.
+	Hours float64 `json:"hours"`
+
+	Extra
+}
+
+// User is a row of a user export.
+type User struct {
+	// Login The login of the user.
+	Login string `json:"login"`
+
+	// Firstname The first name of the user.
+	Firstname string `json:"firstname,omitempty"`
+
+	// Lastname The last name of the user.
+	Lastname string `json:"lastname,omitempty"`
+
+	// Mail The email address of the user.
+	Mail string `json:"mail,omitempty"`
+
+	// Admin Whether the user is an administrator.
+	Admin bool `json:"admin,omitempty"`
+
+	// Status The name of the status of the user.
+	Status string `json:"status,omitempty"`
+
+	// CreatedOn The time the user was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// LastLoginOn The time of the last connection of the user.
+	LastLoginOn *time.Time `json:"last_login_on,omitempty"`
+
+	Extra
+}
+
+// Project is a row of a project export.
+type Project struct {
+	// Name The name of the project.
+	Name string `json:"name"`
+
+	// Identifier The identifier of the project.
+	Identifier string `json:"identifier,omitempty"`
+
+	// Description The description of the project.
+	Description string `json:"description,omitempty"`
+
+	// Homepage The homepage of the project.
+	Homepage string `json:"homepage,omitempty"`
+
+	// Status The name of the status of the project.
+	Status string `json:"status,omitempty"`
+
+	// IsPublic Whether the project is public.
+	IsPublic bool `json:"is_public,omitempty"`
+
+	// Parent The name of the parent project.
+	Parent string `json:"parent,omitempty"`
+
+	// CreatedOn The time the project was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	Extra
+}
+
+// setter sets the field of a column of a record of type T from a value.
+type setter[T any] func(r *T, v string, p *parser) error
+
+func text[T any](field func(r *T) *string) setter[T] {
+	return func(r *T, v string, _ *parser) error {
+		*field(r) = v
+		return nil
+	}
+}
+
+func integer[T any](field func(r *T) *int) setter[T] {
+	return func(r *T, v string, p *parser) (err error) {
+		*field(r), err = p.integer(v)
+		return err
+	}
+}
+
+func number[T any](field func(r *T) **float64) setter[T] {
+	return func(r *T, v string, p *parser) error {
+		f, err := p.number(v)
+		*field(r) = &f
+		return err
+	}
+}
+
+func timestamp[T any](field func(r *T) **time.Time) setter[T] {
+	return func(r *T, v string, p *parser) error {
+		t, err := p.time(v)
+		*field(r) = &t
+		return err
+	}
+}
+
+func boolean[T any](field func(r *T) *bool) setter[T] {
+	return func(r *T, v string, _ *parser) error {
+		b, ok := booleans[v]
+		if !ok {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*field(r) = b
+		return nil
+	}
+}
+
+func reference[T any](field func(r *T) *int) setter[T] {
+	return func(r *T, v string, _ *parser) error {
+		m := idPattern.FindStringSubmatch(v)
+		if m == nil {
+			return fmt.Errorf("no issue ID in %q", v)
+		}
+		*field(r), _ = strconv.Atoi(m[1])
+		return nil
+	}
+}
+
+// idPattern matches the ID of an issue as exported: "12", "#12" or
+// "Bug #12: Subject", whose subject may contain other "#".
+var idPattern = regexp.MustCompile(`^(?:.*?#)?(\d+)`)
+
+var issueFields = map[string]setter[Issue]{
+	"id":                    integer(func(r *Issue) *int { return &r.Id }),
+	"project":               text(func(r *Issue) *string { return &r.Project }),
+	"tracker":               text(func(r *Issue) *string { return &r.Tracker }),
+	"parent":                reference(func(r *Issue) *int { return &r.ParentId }),
+	"status":                text(func(r *Issue) *string { return &r.Status }),
+	"priority":              text(func(r *Issue) *string { return &r.Priority }),
+	"subject":               text(func(r *Issue) *string { return &r.Subject }),
+	"author":                text(func(r *Issue) *string { return &r.Author }),
+	"assigned_to":           text(func(r *Issue) *string { return &r.AssignedTo }),
+	"category":              text(func(r *Issue) *string { return &r.Category }),
+	"fixed_version":         text(func(r *Issue) *string { return &r.FixedVersion }),
+	"start_date":            timestamp(func(r *Issue) **time.Time { return &r.StartDate }),
+	"due_date":              timestamp(func(r *Issue) **time.Time { return &r.DueDate }),
+	"estimated_hours":       number(func(r *Issue) **float64 { return &r.EstimatedHours }),
+	"total_estimated_hours": number(func(r *Issue) **float64 { return &r.TotalEstimatedHours }),
+	"spent_hours":           number(func(r *Issue) **float64 { return &r.SpentHours }),
+	"total_spent_hours":     number(func(r *Issue) **float64 { return &r.TotalSpentHours }),
+	"done_ratio":            integer(func(r *Issue) *int { return &r.DoneRatio }),
+	"created_on":            timestamp(func(r *Issue) **time.Time { return &r.CreatedOn }),
+	"updated_on":            timestamp(func(r *Issue) **time.Time { return &r.UpdatedOn }),
+	"closed_on":             timestamp(func(r *Issue) **time.Time { return &r.ClosedOn }),
+	"last_updated_by":       text(func(r *Issue) *string { return &r.LastUpdatedBy }),
+	"relations":             text(func(r *Issue) *string { return &r.Relations }),
+	"description":           text(func(r *Issue) *string { return &r.Description }),
+	"last_notes":            text(func(r *Issue) *string { return &r.LastNotes }),
+	"is_private":            boolean(func(r *Issue) *bool { return &r.IsPrivate }),
+}
+
+var timeEntryFields = map[string]setter[TimeEntry]{
+	"spent_on": timestamp(func(r *TimeEntry) **time.Time { return &r.SpentOn }),
+	"user":     text(func(r *TimeEntry) *string { return &r.User }),
+	"activity": text(func(r *TimeEntry) *string { return &r.Activity }),
+	"project":  text(func(r *TimeEntry) *string { return &r.Project }),
+	"issue": func(r *TimeEntry, v string, p *parser) error {
+		r.Issue = v
+		return reference(func(r *TimeEntry) *int { return &r.IssueId })(r, v, p)
+	},
+	"comments": text(func(r *TimeEntry) *string { return &r.Comments }),
+	"hours": func(r *TimeEntry, v string, p *parser) (err error) {
+		r.Hours, err = p.number(v)
+		return err
+	},
+}
+
+var userFields = map[string]setter[User]{
+	"login":         text(func(r *User) *string { return &r.Login }),
+	"firstname":     text(func(r *User) *string { return &r.Firstname }),
+	"lastname":      text(func(r *User) *string { return &r.Lastname }),
+	"mail":          text(func(r *User) *string { return &r.Mail }),
+	"admin":         boolean(func(r *User) *bool { return &r.Admin }),
+	"status":        text(func(r *User) *string { return &r.Status }),
+	"created_on":    timestamp(func(r *User) **time.Time { return &r.CreatedOn }),
+	"last_login_on": timestamp(func(r *User) **time.Time { return &r.LastLoginOn }),
+}
+
+var projectFields = map[string]setter[Project]{
+	"name":        text(func(r *Project) *string { return &r.Name }),
+	"identifier":  text(func(r *Project) *string { return &r.Identifier }),
+	"description": text(func(r *Project) *string { return &r.Description }),
+	"homepage":    text(func(r *Project) *string { return &r.Homepage }),
+	"status":      text(func(r *Project) *string { return &r.Status }),
+	"is_public":   boolean(func(r *Project) *bool { return &r.IsPublic }),
+	"parent":      text(func(r *Project) *string { return &r.Parent }),
+	"created_on":  timestamp(func(r *Project) **time.Time { return &r.CreatedOn }),
+}
+
+// parser parses the values of the columns.
+type parser struct {
+	decimalComma bool
+	dateLayouts  []string
+}
+
+func (p *parser) integer(v string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(v))
+}
+
+func (p *parser) number(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	if p.decimalComma {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// time parses a date or a timestamp in one of the date layouts.
+func (p *parser) time(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	for _, d := range p.dateLayouts {
+		for _, t := range timeLayouts {
+			if parsed, err := time.Parse(d+t, v); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", v)
+}
+
+// column is a column of an export.
+type column[T any] struct {
+	header string
+	key    string
+	set    setter[T]
+}
+
+// Reader decodes the rows of an export into records of type T.
+type Reader[T any] struct {
+	// Charset The charset of the export.
+	Charset string
+
+	csv     *csv.Reader
+	columns []column[T]
+	parser  *parser
+	extra   func(r *T) *Extra
+}
+
+// NewIssueReader returns a reader of an issue export.
+func NewIssueReader(r io.Reader, opts Options) (*Reader[Issue], error) {
+	return newReader(r, opts, issueFields, func(r *Issue) *Extra { return &r.Extra })
+}
+
+// NewTimeEntryReader returns a reader of a time entry export.
+func NewTimeEntryReader(r io.Reader, opts Options) (*Reader[TimeEntry], error) {
+	return newReader(r, opts, timeEntryFields, func(r *TimeEntry) *Extra { return &r.Extra })
+}
+
+// NewUserReader returns a reader of a user export.
+func NewUserReader(r io.Reader, opts Options) (*Reader[User], error) {
+	return newReader(r, opts, userFields, func(r *User) *Extra { return &r.Extra })
+}
+
+// NewProjectReader returns a reader of a project export.
+func NewProjectReader(r io.Reader, opts Options) (*Reader[Project], error) {
+	return newReader(r, opts, projectFields, func(r *Project) *Extra { return &r.Extra })
+}
+
+// newReader reads the header line of the export r.
+func newReader[T any](r io.Reader, opts Options, fields map[string]setter[T], extra func(r *T) *Extra) (*Reader[T], error) {
+	decoded, charset, err := Decode(r, opts.Charset)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(decoded, sniffSize)
+	comma := opts.Comma
+	if comma == 0 {
+		comma = detectComma(br)
+	}
+
+	layouts := opts.DateLayouts
+	if len(layouts) == 0 {
+		layouts = DefaultDateLayouts
+	}
+	rd := &Reader[T]{
+		Charset: charset,
+		csv:     csv.NewReader(br),
+		parser:  &parser{decimalComma: opts.DecimalComma, dateLayouts: layouts},
+		extra:   extra,
+	}
+	rd.csv.Comma = comma
+
+	header, err := rd.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty export")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range header {
+		h = strings.TrimSpace(h)
+		key, ok := opts.Headers[h]
+		if !ok {
+			key = headers[h]
+		}
+		rd.columns = append(rd.columns, column[T]{header: h, key: key, set: fields[key]})
+	}
+	return rd, nil
+}
+
+// detectComma returns the separator of the header line at the beginning of
+// br.
+func detectComma(br *bufio.Reader) rune {
+	head, _ := br.Peek(sniffSize)
+	line, _, _ := strings.Cut(string(head), "\n")
+	if strings.Count(line, ";") > strings.Count(line, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// Keys returns the keys of the columns, the header for a column not
+// mapped to a field.
+func (rd *Reader[T]) Keys() []string {
+	keys := []string{}
+	for _, c := range rd.columns {
+		if c.key == "" {
+			keys = append(keys, c.header)
+		} else {
+			keys = append(keys, c.key)
+		}
+	}
+	return keys
+}
+
+// Read returns the next record, io.EOF after the last one.
+func (rd *Reader[T]) Read() (*T, error) {
+	row, err := rd.csv.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(T)
+	for i, v := range row {
+		if v == "" || i >= len(rd.columns) {
+			continue
+		}
+		c := rd.columns[i]
+		if c.set != nil {
+			if err := c.set(r, v, rd.parser); err != nil {
+				line, _ := rd.csv.FieldPos(i)
+				return nil, fmt.Errorf("line %d, %s: %w", line, c.header, err)
+			}
+			continue
+		}
+
+		e := rd.extra(r)
+		if id, ok := strings.CutPrefix(c.key, "cf_"); ok {
+			if n, err := strconv.Atoi(id); err == nil {
+				if e.CustomFields == nil {
+					e.CustomFields = map[int]string{}
+				}
+				e.CustomFields[n] = v
+				continue
+			}
+		}
+		if e.Other == nil {
+			e.Other = map[string]string{}
+		}
+		e.Other[c.header] = v
+	}
+	return r, nil
+}
+
+// All returns the remaining records.
+func (rd *Reader[T]) All() ([]T, error) {
+	records := []T{}
+	for {
+		r, err := rd.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+}
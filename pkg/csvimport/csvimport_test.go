@@ -0,0 +1,166 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+const mapping = `
+project: web
+separator: ";"
+date_format: 02/01/2006
+decimal_comma: true
+columns:
+  id: "#"
+  unique_id: Ref
+  tracker: Type
+  status: State
+  subject: Title
+  assigned_to: Owner
+  fixed_version: Release
+  parent: Parent
+  due_date: Due
+  estimated_hours: Estimate
+  cf_1: Team
+`
+
+const file = "#;Ref;Type;State;Title;Owner;Release;Parent;Due;Estimate;Team\n" +
+	";child;Bug;;Fix login;John Smith;1.0;epic;31/01/2025;1,5;Web\n" +
+	";epic;feature;In Progress;Login;jsmith;;#10;;;\n" +
+	"10;;;Resolved;;;;;;;\n" +
+	";bad;Task;;;nobody;2.0;;30/02/2025;;\n" +
+	";orphan;;;Orphan;;;bad;;;\n"
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Web", Identifier: "web"}}
+	f.Users = append(f.Users, redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith"}})
+	f.Memberships = []model.Membership{{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}}}
+	f.CustomFields = []redminetest.CustomField{{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"}}
+	f.Versions = []model.Version{{Id: 3, Project: &model.Ref{Id: 1}, Name: "1.0", Status: "open"}}
+	f.Issues = []model.Issue{{Id: 10, Project: &model.Ref{Id: 1}, Tracker: &model.Ref{Id: 1}, Status: &model.Status{Id: 1}, Priority: &model.Ref{Id: 2}, Subject: "Old"}}
+	return f
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping([]byte(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Project != "web" || m.Separator != ";" || m.Columns[FieldUniqueId] != "Ref" || m.Columns["cf_1"] != "Team" {
+		t.Errorf("mapping = %+v", m)
+	}
+
+	invalid := map[string]string{
+		"project: web":                           "no column",
+		"columns: {owner: Owner}":                "unknown field",
+		"columns: {cf_x: X}":                     "unknown field",
+		"columns: {subject: ''}":                 "no column",
+		"separator: ';;'\ncolumns: {subject: S}": "invalid separator",
+	}
+	for src, want := range invalid {
+		if _, err := ParseMapping([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	m, err := ParseMapping([]byte(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dry := &Importer{Client: c, Auth: auth, Mapping: m, DryRun: true}
+	results, err := dry.Import(ctx, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []string{}
+	for _, r := range results {
+		actions = append(actions, r.Action)
+	}
+	if strings.Join(actions, ",") != "valid,valid,valid,failed,failed" {
+		t.Errorf("dry run = %+v", results)
+	}
+	if issues, _ := apiutil.Issues(ctx, c, nil, auth...); len(issues) != 1 {
+		t.Errorf("dry run wrote %d issues", len(issues))
+	}
+
+	im := &Importer{Client: c, Auth: auth, Mapping: m}
+	results, err = im.Import(ctx, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, epic := results[0], results[1]
+	if child.Action != ActionCreated || epic.Action != ActionCreated || epic.Id >= child.Id {
+		t.Fatalf("results = %+v", results)
+	}
+	if r := results[2]; r.Action != ActionUpdated || r.Id != 10 {
+		t.Errorf("update = %+v", r)
+	}
+	if r := results[3]; r.Action != ActionFailed ||
+		!strings.Contains(r.Error, `tracker: tracker "Task" not found`) || !strings.Contains(r.Error, `assigned_to: user "nobody" not found`) ||
+		!strings.Contains(r.Error, `fixed_version: version "2.0" not found`) || !strings.Contains(r.Error, "due_date") || !strings.Contains(r.Error, "no subject") {
+		t.Errorf("bad = %+v", r)
+	}
+	if r := results[4]; r.Action != ActionFailed || r.Error != `parent row "bad" failed` {
+		t.Errorf("orphan = %+v", r)
+	}
+
+	i, err := apiutil.Issue(ctx, c, child.Id, nil, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Parent == nil || i.Parent.Id != epic.Id || i.Tracker.Id != 1 || i.AssignedTo.Id != 2 || i.FixedVersion.Id != 3 ||
+		i.DueDate.String() != "2025-01-31" || *i.EstimatedHours != 1.5 || i.CustomFields[0].Value != "Web" {
+		t.Errorf("child = %+v", i)
+	}
+	if i, err := apiutil.Issue(ctx, c, epic.Id, nil, auth...); err != nil || i.Tracker.Id != 2 || i.Status.Id != 2 || i.Parent == nil || i.Parent.Id != 10 {
+		t.Errorf("epic = %+v, %v", i, err)
+	}
+	if i, err := apiutil.Issue(ctx, c, 10, nil, auth...); err != nil || i.Status.Id != 3 || i.Subject != "Old" {
+		t.Errorf("updated = %+v, %v", i, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, results[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "row,unique_id,id,action,error\n4,bad,,failed,") || !strings.HasSuffix(buf.String(), "5,orphan,,failed,\"parent row \"\"bad\"\" failed\"\n") {
+		t.Errorf("results = %q", buf.String())
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+
+	im := &Importer{Client: c, Auth: auth, Mapping: &Mapping{Columns: map[string]string{FieldSubject: "Subject"}}}
+	if _, err := im.Import(ctx, strings.NewReader("Title\nA\n")); err == nil || !strings.Contains(err.Error(), `column "Subject" of field "subject" not found`) {
+		t.Errorf("missing column: %v", err)
+	}
+	if _, err := im.Import(ctx, strings.NewReader("")); err == nil {
+		t.Error("empty file")
+	}
+
+	im.Mapping = &Mapping{Project: "web", Columns: map[string]string{FieldSubject: "Subject", FieldParent: "Parent"}}
+	results, err := im.Import(ctx, strings.NewReader("Subject,Parent\nA,2\nB,1\nC,9\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Error != "circular parent rows" || results[1].Error != "circular parent rows" || results[2].Error != `parent row "9" not found` {
+		t.Errorf("results = %+v", results)
+	}
+}
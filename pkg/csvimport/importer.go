@@ -0,0 +1,495 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/csvexport"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Actions of the results.
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionValid   = "valid"
+	ActionFailed  = "failed"
+)
+
+// Result is the outcome of the import of a row.
+type Result struct {
+	// Row The number of the row, 1 for the first one after the header.
+	Row int `json:"row"`
+
+	// UniqueId The value of the column mapped to unique_id.
+	UniqueId string `json:"unique_id,omitempty"`
+
+	// Id The ID of the issue created or updated, 0 on a dry run or a
+	// failure.
+	Id int `json:"id,omitempty"`
+
+	// Action What was done: created, updated, valid on a dry run, or
+	// failed.
+	Action string `json:"action"`
+
+	// Error The reason of a failure.
+	Error string `json:"error,omitempty"`
+}
+
+// Importer creates and updates the issues of the rows of CSV files.
+type Importer struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Mapping The mapping of the columns.
+	Mapping *Mapping
+
+	// DryRun Whether to only validate the rows, without writing anything.
+	DryRun bool
+
+	trackers   []model.Tracker
+	statuses   []model.Status
+	priorities []model.Ref
+	users      []model.Ref // once per name a user may be given by
+	versions   map[string][]model.Version
+	categories map[string][]model.Ref
+}
+
+// row is a row of a file ready to be imported.
+type row struct {
+	result Result
+	id     int
+	fields apiutil.IssueFields
+
+	// parent The unique ID of the parent row, empty if none.
+	parent string
+}
+
+// Import imports the rows of r, parents first, and returns a result for
+// each of them, in the order of the file. A row that fails does not stop
+// the import, but its children fail. Names are resolved before anything
+// is written. The error is for a file that cannot be read or whose
+// headers miss a mapped column.
+func (im *Importer) Import(ctx context.Context, r io.Reader) ([]Result, error) {
+	records, err := im.read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*row, len(records))
+	byUniqueId := map[string]*row{}
+	for n, record := range records {
+		rows[n] = im.plan(ctx, n+1, record)
+		key := strconv.Itoa(n + 1)
+		if _, ok := im.Mapping.Columns[FieldUniqueId]; ok {
+			key = rows[n].result.UniqueId
+		}
+		if key == "" {
+			continue
+		}
+		if _, ok := byUniqueId[key]; ok {
+			rows[n].fail(fmt.Errorf("duplicate unique ID %q", key))
+			continue
+		}
+		byUniqueId[key] = rows[n]
+	}
+
+	for pending := rows; len(pending) > 0; {
+		next := []*row{}
+		for _, r := range pending {
+			if r.result.Action == ActionFailed {
+				continue
+			}
+			if r.parent != "" {
+				p, ok := byUniqueId[r.parent]
+				switch {
+				case !ok:
+					r.fail(fmt.Errorf("parent row %q not found", r.parent))
+					continue
+				case p.result.Action == ActionFailed:
+					r.fail(fmt.Errorf("parent row %q failed", r.parent))
+					continue
+				case p.result.Action == "":
+					next = append(next, r)
+					continue
+				}
+				r.fields.ParentIssueId = &p.result.Id
+			}
+			im.write(ctx, r)
+		}
+		if len(next) == len(pending) {
+			for _, r := range next {
+				r.fail(errors.New("circular parent rows"))
+			}
+			break
+		}
+		pending = next
+	}
+
+	results := make([]Result, len(rows))
+	for n, r := range rows {
+		results[n] = r.result
+	}
+	return results, nil
+}
+
+// read returns the records of r after the header, as the values of the
+// mapped columns by field.
+func (im *Importer) read(r io.Reader) ([]map[string]string, error) {
+	decoded, _, err := csvexport.Decode(r, im.Mapping.Charset)
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(decoded)
+	if im.Mapping.Separator != "" {
+		cr.Comma = []rune(im.Mapping.Separator)[0]
+	}
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for field, h := range im.Mapping.Columns {
+		i := indexOf(header, h)
+		if i < 0 {
+			return nil, fmt.Errorf("column %q of field %q not found", h, field)
+		}
+		columns[field] = i
+	}
+
+	records := []map[string]string{}
+	for {
+		values, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := map[string]string{}
+		for field, i := range columns {
+			if i < len(values) {
+				record[field] = strings.TrimSpace(values[i])
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+func indexOf(header []string, h string) int {
+	for i, v := range header {
+		if strings.TrimSpace(v) == h {
+			return i
+		}
+	}
+	return -1
+}
+
+// plan converts the values of a record to the fields of its issue.
+func (im *Importer) plan(ctx context.Context, n int, record map[string]string) *row {
+	r := &row{result: Result{Row: n, UniqueId: record[FieldUniqueId]}, parent: record[FieldParent]}
+	errs := []error{}
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+
+	if v := record[FieldId]; v != "" {
+		id, err := strconv.Atoi(strings.TrimPrefix(v, "#"))
+		check(FieldId, err)
+		r.id = id
+	}
+	project := record[FieldProject]
+	if project == "" {
+		project = im.Mapping.Project
+	}
+	if r.id == 0 {
+		if project == "" {
+			errs = append(errs, errors.New("no project"))
+		}
+		if record[FieldSubject] == "" {
+			errs = append(errs, errors.New("no subject"))
+		}
+	}
+	if record[FieldProject] != "" {
+		r.fields.ProjectId = project
+	}
+	if id, ok := strings.CutPrefix(r.parent, "#"); ok {
+		parent, err := strconv.Atoi(id)
+		check(FieldParent, err)
+		r.fields.ParentIssueId = &parent
+		r.parent = ""
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(record)) {
+		v := record[field]
+		if v == "" {
+			continue
+		}
+		var err error
+		switch field {
+		case FieldTracker:
+			r.fields.TrackerId, err = im.resolve(ctx, v, im.resolveTracker)
+		case FieldStatus:
+			r.fields.StatusId, err = im.resolve(ctx, v, im.resolveStatus)
+		case FieldPriority:
+			r.fields.PriorityId, err = im.resolve(ctx, v, im.resolvePriority)
+		case FieldAssignedTo:
+			r.fields.AssignedToId, err = im.resolve(ctx, v, im.resolveUser)
+		case FieldCategory:
+			r.fields.CategoryId, err = im.resolve(ctx, v, func(ctx context.Context, v string) (int, error) {
+				return im.resolveCategory(ctx, project, v)
+			})
+		case FieldFixedVersion:
+			r.fields.FixedVersionId, err = im.resolve(ctx, v, func(ctx context.Context, v string) (int, error) {
+				return im.resolveVersion(ctx, project, v)
+			})
+		case FieldWatchers:
+			for _, login := range strings.Split(v, ",") {
+				id, e := im.resolveUser(ctx, strings.TrimSpace(login))
+				if e != nil {
+					err = e
+					break
+				}
+				r.fields.WatcherUserIds = append(r.fields.WatcherUserIds, id)
+			}
+		case FieldSubject:
+			r.fields.Subject = &v
+		case FieldDescription:
+			r.fields.Description = &v
+		case FieldNotes:
+			r.fields.Notes = &v
+		case FieldStartDate:
+			r.fields.StartDate, err = im.parseDate(v)
+		case FieldDueDate:
+			r.fields.DueDate, err = im.parseDate(v)
+		case FieldEstimatedHours:
+			if im.Mapping.DecimalComma {
+				v = strings.Replace(v, ",", ".", 1)
+			}
+			var hours float64
+			hours, err = strconv.ParseFloat(v, 64)
+			r.fields.EstimatedHours = &hours
+		case FieldDoneRatio:
+			var ratio int
+			ratio, err = strconv.Atoi(strings.TrimSuffix(v, "%"))
+			r.fields.DoneRatio = &ratio
+		case FieldIsPrivate:
+			var private bool
+			private, err = parseBool(v)
+			r.fields.IsPrivate = &private
+		default:
+			if id, e := customFieldId(field); e == nil {
+				r.fields.CustomFields = append(r.fields.CustomFields, apiutil.CustomFieldValue{Id: id, Value: v})
+			}
+		}
+		check(field, err)
+	}
+
+	if len(errs) > 0 {
+		r.fail(errors.Join(errs...))
+	}
+	return r
+}
+
+// write creates or updates the issue of r, unless on a dry run.
+func (im *Importer) write(ctx context.Context, r *row) {
+	switch {
+	case im.DryRun:
+		r.result.Action = ActionValid
+	case r.id != 0:
+		if err := apiutil.UpdateIssue(ctx, im.Client, r.id, r.fields, im.Auth...); err != nil {
+			r.fail(err)
+			return
+		}
+		r.result.Id = r.id
+		r.result.Action = ActionUpdated
+	default:
+		project := r.fields.ProjectId
+		if project == "" {
+			project = im.Mapping.Project
+		}
+		r.fields.ProjectId = ""
+		issue, err := apiutil.CreateProjectIssue(ctx, im.Client, project, r.fields, im.Auth...)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		r.result.Id = issue.Id
+		r.result.Action = ActionCreated
+	}
+}
+
+func (r *row) fail(err error) {
+	r.result.Action = ActionFailed
+	r.result.Error = strings.ReplaceAll(err.Error(), "\n", "; ")
+}
+
+func (im *Importer) parseDate(v string) (*openapi_types.Date, error) {
+	layout := im.Mapping.DateFormat
+	if layout == "" {
+		layout = time.DateOnly
+	}
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return nil, err
+	}
+	return &openapi_types.Date{Time: t}, nil
+}
+
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "yes", "はい":
+		return true, nil
+	case "no", "いいえ":
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// resolve returns a pointer to the ID of the name v, by fn.
+func (im *Importer) resolve(ctx context.Context, v string, fn func(context.Context, string) (int, error)) (*int, error) {
+	id, err := fn(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (im *Importer) resolveTracker(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.trackers == nil {
+		trackers, err := apiutil.Trackers(ctx, im.Client, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		im.trackers = trackers
+	}
+	return apiutil.FindByName("tracker", v, im.trackers, func(t model.Tracker) (int, string) { return t.Id, t.Name })
+}
+
+func (im *Importer) resolveStatus(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.statuses == nil {
+		statuses, err := apiutil.Statuses(ctx, im.Client, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		im.statuses = statuses
+	}
+	return apiutil.FindByName("status", v, im.statuses, func(s model.Status) (int, string) { return s.Id, s.Name })
+}
+
+func (im *Importer) resolvePriority(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.priorities == nil {
+		priorities, err := apiutil.Priorities(ctx, im.Client, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		im.priorities = priorities
+	}
+	return apiutil.FindByName("priority", v, im.priorities, func(r model.Ref) (int, string) { return r.Id, r.Name })
+}
+
+// resolveUser accepts a user ID, login, email address or full name.
+func (im *Importer) resolveUser(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.users == nil {
+		users, err := apiutil.Users(ctx, im.Client, nil, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		im.users = []model.Ref{}
+		for _, u := range users {
+			for _, name := range []string{u.Login, u.Mail, u.Firstname + " " + u.Lastname, u.Lastname + " " + u.Firstname} {
+				im.users = append(im.users, model.Ref{Id: u.Id, Name: name})
+			}
+		}
+	}
+	return apiutil.FindByName("user", v, im.users, func(r model.Ref) (int, string) { return r.Id, r.Name })
+}
+
+func (im *Importer) resolveVersion(ctx context.Context, project, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.versions[project] == nil {
+		if project == "" {
+			return 0, errors.New("no project")
+		}
+		versions, err := apiutil.Versions(ctx, im.Client, project, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		if im.versions == nil {
+			im.versions = map[string][]model.Version{}
+		}
+		im.versions[project] = versions
+	}
+	return apiutil.FindByName("version", v, im.versions[project], func(v model.Version) (int, string) { return v.Id, v.Name })
+}
+
+func (im *Importer) resolveCategory(ctx context.Context, project, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if im.categories[project] == nil {
+		if project == "" {
+			return 0, errors.New("no project")
+		}
+		categories, err := apiutil.Categories(ctx, im.Client, project, im.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		if im.categories == nil {
+			im.categories = map[string][]model.Ref{}
+		}
+		im.categories[project] = categories
+	}
+	return apiutil.FindByName("category", v, im.categories[project], func(r model.Ref) (int, string) { return r.Id, r.Name })
+}
+
+// WriteResults writes results as CSV, with a header.
+func WriteResults(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"row", "unique_id", "id", "action", "error"})
+	for _, r := range results {
+		id := ""
+		if r.Id != 0 {
+			id = strconv.Itoa(r.Id)
+		}
+		_ = cw.Write([]string{strconv.Itoa(r.Row), r.UniqueId, id, r.Action, r.Error})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,134 @@
+// Package csvimport creates and updates issues from the rows of a CSV file,
+// as the import of the Redmine web interface does, with the mapping of the
+// columns to the fields in a YAML file:
+//
+//	project: web
+//	charset: Shift_JIS
+//	date_format: 2006/01/02
+//	columns:
+//	  unique_id: Ref
+//	  tracker: Type
+//	  subject: Title
+//	  assigned_to: Owner
+//	  parent: Parent
+//	  cf_3: Severity
+//
+// The trackers, statuses, priorities, users, versions and categories are
+// given by ID or name, the users also by login or email address. A row
+// with an ID updates the issue, the other rows create one. The parent of a
+// row is "#<id>" for an existing issue, else the unique ID, or the number
+// if no column is mapped to unique_id, of another row, which is imported
+// first.
+package csvimport
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fields of the issues, as keys of Mapping.Columns. A key "cf_<id>" is the
+// custom field with the ID.
+const (
+	FieldId             = "id"
+	FieldUniqueId       = "unique_id"
+	FieldProject        = "project"
+	FieldTracker        = "tracker"
+	FieldStatus         = "status"
+	FieldPriority       = "priority"
+	FieldSubject        = "subject"
+	FieldDescription    = "description"
+	FieldAssignedTo     = "assigned_to"
+	FieldCategory       = "category"
+	FieldFixedVersion   = "fixed_version"
+	FieldParent         = "parent"
+	FieldStartDate      = "start_date"
+	FieldDueDate        = "due_date"
+	FieldEstimatedHours = "estimated_hours"
+	FieldDoneRatio      = "done_ratio"
+	FieldIsPrivate      = "is_private"
+	FieldNotes          = "notes"
+	FieldWatchers       = "watchers"
+)
+
+var fields = []string{
+	FieldId, FieldUniqueId, FieldProject, FieldTracker, FieldStatus, FieldPriority, FieldSubject,
+	FieldDescription, FieldAssignedTo, FieldCategory, FieldFixedVersion, FieldParent, FieldStartDate,
+	FieldDueDate, FieldEstimatedHours, FieldDoneRatio, FieldIsPrivate, FieldNotes, FieldWatchers,
+}
+
+// Mapping describes a CSV file and the fields of its columns.
+type Mapping struct {
+	// Project The ID or identifier of the project of the new issues, when
+	// no column is mapped to project or its value is empty.
+	Project string `yaml:"project,omitempty"`
+
+	// Charset The charset of the file, detected if empty.
+	Charset string `yaml:"charset,omitempty"`
+
+	// Separator The field separator, a comma if empty.
+	Separator string `yaml:"separator,omitempty"`
+
+	// DateFormat The layout of the dates, as in the time package,
+	// 2006-01-02 if empty.
+	DateFormat string `yaml:"date_format,omitempty"`
+
+	// DecimalComma Whether the numbers have a comma as decimal separator.
+	DecimalComma bool `yaml:"decimal_comma,omitempty"`
+
+	// Columns The headers of the columns, by field.
+	Columns map[string]string `yaml:"columns"`
+}
+
+// LoadMapping reads a mapping from a YAML file.
+func LoadMapping(path string) (*Mapping, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMapping(buf)
+}
+
+// ParseMapping parses a mapping and validates it.
+func ParseMapping(buf []byte) (*Mapping, error) {
+	m := &Mapping{}
+	if err := yaml.Unmarshal(buf, m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks the fields of the columns and the separator.
+func (m *Mapping) Validate() error {
+	if len(m.Columns) == 0 {
+		return fmt.Errorf("no column mapped")
+	}
+	for field, header := range m.Columns {
+		if _, err := customFieldId(field); !slices.Contains(fields, field) && err != nil {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if header == "" {
+			return fmt.Errorf("no column for field %q", field)
+		}
+	}
+	if len([]rune(m.Separator)) > 1 {
+		return fmt.Errorf("invalid separator %q", m.Separator)
+	}
+	return nil
+}
+
+// customFieldId returns the ID of the custom field of a field "cf_<id>".
+func customFieldId(field string) (int, error) {
+	id, ok := strings.CutPrefix(field, "cf_")
+	if !ok {
+		return 0, fmt.Errorf("not a custom field: %q", field)
+	}
+	return strconv.Atoi(id)
+}
@@ -0,0 +1,429 @@
+// Package depgraph builds the directed graph of the relations between
+// issues, finds the cycles of dependencies and the chains of open issues
+// blocking each other, computes the critical path and exports the graph as
+// Graphviz DOT or a Mermaid flowchart.
+//
+// The edges go from the issue blocking, preceding, duplicating or copied to
+// the other one: "follows", "blocked", "duplicated" and "copied_from"
+// relations are reversed. The blocks and precedes edges are dependencies;
+// the others are only drawn.
+package depgraph
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Types of the edges.
+const (
+	TypeBlocks     = "blocks"
+	TypePrecedes   = "precedes"
+	TypeDuplicates = "duplicates"
+	TypeCopiedTo   = "copied_to"
+	TypeRelates    = "relates"
+)
+
+// reversed maps the relation types to the type of the edge going the other
+// way.
+var reversed = map[string]string{
+	"blocked":     TypeBlocks,
+	"follows":     TypePrecedes,
+	"duplicated":  TypeDuplicates,
+	"copied_from": TypeCopiedTo,
+}
+
+// edgeType returns the type of the edge of a relation type.
+func edgeType(t string) string {
+	return cmp.Or(reversed[t], t)
+}
+
+// DefaultHoursPerDay is the length of a day of the estimated hours.
+const DefaultHoursPerDay = 8
+
+// Edge is a relation between two issues.
+type Edge struct {
+	// Relation The ID of the relation.
+	Relation int `json:"relation"`
+
+	// From The ID of the issue blocking, preceding, duplicating or copied,
+	// or of the first issue of a relates relation.
+	From int `json:"from"`
+
+	// To The ID of the other issue.
+	To int `json:"to"`
+
+	// Type The type of the edge.
+	Type string `json:"type"`
+
+	// Delay The days between the due date of From and the start date of To
+	// for a precedes edge.
+	Delay int `json:"delay,omitempty"`
+}
+
+// Dependency reports whether To depends on From.
+func (e Edge) Dependency() bool {
+	return e.Type == TypeBlocks || e.Type == TypePrecedes
+}
+
+// Graph is a set of issues and the relations between them.
+type Graph struct {
+	// Issues The issues, by ID.
+	Issues []model.Issue `json:"issues"`
+
+	// Edges The relations between the issues, by relation ID.
+	Edges []Edge `json:"edges"`
+
+	byId map[int]*model.Issue
+}
+
+// New builds the graph of the relations embedded in issues, as returned
+// with include=relations. The relations with an issue not in the list are
+// ignored.
+func New(issues []model.Issue) *Graph {
+	g := &Graph{Issues: slices.Clone(issues), Edges: []Edge{}, byId: map[int]*model.Issue{}}
+	slices.SortFunc(g.Issues, func(a, b model.Issue) int { return cmp.Compare(a.Id, b.Id) })
+	for i := range g.Issues {
+		g.byId[g.Issues[i].Id] = &g.Issues[i]
+	}
+
+	seen := map[int]bool{}
+	for _, i := range g.Issues {
+		for _, r := range i.Relations {
+			if seen[r.Id] || g.byId[r.IssueId] == nil || g.byId[r.IssueToId] == nil {
+				continue
+			}
+			seen[r.Id] = true
+			e := Edge{Relation: r.Id, From: r.IssueId, To: r.IssueToId, Type: edgeType(r.RelationType)}
+			if e.Type != r.RelationType {
+				e.From, e.To = e.To, e.From
+			}
+			if r.Delay != nil {
+				e.Delay = *r.Delay
+			}
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	slices.SortFunc(g.Edges, func(a, b Edge) int { return cmp.Compare(a.Relation, b.Relation) })
+	return g
+}
+
+// Load builds the graph of the issues matching query and of the issues
+// related to them. The dependencies of the related issues are followed
+// until the first issue of every chain; the related issues not visible to
+// the user are left out.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, query *redmine.IssuesIndexParams_Query, reqEditors ...redmine.RequestEditorFn) (*Graph, error) {
+	include := []string{"relations"}
+	issues, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{Query: query, Include: &include}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := map[int]bool{}
+	for _, i := range issues {
+		loaded[i.Id] = true
+	}
+	pending := []int{}
+	follow := func(i model.Issue, all bool) {
+		for _, r := range i.Relations {
+			id := r.IssueToId
+			if id == i.Id {
+				id = r.IssueId
+			}
+			if !loaded[id] && (all || (Edge{Type: edgeType(r.RelationType)}).Dependency()) {
+				loaded[id] = true
+				pending = append(pending, id)
+			}
+		}
+	}
+	for _, i := range issues {
+		follow(i, true)
+	}
+
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		i, err := apiutil.Issue(ctx, c, id, include, reqEditors...)
+		if apiutil.IsNotFound(err) || apiutil.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("issue #%d: %w", id, err)
+		}
+		issues = append(issues, *i)
+		follow(*i, false)
+	}
+	return New(issues), nil
+}
+
+// Issue returns the issue with id, nil if not in the graph.
+func (g *Graph) Issue(id int) *model.Issue {
+	return g.byId[id]
+}
+
+// dependencies returns the dependency edges kept by keep, by ID of the
+// issue depended on.
+func (g *Graph) dependencies(keep func(e Edge) bool) map[int][]Edge {
+	out := map[int][]Edge{}
+	for _, e := range g.Edges {
+		if e.Dependency() && keep(e) {
+			out[e.From] = append(out[e.From], e)
+		}
+	}
+	return out
+}
+
+// Cycles returns the sets of issues depending on each other through blocks
+// and precedes relations, each set by ID.
+func (g *Graph) Cycles() [][]int {
+	out := g.dependencies(func(Edge) bool { return true })
+
+	// Tarjan's strongly connected components.
+	index, low := map[int]int{}, map[int]int{}
+	onStack := map[int]bool{}
+	stack := []int{}
+	cycles := [][]int{}
+	var visit func(id int)
+	visit = func(id int) {
+		index[id], low[id] = len(index), len(index)
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, e := range out[id] {
+			if _, ok := index[e.To]; !ok {
+				visit(e.To)
+				low[id] = min(low[id], low[e.To])
+			} else if onStack[e.To] {
+				low[id] = min(low[id], index[e.To])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		component := []int{}
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			component = append(component, n)
+			if n == id {
+				break
+			}
+		}
+		if len(component) > 1 {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, i := range g.Issues {
+		if _, ok := index[i.Id]; !ok {
+			visit(i.Id)
+		}
+	}
+	slices.SortFunc(cycles, func(a, b []int) int { return cmp.Compare(a[0], b[0]) })
+	return cycles
+}
+
+// BlockedChains returns the chains of open issues blocking each other, each
+// from an open issue not blocked by another open issue to an open issue not
+// blocking another one. The issues of a cycle are only part of the chains
+// entering it.
+func (g *Graph) BlockedChains() [][]int {
+	open := func(id int) bool { return !g.byId[id].IsClosed() }
+	out := g.dependencies(func(e Edge) bool { return e.Type == TypeBlocks && open(e.From) && open(e.To) })
+	blocked := map[int]bool{}
+	for _, edges := range out {
+		for _, e := range edges {
+			blocked[e.To] = true
+		}
+	}
+
+	chains := [][]int{}
+	var extend func(chain []int)
+	extend = func(chain []int) {
+		last := chain[len(chain)-1]
+		next := 0
+		for _, e := range out[last] {
+			if slices.Contains(chain, e.To) {
+				continue
+			}
+			extend(append(slices.Clone(chain), e.To))
+			next++
+		}
+		if next == 0 && len(chain) > 1 {
+			chains = append(chains, chain)
+		}
+	}
+	for _, i := range g.Issues {
+		if len(out[i.Id]) > 0 && !blocked[i.Id] {
+			extend([]int{i.Id})
+		}
+	}
+	return chains
+}
+
+// Path is a chain of dependent issues.
+type Path struct {
+	// Issues The IDs of the issues, first the one without dependency.
+	Issues []int `json:"issues"`
+
+	// Days The duration of the chain with the delays of the precedes
+	// relations.
+	Days float64 `json:"days"`
+}
+
+// CriticalPath returns the longest chain of dependent issues. The duration
+// of an issue is the days from its start date to its due date included,
+// else its estimated hours divided by hoursPerDay, DefaultHoursPerDay if
+// zero, else nothing. The dependencies must not have cycles.
+func (g *Graph) CriticalPath(hoursPerDay float64) (*Path, error) {
+	if len(g.Cycles()) > 0 {
+		return nil, errors.New("dependencies have cycles")
+	}
+	if hoursPerDay <= 0 {
+		hoursPerDay = DefaultHoursPerDay
+	}
+
+	in := map[int][]Edge{}
+	for _, edges := range g.dependencies(func(Edge) bool { return true }) {
+		for _, e := range edges {
+			in[e.To] = append(in[e.To], e)
+		}
+	}
+
+	finish, prev := map[int]float64{}, map[int]int{}
+	var visit func(id int) float64
+	visit = func(id int) float64 {
+		if f, ok := finish[id]; ok {
+			return f
+		}
+		start := 0.0
+		for _, e := range in[id] {
+			if f := visit(e.From) + float64(e.Delay); prev[id] == 0 || f > start {
+				start, prev[id] = f, e.From
+			}
+		}
+		finish[id] = start + duration(g.byId[id], hoursPerDay)
+		return finish[id]
+	}
+
+	p := &Path{Issues: []int{}}
+	last := 0
+	for _, i := range g.Issues {
+		if f := visit(i.Id); last == 0 || f > p.Days {
+			last, p.Days = i.Id, f
+		}
+	}
+	for id := last; id != 0; id = prev[id] {
+		p.Issues = append(p.Issues, id)
+	}
+	slices.Reverse(p.Issues)
+	return p, nil
+}
+
+// duration returns the days of the issue i.
+func duration(i *model.Issue, hoursPerDay float64) float64 {
+	switch {
+	case i.StartDate != nil && i.DueDate != nil:
+		return max(i.DueDate.Sub(i.StartDate.Time).Hours()/24+1, 0)
+	case i.EstimatedHours != nil:
+		return *i.EstimatedHours / hoursPerDay
+	}
+	return 0
+}
+
+// inCycle returns whether each edge joins two issues of the same cycle.
+func (g *Graph) inCycle() map[int]bool {
+	cycle := map[int]int{}
+	for n, c := range g.Cycles() {
+		for _, id := range c {
+			cycle[id] = n + 1
+		}
+	}
+	edges := map[int]bool{}
+	for _, e := range g.Edges {
+		edges[e.Relation] = e.Dependency() && cycle[e.From] != 0 && cycle[e.From] == cycle[e.To]
+	}
+	return edges
+}
+
+// label returns the label of the issue i.
+func label(i *model.Issue) string {
+	return fmt.Sprintf("#%d %s", i.Id, i.Subject)
+}
+
+// DOT renders the graph in the Graphviz DOT language, the closed issues
+// filled in gray, the edges which are not dependencies dashed and those of
+// cycles in red.
+func (g *Graph) DOT() string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace
+	cycle := g.inCycle()
+
+	b := strings.Builder{}
+	b.WriteString("digraph issues {\n  rankdir=LR;\n  node [shape=box];\n")
+	for _, i := range g.Issues {
+		attrs := ""
+		if i.IsClosed() {
+			attrs = ", style=filled, fillcolor=lightgray"
+		}
+		fmt.Fprintf(&b, "  i%d [label=\"%s\"%s];\n", i.Id, escape(label(&i)), attrs)
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		if !e.Dependency() {
+			attrs += ", style=dashed"
+		}
+		if e.Type == TypeRelates {
+			attrs += ", dir=none"
+		}
+		if cycle[e.Relation] {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "  i%d -> i%d [label=\"%s\"%s];\n", e.From, e.To, e.Type, attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, as DOT does.
+func (g *Graph) Mermaid() string {
+	escape := strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace
+	cycle := g.inCycle()
+
+	b := strings.Builder{}
+	b.WriteString("flowchart LR\n")
+	closed := []string{}
+	for _, i := range g.Issues {
+		fmt.Fprintf(&b, "  i%d[\"%s\"]\n", i.Id, escape(label(&i)))
+		if i.IsClosed() {
+			closed = append(closed, fmt.Sprintf("i%d", i.Id))
+		}
+	}
+	red := []string{}
+	for n, e := range g.Edges {
+		arrow := "-->"
+		switch {
+		case e.Type == TypeRelates:
+			arrow = "-.-"
+		case !e.Dependency():
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  i%d %s|%s| i%d\n", e.From, arrow, e.Type, e.To)
+		if cycle[e.Relation] {
+			red = append(red, fmt.Sprint(n))
+		}
+	}
+	if len(closed) > 0 {
+		fmt.Fprintf(&b, "  classDef closed fill:#eee,color:#888\n  class %s closed\n", strings.Join(closed, ","))
+	}
+	if len(red) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red\n", strings.Join(red, ","))
+	}
+	return b.String()
+}
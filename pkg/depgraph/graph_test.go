@@ -0,0 +1,171 @@
+package depgraph
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func date(s string) *openapi_types.Date {
+	t, _ := time.Parse(time.DateOnly, s)
+	return &openapi_types.Date{Time: t}
+}
+
+func relation(id, from, to int, typ string) model.Relation {
+	return model.Relation{Id: id, IssueId: from, IssueToId: to, RelationType: typ}
+}
+
+// release is a release blocked by an API and a UI, the design preceding
+// the API and the docs following the UI.
+func release() []model.Issue {
+	estimate := func(h float64) *float64 { return &h }
+	delay := 1
+	precedes := relation(1, 1, 2, "precedes")
+	precedes.Delay = &delay
+	return []model.Issue{
+		{Id: 1, Project: &model.Ref{Id: 1}, Subject: "Design", Status: &model.Status{Id: 5, IsClosed: true},
+			StartDate: date("2025-01-01"), DueDate: date("2025-01-03"),
+			Relations: []model.Relation{precedes}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Subject: "API", Status: &model.Status{Id: 2}, EstimatedHours: estimate(16),
+			Relations: []model.Relation{relation(6, 2, 3, "blocks")}},
+		{Id: 3, Project: &model.Ref{Id: 1}, Subject: "UI", Status: &model.Status{Id: 2}, EstimatedHours: estimate(8),
+			Relations: []model.Relation{relation(3, 3, 4, "blocks")}},
+		{Id: 4, Project: &model.Ref{Id: 1}, Subject: "Release", Status: &model.Status{Id: 1},
+			Relations: []model.Relation{relation(2, 4, 2, "blocked"), relation(5, 6, 4, "relates")}},
+		{Id: 5, Project: &model.Ref{Id: 1}, Subject: "Docs", Status: &model.Status{Id: 1}, EstimatedHours: estimate(4),
+			Relations: []model.Relation{relation(4, 5, 3, "follows")}},
+		{Id: 6, Project: &model.Ref{Id: 1}, Subject: "Legacy", Status: &model.Status{Id: 1}},
+	}
+}
+
+// loop is two issues depending on each other and a duplicate.
+func loop() []model.Issue {
+	return []model.Issue{
+		{Id: 7, Subject: "A", Status: &model.Status{Id: 1}, Relations: []model.Relation{relation(10, 7, 8, "blocks")}},
+		{Id: 8, Subject: `B "quoted"`, Status: &model.Status{Id: 5, IsClosed: true}, Relations: []model.Relation{relation(11, 8, 7, "precedes")}},
+		{Id: 9, Subject: "C", Status: &model.Status{Id: 1}, Relations: []model.Relation{relation(12, 7, 9, "duplicated")}},
+	}
+}
+
+func TestNew(t *testing.T) {
+	g := New(release())
+
+	want := []Edge{
+		{Relation: 1, From: 1, To: 2, Type: TypePrecedes, Delay: 1},
+		{Relation: 2, From: 2, To: 4, Type: TypeBlocks},
+		{Relation: 3, From: 3, To: 4, Type: TypeBlocks},
+		{Relation: 4, From: 3, To: 5, Type: TypePrecedes},
+		{Relation: 5, From: 6, To: 4, Type: TypeRelates},
+		{Relation: 6, From: 2, To: 3, Type: TypeBlocks},
+	}
+	if !slices.Equal(g.Edges, want) {
+		t.Errorf("edges = %+v", g.Edges)
+	}
+	if g.Issue(4).Subject != "Release" || g.Issue(99) != nil {
+		t.Errorf("issues = %+v", g.Issues)
+	}
+
+	if c := g.Cycles(); len(c) != 0 {
+		t.Errorf("cycles = %v", c)
+	}
+	chains := g.BlockedChains()
+	if len(chains) != 2 || !slices.Equal(chains[0], []int{2, 4}) || !slices.Equal(chains[1], []int{2, 3, 4}) {
+		t.Errorf("chains = %v", chains)
+	}
+
+	p, err := g.CriticalPath(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Issues, []int{1, 2, 3, 5}) || p.Days != 7.5 {
+		t.Errorf("critical path = %+v", p)
+	}
+	if p, _ := g.CriticalPath(4); !slices.Equal(p.Issues, []int{1, 2, 3, 5}) || p.Days != 11 {
+		t.Errorf("critical path with 4 hours a day = %+v", p)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := New(loop())
+
+	if c := g.Cycles(); len(c) != 1 || !slices.Equal(c[0], []int{7, 8}) {
+		t.Errorf("cycles = %v", c)
+	}
+	if c := g.BlockedChains(); len(c) != 0 {
+		t.Errorf("chains = %v", c)
+	}
+	if _, err := g.CriticalPath(0); err == nil {
+		t.Error("critical path of a cycle")
+	}
+
+	dot := "digraph issues {\n" +
+		"  rankdir=LR;\n" +
+		"  node [shape=box];\n" +
+		"  i7 [label=\"#7 A\"];\n" +
+		"  i8 [label=\"#8 B \\\"quoted\\\"\", style=filled, fillcolor=lightgray];\n" +
+		"  i9 [label=\"#9 C\"];\n" +
+		"  i7 -> i8 [label=\"blocks\", color=red];\n" +
+		"  i8 -> i7 [label=\"precedes\", color=red];\n" +
+		"  i9 -> i7 [label=\"duplicates\", style=dashed];\n" +
+		"}\n"
+	if s := g.DOT(); s != dot {
+		t.Errorf("dot = %s", s)
+	}
+
+	mermaid := "flowchart LR\n" +
+		"  i7[\"#7 A\"]\n" +
+		"  i8[\"#8 B #quot;quoted#quot;\"]\n" +
+		"  i9[\"#9 C\"]\n" +
+		"  i7 -->|blocks| i8\n" +
+		"  i8 -->|precedes| i7\n" +
+		"  i9 -.->|duplicates| i7\n" +
+		"  classDef closed fill:#eee,color:#888\n" +
+		"  class i8 closed\n" +
+		"  linkStyle 0,1 stroke:red\n"
+	if s := g.Mermaid(); s != mermaid {
+		t.Errorf("mermaid = %s", s)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "App", Identifier: "app"}, {Id: 2, Name: "Platform", Identifier: "platform"}}
+	f.Issues = append(release(),
+		model.Issue{Id: 7, Project: &model.Ref{Id: 2}, Subject: "Runtime", Status: &model.Status{Id: 1},
+			Relations: []model.Relation{relation(7, 7, 8, "blocks"), relation(8, 9, 7, "relates")}},
+		model.Issue{Id: 8, Project: &model.Ref{Id: 2}, Subject: "SDK", Status: &model.Status{Id: 1},
+			Relations: []model.Relation{relation(9, 8, 2, "blocks")}},
+		model.Issue{Id: 9, Project: &model.Ref{Id: 2}, Subject: "Cleanup", Status: &model.Status{Id: 1}},
+	)
+	c := redminetest.Client(t, f)
+
+	project, status := "app", "*"
+	g, err := Load(context.Background(), c, &redmine.IssuesIndexParams_Query{ProjectId: &project, StatusId: &status}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int{}
+	for _, i := range g.Issues {
+		ids = append(ids, i.Id)
+	}
+	if !slices.Equal(ids, []int{1, 2, 3, 4, 5, 6, 7, 8}) || len(g.Edges) != 8 {
+		t.Errorf("issues = %v, edges = %+v", ids, g.Edges)
+	}
+	if !g.Issue(1).IsClosed() {
+		t.Errorf("issue 1 = %+v", g.Issue(1))
+	}
+	chains := g.BlockedChains()
+	if len(chains) != 2 || !slices.Equal(chains[1], []int{7, 8, 2, 3, 4}) {
+		t.Errorf("chains = %v", chains)
+	}
+}
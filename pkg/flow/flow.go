@@ -0,0 +1,250 @@
+// Package flow computes flow metrics of the issues from the status changes
+// of their journals: the time spent in each status, the lead time from the
+// creation to the closing, the cycle time from the start of the work to the
+// closing, the throughput per week and the work in progress over time.
+//
+// An issue is closed when its status is closed, since the change from an
+// open status to a closed one; it is reopened by a change to an open
+// status. It is started at the first change to a start status, by default
+// any open status other than the one the issue was created with. The time
+// spent in the closed statuses is not counted.
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Period is a stay of an issue in a status.
+type Period struct {
+	Status string    `json:"status"`
+	From   time.Time `json:"from"`
+
+	// To The end of the stay, the time of the analysis if the issue is
+	// still in the status.
+	To time.Time `json:"to"`
+
+	Closed bool `json:"closed"`
+}
+
+// Issue is the history of the statuses of an issue and its metrics.
+type Issue struct {
+	Id       int    `json:"id"`
+	Subject  string `json:"subject"`
+	Tracker  string `json:"tracker,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Status   string `json:"status"`
+
+	Created time.Time `json:"created"`
+
+	// Started The first change to a start status, nil if none.
+	Started *time.Time `json:"started,omitempty"`
+
+	// Closed The change closing the issue, nil if the issue is open.
+	Closed *time.Time `json:"closed,omitempty"`
+
+	// LeadTime The time from the creation to the closing, zero if open.
+	LeadTime time.Duration `json:"-"`
+
+	// CycleTime The time from the start to the closing, zero if open or
+	// never started.
+	CycleTime time.Duration `json:"-"`
+
+	// TimeInStatus The total time spent in each open status.
+	TimeInStatus map[string]time.Duration `json:"-"`
+
+	Periods []Period `json:"periods"`
+}
+
+// MarshalJSON writes the durations in days, as lead_days, cycle_days and
+// days_in_status.
+func (i Issue) MarshalJSON() ([]byte, error) {
+	type issue Issue
+	v := struct {
+		issue
+		LeadDays     float64            `json:"lead_days"`
+		CycleDays    float64            `json:"cycle_days"`
+		DaysInStatus map[string]float64 `json:"days_in_status"`
+	}{issue: issue(i), LeadDays: days(i.LeadTime), CycleDays: days(i.CycleTime), DaysInStatus: map[string]float64{}}
+	for status, d := range i.TimeInStatus {
+		v.DaysInStatus[status] = days(d)
+	}
+	return json.Marshal(v)
+}
+
+// Options selects how the issues are analyzed.
+type Options struct {
+	// Start The IDs or names of the statuses starting the cycle time. Any
+	// open status other than the initial one if empty.
+	Start []string
+
+	// Now The time of the analysis, ending the current periods. The
+	// current time if zero.
+	Now time.Time
+}
+
+// Load fetches the issues matching query, all statuses by default, with
+// their journals, and analyzes them. The issue list does not include the
+// journals, so each issue is fetched again.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, query *redmine.IssuesIndexParams_Query, opts Options, reqEditors ...redmine.RequestEditorFn) ([]Issue, error) {
+	statuses, err := apiutil.Statuses(ctx, c, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	q := redmine.IssuesIndexParams_Query{}
+	if query != nil {
+		q = *query
+	}
+	if q.StatusId == nil {
+		all := "*"
+		q.StatusId = &all
+	}
+	listed, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{Query: &q}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]model.Issue, 0, len(listed))
+	for _, i := range listed {
+		full, err := apiutil.Issue(ctx, c, i.Id, []string{"journals"}, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, *full)
+	}
+	return Analyze(issues, statuses, opts), nil
+}
+
+// Analyze rebuilds the history of the statuses of the issues from their
+// journals, sorted by ID.
+func Analyze(issues []model.Issue, statuses []model.Status, opts Options) []Issue {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	byId := map[int]model.Status{}
+	for _, s := range statuses {
+		byId[s.Id] = s
+	}
+
+	flows := make([]Issue, 0, len(issues))
+	for i := range issues {
+		flows = append(flows, analyze(&issues[i], byId, opts.Start, now))
+	}
+	slices.SortFunc(flows, func(a, b Issue) int { return a.Id - b.Id })
+	return flows
+}
+
+func analyze(i *model.Issue, statuses map[int]model.Status, start []string, now time.Time) Issue {
+	f := Issue{
+		Id:           i.Id,
+		Subject:      i.Subject,
+		Tracker:      refName(i.Tracker),
+		Assignee:     refName(i.AssignedTo),
+		Version:      refName(i.FixedVersion),
+		TimeInStatus: map[string]time.Duration{},
+	}
+	if i.CreatedOn != nil {
+		f.Created = *i.CreatedOn
+	}
+
+	type change struct {
+		at       time.Time
+		from, to int
+	}
+	changes := []change{}
+	for _, j := range i.Journals {
+		for _, d := range j.Details {
+			if d.Property == "attr" && d.Name == "status_id" {
+				changes = append(changes, change{j.CreatedOn, atoi(d.OldValue), atoi(d.NewValue)})
+			}
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b change) int { return a.at.Compare(b.at) })
+
+	initial := 0
+	if i.Status != nil {
+		initial = i.Status.Id
+	}
+	if len(changes) > 0 {
+		initial = changes[0].from
+	}
+
+	isStart := func(id int) bool {
+		s := statuses[id]
+		if len(start) == 0 {
+			return id != initial && !s.IsClosed
+		}
+		return slices.ContainsFunc(start, func(v string) bool {
+			return v == strconv.Itoa(id) || strings.EqualFold(v, s.Name)
+		})
+	}
+
+	status, from := initial, f.Created
+	for k, c := range append(changes, change{at: now}) {
+		if c.at.Before(from) {
+			c.at = from
+		}
+		s := statuses[status]
+		f.Periods = append(f.Periods, Period{Status: statusName(s, status), From: from, To: c.at, Closed: s.IsClosed})
+		if !s.IsClosed {
+			f.TimeInStatus[statusName(s, status)] += c.at.Sub(from)
+		}
+		if k == len(changes) {
+			break
+		}
+
+		at := c.at
+		if f.Started == nil && isStart(c.to) {
+			f.Started = &at
+		}
+		if statuses[c.to].IsClosed && !s.IsClosed {
+			f.Closed = &at
+		} else if !statuses[c.to].IsClosed {
+			f.Closed = nil
+		}
+		status, from = c.to, c.at
+	}
+	f.Status = statusName(statuses[status], status)
+
+	if f.Closed != nil {
+		f.LeadTime = f.Closed.Sub(f.Created)
+		if f.Started != nil && !f.Started.After(*f.Closed) {
+			f.CycleTime = f.Closed.Sub(*f.Started)
+		}
+	}
+	return f
+}
+
+func atoi(s *string) int {
+	if s == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(*s)
+	return n
+}
+
+func refName(r *model.Ref) string {
+	if r == nil {
+		return ""
+	}
+	return r.Name
+}
+
+func statusName(s model.Status, id int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(id)
+}
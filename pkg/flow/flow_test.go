@@ -0,0 +1,158 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+var now = date(27, 0)
+
+// date returns a time of January 2025, 6 being a Monday.
+func date(d, h int) time.Time {
+	return time.Date(2025, 1, d, h, 0, 0, 0, time.UTC)
+}
+
+func moved(id int, at time.Time, from, to int) model.Journal {
+	old, new := strconv.Itoa(from), strconv.Itoa(to)
+	return model.Journal{
+		Id:        id,
+		User:      &model.Ref{Id: 1},
+		CreatedOn: at,
+		Details:   []model.JournalDetail{{Property: "attr", Name: "status_id", OldValue: &old, NewValue: &new}},
+	}
+}
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Web", Identifier: "web"}}
+	issue := func(id, tracker, status int, created time.Time, journals ...model.Journal) model.Issue {
+		return model.Issue{
+			Id:        id,
+			Project:   &model.Ref{Id: 1},
+			Tracker:   &model.Ref{Id: tracker},
+			Status:    &model.Status{Id: status},
+			Priority:  &model.Ref{Id: 2},
+			Author:    &model.Ref{Id: 1},
+			Subject:   "Issue " + strconv.Itoa(id),
+			CreatedOn: &created,
+			Journals:  journals,
+		}
+	}
+	f.Issues = []model.Issue{
+		issue(1, 1, 5, date(6, 0), moved(1, date(8, 0), 1, 2), moved(2, date(13, 0), 2, 5)),
+		issue(2, 2, 5, date(6, 0),
+			moved(3, date(7, 0), 1, 2), moved(4, date(9, 0), 2, 4), moved(5, date(10, 0), 4, 2),
+			moved(6, date(16, 0), 2, 5), moved(7, date(17, 0), 5, 2), moved(8, date(20, 0), 2, 5)),
+		issue(3, 1, 1, date(6, 0)),
+		issue(4, 1, 6, date(10, 0), moved(9, date(10, 12), 1, 6)),
+	}
+	return f
+}
+
+func load(t *testing.T, opts Options) []Issue {
+	c := redminetest.Client(t, fixtures())
+	opts.Now = now
+	issues, err := Load(context.Background(), c, nil, opts, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issues
+}
+
+func TestLoad(t *testing.T) {
+	issues := load(t, Options{})
+	if len(issues) != 4 {
+		t.Fatalf("issues = %+v", issues)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteIssuesCSV(&buf, issues); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,subject,tracker,assignee,version,status,created,started,closed,lead_days,cycle_days,days:Feedback,days:In Progress,days:New\n" +
+		"1,Issue 1,Bug,,,Closed,2025-01-06T00:00:00Z,2025-01-08T00:00:00Z,2025-01-13T00:00:00Z,7.00,5.00,0.00,5.00,2.00\n" +
+		"2,Issue 2,Feature,,,Closed,2025-01-06T00:00:00Z,2025-01-07T00:00:00Z,2025-01-20T00:00:00Z,14.00,13.00,1.00,11.00,1.00\n" +
+		"3,Issue 3,Bug,,,New,2025-01-06T00:00:00Z,,,,,0.00,0.00,21.00\n" +
+		"4,Issue 4,Bug,,,Rejected,2025-01-10T00:00:00Z,,2025-01-10T12:00:00Z,0.50,,0.00,0.00,0.50\n"
+	if buf.String() != want {
+		t.Errorf("issues =\n%s", buf.String())
+	}
+
+	if p := issues[1].Periods; len(p) != 7 || p[4].Status != "Closed" || !p[4].Closed || !p[6].To.Equal(now) {
+		t.Errorf("periods = %+v", p)
+	}
+
+	// The cycle starts in Feedback only.
+	issues = load(t, Options{Start: []string{"feedback"}})
+	if issues[0].Started != nil || issues[1].CycleTime != 11*day {
+		t.Errorf("start = %+v, %+v", issues[0], issues[1])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	issues := load(t, Options{})
+
+	var buf bytes.Buffer
+	if err := WriteSummaryCSV(&buf, Summarize(issues, GroupTracker)); err != nil {
+		t.Fatal(err)
+	}
+	want := "group,metric,count,mean,p50,p85,p95,max\n" +
+		"Bug,lead_time,2,3.75,0.50,7.00,7.00,7.00\n" +
+		"Bug,cycle_time,1,5.00,5.00,5.00,5.00,5.00\n" +
+		"Bug,status:In Progress,1,5.00,5.00,5.00,5.00,5.00\n" +
+		"Bug,status:New,3,7.83,2.00,21.00,21.00,21.00\n" +
+		"Feature,lead_time,1,14.00,14.00,14.00,14.00,14.00\n" +
+		"Feature,cycle_time,1,13.00,13.00,13.00,13.00,13.00\n" +
+		"Feature,status:Feedback,1,1.00,1.00,1.00,1.00,1.00\n" +
+		"Feature,status:In Progress,1,11.00,11.00,11.00,11.00,11.00\n" +
+		"Feature,status:New,1,1.00,1.00,1.00,1.00,1.00\n"
+	if buf.String() != want {
+		t.Errorf("summary =\n%s", buf.String())
+	}
+}
+
+func TestThroughputAndWIP(t *testing.T) {
+	issues := load(t, Options{})
+
+	var buf bytes.Buffer
+	if err := WriteThroughputCSV(&buf, Throughput(issues, GroupTracker)); err != nil {
+		t.Fatal(err)
+	}
+	want := "week,group,closed\n" +
+		"2025-01-06,Bug,1\n2025-01-06,Feature,0\n" +
+		"2025-01-13,Bug,1\n2025-01-13,Feature,0\n" +
+		"2025-01-20,Bug,0\n2025-01-20,Feature,1\n"
+	if buf.String() != want {
+		t.Errorf("throughput =\n%s", buf.String())
+	}
+
+	wip := []string{}
+	for _, s := range WIP(issues, GroupNone, date(6, 0), date(21, 0)) {
+		wip = append(wip, strconv.Itoa(s.WIP))
+	}
+	if got := strings.Join(wip, ""); got != "0122222111011100" {
+		t.Errorf("wip = %s", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	buf, err := json.Marshal(load(t, Options{})[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(buf)
+	if !strings.Contains(s, `"lead_days":7,"cycle_days":5,"days_in_status":{"In Progress":5,"New":2}`) || strings.Contains(s, "lead_time") {
+		t.Errorf("json = %s", s)
+	}
+}
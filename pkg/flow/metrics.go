@@ -0,0 +1,333 @@
+package flow
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Groupings of the issues.
+const (
+	GroupNone     = ""
+	GroupTracker  = "tracker"
+	GroupAssignee = "assignee"
+	GroupVersion  = "version"
+)
+
+// Metrics of the summaries.
+const (
+	MetricLeadTime  = "lead_time"
+	MetricCycleTime = "cycle_time"
+)
+
+// day is the unit of the durations of the summaries and the CSV files.
+const day = 24 * time.Hour
+
+// Percentiles summarizes durations, in days.
+type Percentiles struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P85   float64 `json:"p85"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// Summary is the metrics of a group of issues.
+type Summary struct {
+	// Group The tracker, assignee or version of the issues, "" if not
+	// grouped or not set.
+	Group string `json:"group"`
+
+	Issues int `json:"issues"`
+	Closed int `json:"closed"`
+
+	LeadTime  Percentiles `json:"lead_time"`
+	CycleTime Percentiles `json:"cycle_time"`
+
+	// TimeInStatus The time spent in each open status by the issues which
+	// were in it.
+	TimeInStatus map[string]Percentiles `json:"time_in_status"`
+}
+
+// Week is the number of issues of a group closed in a week.
+type Week struct {
+	// Week The Monday starting the week.
+	Week   time.Time `json:"week"`
+	Group  string    `json:"group"`
+	Closed int       `json:"closed"`
+}
+
+// Sample is the number of issues of a group in progress at a time.
+type Sample struct {
+	At    time.Time `json:"at"`
+	Group string    `json:"group"`
+	WIP   int       `json:"wip"`
+}
+
+// GroupOf returns the group of the issue by the grouping.
+func (i *Issue) GroupOf(by string) string {
+	switch by {
+	case GroupTracker:
+		return i.Tracker
+	case GroupAssignee:
+		return i.Assignee
+	case GroupVersion:
+		return i.Version
+	}
+	return ""
+}
+
+// InProgress tells whether the issue is started and open at t.
+func (i *Issue) InProgress(t time.Time) bool {
+	if i.Started == nil || i.Started.After(t) {
+		return false
+	}
+	for k, p := range i.Periods {
+		if !t.Before(p.From) && (t.Before(p.To) || k == len(i.Periods)-1) {
+			return !p.Closed
+		}
+	}
+	return false
+}
+
+func groups(issues []Issue, by string) []string {
+	names := []string{}
+	for k := range issues {
+		if g := issues[k].GroupOf(by); !slices.Contains(names, g) {
+			names = append(names, g)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Summarize returns the percentiles of the lead times and the cycle times
+// of the closed issues, and of the time spent in each status, by group.
+func Summarize(issues []Issue, by string) []Summary {
+	summaries := []Summary{}
+	for _, g := range groups(issues, by) {
+		s := Summary{Group: g, TimeInStatus: map[string]Percentiles{}}
+		lead, cycle := []time.Duration{}, []time.Duration{}
+		inStatus := map[string][]time.Duration{}
+		for k := range issues {
+			i := &issues[k]
+			if i.GroupOf(by) != g {
+				continue
+			}
+			s.Issues++
+			if i.Closed != nil {
+				s.Closed++
+				lead = append(lead, i.LeadTime)
+				if i.Started != nil {
+					cycle = append(cycle, i.CycleTime)
+				}
+			}
+			for status, d := range i.TimeInStatus {
+				inStatus[status] = append(inStatus[status], d)
+			}
+		}
+		s.LeadTime = percentiles(lead)
+		s.CycleTime = percentiles(cycle)
+		for status, ds := range inStatus {
+			s.TimeInStatus[status] = percentiles(ds)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// percentiles returns the nearest-rank percentiles of durations.
+func percentiles(ds []time.Duration) Percentiles {
+	p := Percentiles{Count: len(ds)}
+	if len(ds) == 0 {
+		return p
+	}
+	slices.Sort(ds)
+	rank := func(q float64) float64 {
+		k := int(math.Ceil(q*float64(len(ds)))) - 1
+		return days(ds[max(k, 0)])
+	}
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+	p.Mean = days(total / time.Duration(len(ds)))
+	p.P50, p.P85, p.P95 = rank(0.50), rank(0.85), rank(0.95)
+	p.Max = days(ds[len(ds)-1])
+	return p
+}
+
+func days(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(day)*100) / 100
+}
+
+// monday returns the start of the week of t.
+func monday(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+}
+
+// Throughput returns the number of issues closed per week and group, from
+// the week of the first closing to the week of the last one, including the
+// weeks without closing.
+func Throughput(issues []Issue, by string) []Week {
+	counts := map[time.Time]map[string]int{}
+	var first, last time.Time
+	for k := range issues {
+		i := &issues[k]
+		if i.Closed == nil {
+			continue
+		}
+		w := monday(*i.Closed)
+		if counts[w] == nil {
+			counts[w] = map[string]int{}
+		}
+		counts[w][i.GroupOf(by)]++
+		if first.IsZero() || w.Before(first) {
+			first = w
+		}
+		if w.After(last) {
+			last = w
+		}
+	}
+
+	weeks := []Week{}
+	if first.IsZero() {
+		return weeks
+	}
+	names := groups(issues, by)
+	for w := first; !w.After(last); w = w.AddDate(0, 0, 7) {
+		for _, g := range names {
+			weeks = append(weeks, Week{Week: w, Group: g, Closed: counts[w][g]})
+		}
+	}
+	return weeks
+}
+
+// WIP returns the number of issues in progress per group, sampled every
+// day from from to to.
+func WIP(issues []Issue, by string, from, to time.Time) []Sample {
+	samples := []Sample{}
+	names := groups(issues, by)
+	for t := from; !t.After(to); t = t.AddDate(0, 0, 1) {
+		for _, g := range names {
+			s := Sample{At: t, Group: g}
+			for k := range issues {
+				if issues[k].GroupOf(by) == g && issues[k].InProgress(t) {
+					s.WIP++
+				}
+			}
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// statusNames returns the sorted names of the statuses the issues spent
+// time in.
+func statusNames(issues []Issue) []string {
+	names := []string{}
+	for k := range issues {
+		for status := range issues[k].TimeInStatus {
+			if !slices.Contains(names, status) {
+				names = append(names, status)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatDays(d float64) string {
+	return strconv.FormatFloat(d, 'f', 2, 64)
+}
+
+// WriteIssuesCSV writes the metrics of the issues as CSV, a row per issue,
+// the durations in days, with a column "days:<status>" per status.
+func WriteIssuesCSV(w io.Writer, issues []Issue) error {
+	statuses := statusNames(issues)
+	header := []string{"id", "subject", "tracker", "assignee", "version", "status", "created", "started", "closed", "lead_days", "cycle_days"}
+	for _, s := range statuses {
+		header = append(header, "days:"+s)
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	for k := range issues {
+		i := &issues[k]
+		lead, cycle := "", ""
+		if i.Closed != nil {
+			lead = formatDays(days(i.LeadTime))
+			if i.Started != nil {
+				cycle = formatDays(days(i.CycleTime))
+			}
+		}
+		row := []string{strconv.Itoa(i.Id), i.Subject, i.Tracker, i.Assignee, i.Version, i.Status,
+			formatTime(&i.Created), formatTime(i.Started), formatTime(i.Closed), lead, cycle}
+		for _, s := range statuses {
+			row = append(row, formatDays(days(i.TimeInStatus[s])))
+		}
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSummaryCSV writes the summaries as CSV, a row per group and metric:
+// lead_time, cycle_time and "status:<name>" for the time in a status.
+func WriteSummaryCSV(w io.Writer, summaries []Summary) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"group", "metric", "count", "mean", "p50", "p85", "p95", "max"})
+	write := func(group, metric string, p Percentiles) {
+		_ = cw.Write([]string{group, metric, strconv.Itoa(p.Count),
+			formatDays(p.Mean), formatDays(p.P50), formatDays(p.P85), formatDays(p.P95), formatDays(p.Max)})
+	}
+	for _, s := range summaries {
+		write(s.Group, MetricLeadTime, s.LeadTime)
+		write(s.Group, MetricCycleTime, s.CycleTime)
+		statuses := make([]string, 0, len(s.TimeInStatus))
+		for status := range s.TimeInStatus {
+			statuses = append(statuses, status)
+		}
+		slices.Sort(statuses)
+		for _, status := range statuses {
+			write(s.Group, "status:"+status, s.TimeInStatus[status])
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteThroughputCSV writes the throughput as CSV, a row per week and
+// group.
+func WriteThroughputCSV(w io.Writer, weeks []Week) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"week", "group", "closed"})
+	for _, wk := range weeks {
+		_ = cw.Write([]string{wk.Week.Format(time.DateOnly), wk.Group, strconv.Itoa(wk.Closed)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteWIPCSV writes the work in progress as CSV, a row per day and group.
+func WriteWIPCSV(w io.Writer, samples []Sample) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "group", "wip"})
+	for _, s := range samples {
+		_ = cw.Write([]string{s.At.Format(time.DateOnly), s.Group, strconv.Itoa(s.WIP)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
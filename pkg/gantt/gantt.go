@@ -0,0 +1,402 @@
+// Package gantt renders issues and versions as a timeline, in SVG or as a
+// Mermaid gantt chart, in place of the fixed PNG and PDF renderings of the
+// server.
+//
+// An issue is drawn from its start date to its due date, the due date of
+// its version if it has none, and on a single day if it only has one of
+// them; an issue without date is left out. A version with a due date is a
+// milestone.
+package gantt
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/depgraph"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Groupings of the issues.
+const (
+	GroupNone     = ""
+	GroupAssignee = "assignee"
+	GroupVersion  = "version"
+)
+
+// DefaultDayWidth is the width of a day in SVG, in pixels.
+const DefaultDayWidth = 12
+
+// Sizes of the SVG, in pixels.
+const (
+	labelWidth   = 240
+	rowHeight    = 22
+	headerHeight = 24
+	barHeight    = 14
+	charWidth    = 7
+)
+
+// Chart is the issues and versions of a timeline.
+type Chart struct {
+	// Issues The issues, drawn as bars.
+	Issues []model.Issue `json:"issues"`
+
+	// Versions The versions, drawn as milestones.
+	Versions []model.Version `json:"versions"`
+}
+
+// Load fetches the issues of the project matching query, with their
+// relations, and the versions of the project.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, project string, query *redmine.IssuesIndexParams_Query, reqEditors ...redmine.RequestEditorFn) (*Chart, error) {
+	q := redmine.IssuesIndexParams_Query{}
+	if query != nil {
+		q = *query
+	}
+	q.ProjectId = &project
+	include := []string{"relations"}
+	issues, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{Query: &q, Include: &include}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := apiutil.Versions(ctx, c, project, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return &Chart{Issues: issues, Versions: versions}, nil
+}
+
+// Options selects the range and the layout of a rendering.
+type Options struct {
+	// From The first day drawn, the first day of the issues and milestones
+	// if zero.
+	From time.Time
+
+	// To The last day drawn, the last day of the issues and milestones if
+	// zero.
+	To time.Time
+
+	// GroupBy The grouping of the issues, GroupNone for a single list.
+	GroupBy string
+
+	// Relations Whether to draw the blocks and precedes relations as arrows.
+	// Mermaid gantt charts have no arrows.
+	Relations bool
+
+	// Milestones Whether to draw the versions with a due date.
+	Milestones bool
+
+	// DayWidth The width of a day in SVG, DefaultDayWidth if zero.
+	DayWidth int
+}
+
+// bar is an issue on the timeline, from start to end included.
+type bar struct {
+	issue      *model.Issue
+	start, end time.Time
+}
+
+// group is the bars of a section, by start date.
+type group struct {
+	name string
+	bars []bar
+}
+
+// layout is the range and the rows of a rendering.
+type layout struct {
+	from, to   time.Time
+	groups     []group
+	milestones []model.Version
+}
+
+// day returns the day of d at midnight UTC, zero if d is nil.
+func day(d *openapi_types.Date) time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	return midnight(d.Time)
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// layout places the issues and milestones of c in the range of opts.
+func (c *Chart) layout(opts Options) *layout {
+	due := map[int]time.Time{}
+	for _, v := range c.Versions {
+		due[v.Id] = day(v.DueDate)
+	}
+
+	bars := []bar{}
+	for i := range c.Issues {
+		issue := &c.Issues[i]
+		b := bar{issue: issue, start: day(issue.StartDate), end: day(issue.DueDate)}
+		if b.end.IsZero() && issue.FixedVersion != nil {
+			b.end = due[issue.FixedVersion.Id]
+		}
+		switch {
+		case b.start.IsZero() && b.end.IsZero():
+			continue
+		case b.start.IsZero():
+			b.start = b.end
+		case b.end.IsZero() || b.end.Before(b.start):
+			b.end = b.start
+		}
+		bars = append(bars, b)
+	}
+	milestones := []model.Version{}
+	if opts.Milestones {
+		for _, v := range c.Versions {
+			if v.DueDate != nil {
+				milestones = append(milestones, v)
+			}
+		}
+		slices.SortFunc(milestones, func(a, b model.Version) int { return day(a.DueDate).Compare(day(b.DueDate)) })
+	}
+
+	l := &layout{from: midnight(opts.From), to: midnight(opts.To)}
+	for _, b := range bars {
+		if opts.From.IsZero() && (l.from.IsZero() || b.start.Before(l.from)) {
+			l.from = b.start
+		}
+		if opts.To.IsZero() && b.end.After(l.to) {
+			l.to = b.end
+		}
+	}
+	for _, v := range milestones {
+		d := day(v.DueDate)
+		if opts.From.IsZero() && (l.from.IsZero() || d.Before(l.from)) {
+			l.from = d
+		}
+		if opts.To.IsZero() && d.After(l.to) {
+			l.to = d
+		}
+	}
+	if l.to.Before(l.from) {
+		l.to = l.from
+	}
+
+	index := map[string]int{}
+	for _, b := range bars {
+		if b.end.Before(l.from) || b.start.After(l.to) {
+			continue
+		}
+		name := groupName(b.issue, opts.GroupBy)
+		if _, ok := index[name]; !ok {
+			index[name] = len(l.groups)
+			l.groups = append(l.groups, group{name: name})
+		}
+		l.groups[index[name]].bars = append(l.groups[index[name]].bars, b)
+	}
+	for _, g := range l.groups {
+		slices.SortFunc(g.bars, func(a, b bar) int {
+			return cmp.Or(a.start.Compare(b.start), cmp.Compare(a.issue.Id, b.issue.Id))
+		})
+	}
+	slices.SortFunc(l.groups, func(a, b group) int {
+		if (a.name == "") != (b.name == "") {
+			return cmp.Compare(b.name, a.name)
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+	for _, v := range milestones {
+		if d := day(v.DueDate); !d.Before(l.from) && !d.After(l.to) {
+			l.milestones = append(l.milestones, v)
+		}
+	}
+	return l
+}
+
+// groupName returns the group of the issue i, empty for the issues without
+// assignee or version.
+func groupName(i *model.Issue, by string) string {
+	ref := (*model.Ref)(nil)
+	switch by {
+	case GroupAssignee:
+		ref = i.AssignedTo
+	case GroupVersion:
+		ref = i.FixedVersion
+	default:
+		return ""
+	}
+	if ref == nil {
+		return ""
+	}
+	return ref.Name
+}
+
+// title returns the title of a group, the empty group named for a grouping.
+func title(name, by string) string {
+	switch {
+	case name != "":
+		return name
+	case by == GroupAssignee:
+		return "Unassigned"
+	case by == GroupVersion:
+		return "No version"
+	}
+	return ""
+}
+
+func label(i *model.Issue) string {
+	return fmt.Sprintf("#%d %s", i.Id, i.Subject)
+}
+
+// days returns the number of days from from to to.
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// SVG renders the chart as a standalone SVG image: the issues grouped and
+// labelled on the left, a bar per issue filled by its done ratio, the
+// closed issues in gray, the months on top and the milestones as dashed
+// lines with their name at the bottom.
+func (c *Chart) SVG(opts Options) string {
+	l := c.layout(opts)
+	dayWidth := cmp.Or(opts.DayWidth, DefaultDayWidth)
+	x := func(d time.Time) int { return labelWidth + days(l.from, d)*dayWidth }
+
+	rows := 0
+	for _, g := range l.groups {
+		rows += len(g.bars)
+		if opts.GroupBy != GroupNone {
+			rows++
+		}
+	}
+	if len(l.milestones) > 0 {
+		rows++
+	}
+	width := x(l.to) + dayWidth
+	height := headerHeight + rows*rowHeight
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 6 6" refX="6" refY="3" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L6,3 L0,6 z" fill="#555"/></marker></defs>` + "\n")
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+
+	for m := time.Date(l.from.Year(), l.from.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(l.to); m = m.AddDate(0, 1, 0) {
+		start := max(x(m), labelWidth)
+		fmt.Fprintf(&b, `<line x1="%d" y1="0" x2="%d" y2="%d" stroke="#ddd"/>`+"\n", start, start, height)
+		fmt.Fprintf(&b, `<text x="%d" y="16">%s</text>`+"\n", start+4, m.Format("2006-01"))
+	}
+	fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", headerHeight, width, headerHeight)
+
+	maxChars := (labelWidth - 8) / charWidth
+	y := headerHeight
+	rowOf := map[int]int{}
+	barOf := map[int]bar{}
+	for _, g := range l.groups {
+		if opts.GroupBy != GroupNone {
+			fmt.Fprintf(&b, `<text x="4" y="%d" font-weight="bold">%s</text>`+"\n", y+15, html.EscapeString(truncate(title(g.name, opts.GroupBy), maxChars)))
+			y += rowHeight
+		}
+		for _, br := range g.bars {
+			rowOf[br.issue.Id], barOf[br.issue.Id] = y, br
+			fill, done := "#9bbbe6", "#3f73c0"
+			if br.issue.IsClosed() {
+				fill, done = "#d0d0d0", "#909090"
+			}
+			x1, x2 := x(maxTime(br.start, l.from)), x(minTime(br.end, l.to))+dayWidth
+			top := y + (rowHeight-barHeight)/2
+			fmt.Fprintf(&b, `<text x="12" y="%d">%s</text>`+"\n", y+15, html.EscapeString(truncate(label(br.issue), maxChars-1)))
+			fmt.Fprintf(&b, `<g><title>%s: %s - %s, %d%%</title>`, html.EscapeString(label(br.issue)), br.start.Format(time.DateOnly), br.end.Format(time.DateOnly), br.issue.DoneRatio)
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x1, top, x2-x1, barHeight, fill)
+			if br.issue.DoneRatio > 0 {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x1, top, (x2-x1)*min(br.issue.DoneRatio, 100)/100, barHeight, done)
+			}
+			b.WriteString("</g>\n")
+			y += rowHeight
+		}
+	}
+
+	if opts.Relations {
+		for _, e := range depgraph.New(c.Issues).Edges {
+			from, ok1 := barOf[e.From]
+			to, ok2 := barOf[e.To]
+			if !e.Dependency() || !ok1 || !ok2 {
+				continue
+			}
+			x1, y1 := x(minTime(from.end, l.to))+dayWidth, rowOf[e.From]+rowHeight/2
+			x2, y2 := x(maxTime(to.start, l.from)), rowOf[e.To]+rowHeight/2
+			fmt.Fprintf(&b, `<path d="M%d,%d h6 V%d H%d" fill="none" stroke="#555" marker-end="url(#arrow)"/>`+"\n", x1, y1, y2, x2)
+		}
+	}
+
+	if len(l.milestones) > 0 {
+		fmt.Fprintf(&b, `<text x="4" y="%d" font-weight="bold">Milestones</text>`+"\n", y+15)
+		for _, v := range l.milestones {
+			mx := x(day(v.DueDate)) + dayWidth/2
+			my := y + rowHeight/2
+			fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#c33" stroke-dasharray="4 3"/>`+"\n", mx, headerHeight, mx, y)
+			fmt.Fprintf(&b, `<polygon points="%d,%d %d,%d %d,%d %d,%d" fill="#c33"><title>%s: %s</title></polygon>`+"\n",
+				mx, my-6, mx+6, my, mx, my+6, mx-6, my, html.EscapeString(v.Name), v.DueDate.String())
+			fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", mx+9, y+15, html.EscapeString(v.Name))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// Mermaid renders the chart as a Mermaid gantt chart, a section per group
+// and the milestones in their own section.
+func (c *Chart) Mermaid(opts Options) string {
+	l := c.layout(opts)
+	name := strings.NewReplacer(":", " ", ";", ",", "\n", " ", "#", "").Replace
+
+	b := strings.Builder{}
+	b.WriteString("gantt\n  dateFormat YYYY-MM-DD\n  inclusiveEndDates\n")
+	for _, g := range l.groups {
+		if opts.GroupBy != GroupNone {
+			fmt.Fprintf(&b, "  section %s\n", name(title(g.name, opts.GroupBy)))
+		}
+		for _, br := range g.bars {
+			tags := ""
+			switch {
+			case br.issue.IsClosed():
+				tags = "done, "
+			case br.issue.DoneRatio > 0:
+				tags = "active, "
+			}
+			fmt.Fprintf(&b, "  %s :%si%d, %s, %s\n", name(label(br.issue)), tags, br.issue.Id,
+				maxTime(br.start, l.from).Format(time.DateOnly), minTime(br.end, l.to).Format(time.DateOnly))
+		}
+	}
+	if len(l.milestones) > 0 {
+		b.WriteString("  section Milestones\n")
+		for _, v := range l.milestones {
+			fmt.Fprintf(&b, "  %s :milestone, v%d, %s, 0d\n", name(v.Name), v.Id, v.DueDate.String())
+		}
+	}
+	return b.String()
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,122 @@
+package gantt
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func date(s string) *openapi_types.Date {
+	t, _ := time.Parse(time.DateOnly, s)
+	return &openapi_types.Date{Time: t}
+}
+
+func chart() *Chart {
+	v1 := &model.Ref{Id: 1, Name: "1.0"}
+	return &Chart{
+		Issues: []model.Issue{
+			{Id: 1, Subject: "Design", Status: &model.Status{Id: 5, IsClosed: true}, DoneRatio: 100,
+				StartDate: date("2025-01-06"), DueDate: date("2025-01-08"),
+				AssignedTo: &model.Ref{Id: 2, Name: "Alice"}, FixedVersion: v1,
+				Relations: []model.Relation{{Id: 1, IssueId: 1, IssueToId: 2, RelationType: "precedes"}}},
+			{Id: 2, Subject: "API <v2>", Status: &model.Status{Id: 2}, DoneRatio: 50,
+				StartDate: date("2025-01-09"), DueDate: date("2025-01-15"),
+				AssignedTo: &model.Ref{Id: 3, Name: "Bob"}, FixedVersion: v1},
+			{Id: 3, Subject: "Docs", Status: &model.Status{Id: 1}, StartDate: date("2025-01-20"), FixedVersion: v1},
+			{Id: 4, Subject: "Idea", Status: &model.Status{Id: 1}},
+			{Id: 5, Subject: "Spike", Status: &model.Status{Id: 1}, StartDate: date("2024-12-01"), DueDate: date("2024-12-05"),
+				AssignedTo: &model.Ref{Id: 2, Name: "Alice"}},
+		},
+		Versions: []model.Version{
+			{Id: 2, Name: "2.0", DueDate: date("2025-03-01")},
+			{Id: 1, Name: "1.0", DueDate: date("2025-01-31")},
+		},
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	opts := Options{From: date("2025-01-01").Time, To: date("2025-02-15").Time, GroupBy: GroupAssignee, Milestones: true}
+	want := "gantt\n" +
+		"  dateFormat YYYY-MM-DD\n" +
+		"  inclusiveEndDates\n" +
+		"  section Alice\n" +
+		"  1 Design :done, i1, 2025-01-06, 2025-01-08\n" +
+		"  section Bob\n" +
+		"  2 API <v2> :active, i2, 2025-01-09, 2025-01-15\n" +
+		"  section Unassigned\n" +
+		"  3 Docs :i3, 2025-01-20, 2025-01-31\n" +
+		"  section Milestones\n" +
+		"  1.0 :milestone, v1, 2025-01-31, 0d\n"
+	if s := chart().Mermaid(opts); s != want {
+		t.Errorf("mermaid = %s", s)
+	}
+
+	opts = Options{From: date("2025-01-07").Time, To: date("2025-01-10").Time}
+	want = "gantt\n" +
+		"  dateFormat YYYY-MM-DD\n" +
+		"  inclusiveEndDates\n" +
+		"  1 Design :done, i1, 2025-01-07, 2025-01-08\n" +
+		"  2 API <v2> :active, i2, 2025-01-09, 2025-01-10\n"
+	if s := chart().Mermaid(opts); s != want {
+		t.Errorf("clipped mermaid = %s", s)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	s := chart().SVG(Options{})
+	// From 2024-12-01 to 2025-01-31: 62 days of 12 pixels.
+	if !strings.Contains(s, `width="984" height="112"`) {
+		t.Errorf("size: %s", s)
+	}
+	for _, want := range []string{"#2 API &lt;v2&gt;", ">2024-12<", ">2025-01<", "#1 Design: 2025-01-06 - 2025-01-08, 100%"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q: %s", want, s)
+		}
+	}
+	if strings.Contains(s, "Idea") || strings.Contains(s, "marker-end") || strings.Contains(s, "Milestones") {
+		t.Errorf("unexpected: %s", s)
+	}
+
+	s = chart().SVG(Options{GroupBy: GroupVersion, Relations: true, Milestones: true, DayWidth: 4})
+	for _, want := range []string{">1.0</text>", ">No version</text>", ">Milestones</text>", `<path d="M396,57 h6 V79 H396"`, "2.0: 2025-03-01"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q: %s", want, s)
+		}
+	}
+	if n := strings.Count(s, "<polygon"); n != 2 {
+		t.Errorf("milestones = %d", n)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "App", Identifier: "app"}}
+	f.Versions = []model.Version{{Id: 1, Project: &model.Ref{Id: 1}, Name: "1.0", DueDate: date("2025-01-31")}}
+	f.Issues = []model.Issue{
+		{Id: 1, Project: &model.Ref{Id: 1}, Subject: "Design", StartDate: date("2025-01-06"), DueDate: date("2025-01-08"),
+			Relations: []model.Relation{{Id: 1, IssueId: 1, IssueToId: 2, RelationType: "precedes"}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Subject: "API", StartDate: date("2025-01-09"), FixedVersion: &model.Ref{Id: 1}},
+	}
+	c := redminetest.Client(t, f)
+
+	ch, err := Load(context.Background(), c, "app", nil, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Issues) != 2 || len(ch.Issues[0].Relations) != 1 || len(ch.Versions) != 1 {
+		t.Errorf("chart = %+v", ch)
+	}
+	s := ch.Mermaid(Options{Milestones: true})
+	if !strings.Contains(s, "2 API :i2, 2025-01-09, 2025-01-31\n") || !strings.Contains(s, "1.0 :milestone, v1, 2025-01-31, 0d") {
+		t.Errorf("mermaid = %s", s)
+	}
+}
@@ -0,0 +1,231 @@
+// Package issuetree builds the tree of an issue and its subtasks and rolls
+// up their estimated hours, spent hours and done ratio from the leaves to
+// the root.
+//
+// The rollups follow Redmine with the done ratio of parent issues derived
+// from their subtasks, the default setting:
+//
+//   - the total estimated and spent hours of an issue are the sums over the
+//     issue and its descendants;
+//   - the done ratio of a parent is the average of the done ratios of its
+//     children weighted by their total estimated hours, a closed child
+//     counting as done and a child without estimate weighing the average
+//     estimate of its siblings, rounded down.
+package issuetree
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Node is an issue of a tree with its rollups.
+type Node struct {
+	Issue model.Issue `json:"issue"`
+
+	// Parent The node of the parent issue, nil for the root.
+	Parent *Node `json:"-"`
+
+	// Children The nodes of the subtasks, by ID.
+	Children []*Node `json:"children,omitempty"`
+
+	// SpentHours The hours logged on the issue itself.
+	SpentHours float64 `json:"spent_hours"`
+
+	// TotalEstimatedHours The estimated hours of the issue and its
+	// descendants.
+	TotalEstimatedHours float64 `json:"total_estimated_hours"`
+
+	// TotalSpentHours The hours logged on the issue and its descendants.
+	TotalSpentHours float64 `json:"total_spent_hours"`
+
+	// DoneRatio The done ratio of the issue for a leaf, derived from the
+	// subtasks for a parent.
+	DoneRatio int `json:"done_ratio"`
+}
+
+// Tree is an issue and its subtasks.
+type Tree struct {
+	Root *Node
+
+	byId map[int]*Node
+}
+
+// New builds the tree of the issue root among issues, which must hold its
+// descendants, and rolls up the hours of the time entries. The issues and
+// time entries outside the tree are ignored.
+func New(root int, issues []model.Issue, entries []model.TimeEntry) (*Tree, error) {
+	nodes := map[int]*Node{}
+	for _, i := range issues {
+		nodes[i.Id] = &Node{Issue: i}
+	}
+	r, ok := nodes[root]
+	if !ok {
+		return nil, fmt.Errorf("issue #%d not found", root)
+	}
+
+	t := &Tree{Root: r, byId: map[int]*Node{root: r}}
+	for _, i := range issues {
+		if p, ok := nodes[i.ParentId()]; ok && i.Id != root {
+			p.Children = append(p.Children, nodes[i.Id])
+		}
+	}
+	var link func(n *Node)
+	link = func(n *Node) {
+		slices.SortFunc(n.Children, func(a, b *Node) int { return cmp.Compare(a.Issue.Id, b.Issue.Id) })
+		for _, c := range n.Children {
+			c.Parent = n
+			t.byId[c.Issue.Id] = c
+			link(c)
+		}
+	}
+	link(r)
+
+	for _, e := range entries {
+		if n, ok := t.byId[e.IssueId()]; ok {
+			n.SpentHours += e.Hours
+		}
+	}
+	rollup(r)
+	return t, nil
+}
+
+// Load fetches the issue with id and its descendants, level by level with
+// the parent_id filter, and the time entries logged on them with the
+// issue_id filter matching an issue and its subtasks.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, id int, reqEditors ...redmine.RequestEditorFn) (*Tree, error) {
+	root, err := apiutil.Issue(ctx, c, id, nil, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []model.Issue{*root}
+	for level := []string{strconv.Itoa(id)}; len(level) > 0; {
+		q := &redmine.IssuesIndexParams_Query{}
+		q.Set("status_id", "*")
+		q.Set("parent_id", strings.Join(level, ","))
+		children, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{Query: q}, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, i := range children {
+			issues = append(issues, i)
+			level = append(level, strconv.Itoa(i.Id))
+		}
+	}
+
+	tree := "~" + strconv.Itoa(id)
+	entries, err := apiutil.TimeEntries(ctx, c, &redmine.TimelogIndexParams{Query: &redmine.TimelogIndexParams_Query{IssueId: &tree}}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return New(id, issues, entries)
+}
+
+// rollup computes the totals and the done ratio of n from its descendants.
+func rollup(n *Node) {
+	n.TotalSpentHours = n.SpentHours
+	n.TotalEstimatedHours = 0
+	if n.Issue.EstimatedHours != nil {
+		n.TotalEstimatedHours = *n.Issue.EstimatedHours
+	}
+	n.DoneRatio = n.Issue.DoneRatio
+	if len(n.Children) == 0 {
+		return
+	}
+
+	estimated, count := 0.0, 0
+	for _, c := range n.Children {
+		rollup(c)
+		n.TotalSpentHours += c.TotalSpentHours
+		n.TotalEstimatedHours += c.TotalEstimatedHours
+		if c.TotalEstimatedHours > 0 {
+			estimated += c.TotalEstimatedHours
+			count++
+		}
+	}
+
+	average := 1.0
+	if count > 0 {
+		average = estimated / float64(count)
+	}
+	done := 0.0
+	for _, c := range n.Children {
+		weight := c.TotalEstimatedHours
+		if weight <= 0 {
+			weight = average
+		}
+		ratio := c.DoneRatio
+		if c.Issue.IsClosed() {
+			ratio = 100
+		}
+		done += weight * float64(ratio)
+	}
+	n.DoneRatio = int(math.Floor(done / (average * float64(len(n.Children)))))
+}
+
+// Get returns the node of the issue with id, nil if not in the tree.
+func (t *Tree) Get(id int) *Node {
+	return t.byId[id]
+}
+
+// Walk calls fn for each node of the tree, each before its subtasks, with
+// its depth from the root.
+func (t *Tree) Walk(fn func(n *Node, depth int)) {
+	var visit func(n *Node, depth int)
+	visit = func(n *Node, depth int) {
+		fn(n, depth)
+		for _, c := range n.Children {
+			visit(c, depth+1)
+		}
+	}
+	visit(t.Root, 0)
+}
+
+// MarshalJSON encodes the tree as its root with its children.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Root)
+}
+
+// Text renders the tree with the subtasks indented by two spaces under
+// their parent, one issue per line with its rollups.
+func (t *Tree) Text() string {
+	b := strings.Builder{}
+	t.Walk(func(n *Node, depth int) {
+		fmt.Fprintf(&b, "%s#%d %s [%s]\n", strings.Repeat("  ", depth), n.Issue.Id, n.Issue.Subject, n.summary())
+	})
+	return b.String()
+}
+
+// Markdown renders the tree as a nested Markdown task list, the closed
+// issues checked.
+func (t *Tree) Markdown() string {
+	b := strings.Builder{}
+	t.Walk(func(n *Node, depth int) {
+		check := " "
+		if n.Issue.IsClosed() {
+			check = "x"
+		}
+		fmt.Fprintf(&b, "%s- [%s] #%d %s (%s)\n", strings.Repeat("  ", depth), check, n.Issue.Id, n.Issue.Subject, n.summary())
+	})
+	return b.String()
+}
+
+// summary returns the done ratio and the total spent and estimated hours.
+func (n *Node) summary() string {
+	return fmt.Sprintf("%d%%, %s/%s h", n.DoneRatio, formatHours(n.TotalSpentHours), formatHours(n.TotalEstimatedHours))
+}
+
+func formatHours(h float64) string {
+	return strconv.FormatFloat(math.Round(h*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,161 @@
+package issuetree
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func issue(id, parent int, subject string, estimated float64, done int, status int) model.Issue {
+	i := model.Issue{Id: id, Project: &model.Ref{Id: 1}, Subject: subject, DoneRatio: done, Status: &model.Status{Id: status, IsClosed: status == 5}}
+	if parent != 0 {
+		i.Parent = &struct {
+			Id int `json:"id"`
+		}{Id: parent}
+	}
+	if estimated != 0 {
+		i.EstimatedHours = &estimated
+	}
+	return i
+}
+
+func entry(id, issue int, hours float64) model.TimeEntry {
+	return model.TimeEntry{Id: id, Project: &model.Ref{Id: 1}, Issue: &struct {
+		Id int `json:"id"`
+	}{Id: issue}, User: &model.Ref{Id: 1}, Activity: &model.Ref{Id: 9}, Hours: hours,
+		SpentOn: &openapi_types.Date{}}
+}
+
+// issues are a release with a task half done, a closed task and a task
+// without estimate split in two subtasks without estimate.
+func issues() []model.Issue {
+	return []model.Issue{
+		issue(1, 0, "Release", 0, 0, 2),
+		issue(2, 1, "Backend", 8, 50, 2),
+		issue(3, 1, "Docs", 2, 0, 5),
+		issue(4, 1, "Frontend", 0, 0, 2),
+		issue(5, 4, "Form", 0, 40, 2),
+		issue(6, 4, "Table", 0, 0, 1),
+		issue(7, 0, "Other", 3, 0, 1),
+	}
+}
+
+func entries() []model.TimeEntry {
+	return []model.TimeEntry{entry(1, 2, 3), entry(2, 5, 1.5), entry(3, 2, 0.25), entry(4, 7, 2)}
+}
+
+func TestNew(t *testing.T) {
+	tree, err := New(1, issues(), entries())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id        int
+		spent     float64
+		estimated float64
+		done      int
+	}{
+		{1, 4.75, 10, 46},
+		{2, 3.25, 8, 50},
+		{3, 0, 2, 0},
+		{4, 1.5, 0, 20},
+		{5, 1.5, 0, 40},
+	}
+	for _, c := range cases {
+		n := tree.Get(c.id)
+		if n == nil || n.TotalSpentHours != c.spent || n.TotalEstimatedHours != c.estimated || n.DoneRatio != c.done {
+			t.Errorf("#%d = %+v", c.id, n)
+		}
+	}
+	if tree.Get(7) != nil || tree.Get(5).Parent != tree.Get(4) || tree.Root.Parent != nil {
+		t.Errorf("tree = %+v", tree.Root)
+	}
+
+	text := "#1 Release [46%, 4.75/10 h]\n" +
+		"  #2 Backend [50%, 3.25/8 h]\n" +
+		"  #3 Docs [0%, 0/2 h]\n" +
+		"  #4 Frontend [20%, 1.5/0 h]\n" +
+		"    #5 Form [40%, 1.5/0 h]\n" +
+		"    #6 Table [0%, 0/0 h]\n"
+	if s := tree.Text(); s != text {
+		t.Errorf("text = %q", s)
+	}
+
+	markdown := "- [ ] #1 Release (46%, 4.75/10 h)\n" +
+		"  - [ ] #2 Backend (50%, 3.25/8 h)\n" +
+		"  - [x] #3 Docs (0%, 0/2 h)\n" +
+		"  - [ ] #4 Frontend (20%, 1.5/0 h)\n" +
+		"    - [ ] #5 Form (40%, 1.5/0 h)\n" +
+		"    - [ ] #6 Table (0%, 0/0 h)\n"
+	if s := tree.Markdown(); s != markdown {
+		t.Errorf("markdown = %q", s)
+	}
+
+	buf, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root struct {
+		Issue     model.Issue `json:"issue"`
+		DoneRatio int         `json:"done_ratio"`
+		Children  []struct {
+			Issue model.Issue `json:"issue"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal(buf, &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Issue.Id != 1 || root.DoneRatio != 46 || len(root.Children) != 3 || root.Children[2].Issue.Id != 4 {
+		t.Errorf("json = %s", buf)
+	}
+
+	if _, err := New(99, issues(), nil); err == nil {
+		t.Error("unknown root")
+	}
+}
+
+func TestNewLeaf(t *testing.T) {
+	tree, err := New(7, issues(), entries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tree.Root; len(n.Children) != 0 || n.TotalSpentHours != 2 || n.TotalEstimatedHours != 3 || n.DoneRatio != 0 {
+		t.Errorf("root = %+v", n)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Project", Identifier: "project"}}
+	f.Issues = issues()
+	f.TimeEntries = entries()
+	c := redminetest.Client(t, f)
+
+	ctx := context.Background()
+	tree, err := Load(ctx, c, 1, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	tree.Walk(func(n *Node, _ int) { ids = append(ids, n.Issue.Id) })
+	if len(ids) != 6 || ids[3] != 4 || ids[4] != 5 {
+		t.Errorf("ids = %v", ids)
+	}
+	if tree.Root.TotalSpentHours != 4.75 || tree.Root.TotalEstimatedHours != 10 || tree.Root.DoneRatio != 46 {
+		t.Errorf("root = %+v", tree.Root)
+	}
+
+	if _, err := Load(ctx, c, 99, auth...); !apiutil.IsNotFound(err) {
+		t.Errorf("unknown issue: %v", err)
+	}
+}
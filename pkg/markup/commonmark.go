@@ -0,0 +1,313 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	commonMarkHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	commonMarkSetext   = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	commonMarkRule     = regexp.MustCompile(`^ {0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	commonMarkFence    = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})\\s*([^`\\s]*)")
+	commonMarkList     = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])(?:\s+(.*))?$`)
+	commonMarkTableSep = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// listLevel is a level of nested lists.
+type listLevel struct {
+	indent  int
+	ordered bool
+}
+
+// CommonMarkToTextile converts text from CommonMark to Textile.
+func CommonMarkToTextile(text string) string {
+	return joinLines(commonMarkToTextile(splitLines(text)), text)
+}
+
+func commonMarkToTextile(lines []string) []string {
+	out := []string{}
+	var lists []listLevel
+	blank := true
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			out = append(out, "")
+			blank = true
+			continue
+		}
+		// A heading of Textile ends at the next blank line.
+		if len(out) > 0 && strings.HasPrefix(out[len(out)-1], "h") && textileHeading.MatchString(out[len(out)-1]) {
+			out = append(out, "")
+		}
+
+		indent := indentWidth(line)
+		if indent == 0 && !commonMarkList.MatchString(line) {
+			lists = nil
+		}
+		wasBlank := blank
+		blank = false
+
+		if m := commonMarkFence.FindStringSubmatch(line); m != nil {
+			var code []string
+			end := i + 1
+			for ; end < len(lines); end++ {
+				trimmed := strings.TrimSpace(lines[end])
+				if strings.HasPrefix(trimmed, m[2]) && strings.Trim(trimmed, m[2][:1]) == "" && indentWidth(lines[end]) < 4 {
+					break
+				}
+				code = append(code, trimIndent(lines[end], len(m[1])))
+			}
+			out = append(out, preBlock(code, m[3])...)
+			i = end
+			continue
+		}
+
+		switch {
+		case indent >= 4 && wasBlank && len(lists) == 0:
+			code := []string{}
+			end := i
+			for ; end < len(lines); end++ {
+				if strings.TrimSpace(lines[end]) != "" && indentWidth(lines[end]) < 4 {
+					break
+				}
+				code = append(code, trimIndent(lines[end], 4))
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+				end--
+			}
+			out = append(out, preBlock(code, "")...)
+			i = end - 1
+		case quotePrefix(line) != "":
+			var quote []string
+			quote, i = convertQuote(lines, i, commonMarkToTextile)
+			out = append(out, quote...)
+		case macroBlock(line):
+			out = append(out, line)
+		case strings.Contains(line, "|") && i+1 < len(lines) && commonMarkTableSep.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			end := i + 2
+			for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			out = append(out, writeTextileTable(parseCommonMarkTable(lines[i], lines[i+1], lines[i+2:end]))...)
+			i = end - 1
+		case commonMarkHeading.MatchString(line):
+			m := commonMarkHeading.FindStringSubmatch(line)
+			out = append(out, "h"+string(rune('0'+len(m[1])))+". "+commonMarkInline(m[2]))
+		case commonMarkRule.MatchString(line) && (wasBlank || !strings.HasPrefix(strings.TrimSpace(line), "-")):
+			out = append(out, "---")
+		case commonMarkList.MatchString(line):
+			m := commonMarkList.FindStringSubmatch(line)
+			ordered := !strings.ContainsAny(m[2], "-*+")
+			for len(lists) > 0 && lists[len(lists)-1].indent > indent {
+				lists = lists[:len(lists)-1]
+			}
+			if len(lists) > 0 && lists[len(lists)-1].indent == indent {
+				lists[len(lists)-1].ordered = ordered
+			} else {
+				lists = append(lists, listLevel{indent: indent, ordered: ordered})
+			}
+			marker := ""
+			for _, l := range lists {
+				if l.ordered {
+					marker += "#"
+				} else {
+					marker += "*"
+				}
+			}
+			out = append(out, marker+" "+commonMarkInline(m[3]))
+		case i+1 < len(lines) && commonMarkSetext.MatchString(lines[i+1]):
+			level := "1"
+			if strings.Contains(lines[i+1], "-") {
+				level = "2"
+			}
+			out = append(out, "h"+level+". "+commonMarkInline(strings.TrimSpace(line)))
+			i++
+		default:
+			out = append(out, commonMarkInline(strings.TrimSpace(line)))
+		}
+	}
+	return out
+}
+
+// trimIndent removes up to n columns of leading spaces from line.
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < len(line) && i < n && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
+
+// preBlock returns the lines of a code block of Textile.
+func preBlock(code []string, lang string) []string {
+	if lang == "" {
+		return append(append([]string{"<pre>"}, code...), "</pre>")
+	}
+	return append(append([]string{`<pre><code class="` + lang + `">`}, code...), "</code></pre>")
+}
+
+// parseCommonMarkTable parses the lines of a table: its header row, the
+// row separating it from the body and the rows of the body. A header whose
+// cells are all empty is no header.
+func parseCommonMarkTable(header, sep string, rows []string) *table {
+	cells := func(row string) []string {
+		row = strings.TrimSpace(row)
+		row = strings.TrimPrefix(row, "|")
+		if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+			row = row[:len(row)-1]
+		}
+		out := splitCells(row)
+		for i, c := range out {
+			out[i] = strings.ReplaceAll(strings.TrimSpace(c), `\|`, "|")
+		}
+		return out
+	}
+
+	t := &table{header: cells(header)}
+	if strings.Join(t.header, "") == "" {
+		t.header = nil
+	}
+	for _, s := range cells(sep) {
+		switch {
+		case strings.HasPrefix(s, ":") && strings.HasSuffix(s, ":"):
+			t.align = append(t.align, alignCenter)
+		case strings.HasPrefix(s, ":"):
+			t.align = append(t.align, alignLeft)
+		case strings.HasSuffix(s, ":"):
+			t.align = append(t.align, alignRight)
+		default:
+			t.align = append(t.align, "")
+		}
+	}
+	for _, row := range rows {
+		t.rows = append(t.rows, cells(row))
+	}
+	return t
+}
+
+// writeTextileTable returns the lines of t in Textile, with the alignment
+// of the columns on all their cells.
+func writeTextileTable(t *table) []string {
+	n := t.columns()
+	row := func(cells []string, header bool) string {
+		out := strings.Builder{}
+		for i := range n {
+			attrs := ""
+			if header {
+				attrs = "_"
+			}
+			switch t.alignment(i) {
+			case alignLeft:
+				attrs += "<"
+			case alignCenter:
+				attrs += "="
+			case alignRight:
+				attrs += ">"
+			}
+			out.WriteString("|")
+			if attrs != "" {
+				out.WriteString(attrs + ". ")
+			}
+			if i < len(cells) {
+				out.WriteString(commonMarkInline(cells[i]))
+			}
+		}
+		out.WriteString("|")
+		return out.String()
+	}
+
+	lines := []string{}
+	if t.header != nil {
+		lines = append(lines, row(t.header, true))
+	}
+	for _, r := range t.rows {
+		lines = append(lines, row(r, false))
+	}
+	return lines
+}
+
+// commonMarkSpan returns the pattern of the text between the marker of an
+// emphasis. The underscores are not within words.
+func commonMarkSpan(marker string) *regexp.Regexp {
+	q := regexp.QuoteMeta(marker)
+	c := regexp.QuoteMeta(marker[:1])
+	before := "()"
+	if marker[0] == '_' {
+		before = `(^|[^\w_])`
+	}
+	return regexp.MustCompile(before + q + `([^\s` + c + `](?:[^\n]*?[^\s` + c + `])?)` + q)
+}
+
+var (
+	commonMarkCode     = regexp.MustCompile("``\\s?(.+?)\\s?``|`([^`\\n]+)`")
+	commonMarkEscape   = regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`)
+	commonMarkAutolink = regexp.MustCompile(`<((?:https?|ftp|mailto):[^\s<>]+)>`)
+	commonMarkImage    = regexp.MustCompile(`!\[([^\]\n]*)\]\(([^\s()]+)(?:\s+"[^"\n]*")?\)`)
+	commonMarkLink     = regexp.MustCompile(`\[([^\]\n]+)\]\(([^\s()]+(?:\([^\s()]*\)[^\s()]*)*)(?:\s+"([^"\n]*)")?\)`)
+
+	// commonMarkEmphasisPatterns are the emphases, bold first. Those with
+	// underscores are not followed by a letter or a digit.
+	commonMarkEmphasisPatterns = []struct {
+		pattern    *regexp.Regexp
+		mark       string
+		underscore bool
+	}{
+		{commonMarkSpan("**"), markBold, false},
+		{commonMarkSpan("__"), markBold, true},
+		{commonMarkSpan("*"), markItalic, false},
+		{commonMarkSpan("_"), markItalic, true},
+		{commonMarkSpan("~~"), markStrike, false},
+	}
+
+	textileMarks = strings.NewReplacer(markBold, "*", markItalic, "_", markStrike, "-")
+)
+
+// commonMarkInline converts the inline markup of a line from CommonMark to
+// Textile.
+func commonMarkInline(line string) string {
+	p := &protector{}
+	line = replace(commonMarkCode, line, func(m []string, _ string) (string, bool) {
+		return p.keep("@" + m[1] + m[2] + "@"), true
+	})
+	line = replace(commonMarkEscape, line, func(m []string, _ string) (string, bool) {
+		return p.keep(m[1]), true
+	})
+	line = replace(commonMarkAutolink, line, func(m []string, _ string) (string, bool) {
+		return p.keep(m[1]), true
+	})
+	line = redmineMacroPattern.ReplaceAllStringFunc(line, p.keep)
+	line = replace(commonMarkImage, line, func(m []string, _ string) (string, bool) {
+		if m[1] != "" {
+			return p.keep("!" + m[2] + "(" + m[1] + ")!"), true
+		}
+		return p.keep("!" + m[2] + "!"), true
+	})
+	line = replace(commonMarkLink, line, func(m []string, next string) (string, bool) {
+		text := textileMarks.Replace(commonMarkEmphasis(m[1]))
+		if m[3] != "" {
+			text += "(" + m[3] + ")"
+		}
+		link := `"` + text + `":` + m[2]
+		if wordStart(next) || strings.HasSuffix(m[2], ".") || strings.ContainsAny(m[2][len(m[2])-1:], ",;:!?)") {
+			link = "[" + link + "]"
+		}
+		return p.keep(link), true
+	})
+	line = redmineRefPattern.ReplaceAllStringFunc(line, p.keep)
+	return p.restore(textileMarks.Replace(commonMarkEmphasis(line)))
+}
+
+// commonMarkEmphasis replaces the emphases of CommonMark with markers.
+func commonMarkEmphasis(s string) string {
+	for _, e := range commonMarkEmphasisPatterns {
+		s = replace(e.pattern, s, func(m []string, next string) (string, bool) {
+			if e.underscore && wordStart(next) {
+				return "", false
+			}
+			return m[1] + e.mark + m[2] + e.mark, true
+		})
+	}
+	return s
+}
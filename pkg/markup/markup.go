@@ -0,0 +1,292 @@
+// Package markup converts the text of issues, wiki pages and news between
+// the Textile and CommonMark formattings of Redmine.
+//
+// The conversion covers:
+//
+//   - headings, paragraphs and quotes, nested quotes included;
+//   - bulleted and numbered lists, nested and mixed;
+//   - tables, with their header row and the alignment of their columns;
+//   - code blocks, with their language, and inline code;
+//   - bold, italic and strikethrough text;
+//   - links and images, linked images included.
+//
+// The syntax specific to Redmine is the same in both formattings and is kept
+// verbatim: issue links (#123), wiki links ([[Page]]), resource links
+// (attachment:, commit:, source:, version:...), user mentions (@login),
+// macros ({{toc}}) and bare URLs. Their content is not converted, so that an
+// underscore of a wiki page title does not become italic.
+//
+// What either formatting cannot express is lost: the classes, styles and
+// spans of Textile cells and blocks, underlined, superscript, subscript and
+// cited text (converted to plain text), the start number of ordered lists,
+// the titles of images and the footnotes. Text that is only markup in the
+// target formatting is not escaped, and intraword emphasis of CommonMark is
+// written as is although Textile ignores it.
+//
+// The conversion is stable: converting the output back gives the same
+// output again, and converting text written as the converter writes it,
+// such as the output of the other direction, gives the text back.
+package markup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Convert converts text from the formatting from to the formatting to, as
+// named in the settings of Redmine: textile, markdown or common_mark. The
+// legacy markdown formatting is handled as CommonMark.
+func Convert(text, from, to string) (string, error) {
+	src, err := commonMark(from)
+	if err != nil {
+		return "", err
+	}
+	dst, err := commonMark(to)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case src == dst:
+		return text, nil
+	case dst:
+		return TextileToCommonMark(text), nil
+	default:
+		return CommonMarkToTextile(text), nil
+	}
+}
+
+// commonMark reports whether the formatting f is one of the Markdown ones.
+func commonMark(f string) (bool, error) {
+	switch f {
+	case "", "textile":
+		return false, nil
+	case "markdown", "common_mark":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown text formatting %q", f)
+	}
+}
+
+// splitLines splits text into lines without their line breaks.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// joinLines joins lines, with a final line break when src has one.
+func joinLines(lines []string, src string) string {
+	s := strings.Join(lines, "\n")
+	if strings.HasSuffix(src, "\n") && s != "" {
+		s += "\n"
+	}
+	return s
+}
+
+// indentWidth returns the width of the leading white space of line, with
+// tabs of 4 columns.
+func indentWidth(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// Placeholders and markers of inline conversion are runes of the private
+// use area, which the patterns of both formattings do not match.
+const (
+	placeholderStart = "\uE000"
+	placeholderEnd   = "\uE001"
+	markBold         = "\uE002"
+	markItalic       = "\uE003"
+	markStrike       = "\uE004"
+)
+
+var placeholderPattern = regexp.MustCompile(placeholderStart + `(\d+)` + placeholderEnd)
+
+// protector replaces the converted parts of a line with placeholders, so
+// that the later patterns do not convert them again.
+type protector struct {
+	kept []string
+}
+
+// keep returns the placeholder of s.
+func (p *protector) keep(s string) string {
+	p.kept = append(p.kept, s)
+	return placeholderStart + strconv.Itoa(len(p.kept)-1) + placeholderEnd
+}
+
+// restore replaces the placeholders of s, which may be nested, with the
+// text they keep.
+func (p *protector) restore(s string) string {
+	for placeholderPattern.MatchString(s) {
+		s = placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+			i, _ := strconv.Atoi(placeholderPattern.FindStringSubmatch(m)[1])
+			return p.kept[i]
+		})
+	}
+	return s
+}
+
+// replace replaces the matches of re in s with the result of fn, given the
+// submatches and the text following the match. A match is left as is when
+// fn returns false.
+func replace(re *regexp.Regexp, s string, fn func(m []string, next string) (string, bool)) string {
+	out := strings.Builder{}
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = s[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		r, ok := fn(m, s[loc[1]:])
+		if !ok {
+			continue
+		}
+		out.WriteString(s[last:loc[0]])
+		out.WriteString(r)
+		last = loc[1]
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// wordStart reports whether s starts with a letter or a digit, which ends a
+// span of Textile only when it is not next to it.
+func wordStart(s string) bool {
+	return s != "" && isWord(s[0])
+}
+
+func isWord(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+var (
+	// redmineMacroPattern matches the wiki links, escaped or not, and the
+	// macros on a line.
+	redmineMacroPattern = regexp.MustCompile(`!?\[\[[^\[\]\n]+\]\]|\{\{[^\n]*?\}\}`)
+
+	// redmineRefPattern matches the resource links, the bare URLs and the
+	// user mentions.
+	redmineRefPattern = regexp.MustCompile(`(?:[\w-]+:)?(?:attachment|commit|source|export|document|version|project|user|forum|message|news|revision):(?:"[^"\n]+"|[^\s<>"]*[^\s<>".,;:!?)])` +
+		`|(?:https?|ftp)://[^\s<>"]*[^\s<>".,;:!?)]` +
+		`|\B@\w[\w.-]*\w|\B@\w`)
+)
+
+// macroBlock reports whether line starts a macro spanning several lines,
+// which is kept verbatim.
+func macroBlock(line string) bool {
+	return strings.HasPrefix(line, "{{") && !strings.Contains(line, "}}")
+}
+
+// quotePrefix returns the quote marker of line with the space following it,
+// if any, or "".
+func quotePrefix(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || !strings.HasPrefix(trimmed, ">") {
+		return ""
+	}
+	n := len(line) - len(trimmed) + 1
+	if strings.HasPrefix(trimmed[1:], " ") {
+		n++
+	}
+	return line[:n]
+}
+
+// convertQuote converts the quote starting at lines[i] with convert, and
+// returns the converted lines and the index of the last line of the quote.
+func convertQuote(lines []string, i int, convert func([]string) []string) ([]string, int) {
+	inner := []string{}
+	end := i
+	for ; end < len(lines); end++ {
+		prefix := quotePrefix(lines[end])
+		if prefix == "" {
+			break
+		}
+		inner = append(inner, lines[end][len(prefix):])
+	}
+
+	out := []string{}
+	for _, line := range convert(inner) {
+		if line == "" {
+			out = append(out, ">")
+		} else {
+			out = append(out, "> "+line)
+		}
+	}
+	return out, end - 1
+}
+
+// Alignment of the columns of tables.
+const (
+	alignLeft   = "left"
+	alignCenter = "center"
+	alignRight  = "right"
+)
+
+// table is a table of either formatting.
+type table struct {
+	// header The cells of the header row, nil if none.
+	header []string
+
+	// align The alignment of the columns, "" when not set.
+	align []string
+
+	rows [][]string
+}
+
+// columns returns the number of columns of t.
+func (t *table) columns() int {
+	n := max(len(t.header), len(t.align))
+	for _, row := range t.rows {
+		n = max(n, len(row))
+	}
+	return n
+}
+
+// alignment returns the alignment of the column i.
+func (t *table) alignment(i int) string {
+	if i < len(t.align) {
+		return t.align[i]
+	}
+	return ""
+}
+
+// splitCells splits the cells of a table row without its outer pipes. The
+// pipes of wiki links and the escaped ones do not split cells.
+func splitCells(row string) []string {
+	cells := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row):
+			i++
+		case strings.HasPrefix(row[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(row[i:], "]]") && depth > 0:
+			depth--
+			i++
+		case row[i] == '|' && depth == 0:
+			cells = append(cells, row[start:i])
+			start = i + 1
+		}
+	}
+	return append(cells, row[start:])
+}
@@ -0,0 +1,190 @@
+package markup
+
+import (
+	"testing"
+)
+
+// textileDoc and commonMarkDoc are the same document, as the converter
+// writes it in either formatting.
+const textileDoc = `h1. Release notes
+
+See #123, [[Wiki_page|the_wiki]], attachment:"my file.png", commit:abc1234 and @john_doe.
+
+{{toc}}
+
+* *bold*, _italic_, -strike- and @snake_case@
+** nested "link":https://example.com/a_b.
+*** "titled(Example)":https://example.com
+# first
+#* mixed
+
+|_. Name|_=. Value|
+|foo|=. [[A|B]]|
+|bar|=. |
+
+|a|b|
+
+<pre><code class="ruby">
+puts "x" # *not bold*
+</code></pre>
+
+<pre>
+plain
+</pre>
+
+> quoted *text*
+> > nested
+
+!image.png(Alt text)! and "!thumb.png!":https://example.com
+
+h2. Second
+
+["Redmine":https://www.redmine.org/]s and {{collapse(Details)
+_hidden_
+}}
+`
+
+const commonMarkDoc = `# Release notes
+
+See #123, [[Wiki_page|the_wiki]], attachment:"my file.png", commit:abc1234 and @john_doe.
+
+{{toc}}
+
+- **bold**, *italic*, ~~strike~~ and ` + "`snake_case`" + `
+  - nested [link](https://example.com/a_b).
+    - [titled](https://example.com "Example")
+1. first
+   - mixed
+
+| Name | Value |
+| --- | :---: |
+| foo | [[A\|B]] |
+| bar |  |
+
+|  |  |
+| --- | --- |
+| a | b |
+
+` + "```ruby" + `
+puts "x" # *not bold*
+` + "```" + `
+
+` + "```" + `
+plain
+` + "```" + `
+
+> quoted **text**
+> > nested
+
+![Alt text](image.png) and [![](thumb.png)](https://example.com)
+
+## Second
+
+[Redmine](https://www.redmine.org/)s and {{collapse(Details)
+*hidden*
+}}
+`
+
+func TestRoundTrip(t *testing.T) {
+	if got := TextileToCommonMark(textileDoc); got != commonMarkDoc {
+		t.Errorf("to CommonMark:\n%s", got)
+	}
+	if got := CommonMarkToTextile(commonMarkDoc); got != textileDoc {
+		t.Errorf("to Textile:\n%s", got)
+	}
+	if got := TextileToCommonMark(CommonMarkToTextile(commonMarkDoc)); got != commonMarkDoc {
+		t.Errorf("CommonMark round trip:\n%s", got)
+	}
+	if got := CommonMarkToTextile(TextileToCommonMark(textileDoc)); got != textileDoc {
+		t.Errorf("Textile round trip:\n%s", got)
+	}
+}
+
+func TestLinkEmphasisRoundTrip(t *testing.T) {
+	tests := []struct {
+		textile, commonMark string
+	}{
+		{`"_it_":http://x`, "[*it*](http://x)"},
+		{`"*bold* link":http://x`, "[**bold** link](http://x)"},
+		{`see "-old- _new_":http://x/a_b`, "see [~~old~~ *new*](http://x/a_b)"},
+	}
+	for _, tt := range tests {
+		if got := TextileToCommonMark(tt.textile); got != tt.commonMark {
+			t.Errorf("%q: got %q", tt.textile, got)
+		}
+		if got := CommonMarkToTextile(tt.commonMark); got != tt.textile {
+			t.Errorf("%q: got %q", tt.commonMark, got)
+		}
+	}
+}
+
+func TestTextileToCommonMark(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"h3(#id). Title", "### Title"},
+		{"p(note). Text", "Text"},
+		{"bq. Quote", "> Quote"},
+		{"bc. x = *y*\nz", "```\nx = *y*\nz\n```"},
+		{"<pre>one line</pre>", "```\none line\n```"},
+		{"+under+ ^sup^ ~sub~ ??cite?? %span%", "under sup sub cite span"},
+		{"a*b* snake_case_name well-known 1 - 2 - 3", "a*b* snake_case_name well-known 1 - 2 - 3"},
+		{"mail me@example.com or @alice and @bob", "mail me@example.com or @alice and @bob"},
+		{"==*raw*== and <notextile>_raw_</notextile>", "*raw* and _raw_"},
+		{"@a`b@", "``a`b``"},
+		{"source:trunk/app_name/file_x.rb#L10 and https://x.org/a_b_c", "source:trunk/app_name/file_x.rb#L10 and https://x.org/a_b_c"},
+		{"!{width:50%}diagram.png!:https://example.com/big.png", "[![](diagram.png)](https://example.com/big.png)"},
+		{"|\\2. span|", "|  |\n| --- |\n| span |"},
+		{"|", "|"},
+		{"|a|\n|", "|  |\n| --- |\n| a |\n|"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TextileToCommonMark(tt.in); got != tt.want {
+			t.Errorf("%q: got %q", tt.in, got)
+		}
+	}
+}
+
+func TestCommonMarkToTextile(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Title\n=====\nText", "h1. Title\n\nText"},
+		{"Sub\n---", "h2. Sub"},
+		{"## Title ##", "h2. Title"},
+		{"* a\n+ b\n\n3) c", "* a\n* b\n\n# c"},
+		{"text\n\n    code *x*\n\n    more\n\nafter", "text\n\n<pre>\ncode *x*\n\nmore\n</pre>\n\nafter"},
+		{"~~~\n```\n~~~", "<pre>\n```\n</pre>"},
+		{"a\n\n***\n\nb", "a\n\n---\n\nb"},
+		{"__bold__ _it_ snake_case_name a*b*c", "*bold* _it_ snake_case_name a_b_c"},
+		{`\*not\* \_em\_`, "*not* _em_"},
+		{"<https://example.com/a_b> and ``a`b``", "https://example.com/a_b and @a`b@"},
+		{"[text](https://example.com/a_(b)) end", `["text":https://example.com/a_(b)] end`},
+		{"![](x.png \"title\")", "!x.png!"},
+		{"| a |\n| -: |\n| b |", "|_>. a|\n|>. b|"},
+		{"> - item\n>\n> text", "> * item\n>\n> text"},
+	}
+	for _, tt := range tests {
+		if got := CommonMarkToTextile(tt.in); got != tt.want {
+			t.Errorf("%q: got %q", tt.in, got)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	got, err := Convert("*bold*", "textile", "common_mark")
+	if err != nil || got != "**bold**" {
+		t.Errorf("textile: %q %v", got, err)
+	}
+	got, err = Convert("**bold**", "markdown", "")
+	if err != nil || got != "*bold*" {
+		t.Errorf("markdown: %q %v", got, err)
+	}
+	if got, _ := Convert("*x*", "common_mark", "markdown"); got != "*x*" {
+		t.Errorf("same formatting: %q", got)
+	}
+	if _, err := Convert("x", "rdoc", "textile"); err == nil {
+		t.Error("unknown formatting accepted")
+	}
+}
@@ -0,0 +1,310 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+)
+
+// textileAttrs matches the attributes of a block or a cell: classes and
+// IDs, styles, languages, alignments and spans.
+const textileAttrs = `(?:\([^()\s]*\)|\{[^}\n]*\}|\[[^\]\n]*\]|<>|[<>=^~]|\\\d+|/\d+)*`
+
+var (
+	textileHeading    = regexp.MustCompile(`^h([1-6])` + textileAttrs + `\.\s+(.*)$`)
+	textileBlock      = regexp.MustCompile(`^(p|bq|bc)` + textileAttrs + `\.\.?(?:\s+(.*))?$`)
+	textileList       = regexp.MustCompile(`^([*#]+)\s+(.*)$`)
+	textileTableAttrs = regexp.MustCompile(`^table` + textileAttrs + `\.\s*$`)
+	textileCell       = regexp.MustCompile(`^(_?)(` + textileAttrs + `)\.(?:\s+|$)`)
+	textilePre        = regexp.MustCompile(`(?s)^\s*<pre[^>]*>(?:\s*<code(?:\s+class="(?:language-)?([^"]*)")?[^>]*>)?(.*?)(?:</code>\s*)?</pre>\s*$`)
+)
+
+// TextileToCommonMark converts text from Textile to CommonMark.
+func TextileToCommonMark(text string) string {
+	return joinLines(textileToCommonMark(splitLines(text)), text)
+}
+
+func textileToCommonMark(lines []string) []string {
+	out := []string{}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := textileHeading.FindStringSubmatch(line); m != nil {
+			out = append(out, strings.Repeat("#", int(m[1][0]-'0'))+" "+textileInline(m[2]))
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "<pre"):
+			end := i
+			for end < len(lines) && !strings.Contains(lines[end], "</pre>") {
+				end++
+			}
+			if end == len(lines) {
+				return append(out, lines[i:]...)
+			}
+			m := textilePre.FindStringSubmatch(strings.Join(lines[i:end+1], "\n"))
+			if m == nil {
+				out = append(out, lines[i:end+1]...)
+			} else {
+				code := strings.TrimSuffix(strings.TrimPrefix(m[2], "\n"), "\n")
+				out = append(out, fence(code, m[1])...)
+			}
+			i = end
+		case quotePrefix(line) != "":
+			var quote []string
+			quote, i = convertQuote(lines, i, textileToCommonMark)
+			out = append(out, quote...)
+		case macroBlock(line):
+			out = append(out, line)
+		case strings.HasPrefix(line, "|") || textileTableAttrs.MatchString(line):
+			start := i
+			if !strings.HasPrefix(line, "|") {
+				start++
+			}
+			end := start
+			for end < len(lines) && textileRow(lines[end]) {
+				end++
+			}
+			if end == start {
+				out = append(out, textileInline(line))
+				continue
+			}
+			out = append(out, writeCommonMarkTable(parseTextileTable(lines[start:end]))...)
+			i = end - 1
+		case textileBlock.MatchString(line):
+			m := textileBlock.FindStringSubmatch(line)
+			switch m[1] {
+			case "bc":
+				// The code block lasts until the next blank line.
+				code := []string{m[2]}
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+					code = append(code, lines[i])
+				}
+				out = append(out, fence(strings.Join(code, "\n"), "")...)
+			case "bq":
+				out = append(out, "> "+textileInline(m[2]))
+			default:
+				out = append(out, textileInline(m[2]))
+			}
+		case textileList.MatchString(line):
+			m := textileList.FindStringSubmatch(line)
+			indent := ""
+			for _, c := range m[1][:len(m[1])-1] {
+				if c == '#' {
+					indent += "   "
+				} else {
+					indent += "  "
+				}
+			}
+			marker := "-"
+			if strings.HasSuffix(m[1], "#") {
+				marker = "1."
+			}
+			out = append(out, indent+marker+" "+textileInline(m[2]))
+		default:
+			out = append(out, textileInline(line))
+		}
+	}
+	return out
+}
+
+// fence returns the lines of a fenced code block of CommonMark.
+func fence(code, lang string) []string {
+	f := "```"
+	for strings.Contains(code, f) {
+		f += "`"
+	}
+	lines := []string{f + lang}
+	if code != "" {
+		lines = append(lines, strings.Split(code, "\n")...)
+	}
+	return append(lines, f)
+}
+
+// textileRow reports whether line is a row of a table, with at least the
+// pipes opening and closing it.
+func textileRow(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= 2 && strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|")
+}
+
+// parseTextileTable parses the rows of a table. The first row is the header
+// when all its cells are header cells, and the alignment of the columns is
+// the one of their first cell.
+func parseTextileTable(rows []string) *table {
+	t := &table{}
+	for n, row := range rows {
+		row = strings.TrimSpace(row)
+		cells := splitCells(row[1 : len(row)-1])
+		header := true
+		for i, cell := range cells {
+			m := textileCell.FindStringSubmatch(cell)
+			if m == nil || m[0] == "." {
+				header = false
+				cells[i] = strings.TrimSpace(cell)
+				continue
+			}
+			header = header && m[1] != ""
+			cells[i] = strings.TrimSpace(cell[len(m[0]):])
+
+			for len(t.align) <= i {
+				t.align = append(t.align, "")
+			}
+			if t.align[i] != "" || strings.Contains(m[2], "<>") {
+				continue
+			}
+			switch {
+			case strings.Contains(m[2], "="):
+				t.align[i] = alignCenter
+			case strings.Contains(m[2], "<"):
+				t.align[i] = alignLeft
+			case strings.Contains(m[2], ">"):
+				t.align[i] = alignRight
+			}
+		}
+
+		if n == 0 && header {
+			t.header = cells
+		} else {
+			t.rows = append(t.rows, cells)
+		}
+	}
+	return t
+}
+
+// writeCommonMarkTable returns the lines of t in CommonMark. A table
+// without header has an empty one.
+func writeCommonMarkTable(t *table) []string {
+	n := t.columns()
+	row := func(cells []string) string {
+		out := make([]string, n)
+		for i := range out {
+			if i < len(cells) {
+				out[i] = strings.ReplaceAll(textileInline(cells[i]), "|", `\|`)
+			}
+		}
+		return "| " + strings.Join(out, " | ") + " |"
+	}
+
+	sep := make([]string, n)
+	for i := range sep {
+		switch t.alignment(i) {
+		case alignLeft:
+			sep[i] = ":---"
+		case alignCenter:
+			sep[i] = ":---:"
+		case alignRight:
+			sep[i] = "---:"
+		default:
+			sep[i] = "---"
+		}
+	}
+
+	lines := []string{row(t.header), "| " + strings.Join(sep, " | ") + " |"}
+	for _, r := range t.rows {
+		lines = append(lines, row(r))
+	}
+	return lines
+}
+
+// textileSpan returns the pattern of the text between the marker of a
+// span, which is not next to a letter or a digit outside of it.
+func textileSpan(marker string) *regexp.Regexp {
+	q := regexp.QuoteMeta(marker)
+	c := regexp.QuoteMeta(marker[:1])
+	return regexp.MustCompile(`(^|[^\w` + c + `])` + q + `([^\s` + c + `](?:[^\n]*?[^\s` + c + `])?)` + q)
+}
+
+var (
+	textileNoTextile = regexp.MustCompile(`==([^\n]+?)==|<notextile>(.*?)</notextile>`)
+	textileCode      = regexp.MustCompile(`(^|[^\w@])@([^\s@](?:[^@\n]*?[^\s@])?)@|<code>(.*?)</code>`)
+	textileImage     = regexp.MustCompile(`(^|[\s(\[>"])!(?:[<>]|\{[^}\n]*\})*([^\s!()]+)(?:\(([^)\n]*)\))?!(?::([^\s<>"]*[^\s<>".,;:!?)]))?`)
+	textileLink      = regexp.MustCompile(`\["([^"\n]+)":([^\s\]]+)\]|(^|[\s(\[>])"([^"\n]+)":([^\s<>"]*[^\s<>".,;:!?)])`)
+	textileLinkTitle = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)$`)
+
+	textileEmphasisPatterns = []struct {
+		pattern *regexp.Regexp
+		mark    string
+	}{
+		{textileSpan("**"), markBold},
+		{textileSpan("*"), markBold},
+		{textileSpan("__"), markItalic},
+		{textileSpan("_"), markItalic},
+		{textileSpan("-"), markStrike},
+		{textileSpan("+"), ""},
+		{textileSpan("^"), ""},
+		{textileSpan("~"), ""},
+		{textileSpan("??"), ""},
+		{textileSpan("%"), ""},
+	}
+
+	commonMarkMarks = strings.NewReplacer(markBold, "**", markItalic, "*", markStrike, "~~")
+)
+
+// textileInline converts the inline markup of a line from Textile to
+// CommonMark.
+func textileInline(line string) string {
+	p := &protector{}
+	line = replace(textileNoTextile, line, func(m []string, _ string) (string, bool) {
+		return p.keep(m[1] + m[2]), true
+	})
+	line = replace(textileCode, line, func(m []string, next string) (string, bool) {
+		if m[3] != "" {
+			return p.keep(codeSpan(m[3])), true
+		}
+		if wordStart(next) {
+			return "", false
+		}
+		return m[1] + p.keep(codeSpan(m[2])), true
+	})
+	line = redmineMacroPattern.ReplaceAllStringFunc(line, p.keep)
+	line = replace(textileImage, line, func(m []string, _ string) (string, bool) {
+		image := "![" + m[3] + "](" + m[2] + ")"
+		if m[4] != "" {
+			image = "[" + image + "](" + m[4] + ")"
+		}
+		return m[1] + p.keep(image), true
+	})
+	line = replace(textileLink, line, func(m []string, next string) (string, bool) {
+		prefix, text, url := "", m[1], m[2]
+		if text == "" {
+			if wordStart(next) {
+				return "", false
+			}
+			prefix, text, url = m[3], m[4], m[5]
+		}
+		title := ""
+		if t := textileLinkTitle.FindStringSubmatch(text); t != nil && t[1] != "" {
+			text, title = t[1], ` "`+t[2]+`"`
+		}
+		return prefix + p.keep("["+commonMarkMarks.Replace(textileEmphasis(text))+"]("+url+title+")"), true
+	})
+	line = redmineRefPattern.ReplaceAllStringFunc(line, p.keep)
+	return p.restore(commonMarkMarks.Replace(textileEmphasis(line)))
+}
+
+// textileEmphasis replaces the spans of Textile with markers, or with their
+// text for those CommonMark does not have.
+func textileEmphasis(s string) string {
+	for _, e := range textileEmphasisPatterns {
+		s = replace(e.pattern, s, func(m []string, next string) (string, bool) {
+			if wordStart(next) {
+				return "", false
+			}
+			return m[1] + e.mark + m[2] + e.mark, true
+		})
+	}
+	return s
+}
+
+// codeSpan returns the inline code of CommonMark for code.
+func codeSpan(code string) string {
+	f := "`"
+	for strings.Contains(code, f) {
+		f += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return f + " " + code + " " + f
+	}
+	return f + code + f
+}
@@ -0,0 +1,204 @@
+package mirror
+
+import (
+	"sort"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// IssueFilter selects issues in the mirror. Zero fields are ignored.
+type IssueFilter struct {
+	// ProjectId The ID of the project of the issues.
+	ProjectId int
+
+	// TrackerId The ID of the tracker of the issues.
+	TrackerId int
+
+	// StatusId The ID of the status of the issues.
+	StatusId int
+
+	// AssignedToId The ID of the assignee of the issues.
+	AssignedToId int
+
+	// FixedVersionId The ID of the fixed version of the issues.
+	FixedVersionId int
+
+	// ParentId The ID of the parent of the issues.
+	ParentId int
+
+	// Open selects open issues if true and closed issues if false.
+	Open *bool
+
+	// UpdatedSince selects issues updated at or after the time.
+	UpdatedSince time.Time
+
+	// Match is an additional predicate.
+	Match func(*model.Issue) bool
+}
+
+func refIs(r *model.Ref, id int) bool {
+	return id == 0 || (r != nil && r.Id == id)
+}
+
+func (f *IssueFilter) match(i *model.Issue) bool {
+	if !refIs(i.Project, f.ProjectId) ||
+		!refIs(i.Tracker, f.TrackerId) ||
+		!refIs(i.AssignedTo, f.AssignedToId) ||
+		!refIs(i.FixedVersion, f.FixedVersionId) {
+		return false
+	}
+
+	if f.StatusId != 0 && (i.Status == nil || i.Status.Id != f.StatusId) {
+		return false
+	}
+
+	if f.ParentId != 0 && i.ParentId() != f.ParentId {
+		return false
+	}
+
+	if f.Open != nil && *f.Open == i.IsClosed() {
+		return false
+	}
+
+	if !f.UpdatedSince.IsZero() && (i.UpdatedOn == nil || i.UpdatedOn.Before(f.UpdatedSince)) {
+		return false
+	}
+
+	return f.Match == nil || f.Match(i)
+}
+
+// Issue returns the issue with id.
+func (s *Store) Issue(id int) (*model.Issue, error) {
+	return get[model.Issue](s, bucketIssues, idKey(id))
+}
+
+// Issues returns the issues matching filter ordered by ID.
+func (s *Store) Issues(filter IssueFilter) ([]model.Issue, error) {
+	return scan(s, bucketIssues, nil, filter.match)
+}
+
+// Journals returns the journals of the issue with id in chronological order.
+func (s *Store) Journals(issueId int) ([]model.Journal, error) {
+	journals, err := get[[]model.Journal](s, bucketJournals, idKey(issueId))
+	if err == ErrNotFound {
+		return []model.Journal{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return *journals, nil
+}
+
+// TimeEntryFilter selects time entries in the mirror. Zero fields are ignored.
+type TimeEntryFilter struct {
+	// ProjectId The ID of the project of the time entries.
+	ProjectId int
+
+	// IssueId The ID of the issue of the time entries.
+	IssueId int
+
+	// UserId The ID of the user of the time entries.
+	UserId int
+
+	// From selects time entries spent on or after the day.
+	From time.Time
+
+	// To selects time entries spent on or before the day.
+	To time.Time
+}
+
+func (f *TimeEntryFilter) match(e *model.TimeEntry) bool {
+	if !refIs(e.Project, f.ProjectId) || !refIs(e.User, f.UserId) {
+		return false
+	}
+
+	if f.IssueId != 0 && e.IssueId() != f.IssueId {
+		return false
+	}
+
+	if !f.From.IsZero() && (e.SpentOn == nil || e.SpentOn.Before(f.From)) {
+		return false
+	}
+
+	if !f.To.IsZero() && (e.SpentOn == nil || e.SpentOn.After(f.To)) {
+		return false
+	}
+
+	return true
+}
+
+// TimeEntries returns the time entries matching filter ordered by ID.
+func (s *Store) TimeEntries(filter TimeEntryFilter) ([]model.TimeEntry, error) {
+	return scan(s, bucketTimeEntries, nil, filter.match)
+}
+
+// Project returns the project with id.
+func (s *Store) Project(id int) (*model.Project, error) {
+	return get[model.Project](s, bucketProjects, idKey(id))
+}
+
+// ProjectByIdentifier returns the project with identifier.
+func (s *Store) ProjectByIdentifier(identifier string) (*model.Project, error) {
+	projects, err := scan(s, bucketProjects, nil, func(p *model.Project) bool { return p.Identifier == identifier })
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return nil, ErrNotFound
+	}
+	return &projects[0], nil
+}
+
+// Projects returns every project ordered by ID.
+func (s *Store) Projects() ([]model.Project, error) {
+	return scan[model.Project](s, bucketProjects, nil, nil)
+}
+
+// User returns the user with id.
+func (s *Store) User(id int) (*model.User, error) {
+	return get[model.User](s, bucketUsers, idKey(id))
+}
+
+// Users returns every user ordered by ID.
+func (s *Store) Users() ([]model.User, error) {
+	return scan[model.User](s, bucketUsers, nil, nil)
+}
+
+// Version returns the version with id.
+func (s *Store) Version(id int) (*model.Version, error) {
+	return get[model.Version](s, bucketVersions, idKey(id))
+}
+
+// Versions returns the versions of the project ordered by due date, then ID.
+// A zero projectId returns every version.
+func (s *Store) Versions(projectId int) ([]model.Version, error) {
+	versions, err := scan(s, bucketVersions, nil, func(v *model.Version) bool { return refIs(v.Project, projectId) })
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i].DueDate, versions[j].DueDate
+		switch {
+		case a == nil && b == nil:
+			return false
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		}
+		return a.Before(b.Time)
+	})
+
+	return versions, nil
+}
+
+// WikiPage returns the wiki page with title in the project.
+func (s *Store) WikiPage(projectId int, title string) (*model.WikiPage, error) {
+	return get[model.WikiPage](s, bucketWiki, wikiKey(projectId, title))
+}
+
+// WikiPages returns the wiki pages of the project ordered by title.
+func (s *Store) WikiPages(projectId int) ([]model.WikiPage, error) {
+	return scan[model.WikiPage](s, bucketWiki, idKey(projectId), nil)
+}
@@ -0,0 +1,185 @@
+// Package mirror keeps a local copy of a Redmine instance in an embedded
+// bbolt database.
+//
+// A Syncer fetches issues, journals, time entries, projects, users, versions
+// and wiki pages. After the first full sync only the records updated since
+// the previous run are fetched, and deleted records are detected by
+// periodically reconciling the IDs known locally with those on the server.
+// The Store answers typed queries over the mirrored records.
+package mirror
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned when a record is not in the mirror.
+var ErrNotFound = errors.New("mirror: record not found")
+
+var (
+	bucketIssues      = []byte("issues")
+	bucketJournals    = []byte("journals")
+	bucketTimeEntries = []byte("time_entries")
+	bucketProjects    = []byte("projects")
+	bucketUsers       = []byte("users")
+	bucketVersions    = []byte("versions")
+	bucketWiki        = []byte("wiki_pages")
+	bucketMeta        = []byte("meta")
+
+	buckets = [][]byte{
+		bucketIssues,
+		bucketJournals,
+		bucketTimeEntries,
+		bucketProjects,
+		bucketUsers,
+		bucketVersions,
+		bucketWiki,
+		bucketMeta,
+	}
+)
+
+// Store is a mirror database.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the mirror database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// idKey encodes an ID so that keys sort in numeric order.
+func idKey(id int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
+
+func keyId(k []byte) int {
+	return int(binary.BigEndian.Uint64(k))
+}
+
+// wikiKey is the key of a wiki page: the project ID followed by the title.
+func wikiKey(projectId int, title string) []byte {
+	return append(idKey(projectId), []byte(title)...)
+}
+
+func put(tx *bolt.Tx, bucket, key []byte, v any) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put(key, buf)
+}
+
+func get[T any](s *Store, bucket, key []byte) (*T, error) {
+	var v *T
+	err := s.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucket).Get(key)
+		if buf == nil {
+			return ErrNotFound
+		}
+		v = new(T)
+		return json.Unmarshal(buf, v)
+	})
+	return v, err
+}
+
+// scan returns the records of the bucket whose key starts with prefix and
+// that satisfy match. A nil match accepts every record.
+func scan[T any](s *Store, bucket, prefix []byte, match func(*T) bool) ([]T, error) {
+	items := []T{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, buf := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, buf = c.Next() {
+			var v T
+			if err := json.Unmarshal(buf, &v); err != nil {
+				return err
+			}
+			if match == nil || match(&v) {
+				items = append(items, v)
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+func hasPrefix(k, prefix []byte) bool {
+	return len(k) >= len(prefix) && string(k[:len(prefix)]) == string(prefix)
+}
+
+// ids returns the IDs of the records in the bucket.
+func (s *Store) ids(bucket []byte) (map[int]bool, error) {
+	ids := map[int]bool{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, _ []byte) error {
+			ids[keyId(k)] = true
+			return nil
+		})
+	})
+	return ids, err
+}
+
+func (s *Store) meta(name string) (time.Time, error) {
+	var t time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucketMeta).Get([]byte(name))
+		if buf == nil {
+			return nil
+		}
+		return t.UnmarshalText(buf)
+	})
+	return t, err
+}
+
+func setMeta(tx *bolt.Tx, name string, t time.Time) error {
+	buf, err := t.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketMeta).Put([]byte(name), buf)
+}
+
+// LastSync returns the time of the last successful sync, or the zero time
+// if the mirror has never been synced.
+func (s *Store) LastSync() (time.Time, error) {
+	return s.meta(metaSyncedAt)
+}
+
+// LastReconcile returns the time of the last ID reconciliation.
+func (s *Store) LastReconcile() (time.Time, error) {
+	return s.meta(metaReconciledAt)
+}
+
+func projectKey(id int) string {
+	return strconv.Itoa(id)
+}
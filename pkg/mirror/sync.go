@@ -0,0 +1,624 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+const (
+	metaSyncedAt         = "synced_at"
+	metaReconciledAt     = "reconciled_at"
+	metaIssuesUpdated    = "issues.updated_on"
+	metaProjectsUpdated  = "projects.updated_on"
+	metaTimeEntryUpdated = "time_entries.updated_on"
+)
+
+// Counts is the number of records changed in the mirror by a sync.
+type Counts struct {
+	// Updated The number of records created or updated.
+	Updated int `json:"updated"`
+
+	// Deleted The number of records deleted.
+	Deleted int `json:"deleted"`
+}
+
+// Report is the result of a sync.
+type Report struct {
+	// Full Whether every record was fetched because the mirror was empty.
+	Full bool `json:"full"`
+
+	// Reconciled Whether the IDs were reconciled to detect deletions.
+	Reconciled bool `json:"reconciled"`
+
+	Projects    Counts `json:"projects"`
+	Users       Counts `json:"users"`
+	Issues      Counts `json:"issues"`
+	Journals    Counts `json:"journals"`
+	TimeEntries Counts `json:"time_entries"`
+	Versions    Counts `json:"versions"`
+	WikiPages   Counts `json:"wiki_pages"`
+}
+
+// Syncer brings a Store in step with a Redmine server.
+type Syncer struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Store The mirror to update.
+	Store *Store
+
+	// Projects The IDs or identifiers of the projects whose versions and wiki
+	// pages are mirrored. Empty means every mirrored project.
+	Projects []string
+
+	// ReconcileInterval The minimum time between two ID reconciliations.
+	// Zero reconciles on every sync.
+	ReconcileInterval time.Duration
+
+	// Now returns the current time. Nil means time.Now.
+	Now func() time.Time
+}
+
+func (s *Syncer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// since formats a high-water mark as an updated_on filter expression.
+func since(t time.Time) *string {
+	v := ">=" + t.UTC().Format(time.RFC3339)
+	return &v
+}
+
+// pageSize is the number of records requested per page of an index.
+var pageSize = apiutil.PageSize
+
+// keyset fetches the records updated since mark, oldest first, and passes
+// the new ones to save a page at a time. Each page starts from the latest
+// updated_on fetched rather than from an offset: a record updated during the
+// sync moves to the end of the order, which would shift the next record to
+// the page already fetched. The records fetched again at that time are
+// dropped, and the offset only steps over a full page of them.
+func keyset[T any](mark time.Time, fetch func(updatedOn *string, offset int) ([]T, error), key func(T) (int, *time.Time), save func([]T) error) error {
+	seen := map[int]*time.Time{}
+	offset := 0
+	for {
+		var updatedOn *string
+		if !mark.IsZero() {
+			updatedOn = since(mark)
+		}
+		items, err := fetch(updatedOn, offset)
+		if err != nil {
+			return err
+		}
+
+		fresh := []T{}
+		next := mark
+		for _, item := range items {
+			id, updated := key(item)
+			if prev, ok := seen[id]; ok && apiutil.TimeEqual(prev, updated) {
+				continue
+			}
+			seen[id] = updated
+			fresh = append(fresh, item)
+			next = later(next, updated)
+		}
+		if err := save(fresh); err != nil {
+			return err
+		}
+
+		if len(items) < pageSize {
+			return nil
+		}
+		if next.Equal(mark) {
+			offset += len(items)
+		} else {
+			mark, offset = next, 0
+		}
+	}
+}
+
+// decodeItems decodes the records listed under key in a page of an index.
+func decodeItems[T any](body []byte, key string) ([]T, error) {
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	items := []T{}
+	if v, ok := page[key]; ok {
+		if err := json.Unmarshal(v, &items); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func later(a time.Time, b *time.Time) time.Time {
+	if b != nil && b.After(a) {
+		return *b
+	}
+	return a
+}
+
+// Sync fetches the records changed since the previous sync, or every record
+// on the first run, and reconciles IDs when ReconcileInterval has elapsed.
+func (s *Syncer) Sync(ctx context.Context) (*Report, error) {
+	synced, err := s.Store.LastSync()
+	if err != nil {
+		return nil, err
+	}
+
+	reconciled, err := s.Store.LastReconcile()
+	if err != nil {
+		return nil, err
+	}
+
+	start := s.now()
+	report := &Report{Full: synced.IsZero()}
+	report.Reconciled = !report.Full && start.Sub(reconciled) >= s.ReconcileInterval
+
+	steps := []func(context.Context, *Report) error{
+		s.syncProjects,
+		s.syncUsers,
+		s.syncIssues,
+		s.syncTimeEntries,
+		s.syncVersions,
+		s.syncWikiPages,
+	}
+	if report.Reconciled {
+		steps = append(steps, s.reconcile)
+	}
+
+	for _, step := range steps {
+		if err := step(ctx, report); err != nil {
+			return report, err
+		}
+	}
+
+	err = s.Store.db.Update(func(tx *bolt.Tx) error {
+		if err := setMeta(tx, metaSyncedAt, start); err != nil {
+			return err
+		}
+		if report.Full || report.Reconciled {
+			return setMeta(tx, metaReconciledAt, start)
+		}
+		return nil
+	})
+
+	return report, err
+}
+
+func (s *Syncer) syncProjects(ctx context.Context, report *Report) error {
+	hwm, err := s.Store.meta(metaProjectsUpdated)
+	if err != nil {
+		return err
+	}
+
+	include := []string{"trackers", "issue_categories", "enabled_modules", "time_entry_activities", "issue_custom_fields"}
+	fetch := func(updatedOn *string, offset int) ([]model.Project, error) {
+		q := redmine.ProjectsIndexParams_Query{UpdatedOn: updatedOn}
+		q.Set("sort", "updated_on:asc,id")
+		params := redmine.ProjectsIndexParams{Query: &q, Include: &include, Pagination: apiutil.Page(offset, pageSize)}
+		resp, err := s.Client.ProjectsIndexWithResponse(ctx, &params, s.Auth...)
+		if err != nil {
+			return nil, err
+		}
+		if err := apiutil.Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodeItems[model.Project](resp.Body, "projects")
+	}
+	key := func(p model.Project) (int, *time.Time) { return p.Id, p.UpdatedOn }
+
+	return keyset(hwm, fetch, key, func(projects []model.Project) error {
+		return s.Store.db.Update(func(tx *bolt.Tx) error {
+			for _, p := range projects {
+				if err := put(tx, bucketProjects, idKey(p.Id), p); err != nil {
+					return err
+				}
+				hwm = later(hwm, p.UpdatedOn)
+			}
+			report.Projects.Updated += len(projects)
+			return setMeta(tx, metaProjectsUpdated, hwm)
+		})
+	})
+}
+
+// syncUsers fetches every user since the API has no filter on updated_on.
+// Users missing from the server are deleted at once.
+func (s *Syncer) syncUsers(ctx context.Context, report *Report) error {
+	// An empty status selects users of any status.
+	all := ""
+	params := redmine.UsersIndexParams{Query: &redmine.UsersIndexParams_Query{Status: &all}}
+	users, err := apiutil.Users(ctx, s.Client, &params, s.Auth...)
+	if apiutil.IsForbidden(err) {
+		// Only administrators can list users.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return s.Store.db.Update(func(tx *bolt.Tx) error {
+		seen := map[int]bool{}
+		for _, u := range users {
+			seen[u.Id] = true
+
+			old := tx.Bucket(bucketUsers).Get(idKey(u.Id))
+			var prev model.User
+			if old != nil && json.Unmarshal(old, &prev) == nil && apiutil.TimeEqual(prev.UpdatedOn, u.UpdatedOn) {
+				continue
+			}
+
+			if err := put(tx, bucketUsers, idKey(u.Id), u); err != nil {
+				return err
+			}
+			report.Users.Updated++
+		}
+
+		n, err := deleteMissing(tx, bucketUsers, seen)
+		report.Users.Deleted += n
+		return err
+	})
+}
+
+func (s *Syncer) syncIssues(ctx context.Context, report *Report) error {
+	hwm, err := s.Store.meta(metaIssuesUpdated)
+	if err != nil {
+		return err
+	}
+
+	fetch := func(updatedOn *string, offset int) ([]model.Issue, error) {
+		all := "*"
+		q := redmine.IssuesIndexParams_Query{StatusId: &all, UpdatedOn: updatedOn}
+		q.Set("sort", "updated_on:asc,id")
+		params := redmine.IssuesIndexParams{Query: &q, Pagination: apiutil.Page(offset, pageSize)}
+		resp, err := s.Client.IssuesIndexWithResponse(ctx, &params, s.Auth...)
+		if err != nil {
+			return nil, err
+		}
+		if err := apiutil.Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodeItems[model.Issue](resp.Body, "issues")
+	}
+	key := func(i model.Issue) (int, *time.Time) { return i.Id, i.UpdatedOn }
+
+	return keyset(hwm, fetch, key, func(issues []model.Issue) error {
+		for _, i := range issues {
+			if err := s.syncIssue(ctx, report, i, &hwm); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// syncIssue fetches the issue i of the index with its journals and saves it,
+// moving the high-water mark hwm to its time in the index.
+func (s *Syncer) syncIssue(ctx context.Context, report *Report, i model.Issue, hwm *time.Time) error {
+	// The issue index cannot include journals.
+	full, err := apiutil.Issue(ctx, s.Client, i.Id, []string{"attachments", "journals", "relations", "watchers"}, s.Auth...)
+	if apiutil.IsNotFound(err) || apiutil.IsForbidden(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	prev, err := s.Store.Journals(i.Id)
+	if err != nil {
+		return err
+	}
+
+	journals := full.Journals
+	full.Journals = nil
+
+	err = s.Store.db.Update(func(tx *bolt.Tx) error {
+		if err := put(tx, bucketIssues, idKey(i.Id), full); err != nil {
+			return err
+		}
+		if err := put(tx, bucketJournals, idKey(i.Id), journals); err != nil {
+			return err
+		}
+		// The time of the index, not of the issue which may have been
+		// updated since, keeps the mark before the issues not synced.
+		*hwm = later(*hwm, i.UpdatedOn)
+		return setMeta(tx, metaIssuesUpdated, *hwm)
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Issues.Updated++
+	if len(journals) > len(prev) {
+		report.Journals.Updated += len(journals) - len(prev)
+	}
+	return nil
+}
+
+func (s *Syncer) syncTimeEntries(ctx context.Context, report *Report) error {
+	hwm, err := s.Store.meta(metaTimeEntryUpdated)
+	if err != nil {
+		return err
+	}
+
+	fetch := func(updatedOn *string, offset int) ([]model.TimeEntry, error) {
+		q := redmine.TimelogIndexParams_Query{}
+		if updatedOn != nil {
+			q.Set("updated_on", *updatedOn)
+		}
+		q.Set("sort", "updated_on:asc,id")
+		params := redmine.TimelogIndexParams{Query: &q, Pagination: apiutil.Page(offset, pageSize)}
+		resp, err := s.Client.TimelogIndexWithResponse(ctx, &params, s.Auth...)
+		if err != nil {
+			return nil, err
+		}
+		if err := apiutil.Check(resp, resp.Body); err != nil {
+			return nil, err
+		}
+		return decodeItems[model.TimeEntry](resp.Body, "time_entries")
+	}
+	key := func(e model.TimeEntry) (int, *time.Time) { return e.Id, e.UpdatedOn }
+
+	return keyset(hwm, fetch, key, func(entries []model.TimeEntry) error {
+		return s.Store.db.Update(func(tx *bolt.Tx) error {
+			for _, e := range entries {
+				if err := put(tx, bucketTimeEntries, idKey(e.Id), e); err != nil {
+					return err
+				}
+				hwm = later(hwm, e.UpdatedOn)
+			}
+			report.TimeEntries.Updated += len(entries)
+			return setMeta(tx, metaTimeEntryUpdated, hwm)
+		})
+	})
+}
+
+// projectIds returns the IDs of the projects whose versions and wiki pages
+// are mirrored.
+func (s *Syncer) projectIds() ([]int, error) {
+	if len(s.Projects) == 0 {
+		projects, err := s.Store.Projects()
+		if err != nil {
+			return nil, err
+		}
+
+		ids := []int{}
+		for _, p := range projects {
+			ids = append(ids, p.Id)
+		}
+		return ids, nil
+	}
+
+	ids := []int{}
+	for _, v := range s.Projects {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+
+		p, err := s.Store.ProjectByIdentifier(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, p.Id)
+	}
+	return ids, nil
+}
+
+// syncVersions fetches every version of the projects since the API has no
+// filter on updated_on. Versions are few per project.
+func (s *Syncer) syncVersions(ctx context.Context, report *Report) error {
+	projects, err := s.projectIds()
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range projects {
+		versions, err := apiutil.Versions(ctx, s.Client, projectKey(pid), s.Auth...)
+		if apiutil.IsNotFound(err) || apiutil.IsForbidden(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = s.Store.db.Update(func(tx *bolt.Tx) error {
+			seen := map[int]bool{}
+			for _, v := range versions {
+				seen[v.Id] = true
+
+				old := tx.Bucket(bucketVersions).Get(idKey(v.Id))
+				var prev model.Version
+				if old != nil && json.Unmarshal(old, &prev) == nil && apiutil.TimeEqual(prev.UpdatedOn, v.UpdatedOn) {
+					continue
+				}
+
+				if err := put(tx, bucketVersions, idKey(v.Id), v); err != nil {
+					return err
+				}
+				report.Versions.Updated++
+			}
+
+			// Delete the versions owned by the project that are gone.
+			gone := [][]byte{}
+			err := tx.Bucket(bucketVersions).ForEach(func(k, buf []byte) error {
+				var v model.Version
+				if err := json.Unmarshal(buf, &v); err != nil {
+					return err
+				}
+				if refIs(v.Project, pid) && !seen[v.Id] {
+					gone = append(gone, k)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			report.Versions.Deleted += len(gone)
+			return deleteKeys(tx, bucketVersions, gone)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncWikiPages fetches the index of the wiki of each project and the pages
+// whose version changed. Pages missing from the index are deleted at once.
+func (s *Syncer) syncWikiPages(ctx context.Context, report *Report) error {
+	projects, err := s.projectIds()
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range projects {
+		index, err := apiutil.WikiPages(ctx, s.Client, projectKey(pid), s.Auth...)
+		if apiutil.IsNotFound(err) || apiutil.IsForbidden(err) {
+			// The wiki module is disabled.
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		seen := map[string]bool{}
+		for _, entry := range index {
+			seen[entry.Title] = true
+
+			prev, err := s.Store.WikiPage(pid, entry.Title)
+			if err == nil && prev.Version == entry.Version {
+				continue
+			} else if err != nil && err != ErrNotFound {
+				return err
+			}
+
+			page, err := apiutil.WikiPage(ctx, s.Client, projectKey(pid), entry.Title, []string{"attachments"}, s.Auth...)
+			if err != nil {
+				return err
+			}
+
+			err = s.Store.db.Update(func(tx *bolt.Tx) error {
+				return put(tx, bucketWiki, wikiKey(pid, page.Title), page)
+			})
+			if err != nil {
+				return err
+			}
+			report.WikiPages.Updated++
+		}
+
+		err = s.Store.db.Update(func(tx *bolt.Tx) error {
+			prefix := idKey(pid)
+			gone := [][]byte{}
+			c := tx.Bucket(bucketWiki).Cursor()
+			for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+				if !seen[string(k[len(prefix):])] {
+					gone = append(gone, append([]byte{}, k...))
+				}
+			}
+
+			report.WikiPages.Deleted += len(gone)
+			return deleteKeys(tx, bucketWiki, gone)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcile deletes the issues, time entries and projects that are in the
+// mirror but no longer on the server.
+func (s *Syncer) reconcile(ctx context.Context, report *Report) error {
+	all := "*"
+	issues, err := apiutil.Issues(ctx, s.Client, &redmine.IssuesIndexParams{Query: &redmine.IssuesIndexParams_Query{StatusId: &all}}, s.Auth...)
+	if err != nil {
+		return err
+	}
+
+	entries, err := apiutil.TimeEntries(ctx, s.Client, nil, s.Auth...)
+	if err != nil {
+		return err
+	}
+
+	projects, err := apiutil.Projects(ctx, s.Client, nil, s.Auth...)
+	if err != nil {
+		return err
+	}
+
+	return s.Store.db.Update(func(tx *bolt.Tx) error {
+		seen := map[int]bool{}
+		for _, i := range issues {
+			seen[i.Id] = true
+		}
+		n, err := deleteMissing(tx, bucketIssues, seen)
+		if err != nil {
+			return err
+		}
+		report.Issues.Deleted += n
+		if _, err := deleteMissing(tx, bucketJournals, seen); err != nil {
+			return err
+		}
+
+		seen = map[int]bool{}
+		for _, e := range entries {
+			seen[e.Id] = true
+		}
+		if n, err = deleteMissing(tx, bucketTimeEntries, seen); err != nil {
+			return err
+		}
+		report.TimeEntries.Deleted += n
+
+		seen = map[int]bool{}
+		for _, p := range projects {
+			seen[p.Id] = true
+		}
+		if n, err = deleteMissing(tx, bucketProjects, seen); err != nil {
+			return err
+		}
+		report.Projects.Deleted += n
+
+		return nil
+	})
+}
+
+// deleteMissing deletes the records of the bucket whose ID is not in seen.
+func deleteMissing(tx *bolt.Tx, bucket []byte, seen map[int]bool) (int, error) {
+	gone := [][]byte{}
+	err := tx.Bucket(bucket).ForEach(func(k, _ []byte) error {
+		if !seen[keyId(k)] {
+			gone = append(gone, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(gone), deleteKeys(tx, bucket, gone)
+}
+
+// deleteKeys deletes keys collected beforehand, since deleting while
+// iterating with a cursor skips records.
+func deleteKeys(tx *bolt.Tx, bucket []byte, keys [][]byte) error {
+	for _, k := range keys {
+		if err := tx.Bucket(bucket).Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package mirror
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+type fakeServer struct {
+	mu      sync.Mutex
+	issues  map[int]string
+	broken  map[int]bool
+	queries []string
+	entries []string
+
+	// paged is called after the nth page of issues is served.
+	paged func(n int)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/projects.json":
+		fmt.Fprint(w, `{"projects":[{"id":1,"identifier":"p","name":"P","status":1,"updated_on":"2025-01-01T00:00:00Z"}],"total_count":1}`)
+	case r.URL.Path == "/users.json":
+		fmt.Fprint(w, `{"users":[{"id":1,"login":"admin","updated_on":"2025-01-01T00:00:00Z"}],"total_count":1}`)
+	case r.URL.Path == "/issues.json":
+		q := r.URL.Query()
+		f.queries = append(f.queries, q.Get("updated_on"))
+		type entry struct {
+			id      int
+			updated string
+		}
+		entries := []entry{}
+		for id, v := range f.issues {
+			var issue struct {
+				UpdatedOn string `json:"updated_on"`
+			}
+			_ = json.Unmarshal([]byte(v), &issue)
+			if ">="+issue.UpdatedOn >= q.Get("updated_on") {
+				entries = append(entries, entry{id, issue.UpdatedOn})
+			}
+		}
+		slices.SortFunc(entries, func(a, b entry) int {
+			return cmp.Or(strings.Compare(a.updated, b.updated), a.id-b.id)
+		})
+		if q.Get("sort") != "updated_on:asc,id" {
+			slices.Reverse(entries)
+		}
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		items := []string{}
+		for _, e := range entries[min(offset, len(entries)):min(offset+limit, len(entries))] {
+			items = append(items, f.issues[e.id])
+		}
+		fmt.Fprintf(w, `{"issues":[%s],"total_count":%d}`, strings.Join(items, ","), len(entries))
+		if f.paged != nil {
+			f.paged(len(f.queries))
+		}
+	case strings.HasPrefix(r.URL.Path, "/issues/"):
+		var id int
+		fmt.Sscanf(r.URL.Path, "/issues/%d.json", &id)
+		if f.broken[id] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		v, ok := f.issues[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var issue map[string]any
+		_ = json.Unmarshal([]byte(v), &issue)
+		issue["journals"] = []any{map[string]any{"id": id * 10, "notes": "n", "created_on": "2025-01-02T00:00:00Z"}}
+		_ = json.NewEncoder(w).Encode(map[string]any{"issue": issue})
+	case r.URL.Path == "/time_entries.json":
+		f.entries = append(f.entries, r.URL.Query().Encode())
+		fmt.Fprint(w, `{"time_entries":[{"id":3,"hours":1.5,"issue":{"id":1},"spent_on":"2025-01-02","updated_on":"2025-01-02T00:00:00Z"}],"total_count":1}`)
+	case r.URL.Path == "/projects/1/versions.json":
+		fmt.Fprint(w, `{"versions":[{"id":4,"name":"v1","project":{"id":1}}],"total_count":1}`)
+	case r.URL.Path == "/projects/1/wiki/index.json":
+		fmt.Fprint(w, `{"wiki_pages":[{"title":"Wiki","version":2}]}`)
+	case r.URL.Path == "/projects/1/wiki/Wiki.json":
+		fmt.Fprint(w, `{"wiki_page":{"title":"Wiki","version":2,"text":"hello"}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSync(t *testing.T) {
+	fake := &fakeServer{issues: map[int]string{
+		1: `{"id":1,"subject":"one","project":{"id":1},"status":{"id":1},"updated_on":"2025-01-02T00:00:00Z"}`,
+		2: `{"id":2,"subject":"two","project":{"id":1},"status":{"id":5,"is_closed":true},"updated_on":"2025-01-03T00:00:00Z"}`,
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+	s := Syncer{Client: c, Store: store, ReconcileInterval: time.Hour, Now: func() time.Time { return now }}
+
+	report, err := s.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Full || report.Issues.Updated != 2 || report.Journals.Updated != 2 || report.WikiPages.Updated != 1 {
+		t.Errorf("first report = %+v", report)
+	}
+
+	open := true
+	issues, err := store.Issues(IssueFilter{ProjectId: 1, Open: &open})
+	if err != nil || len(issues) != 1 || issues[0].Id != 1 {
+		t.Errorf("open issues = %+v, %v", issues, err)
+	}
+
+	journals, err := store.Journals(2)
+	if err != nil || len(journals) != 1 || journals[0].Id != 20 {
+		t.Errorf("journals = %+v, %v", journals, err)
+	}
+
+	page, err := store.WikiPage(1, "Wiki")
+	if err != nil || page.Text != "hello" {
+		t.Errorf("wiki page = %+v, %v", page, err)
+	}
+
+	// Delete issue 2 on the server: an incremental sync does not see it
+	// until the IDs are reconciled.
+	delete(fake.issues, 2)
+
+	now = now.Add(time.Minute)
+	report, err = s.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Full || report.Reconciled || report.WikiPages.Updated != 0 {
+		t.Errorf("second report = %+v", report)
+	}
+	if q := fake.queries[len(fake.queries)-1]; q != ">=2025-01-03T00:00:00Z" {
+		t.Errorf("updated_on = %q", q)
+	}
+	if q := fake.entries[len(fake.entries)-1]; q != "limit=100&offset=0&sort=updated_on%3Aasc%2Cid&updated_on=%3E%3D2025-01-02T00%3A00%3A00Z" {
+		t.Errorf("time entries query = %q", q)
+	}
+	if _, err := store.Issue(2); err != nil {
+		t.Errorf("issue 2 = %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	report, err = s.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Reconciled || report.Issues.Deleted != 1 {
+		t.Errorf("third report = %+v", report)
+	}
+	if _, err := store.Issue(2); err != ErrNotFound {
+		t.Errorf("issue 2 = %v", err)
+	}
+}
+
+func TestSyncResume(t *testing.T) {
+	fake := &fakeServer{
+		issues: map[int]string{
+			1: `{"id":1,"subject":"one","project":{"id":1},"status":{"id":1},"updated_on":"2025-01-02T00:00:00Z"}`,
+			2: `{"id":2,"subject":"two","project":{"id":1},"status":{"id":1},"updated_on":"2025-01-03T00:00:00Z"}`,
+		},
+		broken: map[int]bool{2: true},
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := Syncer{Client: c, Store: store, ReconcileInterval: time.Hour}
+	if _, err := s.Sync(context.TODO()); err == nil {
+		t.Fatal("sync of a broken issue succeeded")
+	}
+	if _, err := store.Issue(1); err != nil {
+		t.Errorf("issue 1 = %v", err)
+	}
+
+	// The next sync resumes from the last issue saved.
+	fake.broken = nil
+	if _, err := s.Sync(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if q := fake.queries[len(fake.queries)-1]; q != ">=2025-01-02T00:00:00Z" {
+		t.Errorf("updated_on = %q", q)
+	}
+	if _, err := store.Issue(2); err != nil {
+		t.Errorf("issue 2 = %v", err)
+	}
+}
+
+func TestSyncUpdatedDuringSync(t *testing.T) {
+	issue := func(id int, updated string) string {
+		return fmt.Sprintf(`{"id":%d,"subject":"s","project":{"id":1},"status":{"id":1},"updated_on":"%s"}`, id, updated)
+	}
+	fake := &fakeServer{issues: map[int]string{}}
+	for id := 1; id <= 5; id++ {
+		fake.issues[id] = issue(id, fmt.Sprintf("2025-01-0%dT00:00:00Z", id))
+	}
+	// Issue 1 is updated once the first page is served, which moves it to
+	// the end of the order.
+	fake.paged = func(n int) {
+		if n == 1 {
+			fake.issues[1] = issue(1, "2025-01-09T00:00:00Z")
+		}
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	defer func(n int) { pageSize = n }(pageSize)
+	pageSize = 2
+
+	s := Syncer{Client: c, Store: store, ReconcileInterval: time.Hour}
+	report, err := s.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Issues.Updated != 6 {
+		t.Errorf("report = %+v", report)
+	}
+	for id := 1; id <= 5; id++ {
+		if _, err := store.Issue(id); err != nil {
+			t.Errorf("issue %d = %v", id, err)
+		}
+	}
+	if i, err := store.Issue(1); err != nil || !i.UpdatedOn.Equal(time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("issue 1 = %+v, %v", i, err)
+	}
+}
@@ -0,0 +1,609 @@
+// Package model provides named record types for Redmine resources.
+//
+// The generated client in package redmine describes every response with
+// anonymous structs, which cannot be passed around or stored by name. The
+// types in this package mirror the JSON representation of the resources and
+// are decoded from the raw response body of the generated client.
+package model
+
+import (
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// Ref is a reference to another resource by ID and display name.
+type Ref struct {
+	// Id The ID of the referenced resource.
+	Id int `json:"id"`
+
+	// Name The name of the referenced resource.
+	Name string `json:"name,omitempty"`
+}
+
+// CustomField is a custom field value attached to a resource.
+type CustomField struct {
+	// Id The ID of the custom field.
+	Id int `json:"id"`
+
+	// Name The name of the custom field.
+	Name string `json:"name,omitempty"`
+
+	// Multiple Whether the custom field can have multiple values.
+	Multiple bool `json:"multiple,omitempty"`
+
+	// Value The value of the custom field, a string or a list of strings.
+	Value any `json:"value,omitempty"`
+}
+
+// Values returns the values of the custom field as a list of strings.
+func (cf CustomField) Values() []string {
+	switch v := cf.Value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := []string{}
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// Status is an issue status.
+type Status struct {
+	// Id The ID of the status.
+	Id int `json:"id"`
+
+	// Name The name of the status.
+	Name string `json:"name,omitempty"`
+
+	// IsClosed The closed of the status.
+	IsClosed bool `json:"is_closed,omitempty"`
+
+	// Description The description of the status.
+	Description string `json:"description,omitempty"`
+}
+
+// Tracker is an issue tracker.
+type Tracker struct {
+	// Id The ID of the tracker.
+	Id int `json:"id"`
+
+	// Name The name of the tracker.
+	Name string `json:"name,omitempty"`
+
+	// DefaultStatus The default status of the tracker.
+	DefaultStatus *Ref `json:"default_status,omitempty"`
+
+	// Description The description of the tracker.
+	Description string `json:"description,omitempty"`
+}
+
+// Attachment is a file attached to an issue, a wiki page or another container.
+type Attachment struct {
+	// Id The ID of the attachment.
+	Id int `json:"id"`
+
+	// Filename The filename of the attachment.
+	Filename string `json:"filename,omitempty"`
+
+	// Filesize The filesize of the attachment.
+	Filesize int `json:"filesize,omitempty"`
+
+	// ContentType The content type of the attachment.
+	ContentType string `json:"content_type,omitempty"`
+
+	// Description The description of the attachment.
+	Description string `json:"description,omitempty"`
+
+	// ContentUrl The content URL of the attachment.
+	ContentUrl string `json:"content_url,omitempty"`
+
+	// Author The author of the attachment.
+	Author *Ref `json:"author,omitempty"`
+
+	// CreatedOn The date and time when the attachment was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+}
+
+// JournalDetail is a single property change recorded in a journal.
+type JournalDetail struct {
+	// Property The kind of the property, such as `attr`, `cf` or `attachment`.
+	Property string `json:"property"`
+
+	// Name The name of the property.
+	Name string `json:"name"`
+
+	// OldValue The value before the change.
+	OldValue *string `json:"old_value,omitempty"`
+
+	// NewValue The value after the change.
+	NewValue *string `json:"new_value,omitempty"`
+}
+
+// Journal is an entry of the issue history.
+type Journal struct {
+	// Id The ID of the journal.
+	Id int `json:"id"`
+
+	// User The user who made the change.
+	User *Ref `json:"user,omitempty"`
+
+	// Notes The notes of the journal.
+	Notes string `json:"notes,omitempty"`
+
+	// PrivateNotes Whether the notes are private.
+	PrivateNotes bool `json:"private_notes,omitempty"`
+
+	// CreatedOn The date and time when the journal was created.
+	CreatedOn time.Time `json:"created_on"`
+
+	// Details The property changes of the journal.
+	Details []JournalDetail `json:"details,omitempty"`
+}
+
+// Relation is a relation between two issues.
+type Relation struct {
+	// Id The ID of the relation.
+	Id int `json:"id"`
+
+	// IssueId The ID of the issue.
+	IssueId int `json:"issue_id"`
+
+	// IssueToId The ID of the related issue.
+	IssueToId int `json:"issue_to_id"`
+
+	// RelationType The type of the relation.
+	RelationType string `json:"relation_type"`
+
+	// Delay The delay of the relation.
+	Delay *int `json:"delay,omitempty"`
+}
+
+// Child is a subtask of an issue as returned with include=children.
+type Child struct {
+	// Id The ID of the child issue.
+	Id int `json:"id"`
+
+	// Subject The subject of the child issue.
+	Subject string `json:"subject,omitempty"`
+
+	// Tracker The tracker of the child issue.
+	Tracker *Ref `json:"tracker,omitempty"`
+
+	// Children The subtasks of the child issue.
+	Children []Child `json:"children,omitempty"`
+}
+
+// Issue is an issue.
+type Issue struct {
+	// Id The ID of the issue.
+	Id int `json:"id"`
+
+	// Project The project of the issue.
+	Project *Ref `json:"project,omitempty"`
+
+	// Tracker The tracker of the issue.
+	Tracker *Ref `json:"tracker,omitempty"`
+
+	// Status The status of the issue.
+	Status *Status `json:"status,omitempty"`
+
+	// Priority The priority of the issue.
+	Priority *Ref `json:"priority,omitempty"`
+
+	// Author The author of the issue.
+	Author *Ref `json:"author,omitempty"`
+
+	// AssignedTo The assignee of the issue.
+	AssignedTo *Ref `json:"assigned_to,omitempty"`
+
+	// Category The category of the issue.
+	Category *Ref `json:"category,omitempty"`
+
+	// FixedVersion The fixed version of the issue.
+	FixedVersion *Ref `json:"fixed_version,omitempty"`
+
+	// Parent The parent of the issue.
+	Parent *struct {
+		Id int `json:"id"`
+	} `json:"parent,omitempty"`
+
+	// Subject The subject of the issue.
+	Subject string `json:"subject"`
+
+	// Description The description of the issue.
+	Description string `json:"description,omitempty"`
+
+	// StartDate The start date of the issue.
+	StartDate *openapi_types.Date `json:"start_date,omitempty"`
+
+	// DueDate The due date of the issue.
+	DueDate *openapi_types.Date `json:"due_date,omitempty"`
+
+	// DoneRatio The done ratio of the issue.
+	DoneRatio int `json:"done_ratio"`
+
+	// IsPrivate The private of the issue.
+	IsPrivate bool `json:"is_private,omitempty"`
+
+	// EstimatedHours The estimated hours of the issue.
+	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
+
+	// TotalEstimatedHours The total estimated hours of the issue.
+	TotalEstimatedHours *float64 `json:"total_estimated_hours,omitempty"`
+
+	// SpentHours The spent hours of the issue.
+	SpentHours *float64 `json:"spent_hours,omitempty"`
+
+	// TotalSpentHours The total spent hours of the issue.
+	TotalSpentHours *float64 `json:"total_spent_hours,omitempty"`
+
+	// CustomFields The custom fields of the issue.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// CreatedOn The date and time when the issue was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the issue was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+
+	// ClosedOn The date and time when the issue was closed.
+	ClosedOn *time.Time `json:"closed_on,omitempty"`
+
+	// Attachments The attachments of the issue (include=attachments).
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Children The subtasks of the issue (include=children).
+	Children []Child `json:"children,omitempty"`
+
+	// Relations The relations of the issue (include=relations).
+	Relations []Relation `json:"relations,omitempty"`
+
+	// Journals The history of the issue (include=journals).
+	Journals []Journal `json:"journals,omitempty"`
+
+	// Watchers The watchers of the issue (include=watchers).
+	Watchers []Ref `json:"watchers,omitempty"`
+}
+
+// ParentId returns the ID of the parent issue, or 0 if the issue is a root.
+func (i Issue) ParentId() int {
+	if i.Parent == nil {
+		return 0
+	}
+	return i.Parent.Id
+}
+
+// IsClosed reports whether the status of the issue is a closed one.
+func (i Issue) IsClosed() bool {
+	return i.Status != nil && i.Status.IsClosed
+}
+
+// Project is a project.
+type Project struct {
+	// Id The ID of the project.
+	Id int `json:"id"`
+
+	// Name The name of the project.
+	Name string `json:"name"`
+
+	// Identifier The identifier of the project.
+	Identifier string `json:"identifier"`
+
+	// Description The description of the project.
+	Description string `json:"description,omitempty"`
+
+	// Homepage The homepage of the project.
+	Homepage string `json:"homepage,omitempty"`
+
+	// Parent The parent of the project.
+	Parent *Ref `json:"parent,omitempty"`
+
+	// Status The status of the project, see ProjectStatusActive and others.
+	Status int `json:"status"`
+
+	// IsPublic Whether the project is public.
+	IsPublic bool `json:"is_public"`
+
+	// InheritMembers Whether the project inherits members from its parent.
+	InheritMembers bool `json:"inherit_members,omitempty"`
+
+	// CustomFields The custom fields of the project.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// Trackers The trackers of the project (include=trackers).
+	Trackers []Ref `json:"trackers,omitempty"`
+
+	// IssueCategories The issue categories of the project (include=issue_categories).
+	IssueCategories []Ref `json:"issue_categories,omitempty"`
+
+	// EnabledModules The enabled modules of the project (include=enabled_modules).
+	EnabledModules []Ref `json:"enabled_modules,omitempty"`
+
+	// TimeEntryActivities The time entry activities of the project (include=time_entry_activities).
+	TimeEntryActivities []Ref `json:"time_entry_activities,omitempty"`
+
+	// IssueCustomFields The issue custom fields of the project (include=issue_custom_fields).
+	IssueCustomFields []Ref `json:"issue_custom_fields,omitempty"`
+
+	// CreatedOn The date and time when the project was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the project was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+}
+
+// Project statuses.
+const (
+	ProjectStatusActive   = 1
+	ProjectStatusClosed   = 5
+	ProjectStatusArchived = 9
+)
+
+// User is a user account.
+type User struct {
+	// Id The ID of the user.
+	Id int `json:"id"`
+
+	// Login The login of the user.
+	Login string `json:"login,omitempty"`
+
+	// Admin Whether the user is an administrator.
+	Admin bool `json:"admin,omitempty"`
+
+	// Firstname The first name of the user.
+	Firstname string `json:"firstname,omitempty"`
+
+	// Lastname The last name of the user.
+	Lastname string `json:"lastname,omitempty"`
+
+	// Mail The mail address of the user.
+	Mail string `json:"mail,omitempty"`
+
+	// Status The status of the user, see UserStatusActive and others.
+	Status int `json:"status,omitempty"`
+
+	// CustomFields The custom fields of the user.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// Groups The groups of the user (include=groups).
+	Groups []Ref `json:"groups,omitempty"`
+
+	// Memberships The memberships of the user (include=memberships).
+	Memberships []Membership `json:"memberships,omitempty"`
+
+	// CreatedOn The date and time when the user was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the user was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+
+	// LastLoginOn The date and time when the user last logged in.
+	LastLoginOn *time.Time `json:"last_login_on,omitempty"`
+}
+
+// User statuses.
+const (
+	UserStatusActive     = 1
+	UserStatusRegistered = 2
+	UserStatusLocked     = 3
+)
+
+// Name returns the display name of the user.
+func (u User) Name() string {
+	switch {
+	case u.Firstname != "" && u.Lastname != "":
+		return u.Firstname + " " + u.Lastname
+	case u.Firstname != "":
+		return u.Firstname
+	case u.Lastname != "":
+		return u.Lastname
+	}
+	return u.Login
+}
+
+// Group is a group of users.
+type Group struct {
+	// Id The ID of the group.
+	Id int `json:"id"`
+
+	// Name The name of the group.
+	Name string `json:"name"`
+
+	// CustomFields The custom fields of the group.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// Users The users of the group (include=users).
+	Users []Ref `json:"users,omitempty"`
+
+	// Memberships The memberships of the group (include=memberships).
+	Memberships []Membership `json:"memberships,omitempty"`
+}
+
+// MembershipRole is a role granted by a membership.
+type MembershipRole struct {
+	// Id The ID of the role.
+	Id int `json:"id"`
+
+	// Name The name of the role.
+	Name string `json:"name,omitempty"`
+
+	// Inherited Whether the role is inherited from a group.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// Membership is a project membership of a user or a group.
+type Membership struct {
+	// Id The ID of the membership.
+	Id int `json:"id"`
+
+	// Project The project of the membership.
+	Project *Ref `json:"project,omitempty"`
+
+	// User The user of the membership.
+	User *Ref `json:"user,omitempty"`
+
+	// Group The group of the membership.
+	Group *Ref `json:"group,omitempty"`
+
+	// Roles The roles of the membership.
+	Roles []MembershipRole `json:"roles,omitempty"`
+}
+
+// Role is a role with its permissions.
+type Role struct {
+	// Id The ID of the role.
+	Id int `json:"id"`
+
+	// Name The name of the role.
+	Name string `json:"name"`
+
+	// Assignable Whether issues can be assigned to the role.
+	Assignable bool `json:"assignable,omitempty"`
+
+	// IssuesVisibility The issues visibility of the role.
+	IssuesVisibility string `json:"issues_visibility,omitempty"`
+
+	// Permissions The permissions of the role.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Version is a project version.
+type Version struct {
+	// Id The ID of the version.
+	Id int `json:"id"`
+
+	// Project The project of the version.
+	Project *Ref `json:"project,omitempty"`
+
+	// Name The name of the version.
+	Name string `json:"name"`
+
+	// Description The description of the version.
+	Description string `json:"description,omitempty"`
+
+	// Status The status of the version, `open`, `locked` or `closed`.
+	Status string `json:"status,omitempty"`
+
+	// Sharing The sharing of the version.
+	Sharing string `json:"sharing,omitempty"`
+
+	// DueDate The due date of the version.
+	DueDate *openapi_types.Date `json:"due_date,omitempty"`
+
+	// WikiPageTitle The wiki page title of the version.
+	WikiPageTitle string `json:"wiki_page_title,omitempty"`
+
+	// EstimatedHours The estimated hours of the version.
+	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
+
+	// SpentHours The spent hours of the version.
+	SpentHours *float64 `json:"spent_hours,omitempty"`
+
+	// CustomFields The custom fields of the version.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// CreatedOn The date and time when the version was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the version was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+}
+
+// TimeEntry is a time entry.
+type TimeEntry struct {
+	// Id The ID of the time entry.
+	Id int `json:"id"`
+
+	// Project The project of the time entry.
+	Project *Ref `json:"project,omitempty"`
+
+	// Issue The issue of the time entry.
+	Issue *struct {
+		Id int `json:"id"`
+	} `json:"issue,omitempty"`
+
+	// User The user of the time entry.
+	User *Ref `json:"user,omitempty"`
+
+	// Activity The activity of the time entry.
+	Activity *Ref `json:"activity,omitempty"`
+
+	// Hours The hours of the time entry.
+	Hours float64 `json:"hours"`
+
+	// Comments The comments of the time entry.
+	Comments string `json:"comments,omitempty"`
+
+	// SpentOn The date when the time was spent.
+	SpentOn *openapi_types.Date `json:"spent_on,omitempty"`
+
+	// CustomFields The custom fields of the time entry.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// CreatedOn The date and time when the time entry was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the time entry was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+}
+
+// IssueId returns the ID of the issue of the time entry, or 0 if there is none.
+func (e TimeEntry) IssueId() int {
+	if e.Issue == nil {
+		return 0
+	}
+	return e.Issue.Id
+}
+
+// WikiParent is a reference to the parent of a wiki page.
+type WikiParent struct {
+	// Title The title of the parent page.
+	Title string `json:"title"`
+}
+
+// WikiPage is a wiki page. Text, Author, Comments and Attachments are only
+// present when the page is fetched individually.
+type WikiPage struct {
+	// Title The title of the wiki page.
+	Title string `json:"title"`
+
+	// Parent The parent of the wiki page.
+	Parent *WikiParent `json:"parent,omitempty"`
+
+	// Version The version of the wiki page.
+	Version int `json:"version"`
+
+	// Text The text of the wiki page.
+	Text string `json:"text,omitempty"`
+
+	// Author The author of the wiki page version.
+	Author *Ref `json:"author,omitempty"`
+
+	// Comments The comments of the wiki page version.
+	Comments string `json:"comments,omitempty"`
+
+	// Attachments The attachments of the wiki page (include=attachments).
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// CreatedOn The date and time when the wiki page was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// UpdatedOn The date and time when the wiki page was updated.
+	UpdatedOn *time.Time `json:"updated_on,omitempty"`
+}
+
+// ParentTitle returns the title of the parent page, or "" for a root page.
+func (p WikiPage) ParentTitle() string {
+	if p.Parent == nil {
+		return ""
+	}
+	return p.Parent.Title
+}
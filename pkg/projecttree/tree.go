@@ -0,0 +1,208 @@
+// Package projecttree builds the hierarchy of projects from the flat list
+// returned by the API, where each project only refers to its parent.
+//
+// A Tree answers the lookups by ID and identifier, the ancestors and the
+// descendants of a project, and renders as an indented text tree, JSON or
+// a Mermaid flowchart.
+package projecttree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// allStatuses selects the active, closed and archived projects.
+const allStatuses = "1|5|9"
+
+// Node is a project of a tree.
+type Node struct {
+	Project model.Project `json:"project"`
+
+	// Parent The node of the parent project, nil for a root.
+	Parent *Node `json:"-"`
+
+	// Children The nodes of the subprojects, in the order of the list.
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Tree is a hierarchy of projects.
+type Tree struct {
+	// Roots The projects without parent, or whose parent is not in the
+	// tree, in the order of the list.
+	Roots []*Node
+
+	byId         map[int]*Node
+	byIdentifier map[string]*Node
+}
+
+// New builds the tree of projects. A project whose parent is not in the
+// list, such as a subproject of a private project, is a root.
+func New(projects []model.Project) *Tree {
+	t := &Tree{byId: map[int]*Node{}, byIdentifier: map[string]*Node{}}
+	nodes := make([]*Node, len(projects))
+	for i, p := range projects {
+		nodes[i] = &Node{Project: p}
+		t.byId[p.Id] = nodes[i]
+		t.byIdentifier[p.Identifier] = nodes[i]
+	}
+
+	for _, n := range nodes {
+		parent := (*Node)(nil)
+		if n.Project.Parent != nil {
+			parent = t.byId[n.Project.Parent.Id]
+		}
+		if parent == nil {
+			t.Roots = append(t.Roots, n)
+			continue
+		}
+		n.Parent = parent
+		parent.Children = append(parent.Children, n)
+	}
+	return t
+}
+
+// Load builds the tree of the projects visible to the user, archived ones
+// included for an administrator.
+func Load(ctx context.Context, c redmine.ClientWithResponsesInterface, reqEditors ...redmine.RequestEditorFn) (*Tree, error) {
+	status := allStatuses
+	q := redmine.ProjectsIndexParams_Query{Status: &status}
+	projects, err := apiutil.Projects(ctx, c, &redmine.ProjectsIndexParams{Query: &q}, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return New(projects), nil
+}
+
+// Get returns the node of the project with id, nil if not in the tree.
+func (t *Tree) Get(id int) *Node {
+	return t.byId[id]
+}
+
+// Find returns the node of the project with the ID or identifier v, nil if
+// not in the tree.
+func (t *Tree) Find(v string) *Node {
+	if n, ok := t.byIdentifier[v]; ok {
+		return n
+	}
+	if id, err := strconv.Atoi(v); err == nil {
+		return t.byId[id]
+	}
+	return nil
+}
+
+// Ancestors returns the ancestors of n, root first.
+func (n *Node) Ancestors() []*Node {
+	ancestors := []*Node{}
+	for p := n.Parent; p != nil; p = p.Parent {
+		ancestors = append(ancestors, p)
+	}
+	slices.Reverse(ancestors)
+	return ancestors
+}
+
+// Descendants returns the descendants of n, each before its subprojects.
+func (n *Node) Descendants() []*Node {
+	descendants := []*Node{}
+	walk(n.Children, func(d *Node, _ int) { descendants = append(descendants, d) })
+	return descendants
+}
+
+// walk calls fn for the nodes and their descendants, each before its
+// subprojects, with the depth of the node from nodes.
+func walk(nodes []*Node, fn func(n *Node, depth int)) {
+	var visit func(nodes []*Node, depth int)
+	visit = func(nodes []*Node, depth int) {
+		for _, n := range nodes {
+			fn(n, depth)
+			visit(n.Children, depth+1)
+		}
+	}
+	visit(nodes, 0)
+}
+
+// Projects returns the projects of the tree, each before its subprojects.
+func (t *Tree) Projects() []model.Project {
+	projects := []model.Project{}
+	walk(t.Roots, func(n *Node, _ int) { projects = append(projects, n.Project) })
+	return projects
+}
+
+// Subtree returns the tree of n and its descendants.
+func (n *Node) Subtree() *Tree {
+	projects := []model.Project{n.Project}
+	for _, d := range n.Descendants() {
+		projects = append(projects, d.Project)
+	}
+	return New(projects)
+}
+
+// Filter returns the tree of the projects with one of the statuses, such
+// as model.ProjectStatusActive. A project kept under a project left out is
+// attached to its nearest ancestor kept.
+func (t *Tree) Filter(statuses ...int) *Tree {
+	projects := []model.Project{}
+	walk(t.Roots, func(n *Node, _ int) {
+		if !slices.Contains(statuses, n.Project.Status) {
+			return
+		}
+		p := n.Project
+		p.Parent = nil
+		for a := n.Parent; a != nil; a = a.Parent {
+			if slices.Contains(statuses, a.Project.Status) {
+				p.Parent = &model.Ref{Id: a.Project.Id, Name: a.Project.Name}
+				break
+			}
+		}
+		projects = append(projects, p)
+	})
+	return New(projects)
+}
+
+// MarshalJSON encodes the tree as the list of its roots with their
+// children.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	if t.Roots == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t.Roots)
+}
+
+// Text renders the tree with the subprojects indented by two spaces under
+// their parent, one project per line as "Name (identifier)".
+func (t *Tree) Text() string {
+	b := strings.Builder{}
+	walk(t.Roots, func(n *Node, depth int) {
+		fmt.Fprintf(&b, "%s%s (%s)\n", strings.Repeat("  ", depth), n.Project.Name, n.Project.Identifier)
+	})
+	return b.String()
+}
+
+// Mermaid renders the tree as a Mermaid flowchart from the parents to the
+// subprojects.
+func (t *Tree) Mermaid() string {
+	b := strings.Builder{}
+	b.WriteString("flowchart TD\n")
+	walk(t.Roots, func(n *Node, _ int) {
+		fmt.Fprintf(&b, "  p%d[\"%s\"]\n", n.Project.Id, mermaidEscape(n.Project.Name))
+	})
+	walk(t.Roots, func(n *Node, _ int) {
+		if n.Parent != nil {
+			fmt.Fprintf(&b, "  p%d --> p%d\n", n.Parent.Project.Id, n.Project.Id)
+		}
+	})
+	return b.String()
+}
+
+// mermaidEscape replaces the characters ending a Mermaid label with their
+// entity codes.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
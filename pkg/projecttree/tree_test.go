@@ -0,0 +1,90 @@
+package projecttree
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var projects = []model.Project{
+	{Id: 1, Name: "Company", Identifier: "company", Status: model.ProjectStatusActive},
+	{Id: 2, Name: "Web", Identifier: "web", Parent: &model.Ref{Id: 1}, Status: model.ProjectStatusClosed},
+	{Id: 3, Name: "Web \"v2\"", Identifier: "web-v2", Parent: &model.Ref{Id: 2}, Status: model.ProjectStatusActive},
+	{Id: 4, Name: "Mobile", Identifier: "mobile", Parent: &model.Ref{Id: 1}, Status: model.ProjectStatusArchived},
+	{Id: 5, Name: "Internal", Identifier: "internal", Status: model.ProjectStatusActive},
+	{Id: 6, Name: "Orphan", Identifier: "orphan", Parent: &model.Ref{Id: 99}, Status: model.ProjectStatusActive},
+}
+
+func identifiers(nodes []*Node) []string {
+	ids := []string{}
+	for _, n := range nodes {
+		ids = append(ids, n.Project.Identifier)
+	}
+	return ids
+}
+
+func TestTree(t *testing.T) {
+	tree := New(projects)
+	if got := identifiers(tree.Roots); len(got) != 3 || got[0] != "company" || got[2] != "orphan" {
+		t.Errorf("roots = %v", got)
+	}
+	if tree.Get(3) != tree.Find("web-v2") || tree.Find("3") != tree.Get(3) || tree.Find("unknown") != nil || tree.Get(99) != nil {
+		t.Errorf("lookups")
+	}
+
+	if got := identifiers(tree.Get(3).Ancestors()); len(got) != 2 || got[0] != "company" || got[1] != "web" {
+		t.Errorf("ancestors = %v", got)
+	}
+	if got := identifiers(tree.Get(1).Descendants()); len(got) != 3 || got[0] != "web" || got[1] != "web-v2" || got[2] != "mobile" {
+		t.Errorf("descendants = %v", got)
+	}
+	if got := tree.Get(2).Subtree().Projects(); len(got) != 2 || got[0].Id != 2 || got[1].Id != 3 {
+		t.Errorf("subtree = %+v", got)
+	}
+
+	active := tree.Get(1).Subtree().Filter(model.ProjectStatusActive)
+	if got := active.Text(); got != "Company (company)\n  Web \"v2\" (web-v2)\n" {
+		t.Errorf("active = %q", got)
+	}
+	if got := active.Get(3).Ancestors(); len(got) != 1 || got[0].Project.Id != 1 {
+		t.Errorf("reattached = %v", identifiers(got))
+	}
+
+	want := "flowchart TD\n  p1[\"Company\"]\n  p2[\"Web\"]\n  p3[\"Web #quot;v2#quot;\"]\n  p1 --> p2\n  p2 --> p3\n"
+	if got := tree.Filter(model.ProjectStatusActive, model.ProjectStatusClosed).Get(1).Subtree().Mermaid(); got != want {
+		t.Errorf("mermaid = %q", got)
+	}
+
+	buf, err := json.Marshal(tree.Get(2).Subtree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []struct {
+		Project  model.Project `json:"project"`
+		Children []struct {
+			Project model.Project `json:"project"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0].Project.Id != 2 || len(decoded[0].Children) != 1 || decoded[0].Children[0].Project.Id != 3 {
+		t.Errorf("json = %s", buf)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f := redminetest.DefaultFixtures()
+	f.Projects = projects[:5]
+	c := redminetest.Client(t, f)
+	tree, err := Load(context.Background(), c, redminetest.Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := identifiers(tree.Roots); len(got) != 2 || tree.Find("mobile") == nil || tree.Find("mobile").Parent != tree.Get(1) {
+		t.Errorf("roots = %v, mobile = %+v", got, tree.Find("mobile"))
+	}
+}
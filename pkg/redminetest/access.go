@@ -0,0 +1,134 @@
+package redminetest
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// moduleOf returns the project module a permission belongs to, or "" if the
+// permission does not depend on a module.
+func moduleOf(permission string) string {
+	switch {
+	case strings.Contains(permission, "issue"), permission == "manage_subtasks", permission == "manage_categories":
+		return "issue_tracking"
+	case strings.Contains(permission, "time"):
+		return "time_tracking"
+	case strings.Contains(permission, "wiki"):
+		return "wiki"
+	case strings.Contains(permission, "news"):
+		return "news"
+	case strings.Contains(permission, "files"):
+		return "files"
+	}
+	return ""
+}
+
+// viewPermissions are granted to non-members on public projects.
+var viewPermissions = []string{"view_issues", "view_time_entries", "view_wiki_pages", "view_wiki_edits", "view_issue_watchers", "view_news", "view_files"}
+
+func moduleEnabled(p *model.Project, module string) bool {
+	if module == "" || p.EnabledModules == nil {
+		return true
+	}
+	return slices.ContainsFunc(p.EnabledModules, func(m model.Ref) bool { return m.Name == module })
+}
+
+// groupIds returns the IDs of the groups of a user.
+func (s *Server) groupIds(userId int) []int {
+	ids := []int{}
+	for _, id := range sortedIds(s.groups) {
+		if slices.ContainsFunc(s.groups[id].Users, func(u model.Ref) bool { return u.Id == userId }) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// roles returns the roles of a user in a project, directly or through groups.
+func (s *Server) memberRoles(userId, projectId int) []*model.Role {
+	principals := append(s.groupIds(userId), userId)
+
+	roles := []*model.Role{}
+	for _, m := range s.memberships {
+		if m.Project == nil || m.Project.Id != projectId {
+			continue
+		}
+		if (m.User == nil || !slices.Contains(principals, m.User.Id)) && (m.Group == nil || !slices.Contains(principals, m.Group.Id)) {
+			continue
+		}
+		for _, mr := range m.Roles {
+			if role, ok := s.roles[mr.Id]; ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// projectVisible reports whether the user of r can see project p.
+func (s *Server) projectVisible(r *request, p *model.Project) bool {
+	if r.admin() {
+		return true
+	}
+	if p.Status == model.ProjectStatusArchived {
+		return false
+	}
+	return p.IsPublic || len(s.memberRoles(r.userId(), p.Id)) > 0
+}
+
+// allowed reports whether the user of r has permission in project p.
+func (s *Server) allowed(r *request, p *model.Project, permission string) bool {
+	if p.Status == model.ProjectStatusArchived || !moduleEnabled(p, moduleOf(permission)) {
+		return false
+	}
+	if p.Status == model.ProjectStatusClosed && !strings.HasPrefix(permission, "view_") {
+		return false
+	}
+	if r.admin() {
+		return true
+	}
+
+	for _, role := range s.memberRoles(r.userId(), p.Id) {
+		if slices.Contains(role.Permissions, permission) {
+			return true
+		}
+	}
+
+	return p.IsPublic && slices.Contains(viewPermissions, permission)
+}
+
+// seesAllIssues reports whether the roles of the user of r in project p
+// show the private issues of other users.
+func (s *Server) seesAllIssues(r *request, p *model.Project) bool {
+	if r.admin() {
+		return true
+	}
+	for _, role := range s.memberRoles(r.userId(), p.Id) {
+		if role.IssuesVisibility == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// require returns an error unless the user of r has permission in project p.
+// Invisible projects are not found.
+func (s *Server) require(r *request, p *model.Project, permission string) error {
+	if !s.projectVisible(r, p) {
+		return errNotFound
+	}
+	if !s.allowed(r, p, permission) {
+		return errForbidden
+	}
+	return nil
+}
+
+// requireAdmin returns an error unless the user of r is an administrator.
+func requireAdmin(r *request) error {
+	if !r.admin() {
+		return errForbidden
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package redminetest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodPost, "/uploads", attachmentsUpload)
+	handle(http.MethodGet, "/attachments/:id", attachmentsShow)
+	handle(http.MethodPut, "/attachments/:id", attachmentsUpdate)
+	handle(http.MethodPatch, "/attachments/:id", attachmentsUpdate)
+	handle(http.MethodDelete, "/attachments/:id", attachmentsDestroy)
+	handle(http.MethodGet, "/attachments/download/:id", attachmentsDownload)
+	handle(http.MethodGet, "/attachments/download/:id/:filename", attachmentsDownload)
+}
+
+// attachment is a file attached to an issue, a wiki page, a news item, a
+// project or a version, or uploaded and waiting to be attached when
+// container is empty.
+type attachment struct {
+	model.Attachment
+
+	// container is `issue`, `wiki_page`, `news`, `project` or `version`.
+	container string
+
+	// containerId is the ID of the issue, news, project or version.
+	containerId int
+
+	// wikiKey is the key of the wiki page.
+	wikiKey string
+
+	// digest is the SHA-256 digest of content.
+	digest string
+
+	// downloads counts the downloads of the files of projects and versions.
+	downloads int
+
+	content []byte
+}
+
+// uploadInput is a reference to an upload in the uploads of a record.
+type uploadInput struct {
+	Token       string `json:"token"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Description string `json:"description"`
+}
+
+// download is a response body sent as a file.
+type download struct {
+	contentType string
+	content     []byte
+}
+
+func attachmentsUpload(s *Server, r *request) (int, any, error) {
+	if mediaType(r) != "application/octet-stream" {
+		return 0, nil, &apiError{status: http.StatusNotAcceptable}
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	q := r.URL.Query()
+	a := &attachment{
+		Attachment: model.Attachment{
+			Id:          s.nextId("attachment"),
+			Filename:    q.Get("filename"),
+			Filesize:    len(content),
+			ContentType: strings.TrimSpace(q.Get("content_type")),
+			Author:      &model.Ref{Id: r.userId()},
+			CreatedOn:   timePtr(s.now()),
+		},
+		digest:  fmt.Sprintf("%x", sha256.Sum256(content)),
+		content: content,
+	}
+	if a.Filename == "" {
+		a.Filename = "upload"
+	}
+	if a.ContentType == "" {
+		a.ContentType = mime.TypeByExtension(path.Ext(a.Filename))
+	}
+
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	token := fmt.Sprintf("%d.%s", a.Id, hex.EncodeToString(buf))
+	s.uploads[token] = a
+
+	return http.StatusCreated, map[string]any{"upload": map[string]any{"id": a.Id, "token": token}}, nil
+}
+
+// attach attaches the upload of u to a container and returns the
+// attachment. The upload must exist.
+func (s *Server) attach(u uploadInput, container string, id int, wikiKey string) *attachment {
+	a := s.uploads[u.Token]
+	delete(s.uploads, u.Token)
+
+	if u.Filename != "" {
+		a.Filename = u.Filename
+	}
+	if u.ContentType != "" {
+		a.ContentType = u.ContentType
+	}
+	if u.Description != "" {
+		a.Description = u.Description
+	}
+	a.container, a.containerId, a.wikiKey = container, id, wikiKey
+	s.attachments[a.Id] = a
+	return a
+}
+
+// attachmentsOf returns the attachments of a container.
+func (s *Server) attachmentsOf(r *request, container string, id int, wikiKey string) []model.Attachment {
+	attachments := []model.Attachment{}
+	for _, aid := range sortedIds(s.attachments) {
+		a := s.attachments[aid]
+		if a.container == container && a.containerId == id && a.wikiKey == wikiKey {
+			attachments = append(attachments, s.renderAttachment(r, a))
+		}
+	}
+	return attachments
+}
+
+// deleteAttachmentsOf deletes the attachments of a news, project or version.
+func (s *Server) deleteAttachmentsOf(container string, id int) {
+	for aid, a := range s.attachments {
+		if a.container == container && a.containerId == id {
+			delete(s.attachments, aid)
+		}
+	}
+}
+
+func (s *Server) renderAttachment(r *request, a *attachment) model.Attachment {
+	out := a.Attachment
+	out.Author = s.principalRef(refId(a.Author))
+	out.ContentUrl = fmt.Sprintf("http://%s/attachments/download/%d/%s", r.Host, a.Id, url.PathEscape(a.Filename))
+	return out
+}
+
+// attachmentProject returns the project of the container of attachment a
+// with the permissions to view and to edit it.
+func (s *Server) attachmentProject(r *request, a *attachment) (p *model.Project, view, edit string, ok bool) {
+	switch a.container {
+	case "issue":
+		i, found := s.issues[a.containerId]
+		if !found || !s.issueVisible(r, i) {
+			return nil, "", "", false
+		}
+		return s.projects[refId(i.Project)], "view_issues", "edit_issues", true
+	case "wiki_page":
+		w, found := s.wikiPages[a.wikiKey]
+		if !found {
+			return nil, "", "", false
+		}
+		return s.projects[w.projectId], "view_wiki_pages", "delete_wiki_pages_attachments", true
+	case "news":
+		n, found := s.news[a.containerId]
+		if !found {
+			return nil, "", "", false
+		}
+		return s.projects[refId(n.Project)], "view_news", "manage_news", true
+	case "project", "version":
+		p, found := s.fileProject(a)
+		return p, "view_files", "manage_files", found
+	}
+	return nil, "", "", false
+}
+
+// attachment returns the attachment of the path variable id, visible to the
+// user of r, and the project and the permission to edit it.
+func (s *Server) attachment(r *request) (*attachment, *model.Project, string, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	a, ok := s.attachments[id]
+	if !ok {
+		return nil, nil, "", errNotFound
+	}
+	p, view, edit, ok := s.attachmentProject(r, a)
+	if !ok {
+		return nil, nil, "", errNotFound
+	}
+	if err := s.require(r, p, view); err != nil {
+		return nil, nil, "", err
+	}
+	return a, p, edit, nil
+}
+
+func attachmentsShow(s *Server, r *request) (int, any, error) {
+	a, _, _, err := s.attachment(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"attachment": s.renderAttachment(r, a)}, nil
+}
+
+func attachmentsDownload(s *Server, r *request) (int, any, error) {
+	a, _, _, err := s.attachment(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if a.container == "project" || a.container == "version" {
+		a.downloads++
+	}
+	return http.StatusOK, download{contentType: a.ContentType, content: a.content}, nil
+}
+
+func attachmentsUpdate(s *Server, r *request) (int, any, error) {
+	a, p, edit, err := s.attachment(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, edit); err != nil {
+		return 0, nil, err
+	}
+
+	var in struct {
+		Filename    *string `json:"filename"`
+		Description *string `json:"description"`
+		ContentType *string `json:"content_type"`
+	}
+	if err := r.decode("attachment", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *a
+	if in.Filename != nil {
+		updated.Filename = strings.TrimSpace(*in.Filename)
+	}
+	if in.Description != nil {
+		updated.Description = *in.Description
+	}
+	if in.ContentType != nil {
+		updated.ContentType = *in.ContentType
+	}
+
+	v := validation{}
+	switch {
+	case updated.Filename == "":
+		v.add("Filename cannot be blank")
+	case len(updated.Filename) > 255:
+		v.add("Filename is too long (maximum is 255 characters)")
+	}
+	if len(updated.Description) > 255 {
+		v.add("Description is too long (maximum is 255 characters)")
+	}
+	if err := v.err(); err != nil {
+		return 0, nil, err
+	}
+	*a = updated
+	return http.StatusNoContent, nil, nil
+}
+
+func attachmentsDestroy(s *Server, r *request) (int, any, error) {
+	a, p, edit, err := s.attachment(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, edit); err != nil {
+		return 0, nil, err
+	}
+
+	if i, ok := s.issues[a.containerId]; ok && a.container == "issue" {
+		now := s.now()
+		i.Journals = append(i.Journals, model.Journal{
+			Id:        s.nextId("journal"),
+			User:      &model.Ref{Id: r.userId()},
+			CreatedOn: now,
+			Details:   []model.JournalDetail{detail("attachment", idString(a.Id), a.Filename, "")},
+		})
+		i.UpdatedOn = timePtr(now)
+	}
+	delete(s.attachments, a.Id)
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,180 @@
+package redminetest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects/:project/issue_categories", categoriesIndex)
+	handle(http.MethodPost, "/projects/:project/issue_categories", categoriesCreate)
+	handle(http.MethodGet, "/issue_categories/:id", categoriesShow)
+	handle(http.MethodPut, "/issue_categories/:id", categoriesUpdate)
+	handle(http.MethodPatch, "/issue_categories/:id", categoriesUpdate)
+	handle(http.MethodDelete, "/issue_categories/:id", categoriesDestroy)
+}
+
+func (s *Server) renderCategory(c *Category) Category {
+	out := Category{Id: c.Id, Project: s.projectRef(refId(c.Project)), Name: c.Name}
+	if c.AssignedTo != nil {
+		out.AssignedTo = s.principalRef(c.AssignedTo.Id)
+	}
+	return out
+}
+
+func categoriesIndex(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_issues"); err != nil {
+		return 0, nil, err
+	}
+
+	categories := []Category{}
+	for _, id := range sortedIds(s.categories) {
+		if c := s.categories[id]; refId(c.Project) == p.Id {
+			categories = append(categories, s.renderCategory(c))
+		}
+	}
+	return http.StatusOK, map[string]any{"issue_categories": categories, "total_count": len(categories)}, nil
+}
+
+// category returns the category of the path variable id, visible to the
+// user of r.
+func (s *Server) category(r *request) (*Category, *model.Project, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	c, ok := s.categories[id]
+	if !ok {
+		return nil, nil, errNotFound
+	}
+	p, ok := s.projects[refId(c.Project)]
+	if !ok || !s.projectVisible(r, p) {
+		return nil, nil, errNotFound
+	}
+	return c, p, nil
+}
+
+func categoriesShow(s *Server, r *request) (int, any, error) {
+	c, p, err := s.category(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_issues"); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"issue_category": s.renderCategory(c)}, nil
+}
+
+type categoryInput struct {
+	Name         *string `json:"name"`
+	AssignedToId optInt  `json:"assigned_to_id"`
+}
+
+func (in *categoryInput) apply(s *Server, p *model.Project, c *Category) error {
+	v := validation{}
+
+	if in.Name != nil {
+		c.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.AssignedToId.set {
+		c.AssignedTo = nil
+		if in.AssignedToId.v != 0 {
+			if !s.assignable(p, in.AssignedToId.v) {
+				v.add("Assignee is invalid")
+			}
+			c.AssignedTo = &model.Ref{Id: in.AssignedToId.v}
+		}
+	}
+
+	switch {
+	case c.Name == "":
+		v.add("Name cannot be blank")
+	case len(c.Name) > 60:
+		v.add("Name is too long (maximum is 60 characters)")
+	}
+	for _, other := range s.categories {
+		if other.Id != c.Id && refId(other.Project) == p.Id && strings.EqualFold(other.Name, c.Name) {
+			v.add("Name has already been taken")
+		}
+	}
+	return v.err()
+}
+
+func categoriesCreate(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_categories"); err != nil {
+		return 0, nil, err
+	}
+
+	in := categoryInput{}
+	if err := r.decode("issue_category", &in); err != nil {
+		return 0, nil, err
+	}
+
+	c := &Category{Project: &model.Ref{Id: p.Id}}
+	if err := in.apply(s, p, c); err != nil {
+		return 0, nil, err
+	}
+	c.Id = s.nextId("category")
+	s.categories[c.Id] = c
+	return http.StatusCreated, map[string]any{"issue_category": s.renderCategory(c)}, nil
+}
+
+func categoriesUpdate(s *Server, r *request) (int, any, error) {
+	c, p, err := s.category(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_categories"); err != nil {
+		return 0, nil, err
+	}
+
+	in := categoryInput{}
+	if err := r.decode("issue_category", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *c
+	if err := in.apply(s, p, &updated); err != nil {
+		return 0, nil, err
+	}
+	*c = updated
+	return http.StatusNoContent, nil, nil
+}
+
+// categoriesDestroy deletes a category. The issues of the category move to
+// the category reassign_to_id of the same project, or lose their category.
+func categoriesDestroy(s *Server, r *request) (int, any, error) {
+	c, p, err := s.category(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_categories"); err != nil {
+		return 0, nil, err
+	}
+
+	var reassign *model.Ref
+	if id, err := strconv.Atoi(r.URL.Query().Get("reassign_to_id")); err == nil {
+		if other, ok := s.categories[id]; ok && other.Id != c.Id && refId(other.Project) == p.Id {
+			reassign = &model.Ref{Id: id}
+		}
+	}
+
+	for _, i := range s.issues {
+		if refId(i.Category) == c.Id {
+			i.Category = reassign
+		}
+	}
+	delete(s.categories, c.Id)
+	return http.StatusNoContent, nil, nil
+}
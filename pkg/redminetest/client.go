@@ -0,0 +1,30 @@
+package redminetest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Admin authenticates a request as the administrator of DefaultFixtures.
+func Admin(ctx context.Context, req *http.Request) error {
+	req.Header.Set("X-Redmine-API-Key", "admin")
+	return nil
+}
+
+// Client starts a server seeded with f, closed at the end of the test, and
+// returns a client of it.
+func Client(t testing.TB, f *Fixtures) *redmine.ClientWithResponses {
+	t.Helper()
+
+	srv := NewServer(f)
+	t.Cleanup(srv.Close)
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,117 @@
+package redminetest
+
+import (
+	"net/http"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/issue_statuses", statusesIndex)
+	handle(http.MethodGet, "/trackers", trackersIndex)
+	handle(http.MethodGet, "/enumerations/issue_priorities", prioritiesIndex)
+	handle(http.MethodGet, "/enumerations/time_entry_activities", activitiesIndex)
+	handle(http.MethodGet, "/enumerations/document_categories", documentCategoriesIndex)
+	handle(http.MethodGet, "/roles", rolesIndex)
+	handle(http.MethodGet, "/roles/:id", rolesShow)
+	handle(http.MethodGet, "/custom_fields", customFieldsIndex)
+}
+
+func statusesIndex(s *Server, r *request) (int, any, error) {
+	statuses := []model.Status{}
+	for _, id := range sortedIds(s.statuses) {
+		statuses = append(statuses, *s.statuses[id])
+	}
+	return http.StatusOK, map[string]any{"issue_statuses": statuses}, nil
+}
+
+func trackersIndex(s *Server, r *request) (int, any, error) {
+	trackers := []model.Tracker{}
+	for _, id := range sortedIds(s.trackers) {
+		t := *s.trackers[id]
+		if t.DefaultStatus != nil {
+			t.DefaultStatus = s.statusRef(t.DefaultStatus.Id)
+		}
+		trackers = append(trackers, t)
+	}
+	return http.StatusOK, map[string]any{"trackers": trackers}, nil
+}
+
+func prioritiesIndex(s *Server, r *request) (int, any, error) {
+	return http.StatusOK, map[string]any{"issue_priorities": s.priorities}, nil
+}
+
+func activitiesIndex(s *Server, r *request) (int, any, error) {
+	return http.StatusOK, map[string]any{"time_entry_activities": s.activities}, nil
+}
+
+func documentCategoriesIndex(s *Server, r *request) (int, any, error) {
+	return http.StatusOK, map[string]any{"document_categories": []Enumeration{}}, nil
+}
+
+func rolesIndex(s *Server, r *request) (int, any, error) {
+	roles := []model.Ref{}
+	for _, id := range sortedIds(s.roles) {
+		roles = append(roles, model.Ref{Id: id, Name: s.roles[id].Name})
+	}
+	return http.StatusOK, map[string]any{"roles": roles}, nil
+}
+
+func rolesShow(s *Server, r *request) (int, any, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	role, ok := s.roles[id]
+	if !ok {
+		return 0, nil, errNotFound
+	}
+	return http.StatusOK, map[string]any{"role": role}, nil
+}
+
+func customFieldsIndex(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"custom_fields": s.customFields}, nil
+}
+
+func (s *Server) defaultPriority() int {
+	for _, p := range s.priorities {
+		if p.IsDefault {
+			return p.Id
+		}
+	}
+	if len(s.priorities) > 0 {
+		return s.priorities[0].Id
+	}
+	return 0
+}
+
+func (s *Server) defaultActivity() int {
+	for _, a := range s.activities {
+		if a.IsDefault {
+			return a.Id
+		}
+	}
+	return 0
+}
+
+func findEnumeration(list []Enumeration, id int) (Enumeration, bool) {
+	for _, e := range list {
+		if e.Id == id {
+			return e, true
+		}
+	}
+	return Enumeration{}, false
+}
+
+func (s *Server) customField(id int) (CustomField, bool) {
+	for _, cf := range s.customFields {
+		if cf.Id == id {
+			return cf, true
+		}
+	}
+	return CustomField{}, false
+}
@@ -0,0 +1,119 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects/:project/files", filesIndex)
+	handle(http.MethodPost, "/projects/:project/files", filesCreate)
+}
+
+// fileProject returns the project of a file attached to a project or to a
+// version.
+func (s *Server) fileProject(a *attachment) (*model.Project, bool) {
+	id := a.containerId
+	if a.container == "version" {
+		v, ok := s.versions[a.containerId]
+		if !ok {
+			return nil, false
+		}
+		id = refId(v.Project)
+	}
+	p, ok := s.projects[id]
+	return p, ok
+}
+
+func (s *Server) renderFile(r *request, a *attachment) File {
+	out := File{Attachment: s.renderAttachment(r, a), Digest: a.digest, Downloads: a.downloads}
+	if a.container == "version" {
+		out.Version = s.versionRef(a.containerId)
+	}
+	return out
+}
+
+// filesIndex lists the files of a project and of its versions, sorted by
+// the sort parameter: filename, created_on, size or downloads.
+func filesIndex(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_files"); err != nil {
+		return 0, nil, err
+	}
+
+	files := []*attachment{}
+	for _, id := range sortedIds(s.attachments) {
+		a := s.attachments[id]
+		if a.container != "project" && a.container != "version" {
+			continue
+		}
+		if fp, ok := s.fileProject(a); ok && fp.Id == p.Id {
+			files = append(files, a)
+		}
+	}
+
+	name, dir, _ := strings.Cut(r.URL.Query().Get("sort"), ":")
+	slices.SortStableFunc(files, func(a, b *attachment) int {
+		c := 0
+		switch name {
+		case "created_on":
+			c = a.CreatedOn.Compare(*b.CreatedOn)
+		case "size":
+			c = a.Filesize - b.Filesize
+		case "downloads":
+			c = a.downloads - b.downloads
+		default:
+			c = strings.Compare(strings.ToLower(a.Filename), strings.ToLower(b.Filename))
+		}
+		if dir == "desc" {
+			c = -c
+		}
+		return c
+	})
+
+	rendered := []File{}
+	for _, a := range files {
+		rendered = append(rendered, s.renderFile(r, a))
+	}
+	return http.StatusOK, map[string]any{"files": rendered}, nil
+}
+
+// filesCreate attaches an upload to a project, or to the version_id of the
+// project. It answers 400 without upload.
+func filesCreate(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_files"); err != nil {
+		return 0, nil, err
+	}
+
+	var in struct {
+		uploadInput
+		VersionId optInt `json:"version_id"`
+	}
+	if err := r.decode("file", &in); err != nil {
+		return 0, nil, err
+	}
+
+	container, id := "project", p.Id
+	if in.VersionId.v != 0 {
+		v, ok := s.versions[in.VersionId.v]
+		if !ok || refId(v.Project) != p.Id {
+			return 0, nil, invalid("Version is invalid")
+		}
+		container, id = "version", v.Id
+	}
+	if _, ok := s.uploads[in.Token]; !ok {
+		return 0, nil, &apiError{status: http.StatusBadRequest}
+	}
+	s.attach(in.uploadInput, container, id, "")
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,268 @@
+package redminetest
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kind is the type of a filtered field, which decides how values compare.
+type kind int
+
+const (
+	kindInt kind = iota
+	kindString
+	kindDate
+	kindTime
+	kindFloat
+
+	// kindIdList is an ID filtered with comma separated lists, such as
+	// `1,2,3`, besides the values separated by a pipe.
+	kindIdList
+
+	// kindTree is an ID followed by the IDs of its ancestors, such as an
+	// issue and its parents. `~5` matches 5 and its descendants, the other
+	// operators only the ID.
+	kindTree
+)
+
+// field is a filterable and sortable field of a record. get returns the
+// values of the field, empty if the field is blank.
+type field[T any] struct {
+	kind kind
+	get  func(T) []string
+}
+
+func intValue(v int) []string {
+	if v == 0 {
+		return nil
+	}
+	return []string{strconv.Itoa(v)}
+}
+
+func stringValue(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return []string{v}
+}
+
+func timeValue(v *time.Time) []string {
+	if v == nil {
+		return nil
+	}
+	return []string{v.UTC().Format(time.RFC3339)}
+}
+
+func floatValue(v *float64) []string {
+	if v == nil {
+		return nil
+	}
+	return []string{strconv.FormatFloat(*v, 'f', -1, 64)}
+}
+
+func boolValue(v bool) []string {
+	if v {
+		return []string{"1"}
+	}
+	return []string{"0"}
+}
+
+// compare compares two values of a kind.
+func compare(k kind, a, b string) int {
+	switch k {
+	case kindInt, kindFloat, kindIdList, kindTree:
+		x, errX := strconv.ParseFloat(a, 64)
+		y, errY := strconv.ParseFloat(b, 64)
+		switch {
+		case errX != nil || errY != nil:
+			return strings.Compare(a, b)
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case kindString:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// bound normalizes an operand of a date or time comparison. A date compared
+// with times covers the whole day.
+func bound(k kind, v string, end bool) string {
+	if k != kindTime {
+		return v
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		if end {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t.Format(time.RFC3339)
+	}
+	return v
+}
+
+// splitValues splits the operand of an expression into values, separated
+// by a pipe, or also by a comma if commas is true.
+func splitValues(v string, commas bool) []string {
+	return strings.FieldsFunc(v, func(r rune) bool { return r == '|' || (commas && r == ',') })
+}
+
+// matchExpr evaluates a Redmine filter expression, such as `5`, `1|2`,
+// `!3`, `*`, `!*`, `>=2025-01-01`, `><1|5`, `~text` or `me`, against the
+// values of a field. me is the ID of the current user.
+func matchExpr(k kind, expr string, values []string, me int) bool {
+	if k == kindTree {
+		if strings.HasPrefix(expr, "~") {
+			return slices.Contains(values, expr[1:])
+		}
+		k, values = kindInt, values[:min(len(values), 1)]
+	}
+	commas := k == kindIdList
+	if commas {
+		k = kindInt
+	}
+
+	anyValue := func(pred func(string) bool) bool {
+		for _, v := range values {
+			if pred(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	operands := func(s string) []string {
+		ops := splitValues(s, commas)
+		for i, op := range ops {
+			if op == "me" {
+				ops[i] = strconv.Itoa(me)
+			}
+		}
+		return ops
+	}
+
+	in := func(ops []string) bool {
+		return anyValue(func(v string) bool {
+			for _, op := range ops {
+				if compare(k, v, bound(k, op, false)) == 0 {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	contains := func(s string) bool {
+		return anyValue(func(v string) bool { return strings.Contains(strings.ToLower(v), strings.ToLower(s)) })
+	}
+
+	switch {
+	case expr == "*":
+		return len(values) > 0
+	case expr == "!*":
+		return len(values) == 0
+	case strings.HasPrefix(expr, "><"):
+		ops := splitValues(expr[2:], false)
+		if len(ops) != 2 {
+			return false
+		}
+		lo, hi := bound(k, ops[0], false), bound(k, ops[1], true)
+		return anyValue(func(v string) bool { return compare(k, v, lo) >= 0 && compare(k, v, hi) <= 0 })
+	case strings.HasPrefix(expr, ">="):
+		op := bound(k, expr[2:], false)
+		return anyValue(func(v string) bool { return compare(k, v, op) >= 0 })
+	case strings.HasPrefix(expr, "<="):
+		op := bound(k, expr[2:], true)
+		return anyValue(func(v string) bool { return compare(k, v, op) <= 0 })
+	case strings.HasPrefix(expr, "!~"):
+		return !contains(expr[2:])
+	case strings.HasPrefix(expr, "~"):
+		return contains(expr[1:])
+	case strings.HasPrefix(expr, "^"):
+		return anyValue(func(v string) bool { return strings.HasPrefix(strings.ToLower(v), strings.ToLower(expr[1:])) })
+	case strings.HasPrefix(expr, "$"):
+		return anyValue(func(v string) bool { return strings.HasSuffix(strings.ToLower(v), strings.ToLower(expr[1:])) })
+	case strings.HasPrefix(expr, "!"):
+		return !in(operands(expr[1:]))
+	}
+	return in(operands(expr))
+}
+
+// filter returns the items matching the query parameters named after the
+// fields. Parameters without field are ignored.
+func filter[T any](r *request, items []T, fields map[string]field[T]) []T {
+	q := r.URL.Query()
+
+	matched := []T{}
+	for _, item := range items {
+		ok := true
+		for name, f := range fields {
+			expr := q.Get(name)
+			if expr == "" {
+				continue
+			}
+			if !matchExpr(f.kind, expr, f.get(item), r.userId()) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+// sortBy orders items by the sort parameter, such as `updated_on:desc,id`.
+// Unknown fields are ignored and def applies when the parameter is absent.
+func sortBy[T any](r *request, items []T, fields map[string]field[T], def string) {
+	spec := r.URL.Query().Get("sort")
+	if spec == "" {
+		spec = def
+	}
+
+	type key struct {
+		field field[T]
+		desc  bool
+	}
+
+	keys := []key{}
+	for _, s := range strings.Split(spec, ",") {
+		name, dir, _ := strings.Cut(strings.TrimSpace(s), ":")
+		if f, ok := fields[name]; ok {
+			keys = append(keys, key{f, dir == "desc"})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := k.field.get(items[i]), k.field.get(items[j])
+			var c int
+			switch {
+			case len(a) == 0 && len(b) == 0:
+				c = 0
+			case len(a) == 0:
+				c = 1
+			case len(b) == 0:
+				c = -1
+			default:
+				c = compare(k.field.kind, a[0], b[0])
+			}
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,529 @@
+package redminetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// User is a user fixture with its credentials.
+type User struct {
+	model.User
+
+	// Password The password for basic authentication.
+	Password string `json:"password,omitempty"`
+
+	// APIKey The API key of the user.
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// Enumeration is an issue priority or a time entry activity.
+type Enumeration struct {
+	// Id The ID of the enumeration.
+	Id int `json:"id"`
+
+	// Name The name of the enumeration.
+	Name string `json:"name"`
+
+	// IsDefault Whether the enumeration is the default value.
+	IsDefault bool `json:"is_default"`
+
+	// Active Whether the enumeration can be selected.
+	Active bool `json:"active"`
+}
+
+// PossibleValue is a value of a list custom field.
+type PossibleValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+}
+
+// CustomField is the definition of a custom field.
+type CustomField struct {
+	// Id The ID of the custom field.
+	Id int `json:"id"`
+
+	// Name The name of the custom field.
+	Name string `json:"name"`
+
+	// CustomizedType The kind of the customized records: `issue`, `project`,
+	// `user`, `time_entry`, `version` or `group`.
+	CustomizedType string `json:"customized_type"`
+
+	// FieldFormat The format of the value, such as `string`, `int` or `list`.
+	FieldFormat string `json:"field_format"`
+
+	// IsRequired Whether a value is required.
+	IsRequired bool `json:"is_required"`
+
+	// Multiple Whether the field accepts several values.
+	Multiple bool `json:"multiple"`
+
+	// DefaultValue The value of new records.
+	DefaultValue string `json:"default_value,omitempty"`
+
+	// PossibleValues The values of a list field.
+	PossibleValues []PossibleValue `json:"possible_values,omitempty"`
+
+	// Trackers The trackers of an issue custom field. Empty means every tracker.
+	Trackers []model.Ref `json:"trackers,omitempty"`
+}
+
+// Category is an issue category.
+type Category struct {
+	// Id The ID of the category.
+	Id int `json:"id"`
+
+	// Project The project of the category.
+	Project *model.Ref `json:"project,omitempty"`
+
+	// Name The name of the category.
+	Name string `json:"name"`
+
+	// AssignedTo The default assignee of the issues of the category.
+	AssignedTo *model.Ref `json:"assigned_to,omitempty"`
+}
+
+// News is a news item of a project.
+type News struct {
+	// Id The ID of the news.
+	Id int `json:"id"`
+
+	// Project The project of the news.
+	Project *model.Ref `json:"project,omitempty"`
+
+	// Author The author of the news.
+	Author *model.Ref `json:"author,omitempty"`
+
+	// Title The title of the news.
+	Title string `json:"title"`
+
+	// Summary The summary of the news.
+	Summary string `json:"summary,omitempty"`
+
+	// Description The text of the news.
+	Description string `json:"description,omitempty"`
+
+	// CreatedOn The date and time when the news was created.
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+
+	// Comments The comments of the news (include=comments).
+	Comments []NewsComment `json:"comments,omitempty"`
+
+	// Attachments The attachments of the news (include=attachments).
+	Attachments []model.Attachment `json:"attachments,omitempty"`
+}
+
+// NewsComment is a comment of a news item.
+type NewsComment struct {
+	Id      int        `json:"id"`
+	Author  *model.Ref `json:"author,omitempty"`
+	Content string     `json:"content"`
+}
+
+// File is a file of the Files module of a project, optionally released with
+// a version.
+type File struct {
+	model.Attachment
+
+	// Project The project of the file.
+	Project *model.Ref `json:"project,omitempty"`
+
+	// Version The version of the file.
+	Version *model.Ref `json:"version,omitempty"`
+
+	// Digest The SHA-256 digest of the content.
+	Digest string `json:"digest,omitempty"`
+
+	// Downloads The number of downloads.
+	Downloads int `json:"downloads"`
+}
+
+// Query is a saved issue query.
+type Query struct {
+	// Id The ID of the query.
+	Id int `json:"id"`
+
+	// Name The name of the query.
+	Name string `json:"name"`
+
+	// IsPublic Whether every user sees the query, or only its owner.
+	IsPublic bool `json:"is_public"`
+
+	// ProjectId The project of the query, nil for a global query.
+	ProjectId *int `json:"project_id,omitempty"`
+
+	// UserId The owner of the query.
+	UserId int `json:"user_id,omitempty"`
+}
+
+// WikiPage is a wiki page fixture.
+type WikiPage struct {
+	model.WikiPage
+
+	// Project The ID or identifier of the project of the page.
+	Project string `json:"project"`
+}
+
+// Fixtures is the initial state of a Server.
+//
+// References only need IDs: names are taken from the referenced records.
+// Issues, wiki pages and news may embed their attachments, and issues their
+// journals, relations and watchers.
+type Fixtures struct {
+	Users        []User             `json:"users,omitempty"`
+	Groups       []model.Group      `json:"groups,omitempty"`
+	Roles        []model.Role       `json:"roles,omitempty"`
+	Statuses     []model.Status     `json:"issue_statuses,omitempty"`
+	Trackers     []model.Tracker    `json:"trackers,omitempty"`
+	Priorities   []Enumeration      `json:"issue_priorities,omitempty"`
+	Activities   []Enumeration      `json:"time_entry_activities,omitempty"`
+	CustomFields []CustomField      `json:"custom_fields,omitempty"`
+	Projects     []model.Project    `json:"projects,omitempty"`
+	Memberships  []model.Membership `json:"memberships,omitempty"`
+	Categories   []Category         `json:"issue_categories,omitempty"`
+	Versions     []model.Version    `json:"versions,omitempty"`
+	Issues       []model.Issue      `json:"issues,omitempty"`
+	Relations    []model.Relation   `json:"relations,omitempty"`
+	TimeEntries  []model.TimeEntry  `json:"time_entries,omitempty"`
+	WikiPages    []WikiPage         `json:"wiki_pages,omitempty"`
+	News         []News             `json:"news,omitempty"`
+	Files        []File             `json:"files,omitempty"`
+	Queries      []Query            `json:"queries,omitempty"`
+}
+
+// LoadFixtures reads fixtures from a JSON or YAML file. The YAML keys are
+// the JSON keys of the API.
+func LoadFixtures(path string) (*Fixtures, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		var v any
+		if err := yaml.Unmarshal(buf, &v); err != nil {
+			return nil, err
+		}
+		if buf, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &Fixtures{}
+	if err := json.Unmarshal(buf, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Permissions lists the permissions known by the server.
+var Permissions = []string{
+	"add_project", "edit_project", "close_project", "select_project_modules",
+	"manage_members", "manage_versions", "add_subprojects", "manage_categories",
+	"view_issues", "add_issues", "edit_issues", "edit_own_issues", "copy_issues",
+	"manage_issue_relations", "manage_subtasks", "set_issues_private",
+	"add_issue_notes", "edit_issue_notes", "edit_own_issue_notes",
+	"view_private_notes", "set_notes_private", "delete_issues",
+	"view_issue_watchers", "add_issue_watchers", "delete_issue_watchers",
+	"log_time", "view_time_entries", "edit_time_entries", "edit_own_time_entries",
+	"log_time_for_other_users",
+	"view_wiki_pages", "view_wiki_edits", "edit_wiki_pages", "rename_wiki_pages",
+	"delete_wiki_pages", "delete_wiki_pages_attachments", "export_wiki_pages",
+	"view_news", "manage_news", "comment_news", "view_files", "manage_files",
+	"save_queries", "manage_public_queries",
+}
+
+// DefaultFixtures returns the state of a fresh Redmine with the default
+// configuration loaded: an administrator admin/admin (API key "admin"),
+// statuses, trackers, priorities, activities and the Manager, Developer and
+// Reporter roles. It has no project.
+func DefaultFixtures() *Fixtures {
+	developer := []string{
+		"view_issues", "add_issues", "edit_issues", "manage_issue_relations",
+		"manage_subtasks", "add_issue_notes", "view_issue_watchers",
+		"add_issue_watchers", "log_time", "view_time_entries",
+		"view_wiki_pages", "view_wiki_edits", "edit_wiki_pages",
+		"view_news", "comment_news", "view_files", "manage_files", "save_queries",
+	}
+	reporter := []string{
+		"view_issues", "add_issues", "add_issue_notes", "view_issue_watchers",
+		"view_time_entries", "view_wiki_pages", "view_wiki_edits",
+		"view_news", "comment_news", "view_files", "save_queries",
+	}
+
+	return &Fixtures{
+		Users: []User{
+			{
+				User: model.User{
+					Id:        1,
+					Login:     "admin",
+					Admin:     true,
+					Firstname: "Redmine",
+					Lastname:  "Admin",
+					Mail:      "admin@example.net",
+					Status:    model.UserStatusActive,
+				},
+				Password: "admin",
+				APIKey:   "admin",
+			},
+		},
+		Roles: []model.Role{
+			{Id: 1, Name: "Manager", Assignable: true, IssuesVisibility: "all", Permissions: Permissions},
+			{Id: 2, Name: "Developer", Assignable: true, IssuesVisibility: "default", Permissions: developer},
+			{Id: 3, Name: "Reporter", Assignable: true, IssuesVisibility: "default", Permissions: reporter},
+		},
+		Statuses: []model.Status{
+			{Id: 1, Name: "New"},
+			{Id: 2, Name: "In Progress"},
+			{Id: 3, Name: "Resolved"},
+			{Id: 4, Name: "Feedback"},
+			{Id: 5, Name: "Closed", IsClosed: true},
+			{Id: 6, Name: "Rejected", IsClosed: true},
+		},
+		Trackers: []model.Tracker{
+			{Id: 1, Name: "Bug", DefaultStatus: &model.Ref{Id: 1}},
+			{Id: 2, Name: "Feature", DefaultStatus: &model.Ref{Id: 1}},
+			{Id: 3, Name: "Support", DefaultStatus: &model.Ref{Id: 1}},
+		},
+		Priorities: []Enumeration{
+			{Id: 1, Name: "Low", Active: true},
+			{Id: 2, Name: "Normal", IsDefault: true, Active: true},
+			{Id: 3, Name: "High", Active: true},
+			{Id: 4, Name: "Urgent", Active: true},
+			{Id: 5, Name: "Immediate", Active: true},
+		},
+		Activities: []Enumeration{
+			{Id: 8, Name: "Design", Active: true},
+			{Id: 9, Name: "Development", IsDefault: true, Active: true},
+		},
+	}
+}
+
+// Seed adds the fixtures to the state of the server. A fixture with the ID
+// of an existing record replaces it.
+func (s *Server) Seed(f *Fixtures) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range f.Users {
+		if u.Status == 0 {
+			u.Status = model.UserStatusActive
+		}
+		if u.CreatedOn == nil {
+			u.CreatedOn = timePtr(s.now())
+			u.UpdatedOn = u.CreatedOn
+		}
+		s.users[u.Id] = &u
+		s.useId("principal", u.Id)
+	}
+
+	for _, g := range f.Groups {
+		s.groups[g.Id] = &g
+		s.useId("principal", g.Id)
+	}
+
+	for _, r := range f.Roles {
+		s.roles[r.Id] = &r
+		s.useId("role", r.Id)
+	}
+
+	for _, st := range f.Statuses {
+		s.statuses[st.Id] = &st
+	}
+
+	for _, t := range f.Trackers {
+		s.trackers[t.Id] = &t
+	}
+
+	s.priorities = upsert(s.priorities, f.Priorities, func(e Enumeration) int { return e.Id })
+	s.activities = upsert(s.activities, f.Activities, func(e Enumeration) int { return e.Id })
+	s.customFields = upsert(s.customFields, f.CustomFields, func(c CustomField) int { return c.Id })
+
+	for _, p := range f.Projects {
+		if p.Status == 0 {
+			p.Status = model.ProjectStatusActive
+		}
+		if p.CreatedOn == nil {
+			p.CreatedOn = timePtr(s.now())
+			p.UpdatedOn = p.CreatedOn
+		}
+		s.projects[p.Id] = &p
+		s.useId("project", p.Id)
+	}
+
+	for _, m := range f.Memberships {
+		s.memberships[m.Id] = &m
+		s.useId("membership", m.Id)
+	}
+
+	for _, c := range f.Categories {
+		s.categories[c.Id] = &c
+		s.useId("category", c.Id)
+	}
+
+	for _, v := range f.Versions {
+		if v.Status == "" {
+			v.Status = "open"
+		}
+		if v.Sharing == "" {
+			v.Sharing = "none"
+		}
+		if v.CreatedOn == nil {
+			v.CreatedOn = timePtr(s.now())
+			v.UpdatedOn = v.CreatedOn
+		}
+		s.versions[v.Id] = &v
+		s.useId("version", v.Id)
+	}
+
+	for _, i := range f.Issues {
+		if err := s.seedIssue(i); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range f.Relations {
+		s.relations[rel.Id] = &rel
+		s.useId("relation", rel.Id)
+	}
+
+	for _, e := range f.TimeEntries {
+		if e.CreatedOn == nil {
+			e.CreatedOn = timePtr(s.now())
+			e.UpdatedOn = e.CreatedOn
+		}
+		s.timeEntries[e.Id] = &e
+		s.useId("time_entry", e.Id)
+	}
+
+	for _, w := range f.WikiPages {
+		if err := s.seedWikiPage(w); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range f.News {
+		if n.Project == nil {
+			return fmt.Errorf("news %d has no project", n.Id)
+		}
+		if n.Author == nil {
+			n.Author = &model.Ref{Id: 1}
+		}
+		if n.CreatedOn == nil {
+			n.CreatedOn = timePtr(s.now())
+		}
+		for _, a := range n.Attachments {
+			s.seedAttachment(a, "news", n.Id, "")
+		}
+		n.Attachments = nil
+		for _, c := range n.Comments {
+			s.useId("comment", c.Id)
+		}
+		s.news[n.Id] = &n
+		s.useId("news", n.Id)
+	}
+
+	for _, file := range f.Files {
+		switch {
+		case file.Version != nil:
+			s.seedAttachment(file.Attachment, "version", file.Version.Id, "")
+		case file.Project != nil:
+			s.seedAttachment(file.Attachment, "project", file.Project.Id, "")
+		default:
+			return fmt.Errorf("file %d has no project", file.Id)
+		}
+		a := s.attachments[file.Id]
+		a.digest, a.downloads = file.Digest, file.Downloads
+	}
+
+	for _, q := range f.Queries {
+		s.queries[q.Id] = &q
+		s.useId("query", q.Id)
+	}
+
+	return nil
+}
+
+func (s *Server) seedIssue(i model.Issue) error {
+	if i.Project == nil {
+		return fmt.Errorf("issue %d has no project", i.Id)
+	}
+	if i.Tracker == nil {
+		i.Tracker = &model.Ref{Id: 1}
+	}
+	if i.Status == nil {
+		i.Status = &model.Status{Id: 1}
+	}
+	if i.Priority == nil {
+		i.Priority = &model.Ref{Id: s.defaultPriority()}
+	}
+	if i.Author == nil {
+		i.Author = &model.Ref{Id: 1}
+	}
+	if i.CreatedOn == nil {
+		i.CreatedOn = timePtr(s.now())
+	}
+	if i.UpdatedOn == nil {
+		i.UpdatedOn = i.CreatedOn
+	}
+
+	for _, a := range i.Attachments {
+		s.seedAttachment(a, "issue", i.Id, "")
+	}
+	i.Attachments = nil
+
+	for _, rel := range i.Relations {
+		if rel.IssueId == 0 {
+			rel.IssueId = i.Id
+		}
+		s.relations[rel.Id] = &rel
+		s.useId("relation", rel.Id)
+	}
+	i.Relations = nil
+	i.Children = nil
+
+	for _, j := range i.Journals {
+		s.useId("journal", j.Id)
+	}
+
+	s.issues[i.Id] = &i
+	s.useId("issue", i.Id)
+	return nil
+}
+
+func (s *Server) seedAttachment(a model.Attachment, container string, id int, wikiKey string) {
+	if a.CreatedOn == nil {
+		a.CreatedOn = timePtr(s.now())
+	}
+	if a.Author == nil {
+		a.Author = &model.Ref{Id: 1}
+	}
+	s.attachments[a.Id] = &attachment{Attachment: a, container: container, containerId: id, wikiKey: wikiKey}
+	s.useId("attachment", a.Id)
+}
+
+// upsert adds items to list, replacing the items with the same ID.
+func upsert[T any](list, items []T, id func(T) int) []T {
+	for _, item := range items {
+		replaced := false
+		for i := range list {
+			if id(list[i]) == id(item) {
+				list[i] = item
+				replaced = true
+			}
+		}
+		if !replaced {
+			list = append(list, item)
+		}
+	}
+	return list
+}
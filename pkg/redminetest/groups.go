@@ -0,0 +1,216 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/groups", groupsIndex)
+	handle(http.MethodPost, "/groups", groupsCreate)
+	handle(http.MethodGet, "/groups/:id", groupsShow)
+	handle(http.MethodPut, "/groups/:id", groupsUpdate)
+	handle(http.MethodPatch, "/groups/:id", groupsUpdate)
+	handle(http.MethodDelete, "/groups/:id", groupsDestroy)
+	handle(http.MethodPost, "/groups/:id/users", groupsAddUsers)
+	handle(http.MethodDelete, "/groups/:id/users/:user", groupsRemoveUser)
+}
+
+func (s *Server) renderGroup(r *request, g *model.Group) model.Group {
+	out := model.Group{Id: g.Id, Name: g.Name, CustomFields: renderCustomFields(g.CustomFields, s.customFieldsOf("group", 0))}
+	if r.include("users") {
+		out.Users = []model.Ref{}
+		for _, u := range g.Users {
+			out.Users = append(out.Users, *s.principalRef(u.Id))
+		}
+	}
+	if r.include("memberships") {
+		out.Memberships = s.principalMemberships(r, g.Id)
+	}
+	return out
+}
+
+func groupsIndex(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+
+	groups := []model.Group{}
+	for _, id := range sortedIds(s.groups) {
+		g := s.groups[id]
+		groups = append(groups, model.Group{Id: g.Id, Name: g.Name})
+	}
+	slices.SortStableFunc(groups, func(a, b model.Group) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) })
+	return http.StatusOK, map[string]any{"groups": groups}, nil
+}
+
+// group returns the group of the path variable id. Groups are only visible
+// to administrators.
+func (s *Server) group(r *request) (*model.Group, error) {
+	if err := requireAdmin(r); err != nil {
+		return nil, err
+	}
+	id, err := r.id("id")
+	if err != nil {
+		return nil, err
+	}
+	g, ok := s.groups[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return g, nil
+}
+
+func groupsShow(s *Server, r *request) (int, any, error) {
+	g, err := s.group(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"group": s.renderGroup(r, g)}, nil
+}
+
+type groupInput struct {
+	customValues
+	Name    *string `json:"name"`
+	UserIds *[]int  `json:"user_ids"`
+}
+
+func (in *groupInput) apply(s *Server, g *model.Group, isNew bool) error {
+	v := validation{}
+
+	if in.Name != nil {
+		g.Name = strings.TrimSpace(*in.Name)
+	}
+	g.CustomFields = s.applyCustomFields(g.CustomFields, s.customFieldsOf("group", 0), in.customValues, isNew, &v)
+
+	switch {
+	case g.Name == "":
+		v.add("Name cannot be blank")
+	case len(g.Name) > 255:
+		v.add("Name is too long (maximum is 255 characters)")
+	}
+	for _, other := range s.groups {
+		if other.Id != g.Id && strings.EqualFold(other.Name, g.Name) {
+			v.add("Name has already been taken")
+		}
+	}
+
+	if in.UserIds != nil {
+		g.Users = []model.Ref{}
+		for _, id := range *in.UserIds {
+			if _, ok := s.users[id]; ok && !slices.ContainsFunc(g.Users, func(u model.Ref) bool { return u.Id == id }) {
+				g.Users = append(g.Users, model.Ref{Id: id})
+			}
+		}
+	}
+	return v.err()
+}
+
+func groupsCreate(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+
+	in := groupInput{}
+	if err := r.decode("group", &in); err != nil {
+		return 0, nil, err
+	}
+
+	g := &model.Group{}
+	if err := in.apply(s, g, true); err != nil {
+		return 0, nil, err
+	}
+	g.Id = s.nextId("principal")
+	s.groups[g.Id] = g
+	return http.StatusCreated, map[string]any{"group": s.renderGroup(r, g)}, nil
+}
+
+func groupsUpdate(s *Server, r *request) (int, any, error) {
+	g, err := s.group(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	in := groupInput{}
+	if err := r.decode("group", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *g
+	if err := in.apply(s, &updated, false); err != nil {
+		return 0, nil, err
+	}
+	*g = updated
+	return http.StatusNoContent, nil, nil
+}
+
+// groupsDestroy deletes a group with its memberships. Its users lose the
+// roles given through the group.
+func groupsDestroy(s *Server, r *request) (int, any, error) {
+	g, err := s.group(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for id, m := range s.memberships {
+		if refId(m.Group) == g.Id {
+			delete(s.memberships, id)
+		}
+	}
+	for _, i := range s.issues {
+		i.Watchers = slices.DeleteFunc(i.Watchers, func(w model.Ref) bool { return w.Id == g.Id })
+	}
+	delete(s.groups, g.Id)
+	return http.StatusNoContent, nil, nil
+}
+
+func groupsAddUsers(s *Server, r *request) (int, any, error) {
+	g, err := s.group(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var userId int
+	var userIds []int
+	if err := r.decode("user_id", &userId); err != nil {
+		return 0, nil, err
+	}
+	if err := r.decode("user_ids", &userIds); err != nil {
+		return 0, nil, err
+	}
+	if userId != 0 {
+		userIds = append(userIds, userId)
+	}
+
+	for _, id := range userIds {
+		if _, ok := s.users[id]; !ok {
+			return 0, nil, errNotFound
+		}
+	}
+	for _, id := range userIds {
+		if !slices.ContainsFunc(g.Users, func(u model.Ref) bool { return u.Id == id }) {
+			g.Users = append(g.Users, model.Ref{Id: id})
+		}
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func groupsRemoveUser(s *Server, r *request) (int, any, error) {
+	g, err := s.group(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	userId, err := r.id("user")
+	if err != nil {
+		return 0, nil, err
+	}
+	if !slices.ContainsFunc(g.Users, func(u model.Ref) bool { return u.Id == userId }) {
+		return 0, nil, errNotFound
+	}
+
+	g.Users = slices.DeleteFunc(g.Users, func(u model.Ref) bool { return u.Id == userId })
+	return http.StatusNoContent, nil, nil
+}
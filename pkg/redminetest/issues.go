@@ -0,0 +1,947 @@
+package redminetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/issues", issuesIndex)
+	handle(http.MethodPost, "/issues", issuesCreate)
+	handle(http.MethodGet, "/projects/:project/issues", issuesIndexProject)
+	handle(http.MethodPost, "/projects/:project/issues", issuesCreateProject)
+	handle(http.MethodGet, "/issues/:id", issuesShow)
+	handle(http.MethodPut, "/issues/:id", issuesUpdate)
+	handle(http.MethodPatch, "/issues/:id", issuesUpdate)
+	handle(http.MethodDelete, "/issues/:id", issuesDestroy)
+	handle(http.MethodPut, "/journals/:id", journalsUpdate)
+	handle(http.MethodPatch, "/journals/:id", journalsUpdate)
+}
+
+// issueVisible reports whether the user of r can see issue i, following the
+// issues visibility of the roles for private issues.
+func (s *Server) issueVisible(r *request, i *model.Issue) bool {
+	p, ok := s.projects[refId(i.Project)]
+	if !ok || !s.projectVisible(r, p) || !s.allowed(r, p, "view_issues") {
+		return false
+	}
+	if r.admin() {
+		return true
+	}
+
+	me := r.userId()
+	own := me != 0 && (refId(i.Author) == me || refId(i.AssignedTo) == me || slices.Contains(s.groupIds(me), refId(i.AssignedTo)))
+
+	roles := s.memberRoles(me, p.Id)
+	if len(roles) == 0 {
+		return !i.IsPrivate || own
+	}
+	for _, role := range roles {
+		if !slices.Contains(role.Permissions, "view_issues") {
+			continue
+		}
+		switch role.IssuesVisibility {
+		case "all":
+			return true
+		case "own":
+			if own {
+				return true
+			}
+		default:
+			if !i.IsPrivate || own {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// issue returns the issue of the path variable name, visible to the user
+// of r.
+func (s *Server) issue(r *request, name string) (*model.Issue, *model.Project, error) {
+	id, err := r.id(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, ok := s.issues[id]
+	if !ok || !s.issueVisible(r, i) {
+		return nil, nil, errNotFound
+	}
+	return i, s.projects[refId(i.Project)], nil
+}
+
+// descendants returns the IDs of the subtasks of issue id, recursively.
+func (s *Server) descendants(id int) []int {
+	ids := []int{}
+	for _, cid := range sortedIds(s.issues) {
+		if s.issues[cid].ParentId() == id && !slices.Contains(ids, cid) {
+			ids = append(ids, cid)
+			ids = append(ids, s.descendants(cid)...)
+		}
+	}
+	return ids
+}
+
+// moveIssue moves issue i to project p. As in Redmine, the category is
+// mapped by name, and the tracker, assignee, version and parent that p does
+// not allow are replaced by the first tracker of p or cleared.
+func (s *Server) moveIssue(i *model.Issue, p *model.Project) {
+	i.Project = &model.Ref{Id: p.Id}
+
+	if trackers := s.projectTrackers(p); len(trackers) > 0 && !slices.Contains(trackers, refId(i.Tracker)) {
+		i.Tracker = &model.Ref{Id: trackers[0]}
+	}
+	if i.Category != nil {
+		name := ""
+		if c, ok := s.categories[i.Category.Id]; ok {
+			name = c.Name
+		}
+		i.Category = nil
+		for _, id := range sortedIds(s.categories) {
+			if c := s.categories[id]; refId(c.Project) == p.Id && c.Name == name {
+				i.Category = &model.Ref{Id: id}
+				break
+			}
+		}
+	}
+	if i.AssignedTo != nil && !s.assignable(p, i.AssignedTo.Id) {
+		i.AssignedTo = nil
+	}
+	if i.FixedVersion != nil {
+		if v, ok := s.versions[i.FixedVersion.Id]; !ok || !s.versionShared(v, p.Id) {
+			i.FixedVersion = nil
+		}
+	}
+	if parent, ok := s.issues[i.ParentId()]; ok && s.root(refId(parent.Project)) != s.root(p.Id) {
+		i.Parent = nil
+	}
+}
+
+// moveSubtasks moves the subtasks of issue id to the project of the issue,
+// with the time logged on them all.
+func (s *Server) moveSubtasks(id int) {
+	p := s.projects[refId(s.issues[id].Project)]
+	ids := s.descendants(id)
+	// Every subtask is in p before the parents are checked.
+	for _, cid := range ids {
+		s.issues[cid].Project = &model.Ref{Id: p.Id}
+	}
+	for _, cid := range ids {
+		s.moveIssue(s.issues[cid], p)
+	}
+	for _, e := range s.timeEntries {
+		if e.IssueId() == id || slices.Contains(ids, e.IssueId()) {
+			e.Project = &model.Ref{Id: p.Id}
+		}
+	}
+}
+
+// spentHours returns the hours logged on issue id.
+func (s *Server) spentHours(id int) float64 {
+	hours := 0.0
+	for _, e := range s.timeEntries {
+		if e.IssueId() == id {
+			hours += e.Hours
+		}
+	}
+	return hours
+}
+
+// issueCustomFields returns the custom fields of the project and the
+// tracker of issue i.
+func (s *Server) issueCustomFields(i *model.Issue) []CustomField {
+	p, ok := s.projects[refId(i.Project)]
+	if !ok {
+		return nil
+	}
+	fields := []CustomField{}
+	for _, cf := range s.projectIssueCustomFields(p) {
+		if len(cf.Trackers) == 0 || slices.ContainsFunc(cf.Trackers, func(t model.Ref) bool { return t.Id == refId(i.Tracker) }) {
+			fields = append(fields, cf)
+		}
+	}
+	return fields
+}
+
+func (s *Server) renderIssue(r *request, i *model.Issue, show bool) model.Issue {
+	out := *i
+	out.Project = s.projectRef(refId(i.Project))
+	out.Tracker = s.trackerRef(refId(i.Tracker))
+	out.Status = s.issueStatus(i.Status.Id)
+	out.Priority = s.priorityRef(refId(i.Priority))
+	out.Author = s.principalRef(refId(i.Author))
+	if i.AssignedTo != nil {
+		out.AssignedTo = s.principalRef(i.AssignedTo.Id)
+	}
+	if i.Category != nil {
+		out.Category = s.categoryRef(i.Category.Id)
+	}
+	if i.FixedVersion != nil {
+		out.FixedVersion = s.versionRef(i.FixedVersion.Id)
+	}
+	out.CustomFields = renderCustomFields(i.CustomFields, s.issueCustomFields(i))
+
+	spent, totalSpent := s.spentHours(i.Id), s.spentHours(i.Id)
+	var totalEstimated *float64
+	if i.EstimatedHours != nil {
+		totalEstimated = new(float64)
+		*totalEstimated = *i.EstimatedHours
+	}
+	for _, id := range s.descendants(i.Id) {
+		totalSpent += s.spentHours(id)
+		if e := s.issues[id].EstimatedHours; e != nil {
+			if totalEstimated == nil {
+				totalEstimated = new(float64)
+			}
+			*totalEstimated += *e
+		}
+	}
+	out.SpentHours = &spent
+	out.TotalSpentHours = &totalSpent
+	out.TotalEstimatedHours = totalEstimated
+
+	out.Attachments = nil
+	if r.include("attachments") {
+		out.Attachments = s.attachmentsOf(r, "issue", i.Id, "")
+	}
+
+	out.Relations = nil
+	if r.include("relations") {
+		out.Relations = s.issueRelations(r, i.Id)
+	}
+
+	out.Journals = nil
+	out.Watchers = nil
+	out.Children = nil
+	if !show {
+		return out
+	}
+
+	p := s.projects[refId(i.Project)]
+	if r.include("journals") {
+		out.Journals = []model.Journal{}
+		for _, j := range i.Journals {
+			if j.PrivateNotes && !s.allowed(r, p, "view_private_notes") && refId(j.User) != r.userId() {
+				continue
+			}
+			j.User = s.principalRef(refId(j.User))
+			if j.Details == nil {
+				j.Details = []model.JournalDetail{}
+			}
+			out.Journals = append(out.Journals, j)
+		}
+	}
+	if r.include("watchers") && s.allowed(r, p, "view_issue_watchers") {
+		out.Watchers = []model.Ref{}
+		for _, w := range i.Watchers {
+			out.Watchers = append(out.Watchers, *s.principalRef(w.Id))
+		}
+	}
+	if r.include("children") {
+		out.Children = s.children(r, i.Id)
+	}
+	return out
+}
+
+// children returns the visible subtasks of issue id.
+func (s *Server) children(r *request, id int) []model.Child {
+	children := []model.Child{}
+	for _, cid := range sortedIds(s.issues) {
+		c := s.issues[cid]
+		if c.ParentId() != id || !s.issueVisible(r, c) {
+			continue
+		}
+		child := model.Child{Id: c.Id, Tracker: s.trackerRef(refId(c.Tracker)), Subject: c.Subject}
+		if grandchildren := s.children(r, c.Id); len(grandchildren) > 0 {
+			child.Children = grandchildren
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+func (s *Server) issueFields() map[string]field[*model.Issue] {
+	fields := map[string]field[*model.Issue]{
+		"issue_id":         {kindIdList, func(i *model.Issue) []string { return intValue(i.Id) }},
+		"id":               {kindInt, func(i *model.Issue) []string { return intValue(i.Id) }},
+		"tracker_id":       {kindInt, func(i *model.Issue) []string { return intValue(refId(i.Tracker)) }},
+		"priority_id":      {kindInt, func(i *model.Issue) []string { return intValue(refId(i.Priority)) }},
+		"author_id":        {kindInt, func(i *model.Issue) []string { return intValue(refId(i.Author)) }},
+		"assigned_to_id":   {kindInt, func(i *model.Issue) []string { return intValue(refId(i.AssignedTo)) }},
+		"category_id":      {kindInt, func(i *model.Issue) []string { return intValue(refId(i.Category)) }},
+		"fixed_version_id": {kindInt, func(i *model.Issue) []string { return intValue(refId(i.FixedVersion)) }},
+		"parent_id":        {kindIdList, func(i *model.Issue) []string { return intValue(i.ParentId()) }},
+		"subject":          {kindString, func(i *model.Issue) []string { return stringValue(i.Subject) }},
+		"description":      {kindString, func(i *model.Issue) []string { return stringValue(i.Description) }},
+		"is_private":       {kindInt, func(i *model.Issue) []string { return boolValue(i.IsPrivate) }},
+		"start_date":       {kindDate, func(i *model.Issue) []string { return dateValue(i.StartDate) }},
+		"due_date":         {kindDate, func(i *model.Issue) []string { return dateValue(i.DueDate) }},
+		"done_ratio":       {kindInt, func(i *model.Issue) []string { return []string{strconv.Itoa(i.DoneRatio)} }},
+		"estimated_hours":  {kindFloat, func(i *model.Issue) []string { return floatValue(i.EstimatedHours) }},
+		"created_on":       {kindTime, func(i *model.Issue) []string { return timeValue(i.CreatedOn) }},
+		"updated_on":       {kindTime, func(i *model.Issue) []string { return timeValue(i.UpdatedOn) }},
+		"closed_on":        {kindTime, func(i *model.Issue) []string { return timeValue(i.ClosedOn) }},
+		"watcher_id": {kindInt, func(i *model.Issue) []string {
+			ids := []string{}
+			for _, w := range i.Watchers {
+				ids = append(ids, strconv.Itoa(w.Id))
+			}
+			return ids
+		}},
+	}
+
+	for _, cf := range s.customFields {
+		if cf.CustomizedType != "issue" {
+			continue
+		}
+		k := kindString
+		switch cf.FieldFormat {
+		case "int", "bool", "user", "version":
+			k = kindInt
+		case "float":
+			k = kindFloat
+		case "date":
+			k = kindDate
+		}
+		id := cf.Id
+		fields["cf_"+strconv.Itoa(id)] = field[*model.Issue]{k, func(i *model.Issue) []string {
+			if j := slices.IndexFunc(i.CustomFields, func(c model.CustomField) bool { return c.Id == id }); j >= 0 {
+				return i.CustomFields[j].Values()
+			}
+			return nil
+		}}
+	}
+	return fields
+}
+
+// issueSorts are the sort keys of issues.
+func (s *Server) issueSorts() map[string]field[*model.Issue] {
+	fields := s.issueFields()
+	sorts := map[string]field[*model.Issue]{}
+	for _, name := range []string{"id", "subject", "start_date", "due_date", "done_ratio", "estimated_hours", "created_on", "updated_on", "closed_on"} {
+		sorts[name] = fields[name]
+	}
+	sorts["parent"] = fields["parent_id"]
+	sorts["priority"] = fields["priority_id"]
+	for name, get := range map[string]func(i *model.Issue) string{
+		"project":       func(i *model.Issue) string { return s.projectRef(refId(i.Project)).Name },
+		"tracker":       func(i *model.Issue) string { return s.trackerRef(refId(i.Tracker)).Name },
+		"status":        func(i *model.Issue) string { return s.issueStatus(i.Status.Id).Name },
+		"author":        func(i *model.Issue) string { return s.principalRef(refId(i.Author)).Name },
+		"assigned_to":   func(i *model.Issue) string { return nameOf(s.principalRef(refId(i.AssignedTo))) },
+		"category":      func(i *model.Issue) string { return nameOf(s.categoryRef(refId(i.Category))) },
+		"fixed_version": func(i *model.Issue) string { return nameOf(s.versionRef(refId(i.FixedVersion))) },
+	} {
+		sorts[name] = field[*model.Issue]{kindString, func(i *model.Issue) []string { return stringValue(get(i)) }}
+	}
+	return sorts
+}
+
+func nameOf(r *model.Ref) string {
+	if r == nil {
+		return ""
+	}
+	return r.Name
+}
+
+// projectScope returns the IDs of the projects of an issue query: the
+// projects of the project_id parameter or scope, with their subprojects
+// unless subproject_id is `!*`. It returns nil for all projects.
+func (s *Server) projectScope(r *request, scope *model.Project) []int {
+	q := r.URL.Query()
+
+	ids := []int{}
+	if scope != nil {
+		ids = append(ids, scope.Id)
+	} else if expr := q.Get("project_id"); expr != "" {
+		for _, key := range splitValues(expr, false) {
+			if key == "mine" {
+				for _, pid := range sortedIds(s.projects) {
+					if len(s.memberRoles(r.userId(), pid)) > 0 {
+						ids = append(ids, pid)
+					}
+				}
+			} else if p, ok := s.project(key); ok {
+				ids = append(ids, p.Id)
+			}
+		}
+	} else {
+		return nil
+	}
+
+	if q.Get("subproject_id") != "!*" {
+		for _, pid := range sortedIds(s.projects) {
+			if slices.ContainsFunc(s.ancestors(pid), func(a int) bool { return slices.Contains(ids, a) }) && !slices.Contains(ids, pid) {
+				ids = append(ids, pid)
+			}
+		}
+	}
+	return ids
+}
+
+// statusMatches evaluates the status_id parameter, `open` by default.
+func (s *Server) statusMatches(r *request, i *model.Issue) bool {
+	expr := r.URL.Query().Get("status_id")
+	closed := s.issueStatus(i.Status.Id).IsClosed
+	switch expr {
+	case "", "o", "open":
+		return !closed
+	case "c", "closed":
+		return closed
+	case "*":
+		return true
+	}
+	return matchExpr(kindInt, expr, intValue(i.Status.Id), r.userId())
+}
+
+func (s *Server) listIssues(r *request, scope *model.Project) (int, any, error) {
+	projects := s.projectScope(r, scope)
+
+	issues := []*model.Issue{}
+	for _, id := range sortedIds(s.issues) {
+		i := s.issues[id]
+		if projects != nil && !slices.Contains(projects, refId(i.Project)) {
+			continue
+		}
+		if !s.statusMatches(r, i) || !s.issueVisible(r, i) {
+			continue
+		}
+		issues = append(issues, i)
+	}
+
+	issues = filter(r, issues, s.issueFields())
+	sortBy(r, issues, s.issueSorts(), "id:desc")
+
+	rendered := []model.Issue{}
+	for _, i := range issues {
+		rendered = append(rendered, s.renderIssue(r, i, false))
+	}
+	return http.StatusOK, paginate(r, "issues", rendered), nil
+}
+
+func issuesIndex(s *Server, r *request) (int, any, error) {
+	return s.listIssues(r, nil)
+}
+
+func issuesIndexProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_issues"); err != nil {
+		return 0, nil, err
+	}
+	return s.listIssues(r, p)
+}
+
+func issuesShow(s *Server, r *request) (int, any, error) {
+	i, _, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	out := s.renderIssue(r, i, true)
+	body := map[string]any{"issue": out}
+	if r.include("allowed_statuses") {
+		statuses := []model.Status{}
+		for _, id := range sortedIds(s.statuses) {
+			statuses = append(statuses, *s.issueStatus(id))
+		}
+		return http.StatusOK, map[string]any{"issue": struct {
+			model.Issue
+			AllowedStatuses []model.Status `json:"allowed_statuses"`
+		}{out, statuses}}, nil
+	}
+	return http.StatusOK, body, nil
+}
+
+type issueInput struct {
+	customValues
+	ProjectId            idOrKey       `json:"project_id"`
+	TrackerId            optInt        `json:"tracker_id"`
+	StatusId             optInt        `json:"status_id"`
+	PriorityId           optInt        `json:"priority_id"`
+	AssignedToId         optInt        `json:"assigned_to_id"`
+	CategoryId           optInt        `json:"category_id"`
+	FixedVersionId       optInt        `json:"fixed_version_id"`
+	ParentIssueId        optInt        `json:"parent_issue_id"`
+	Subject              *string       `json:"subject"`
+	Description          *string       `json:"description"`
+	StartDate            optDate       `json:"start_date"`
+	DueDate              optDate       `json:"due_date"`
+	DoneRatio            optInt        `json:"done_ratio"`
+	EstimatedHours       optFloat      `json:"estimated_hours"`
+	IsPrivate            *bool         `json:"is_private"`
+	Notes                *string       `json:"notes"`
+	PrivateNotes         *bool         `json:"private_notes"`
+	WatcherUserIds       []int         `json:"watcher_user_ids"`
+	Uploads              []uploadInput `json:"uploads"`
+	DeletedAttachmentIds []int         `json:"deleted_attachment_ids"`
+}
+
+// apply applies the input to issue i, which belongs to project p unless the
+// input moves it, and validates the result.
+func (in *issueInput) apply(s *Server, r *request, i *model.Issue, isNew bool) error {
+	v := validation{}
+
+	p := s.projects[refId(i.Project)]
+	if in.ProjectId != "" {
+		target, ok := s.project(string(in.ProjectId))
+		switch {
+		case !ok || !s.projectVisible(r, target):
+			v.add("Project cannot be blank")
+		case target.Id != refId(i.Project) && !s.allowed(r, target, "add_issues"):
+			v.add("Project is not included in the list")
+		default:
+			if target.Id != refId(i.Project) {
+				s.moveIssue(i, target)
+			}
+			p = target
+		}
+	}
+	if p == nil {
+		if len(v) == 0 {
+			v.add("Project cannot be blank")
+		}
+		return v.err()
+	}
+
+	trackers := s.projectTrackers(p)
+	switch {
+	case in.TrackerId.set:
+		i.Tracker = &model.Ref{Id: in.TrackerId.v}
+	case isNew && len(trackers) > 0:
+		i.Tracker = &model.Ref{Id: trackers[0]}
+	}
+	if !slices.Contains(trackers, refId(i.Tracker)) {
+		v.add("Tracker is not included in the list")
+	}
+
+	if in.Subject != nil {
+		i.Subject = strings.TrimSpace(*in.Subject)
+	}
+	if in.Description != nil {
+		i.Description = *in.Description
+	}
+
+	switch {
+	case in.StatusId.set:
+		i.Status = &model.Status{Id: in.StatusId.v}
+	case isNew:
+		i.Status = &model.Status{Id: 1}
+		if t := s.trackers[refId(i.Tracker)]; t != nil && t.DefaultStatus != nil {
+			i.Status = &model.Status{Id: t.DefaultStatus.Id}
+		}
+	}
+	if _, ok := s.statuses[i.Status.Id]; !ok {
+		v.add("Status cannot be blank")
+	}
+
+	switch {
+	case in.PriorityId.set:
+		i.Priority = &model.Ref{Id: in.PriorityId.v}
+	case isNew:
+		i.Priority = &model.Ref{Id: s.defaultPriority()}
+	}
+	if _, ok := findEnumeration(s.priorities, refId(i.Priority)); !ok {
+		v.add("Priority cannot be blank")
+	}
+
+	if in.CategoryId.set {
+		i.Category = nil
+		if in.CategoryId.v != 0 {
+			i.Category = &model.Ref{Id: in.CategoryId.v}
+		}
+	}
+	if i.Category != nil {
+		if c, ok := s.categories[i.Category.Id]; !ok || refId(c.Project) != p.Id {
+			v.add("Category is not included in the list")
+		} else if isNew && !in.AssignedToId.set && c.AssignedTo != nil {
+			i.AssignedTo = &model.Ref{Id: c.AssignedTo.Id}
+		}
+	}
+
+	// As in Redmine, only an empty value unassigns: the ID 0 is invalid.
+	if in.AssignedToId.set {
+		i.AssignedTo = nil
+		if !in.AssignedToId.null {
+			i.AssignedTo = &model.Ref{Id: in.AssignedToId.v}
+		}
+	}
+	if i.AssignedTo != nil && (in.AssignedToId.set || in.ProjectId != "") && !s.assignable(p, i.AssignedTo.Id) {
+		v.add("Assignee is invalid")
+	}
+
+	if in.FixedVersionId.set {
+		i.FixedVersion = nil
+		if in.FixedVersionId.v != 0 {
+			i.FixedVersion = &model.Ref{Id: in.FixedVersionId.v}
+			if ver, ok := s.versions[in.FixedVersionId.v]; !ok || ver.Status != "open" {
+				v.add("Target version is not included in the list")
+			}
+		}
+	}
+	if i.FixedVersion != nil {
+		if ver, ok := s.versions[i.FixedVersion.Id]; !ok || !s.versionShared(ver, p.Id) {
+			v.add("Target version is not included in the list")
+		}
+	}
+
+	if in.ParentIssueId.set {
+		i.Parent = nil
+		if in.ParentIssueId.v != 0 {
+			parent, ok := s.issues[in.ParentIssueId.v]
+			switch {
+			case !ok || !s.issueVisible(r, parent):
+				v.add("Parent task does not exist")
+			case i.Id != 0 && (parent.Id == i.Id || slices.Contains(s.descendants(i.Id), parent.Id)) || s.root(refId(parent.Project)) != s.root(p.Id):
+				v.add("Parent task is invalid")
+			default:
+				i.Parent = &struct {
+					Id int `json:"id"`
+				}{parent.Id}
+			}
+		}
+	}
+
+	if in.StartDate.set {
+		i.StartDate = in.StartDate.v
+	}
+	if in.DueDate.set {
+		i.DueDate = in.DueDate.v
+	}
+	if i.StartDate != nil && i.DueDate != nil && i.DueDate.Before(i.StartDate.Time) {
+		v.add("Due date must be greater than start date")
+	}
+
+	if in.DoneRatio.set {
+		i.DoneRatio = in.DoneRatio.v
+	}
+	if i.DoneRatio < 0 || i.DoneRatio > 100 {
+		v.add("%% Done is not included in the list")
+	}
+
+	if in.EstimatedHours.set {
+		i.EstimatedHours = in.EstimatedHours.v
+	}
+	if i.EstimatedHours != nil && *i.EstimatedHours < 0 {
+		v.add("Estimated time is invalid")
+	}
+
+	if in.IsPrivate != nil {
+		i.IsPrivate = *in.IsPrivate
+	}
+
+	i.CustomFields = s.applyCustomFields(i.CustomFields, s.issueCustomFields(i), in.customValues, isNew, &v)
+
+	switch {
+	case i.Subject == "":
+		v.add("Subject cannot be blank")
+	case len(i.Subject) > 255:
+		v.add("Subject is too long (maximum is 255 characters)")
+	}
+
+	if isNew {
+		for _, id := range in.WatcherUserIds {
+			if _, ok := s.users[id]; ok && !slices.ContainsFunc(i.Watchers, func(w model.Ref) bool { return w.Id == id }) {
+				i.Watchers = append(i.Watchers, model.Ref{Id: id})
+			}
+		}
+	}
+
+	for _, u := range in.Uploads {
+		if _, ok := s.uploads[u.Token]; !ok {
+			v.add("Attachments is invalid")
+			break
+		}
+	}
+
+	return v.err()
+}
+
+// issueDetails returns the journal details of the changes from old to new.
+func (s *Server) issueDetails(old, new *model.Issue) []model.JournalDetail {
+	attrs := []struct {
+		name string
+		get  func(i *model.Issue) string
+	}{
+		{"project_id", func(i *model.Issue) string { return idString(refId(i.Project)) }},
+		{"tracker_id", func(i *model.Issue) string { return idString(refId(i.Tracker)) }},
+		{"subject", func(i *model.Issue) string { return i.Subject }},
+		{"description", func(i *model.Issue) string { return i.Description }},
+		{"status_id", func(i *model.Issue) string { return idString(i.Status.Id) }},
+		{"priority_id", func(i *model.Issue) string { return idString(refId(i.Priority)) }},
+		{"assigned_to_id", func(i *model.Issue) string { return idString(refId(i.AssignedTo)) }},
+		{"category_id", func(i *model.Issue) string { return idString(refId(i.Category)) }},
+		{"fixed_version_id", func(i *model.Issue) string { return idString(refId(i.FixedVersion)) }},
+		{"parent_id", func(i *model.Issue) string { return idString(i.ParentId()) }},
+		{"start_date", func(i *model.Issue) string { return dateString(i.StartDate) }},
+		{"due_date", func(i *model.Issue) string { return dateString(i.DueDate) }},
+		{"done_ratio", func(i *model.Issue) string { return strconv.Itoa(i.DoneRatio) }},
+		{"estimated_hours", func(i *model.Issue) string { return floatString(i.EstimatedHours) }},
+		{"is_private", func(i *model.Issue) string { return boolValue(i.IsPrivate)[0] }},
+	}
+
+	details := []model.JournalDetail{}
+	for _, a := range attrs {
+		if before, after := a.get(old), a.get(new); before != after {
+			details = append(details, detail("attr", a.name, before, after))
+		}
+	}
+	return append(details, changedValues(old.CustomFields, new.CustomFields)...)
+}
+
+func issuesCreate(s *Server, r *request) (int, any, error) {
+	return s.createIssue(r, nil)
+}
+
+func issuesCreateProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.createIssue(r, p)
+}
+
+func (s *Server) createIssue(r *request, p *model.Project) (int, any, error) {
+	in := issueInput{}
+	if err := r.decode("issue", &in); err != nil {
+		return 0, nil, err
+	}
+
+	i := &model.Issue{Author: &model.Ref{Id: r.userId()}, Status: &model.Status{}}
+	if p != nil && in.ProjectId == "" {
+		i.Project = &model.Ref{Id: p.Id}
+	}
+	if in.ProjectId != "" {
+		if target, ok := s.project(string(in.ProjectId)); ok && s.projectVisible(r, target) {
+			p = target
+		}
+	}
+	if p != nil {
+		if err := s.require(r, p, "add_issues"); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := in.apply(s, r, i, true); err != nil {
+		return 0, nil, err
+	}
+
+	now := s.now()
+	i.Id = s.nextId("issue")
+	i.CreatedOn = timePtr(now)
+	i.UpdatedOn = timePtr(now)
+	if s.issueStatus(i.Status.Id).IsClosed {
+		i.ClosedOn = timePtr(now)
+	}
+	s.issues[i.Id] = i
+
+	for _, u := range in.Uploads {
+		s.attach(u, "issue", i.Id, "")
+	}
+
+	return http.StatusCreated, map[string]any{"issue": s.renderIssue(r, i, true)}, nil
+}
+
+// notesOnly reports whether the body of r only adds notes to an issue.
+func notesOnly(r *request) bool {
+	var keys map[string]json.RawMessage
+	if err := r.decode("issue", &keys); err != nil {
+		return false
+	}
+	for k := range keys {
+		if k != "notes" && k != "private_notes" && k != "uploads" {
+			return false
+		}
+	}
+	return true
+}
+
+func issuesUpdate(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	editable := s.allowed(r, p, "edit_issues") || s.allowed(r, p, "edit_own_issues") && refId(i.Author) == r.userId()
+	if !editable && !(notesOnly(r) && s.allowed(r, p, "add_issue_notes")) {
+		return 0, nil, errForbidden
+	}
+
+	in := issueInput{}
+	if err := r.decode("issue", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *i
+	updated.Status = &model.Status{Id: i.Status.Id}
+	if err := in.apply(s, r, &updated, false); err != nil {
+		return 0, nil, err
+	}
+
+	details := s.issueDetails(i, &updated)
+	for _, id := range in.DeletedAttachmentIds {
+		if a, ok := s.attachments[id]; ok && a.container == "issue" && a.containerId == i.Id {
+			details = append(details, detail("attachment", strconv.Itoa(id), a.Filename, ""))
+			delete(s.attachments, id)
+		}
+	}
+	for _, u := range in.Uploads {
+		a := s.attach(u, "issue", i.Id, "")
+		details = append(details, detail("attachment", strconv.Itoa(a.Id), "", a.Filename))
+	}
+
+	notes := ""
+	if in.Notes != nil {
+		notes = strings.TrimSpace(*in.Notes)
+	}
+
+	now := s.now()
+	if len(details) > 0 || notes != "" {
+		updated.Journals = append(slices.Clone(i.Journals), model.Journal{
+			Id:           s.nextId("journal"),
+			User:         &model.Ref{Id: r.userId()},
+			Notes:        notes,
+			PrivateNotes: notes != "" && in.PrivateNotes != nil && *in.PrivateNotes,
+			CreatedOn:    now,
+			Details:      details,
+		})
+		updated.UpdatedOn = timePtr(now)
+	}
+	if s.issueStatus(updated.Status.Id).IsClosed && !s.issueStatus(i.Status.Id).IsClosed {
+		updated.ClosedOn = timePtr(now)
+	}
+
+	*i = updated
+	if refId(i.Project) != p.Id {
+		s.moveSubtasks(i.Id)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// issuesDestroy deletes an issue with its subtasks. The time logged on them
+// is deleted, unless the todo parameter is `nullify` or `reassign` to the
+// issue reassign_to_id.
+func issuesDestroy(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "delete_issues"); err != nil {
+		return 0, nil, err
+	}
+
+	ids := append([]int{i.Id}, s.descendants(i.Id)...)
+
+	q := r.URL.Query()
+	switch q.Get("todo") {
+	case "nullify":
+		for _, e := range s.timeEntries {
+			if slices.Contains(ids, e.IssueId()) {
+				e.Issue = nil
+			}
+		}
+	case "reassign":
+		target, ok := s.issues[atoi(q.Get("reassign_to_id"))]
+		if !ok || slices.Contains(ids, target.Id) || !s.issueVisible(r, target) {
+			return 0, nil, invalid("The issue to reassign the spent time to is invalid")
+		}
+		for _, e := range s.timeEntries {
+			if slices.Contains(ids, e.IssueId()) {
+				e.Issue = &struct {
+					Id int `json:"id"`
+				}{target.Id}
+				e.Project = &model.Ref{Id: refId(target.Project)}
+			}
+		}
+	}
+
+	for _, id := range ids {
+		s.deleteIssue(id)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// deleteIssue deletes an issue with its relations, attachments and time
+// entries. Subtasks must be deleted separately.
+func (s *Server) deleteIssue(id int) {
+	for rid, rel := range s.relations {
+		if rel.IssueId == id || rel.IssueToId == id {
+			delete(s.relations, rid)
+		}
+	}
+	for aid, a := range s.attachments {
+		if a.container == "issue" && a.containerId == id {
+			delete(s.attachments, aid)
+		}
+	}
+	for eid, e := range s.timeEntries {
+		if e.IssueId() == id {
+			delete(s.timeEntries, eid)
+		}
+	}
+	delete(s.issues, id)
+}
+
+func atoi(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+// journal returns the issue and the index of journal id.
+func (s *Server) journal(id int) (*model.Issue, int) {
+	for _, i := range s.issues {
+		for k, j := range i.Journals {
+			if j.Id == id {
+				return i, k
+			}
+		}
+	}
+	return nil, -1
+}
+
+// journalsUpdate edits the notes of a journal. A journal left without notes
+// nor details is deleted.
+func journalsUpdate(s *Server, r *request) (int, any, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return 0, nil, err
+	}
+	i, k := s.journal(id)
+	if i == nil || !s.issueVisible(r, i) {
+		return 0, nil, errNotFound
+	}
+	p := s.projects[refId(i.Project)]
+	j := &i.Journals[k]
+	if j.PrivateNotes && !s.allowed(r, p, "view_private_notes") && refId(j.User) != r.userId() {
+		return 0, nil, errNotFound
+	}
+	if !s.allowed(r, p, "edit_issue_notes") && !(s.allowed(r, p, "edit_own_issue_notes") && refId(j.User) == r.userId()) {
+		return 0, nil, errForbidden
+	}
+
+	var in struct {
+		Notes        *string `json:"notes"`
+		PrivateNotes *bool   `json:"private_notes"`
+	}
+	if err := r.decode("journal", &in); err != nil {
+		return 0, nil, err
+	}
+
+	if in.Notes != nil {
+		j.Notes = strings.TrimSpace(*in.Notes)
+	}
+	if in.PrivateNotes != nil && s.allowed(r, p, "set_notes_private") {
+		j.PrivateNotes = *in.PrivateNotes
+	}
+	if j.Notes == "" && len(j.Details) == 0 {
+		i.Journals = slices.Delete(i.Journals, k, k+1)
+	}
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,206 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects/:project/memberships", membershipsIndex)
+	handle(http.MethodPost, "/projects/:project/memberships", membershipsCreate)
+	handle(http.MethodGet, "/memberships/:id", membershipsShow)
+	handle(http.MethodPut, "/memberships/:id", membershipsUpdate)
+	handle(http.MethodPatch, "/memberships/:id", membershipsUpdate)
+	handle(http.MethodDelete, "/memberships/:id", membershipsDestroy)
+}
+
+func membershipsIndex(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	memberships := []model.Membership{}
+	for _, id := range sortedIds(s.memberships) {
+		if m := s.memberships[id]; refId(m.Project) == p.Id {
+			memberships = append(memberships, s.renderMembership(m))
+		}
+	}
+	return http.StatusOK, paginate(r, "memberships", memberships), nil
+}
+
+func (s *Server) renderMembership(m *model.Membership) model.Membership {
+	out := model.Membership{Id: m.Id, Project: s.projectRef(refId(m.Project)), Roles: []model.MembershipRole{}}
+	if m.User != nil {
+		out.User = s.principalRef(m.User.Id)
+	}
+	if m.Group != nil {
+		out.Group = s.principalRef(m.Group.Id)
+	}
+	for _, role := range m.Roles {
+		out.Roles = append(out.Roles, model.MembershipRole{Id: role.Id, Name: s.roleRef(role.Id).Name, Inherited: role.Inherited})
+	}
+	return out
+}
+
+// membership returns the membership of the path variable id, visible to the
+// user of r.
+func (s *Server) membership(r *request) (*model.Membership, *model.Project, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	m, ok := s.memberships[id]
+	if !ok {
+		return nil, nil, errNotFound
+	}
+	p, ok := s.projects[refId(m.Project)]
+	if !ok || !s.projectVisible(r, p) {
+		return nil, nil, errNotFound
+	}
+	return m, p, nil
+}
+
+func membershipsShow(s *Server, r *request) (int, any, error) {
+	m, _, err := s.membership(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"membership": s.renderMembership(m)}, nil
+}
+
+type membershipInput struct {
+	UserId  int    `json:"user_id"`
+	UserIds []int  `json:"user_ids"`
+	RoleIds *[]int `json:"role_ids"`
+}
+
+// roles returns the roles of the input, validated.
+func (in *membershipInput) roles(s *Server, v *validation) []model.MembershipRole {
+	roles := []model.MembershipRole{}
+	if in.RoleIds != nil {
+		for _, id := range *in.RoleIds {
+			if _, ok := s.roles[id]; ok {
+				roles = append(roles, model.MembershipRole{Id: id})
+			}
+		}
+	}
+	if len(roles) == 0 {
+		v.add("Role cannot be empty")
+	}
+	return roles
+}
+
+func membershipsCreate(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_members"); err != nil {
+		return 0, nil, err
+	}
+
+	in := membershipInput{}
+	if err := r.decode("membership", &in); err != nil {
+		return 0, nil, err
+	}
+
+	v := validation{}
+	roles := in.roles(s, &v)
+
+	principals := in.UserIds
+	if in.UserId != 0 {
+		principals = append([]int{in.UserId}, principals...)
+	}
+	if len(principals) == 0 {
+		v.add("Principal cannot be blank")
+	}
+
+	memberships := []*model.Membership{}
+	for _, pid := range principals {
+		m := &model.Membership{Project: &model.Ref{Id: p.Id}, Roles: slices.Clone(roles)}
+		switch _, isUser := s.users[pid]; {
+		case isUser:
+			m.User = &model.Ref{Id: pid}
+		case s.groups[pid] != nil:
+			m.Group = &model.Ref{Id: pid}
+		default:
+			v.add("Principal cannot be blank")
+			continue
+		}
+		for _, other := range s.memberships {
+			if refId(other.Project) == p.Id && (m.User != nil && refId(other.User) == pid || m.Group != nil && refId(other.Group) == pid) {
+				v.add("User has already been taken")
+			}
+		}
+		memberships = append(memberships, m)
+	}
+	if err := v.err(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range memberships {
+		m.Id = s.nextId("membership")
+		s.memberships[m.Id] = m
+	}
+	return http.StatusCreated, map[string]any{"membership": s.renderMembership(memberships[0])}, nil
+}
+
+func membershipsUpdate(s *Server, r *request) (int, any, error) {
+	m, p, err := s.membership(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_members"); err != nil {
+		return 0, nil, err
+	}
+
+	in := membershipInput{}
+	if err := r.decode("membership", &in); err != nil {
+		return 0, nil, err
+	}
+	if in.RoleIds == nil {
+		return http.StatusNoContent, nil, nil
+	}
+
+	v := validation{}
+	roles := in.roles(s, &v)
+	if err := v.err(); err != nil {
+		return 0, nil, err
+	}
+	m.Roles = roles
+	return http.StatusNoContent, nil, nil
+}
+
+func membershipsDestroy(s *Server, r *request) (int, any, error) {
+	m, p, err := s.membership(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_members"); err != nil {
+		return 0, nil, err
+	}
+	if slices.ContainsFunc(m.Roles, func(role model.MembershipRole) bool { return role.Inherited }) {
+		return 0, nil, invalid("Membership is inherited and cannot be deleted")
+	}
+
+	delete(s.memberships, m.Id)
+	return http.StatusNoContent, nil, nil
+}
+
+// assignable reports whether issues of project p can be assigned to the
+// principal id: a user or a group member of the project with an assignable
+// role, the user possibly being a member through a group.
+func (s *Server) assignable(p *model.Project, id int) bool {
+	if u, ok := s.users[id]; ok && u.Status != model.UserStatusActive {
+		return false
+	}
+	for _, role := range s.memberRoles(id, p.Id) {
+		if role.Assignable {
+			return true
+		}
+	}
+	return false
+}
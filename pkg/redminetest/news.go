@@ -0,0 +1,247 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/news", newsIndex)
+	handle(http.MethodPost, "/news", newsCreate)
+	handle(http.MethodGet, "/projects/:project/news", newsIndexProject)
+	handle(http.MethodPost, "/projects/:project/news", newsCreateProject)
+	handle(http.MethodGet, "/news/:id", newsShow)
+	handle(http.MethodPut, "/news/:id", newsUpdate)
+	handle(http.MethodPatch, "/news/:id", newsUpdate)
+	handle(http.MethodDelete, "/news/:id", newsDestroy)
+}
+
+// renderedNews is a news item with the associated data requested.
+type renderedNews struct {
+	News
+	Comments    *[]NewsComment      `json:"comments,omitempty"`
+	Attachments *[]model.Attachment `json:"attachments,omitempty"`
+}
+
+func (s *Server) renderNews(r *request, n *News, includes bool) renderedNews {
+	out := renderedNews{News: *n}
+	out.Project = s.projectRef(refId(n.Project))
+	out.Author = s.principalRef(refId(n.Author))
+	if includes && r.include("comments") {
+		comments := []NewsComment{}
+		for _, c := range n.Comments {
+			c.Author = s.principalRef(refId(c.Author))
+			comments = append(comments, c)
+		}
+		out.Comments = &comments
+	}
+	if includes && r.include("attachments") {
+		attachments := s.attachmentsOf(r, "news", n.Id, "")
+		out.Attachments = &attachments
+	}
+	return out
+}
+
+// newsVisible reports whether the user of r can see news n.
+func (s *Server) newsVisible(r *request, n *News) bool {
+	p, ok := s.projects[refId(n.Project)]
+	return ok && s.projectVisible(r, p) && s.allowed(r, p, "view_news")
+}
+
+// listNews returns the news of the projects of scope, or of every project,
+// newest first.
+func (s *Server) listNews(r *request, scope *model.Project) (int, any, error) {
+	projects := s.projectScope(r, scope)
+
+	news := []*News{}
+	for _, id := range sortedIds(s.news) {
+		n := s.news[id]
+		if projects != nil && !slices.Contains(projects, refId(n.Project)) {
+			continue
+		}
+		if s.newsVisible(r, n) {
+			news = append(news, n)
+		}
+	}
+	slices.SortStableFunc(news, func(a, b *News) int { return b.CreatedOn.Compare(*a.CreatedOn) })
+
+	rendered := []renderedNews{}
+	for _, n := range news {
+		rendered = append(rendered, s.renderNews(r, n, false))
+	}
+	return http.StatusOK, paginate(r, "news", rendered), nil
+}
+
+func newsIndex(s *Server, r *request) (int, any, error) {
+	return s.listNews(r, nil)
+}
+
+func newsIndexProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_news"); err != nil {
+		return 0, nil, err
+	}
+	return s.listNews(r, p)
+}
+
+// newsItem returns the news of the path variable id, visible to the user of
+// r.
+func (s *Server) newsItem(r *request) (*News, *model.Project, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	n, ok := s.news[id]
+	if !ok {
+		return nil, nil, errNotFound
+	}
+	p, ok := s.projects[refId(n.Project)]
+	if !ok || !s.projectVisible(r, p) {
+		return nil, nil, errNotFound
+	}
+	return n, p, nil
+}
+
+func newsShow(s *Server, r *request) (int, any, error) {
+	n, p, err := s.newsItem(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_news"); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"news": s.renderNews(r, n, true)}, nil
+}
+
+type newsInput struct {
+	Title       *string       `json:"title"`
+	Summary     *string       `json:"summary"`
+	Description *string       `json:"description"`
+	Uploads     []uploadInput `json:"uploads"`
+}
+
+func (in *newsInput) apply(s *Server, n *News) error {
+	v := validation{}
+
+	if in.Title != nil {
+		n.Title = strings.TrimSpace(*in.Title)
+	}
+	if in.Summary != nil {
+		n.Summary = *in.Summary
+	}
+	if in.Description != nil {
+		n.Description = *in.Description
+	}
+
+	switch {
+	case n.Title == "":
+		v.add("Title cannot be blank")
+	case len(n.Title) > 60:
+		v.add("Title is too long (maximum is 60 characters)")
+	}
+	if len(n.Summary) > 255 {
+		v.add("Summary is too long (maximum is 255 characters)")
+	}
+	if strings.TrimSpace(n.Description) == "" {
+		v.add("Description cannot be blank")
+	}
+	for _, u := range in.Uploads {
+		if _, ok := s.uploads[u.Token]; !ok {
+			v.add("Attachments is invalid")
+			break
+		}
+	}
+	return v.err()
+}
+
+// newsCreate adds news to the project of the project_id of the body.
+func newsCreate(s *Server, r *request) (int, any, error) {
+	var key idOrKey
+	if err := r.decode("project_id", &key); err != nil {
+		return 0, nil, err
+	}
+	p, ok := s.project(string(key))
+	if !ok || !s.projectVisible(r, p) {
+		return 0, nil, errNotFound
+	}
+	return s.createNews(r, p)
+}
+
+func newsCreateProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.createNews(r, p)
+}
+
+func (s *Server) createNews(r *request, p *model.Project) (int, any, error) {
+	if err := s.require(r, p, "manage_news"); err != nil {
+		return 0, nil, err
+	}
+
+	in := newsInput{}
+	if err := r.decode("news", &in); err != nil {
+		return 0, nil, err
+	}
+
+	n := &News{Project: &model.Ref{Id: p.Id}, Author: &model.Ref{Id: r.userId()}, CreatedOn: timePtr(s.now())}
+	if err := in.apply(s, n); err != nil {
+		return 0, nil, err
+	}
+	n.Id = s.nextId("news")
+	s.news[n.Id] = n
+	for _, u := range in.Uploads {
+		s.attach(u, "news", n.Id, "")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func newsUpdate(s *Server, r *request) (int, any, error) {
+	n, p, err := s.newsItem(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_news"); err != nil {
+		return 0, nil, err
+	}
+
+	in := newsInput{}
+	if err := r.decode("news", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *n
+	if err := in.apply(s, &updated); err != nil {
+		return 0, nil, err
+	}
+	*n = updated
+	for _, u := range in.Uploads {
+		s.attach(u, "news", n.Id, "")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func newsDestroy(s *Server, r *request) (int, any, error) {
+	n, p, err := s.newsItem(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_news"); err != nil {
+		return 0, nil, err
+	}
+	s.deleteNews(n.Id)
+	return http.StatusNoContent, nil, nil
+}
+
+// deleteNews deletes news with its attachments.
+func (s *Server) deleteNews(id int) {
+	s.deleteAttachmentsOf("news", id)
+	delete(s.news, id)
+}
@@ -0,0 +1,442 @@
+package redminetest
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects", projectsIndex)
+	handle(http.MethodPost, "/projects", projectsCreate)
+	handle(http.MethodGet, "/projects/:project", projectsShow)
+	handle(http.MethodPut, "/projects/:project", projectsUpdate)
+	handle(http.MethodPatch, "/projects/:project", projectsUpdate)
+	handle(http.MethodDelete, "/projects/:project", projectsDestroy)
+	handle(http.MethodPut, "/projects/:project/archive", projectsArchive)
+	handle(http.MethodPost, "/projects/:project/archive", projectsArchive)
+	handle(http.MethodPut, "/projects/:project/unarchive", projectsUnarchive)
+	handle(http.MethodPost, "/projects/:project/unarchive", projectsUnarchive)
+}
+
+// Modules are the project modules, enabled by default on new projects.
+var Modules = []string{
+	"issue_tracking", "time_tracking", "news", "documents", "files", "wiki",
+	"repository", "boards", "calendar", "gantt",
+}
+
+var identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9\-_]*$`)
+
+var projectFields = map[string]field[*model.Project]{
+	"id":         {kindInt, func(p *model.Project) []string { return intValue(p.Id) }},
+	"name":       {kindString, func(p *model.Project) []string { return stringValue(p.Name) }},
+	"identifier": {kindString, func(p *model.Project) []string { return stringValue(p.Identifier) }},
+	"status":     {kindInt, func(p *model.Project) []string { return intValue(p.Status) }},
+	"parent_id":  {kindInt, func(p *model.Project) []string { return intValue(refId(p.Parent)) }},
+	"is_public":  {kindInt, func(p *model.Project) []string { return boolValue(p.IsPublic) }},
+	"created_on": {kindTime, func(p *model.Project) []string { return timeValue(p.CreatedOn) }},
+	"updated_on": {kindTime, func(p *model.Project) []string { return timeValue(p.UpdatedOn) }},
+}
+
+// treeKey orders projects like the project tree, siblings by name.
+func (s *Server) treeKey(p *model.Project) string {
+	names := []string{strings.ToLower(p.Name)}
+	for _, id := range s.ancestors(p.Id) {
+		names = append([]string{strings.ToLower(s.projects[id].Name)}, names...)
+	}
+	return strings.Join(names, "\x00")
+}
+
+func projectsIndex(s *Server, r *request) (int, any, error) {
+	projects := []*model.Project{}
+	for _, id := range sortedIds(s.projects) {
+		p := s.projects[id]
+		if !s.projectVisible(r, p) {
+			continue
+		}
+		if p.Status == model.ProjectStatusArchived && r.URL.Query().Get("status") == "" {
+			continue
+		}
+		projects = append(projects, p)
+	}
+
+	projects = filter(r, projects, projectFields)
+	slices.SortStableFunc(projects, func(a, b *model.Project) int { return strings.Compare(s.treeKey(a), s.treeKey(b)) })
+	sortBy(r, projects, projectFields, "")
+
+	rendered := []model.Project{}
+	for _, p := range projects {
+		rendered = append(rendered, s.renderProject(r, p))
+	}
+	return http.StatusOK, paginate(r, "projects", rendered), nil
+}
+
+func projectsShow(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if p.Status == model.ProjectStatusArchived {
+		return 0, nil, errForbidden
+	}
+	return http.StatusOK, map[string]any{"project": s.renderProject(r, p)}, nil
+}
+
+func (s *Server) renderProject(r *request, p *model.Project) model.Project {
+	out := *p
+	out.Parent = nil
+	if p.Parent != nil {
+		out.Parent = s.projectRef(p.Parent.Id)
+	}
+	out.CustomFields = renderCustomFields(p.CustomFields, s.customFieldsOf("project", 0))
+	out.Trackers = nil
+	out.IssueCategories = nil
+	out.EnabledModules = nil
+	out.TimeEntryActivities = nil
+	out.IssueCustomFields = nil
+
+	if r.include("trackers") {
+		out.Trackers = []model.Ref{}
+		for _, id := range s.projectTrackers(p) {
+			out.Trackers = append(out.Trackers, *s.trackerRef(id))
+		}
+	}
+	if r.include("issue_categories") {
+		out.IssueCategories = []model.Ref{}
+		for _, id := range sortedIds(s.categories) {
+			if c := s.categories[id]; refId(c.Project) == p.Id {
+				out.IssueCategories = append(out.IssueCategories, model.Ref{Id: id, Name: c.Name})
+			}
+		}
+	}
+	if r.include("enabled_modules") {
+		out.EnabledModules = []model.Ref{}
+		if p.EnabledModules == nil {
+			for i, name := range Modules {
+				out.EnabledModules = append(out.EnabledModules, model.Ref{Id: i + 1, Name: name})
+			}
+		} else {
+			out.EnabledModules = append(out.EnabledModules, p.EnabledModules...)
+		}
+	}
+	if r.include("time_entry_activities") {
+		out.TimeEntryActivities = []model.Ref{}
+		for _, a := range s.activities {
+			if a.Active {
+				out.TimeEntryActivities = append(out.TimeEntryActivities, model.Ref{Id: a.Id, Name: a.Name})
+			}
+		}
+	}
+	if r.include("issue_custom_fields") {
+		out.IssueCustomFields = []model.Ref{}
+		for _, cf := range s.projectIssueCustomFields(p) {
+			out.IssueCustomFields = append(out.IssueCustomFields, model.Ref{Id: cf.Id, Name: cf.Name})
+		}
+	}
+	return out
+}
+
+// projectTrackers returns the IDs of the trackers of a project. A project
+// without trackers set uses every tracker.
+func (s *Server) projectTrackers(p *model.Project) []int {
+	if p.Trackers == nil {
+		return sortedIds(s.trackers)
+	}
+	ids := []int{}
+	for _, t := range p.Trackers {
+		ids = append(ids, t.Id)
+	}
+	return ids
+}
+
+// projectIssueCustomFields returns the issue custom fields of a project. A
+// project without issue custom fields set uses every field.
+func (s *Server) projectIssueCustomFields(p *model.Project) []CustomField {
+	fields := []CustomField{}
+	for _, cf := range s.customFields {
+		if cf.CustomizedType != "issue" {
+			continue
+		}
+		if p.IssueCustomFields != nil && !slices.ContainsFunc(p.IssueCustomFields, func(r model.Ref) bool { return r.Id == cf.Id }) {
+			continue
+		}
+		fields = append(fields, cf)
+	}
+	return fields
+}
+
+type projectInput struct {
+	customValues
+	Name                *string   `json:"name"`
+	Identifier          *string   `json:"identifier"`
+	Description         *string   `json:"description"`
+	Homepage            *string   `json:"homepage"`
+	IsPublic            *bool     `json:"is_public"`
+	InheritMembers      *bool     `json:"inherit_members"`
+	ParentId            optInt    `json:"parent_id"`
+	TrackerIds          *[]int    `json:"tracker_ids"`
+	EnabledModuleNames  *[]string `json:"enabled_module_names"`
+	IssueCustomFieldIds *[]int    `json:"issue_custom_field_ids"`
+}
+
+// apply applies the input to project p and validates the result.
+func (in *projectInput) apply(s *Server, r *request, p *model.Project, isNew bool) error {
+	v := validation{}
+
+	if in.Name != nil {
+		p.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Identifier != nil && isNew {
+		p.Identifier = *in.Identifier
+	}
+	if in.Description != nil {
+		p.Description = *in.Description
+	}
+	if in.Homepage != nil {
+		p.Homepage = *in.Homepage
+	}
+	if in.IsPublic != nil {
+		p.IsPublic = *in.IsPublic
+	}
+	if in.InheritMembers != nil {
+		p.InheritMembers = *in.InheritMembers
+	}
+
+	if in.ParentId.set {
+		p.Parent = nil
+		if in.ParentId.v != 0 {
+			parent, ok := s.projects[in.ParentId.v]
+			if !ok || parent.Id == p.Id || slices.Contains(s.ancestors(parent.Id), p.Id) || !s.allowed(r, parent, "add_subprojects") {
+				v.add("Subproject of is invalid")
+			} else {
+				p.Parent = &model.Ref{Id: parent.Id}
+			}
+		}
+	}
+
+	if in.TrackerIds != nil {
+		p.Trackers = []model.Ref{}
+		for _, id := range *in.TrackerIds {
+			if _, ok := s.trackers[id]; ok {
+				p.Trackers = append(p.Trackers, model.Ref{Id: id})
+			}
+		}
+	}
+
+	if in.EnabledModuleNames != nil {
+		modules := []model.Ref{}
+		for _, name := range *in.EnabledModuleNames {
+			if !slices.Contains(Modules, name) {
+				continue
+			}
+			if i := slices.IndexFunc(p.EnabledModules, func(m model.Ref) bool { return m.Name == name }); i >= 0 {
+				modules = append(modules, p.EnabledModules[i])
+			} else {
+				modules = append(modules, model.Ref{Id: s.nextId("enabled_module"), Name: name})
+			}
+		}
+		p.EnabledModules = modules
+	}
+
+	if in.IssueCustomFieldIds != nil {
+		p.IssueCustomFields = []model.Ref{}
+		for _, id := range *in.IssueCustomFieldIds {
+			if cf, ok := s.customField(id); ok && cf.CustomizedType == "issue" {
+				p.IssueCustomFields = append(p.IssueCustomFields, model.Ref{Id: id})
+			}
+		}
+	}
+
+	p.CustomFields = s.applyCustomFields(p.CustomFields, s.customFieldsOf("project", 0), in.customValues, isNew, &v)
+
+	if p.Name == "" {
+		v.add("Name cannot be blank")
+	} else if len(p.Name) > 255 {
+		v.add("Name is too long (maximum is 255 characters)")
+	}
+	switch {
+	case p.Identifier == "":
+		v.add("Identifier cannot be blank")
+	case len(p.Identifier) > 100:
+		v.add("Identifier is too long (maximum is 100 characters)")
+	case !identifierPattern.MatchString(p.Identifier):
+		v.add("Identifier is invalid")
+	}
+	for _, other := range s.projects {
+		if other.Id != p.Id && other.Identifier == p.Identifier {
+			v.add("Identifier has already been taken")
+		}
+	}
+
+	return v.err()
+}
+
+func projectsCreate(s *Server, r *request) (int, any, error) {
+	in := projectInput{}
+	if err := r.decode("project", &in); err != nil {
+		return 0, nil, err
+	}
+
+	if !r.admin() {
+		parent, ok := s.projects[in.ParentId.v]
+		if !ok || !s.allowed(r, parent, "add_subprojects") {
+			return 0, nil, errForbidden
+		}
+	}
+
+	now := s.now()
+	p := &model.Project{Status: model.ProjectStatusActive, CreatedOn: timePtr(now), UpdatedOn: timePtr(now)}
+	if in.EnabledModuleNames == nil {
+		names := slices.Clone(Modules)
+		in.EnabledModuleNames = &names
+	}
+	if in.IsPublic == nil {
+		p.IsPublic = true
+	}
+	if err := in.apply(s, r, p, true); err != nil {
+		return 0, nil, err
+	}
+
+	p.Id = s.nextId("project")
+	s.projects[p.Id] = p
+
+	// The creator who is not an administrator becomes a manager, like the
+	// "Role given to a non-admin user who creates a project" setting.
+	if !r.admin() {
+		id := s.nextId("membership")
+		s.memberships[id] = &model.Membership{Id: id, Project: &model.Ref{Id: p.Id}, User: &model.Ref{Id: r.userId()}, Roles: []model.MembershipRole{{Id: 1}}}
+	}
+
+	return http.StatusCreated, map[string]any{"project": s.renderProject(r, p)}, nil
+}
+
+func projectsUpdate(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "edit_project"); err != nil {
+		return 0, nil, err
+	}
+
+	in := projectInput{}
+	if err := r.decode("project", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *p
+	if err := in.apply(s, r, &updated, false); err != nil {
+		return 0, nil, err
+	}
+	updated.UpdatedOn = timePtr(s.now())
+	*p = updated
+	return http.StatusNoContent, nil, nil
+}
+
+func projectsDestroy(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+
+	s.deleteProject(p.Id)
+	return http.StatusNoContent, nil, nil
+}
+
+// deleteProject deletes a project with its subprojects and their contents.
+func (s *Server) deleteProject(id int) {
+	for _, child := range sortedIds(s.projects) {
+		if refId(s.projects[child].Parent) == id {
+			s.deleteProject(child)
+		}
+	}
+
+	for iid, i := range s.issues {
+		if refId(i.Project) == id {
+			s.deleteIssue(iid)
+		}
+	}
+	for mid, m := range s.memberships {
+		if refId(m.Project) == id {
+			delete(s.memberships, mid)
+		}
+	}
+	for cid, c := range s.categories {
+		if refId(c.Project) == id {
+			delete(s.categories, cid)
+		}
+	}
+	for vid, v := range s.versions {
+		if refId(v.Project) == id {
+			s.deleteAttachmentsOf("version", vid)
+			delete(s.versions, vid)
+		}
+	}
+	for eid, e := range s.timeEntries {
+		if refId(e.Project) == id {
+			delete(s.timeEntries, eid)
+		}
+	}
+	for key, w := range s.wikiPages {
+		if w.projectId == id {
+			s.deleteWikiPage(key)
+		}
+	}
+	for nid, n := range s.news {
+		if refId(n.Project) == id {
+			s.deleteNews(nid)
+		}
+	}
+	for qid, q := range s.queries {
+		if q.ProjectId != nil && *q.ProjectId == id {
+			delete(s.queries, qid)
+		}
+	}
+	s.deleteAttachmentsOf("project", id)
+	delete(s.projects, id)
+}
+
+func projectsArchive(s *Server, r *request) (int, any, error) {
+	return s.setProjectStatus(r, model.ProjectStatusArchived)
+}
+
+func projectsUnarchive(s *Server, r *request) (int, any, error) {
+	return s.setProjectStatus(r, model.ProjectStatusActive)
+}
+
+// setProjectStatus archives or unarchives a project with its subprojects.
+func (s *Server) setProjectStatus(r *request, status int) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+	p, ok := s.project(r.vars["project"])
+	if !ok {
+		return 0, nil, errNotFound
+	}
+
+	if status == model.ProjectStatusActive && p.Parent != nil {
+		if parent := s.projects[p.Parent.Id]; parent != nil && parent.Status == model.ProjectStatusArchived {
+			return 0, nil, invalid("Parent project is archived")
+		}
+	}
+
+	ids := []int{p.Id}
+	if status == model.ProjectStatusArchived {
+		for _, id := range sortedIds(s.projects) {
+			if slices.Contains(s.ancestors(id), p.Id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, id := range ids {
+		s.projects[id].Status = status
+		s.projects[id].UpdatedOn = timePtr(s.now())
+	}
+	return http.StatusNoContent, nil, nil
+}
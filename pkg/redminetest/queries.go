@@ -0,0 +1,38 @@
+package redminetest
+
+import (
+	"net/http"
+)
+
+func init() {
+	handle(http.MethodGet, "/queries", queriesIndex)
+}
+
+// queryVisible reports whether the user of r can see query q: the public
+// queries of the visible projects, and the private queries of the user.
+func (s *Server) queryVisible(r *request, q *Query) bool {
+	if r.admin() {
+		return true
+	}
+	if q.ProjectId != nil {
+		p, ok := s.projects[*q.ProjectId]
+		if !ok || !s.projectVisible(r, p) {
+			return false
+		}
+	}
+	return q.IsPublic || r.user != nil && q.UserId == r.userId()
+}
+
+// queriesIndex lists the visible queries. The owner of a query is not
+// rendered.
+func queriesIndex(s *Server, r *request) (int, any, error) {
+	queries := []Query{}
+	for _, id := range sortedIds(s.queries) {
+		if q := s.queries[id]; s.queryVisible(r, q) {
+			out := *q
+			out.UserId = 0
+			queries = append(queries, out)
+		}
+	}
+	return http.StatusOK, paginate(r, "queries", queries), nil
+}
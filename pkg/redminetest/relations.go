@@ -0,0 +1,178 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/issues/:id/relations", relationsIndex)
+	handle(http.MethodPost, "/issues/:id/relations", relationsCreate)
+	handle(http.MethodGet, "/relations/:id", relationsShow)
+	handle(http.MethodDelete, "/relations/:id", relationsDestroy)
+}
+
+// reverseRelations maps the reverse relation types to the types stored.
+var reverseRelations = map[string]string{
+	"duplicated":  "duplicates",
+	"blocked":     "blocks",
+	"follows":     "precedes",
+	"copied_from": "copied_to",
+}
+
+var relationTypes = []string{"relates", "duplicates", "blocks", "precedes", "copied_to"}
+
+// issueRelations returns the relations of issue id with visible issues.
+func (s *Server) issueRelations(r *request, id int) []model.Relation {
+	relations := []model.Relation{}
+	for _, rid := range sortedIds(s.relations) {
+		rel := s.relations[rid]
+		other := rel.IssueToId
+		switch id {
+		case rel.IssueId:
+		case rel.IssueToId:
+			other = rel.IssueId
+		default:
+			continue
+		}
+		if i, ok := s.issues[other]; ok && s.issueVisible(r, i) {
+			relations = append(relations, *rel)
+		}
+	}
+	return relations
+}
+
+func relationsIndex(s *Server, r *request) (int, any, error) {
+	i, _, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"relations": s.issueRelations(r, i.Id)}, nil
+}
+
+// precedes reports whether issue from precedes or blocks issue to, directly
+// or through other issues.
+func (s *Server) precedes(from, to int, seen map[int]bool) bool {
+	if from == to {
+		return true
+	}
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	for _, rel := range s.relations {
+		if rel.IssueId == from && (rel.RelationType == "precedes" || rel.RelationType == "blocks") && s.precedes(rel.IssueToId, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func relationsCreate(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_issue_relations"); err != nil {
+		return 0, nil, err
+	}
+
+	var in struct {
+		IssueToId    idOrKey `json:"issue_to_id"`
+		RelationType string  `json:"relation_type"`
+		Delay        optInt  `json:"delay"`
+	}
+	if err := r.decode("relation", &in); err != nil {
+		return 0, nil, err
+	}
+
+	rel := &model.Relation{IssueId: i.Id, RelationType: in.RelationType}
+	if rel.RelationType == "" {
+		rel.RelationType = "relates"
+	}
+
+	v := validation{}
+	to, ok := s.issues[atoi(string(in.IssueToId))]
+	if !ok || !s.issueVisible(r, to) {
+		v.add("Related issue cannot be blank")
+		return 0, nil, v.err()
+	}
+	rel.IssueToId = to.Id
+
+	if t, ok := reverseRelations[rel.RelationType]; ok {
+		rel.RelationType = t
+		rel.IssueId, rel.IssueToId = rel.IssueToId, rel.IssueId
+	}
+	if !slices.Contains(relationTypes, rel.RelationType) {
+		v.add("Type is not included in the list")
+	}
+	if rel.RelationType == "precedes" {
+		delay := in.Delay.v
+		rel.Delay = &delay
+	}
+
+	switch {
+	case rel.IssueId == rel.IssueToId:
+		v.add("Related issue is invalid")
+	case (rel.RelationType == "precedes" || rel.RelationType == "blocks") && s.precedes(rel.IssueToId, rel.IssueId, map[int]bool{}):
+		v.add("This relation would create a circular dependency")
+	}
+	for _, other := range s.relations {
+		if other.IssueId == rel.IssueId && other.IssueToId == rel.IssueToId || other.IssueId == rel.IssueToId && other.IssueToId == rel.IssueId {
+			v.add("Related issue has already been taken")
+			break
+		}
+	}
+	if err := v.err(); err != nil {
+		return 0, nil, err
+	}
+
+	rel.Id = s.nextId("relation")
+	s.relations[rel.Id] = rel
+
+	now := s.now()
+	for _, id := range []int{rel.IssueId, rel.IssueToId} {
+		s.issues[id].UpdatedOn = timePtr(now)
+	}
+	return http.StatusCreated, map[string]any{"relation": rel}, nil
+}
+
+// relation returns the relation of the path variable id between issues
+// visible to the user of r.
+func (s *Server) relation(r *request) (*model.Relation, *model.Issue, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	rel, ok := s.relations[id]
+	if !ok {
+		return nil, nil, errNotFound
+	}
+	from, to := s.issues[rel.IssueId], s.issues[rel.IssueToId]
+	if from == nil || to == nil || !s.issueVisible(r, from) || !s.issueVisible(r, to) {
+		return nil, nil, errNotFound
+	}
+	return rel, from, nil
+}
+
+func relationsShow(s *Server, r *request) (int, any, error) {
+	rel, _, err := s.relation(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"relation": rel}, nil
+}
+
+func relationsDestroy(s *Server, r *request) (int, any, error) {
+	rel, from, err := s.relation(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, s.projects[refId(from.Project)], "manage_issue_relations"); err != nil {
+		return 0, nil, err
+	}
+	delete(s.relations, rel.Id)
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,470 @@
+package redminetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// The records keep references by ID. The helpers below return references
+// with the current names, so that renaming a record shows everywhere.
+
+func (s *Server) statusRef(id int) *model.Ref {
+	if st, ok := s.statuses[id]; ok {
+		return ref(id, st.Name)
+	}
+	return ref(id, "")
+}
+
+func (s *Server) issueStatus(id int) *model.Status {
+	if st, ok := s.statuses[id]; ok {
+		return &model.Status{Id: id, Name: st.Name, IsClosed: st.IsClosed}
+	}
+	return &model.Status{Id: id}
+}
+
+func (s *Server) trackerRef(id int) *model.Ref {
+	if t, ok := s.trackers[id]; ok {
+		return ref(id, t.Name)
+	}
+	return ref(id, "")
+}
+
+func (s *Server) priorityRef(id int) *model.Ref {
+	e, _ := findEnumeration(s.priorities, id)
+	return ref(id, e.Name)
+}
+
+func (s *Server) activityRef(id int) *model.Ref {
+	e, _ := findEnumeration(s.activities, id)
+	return ref(id, e.Name)
+}
+
+func (s *Server) projectRef(id int) *model.Ref {
+	if p, ok := s.projects[id]; ok {
+		return ref(id, p.Name)
+	}
+	return ref(id, "")
+}
+
+func (s *Server) categoryRef(id int) *model.Ref {
+	if c, ok := s.categories[id]; ok {
+		return ref(id, c.Name)
+	}
+	return ref(id, "")
+}
+
+func (s *Server) versionRef(id int) *model.Ref {
+	if v, ok := s.versions[id]; ok {
+		return ref(id, v.Name)
+	}
+	return ref(id, "")
+}
+
+func (s *Server) roleRef(id int) *model.Ref {
+	if r, ok := s.roles[id]; ok {
+		return ref(id, r.Name)
+	}
+	return ref(id, "")
+}
+
+// principalRef returns a reference to a user or a group.
+func (s *Server) principalRef(id int) *model.Ref {
+	if u, ok := s.users[id]; ok {
+		return ref(id, u.Name())
+	}
+	if g, ok := s.groups[id]; ok {
+		return ref(id, g.Name)
+	}
+	return ref(id, "")
+}
+
+// refId returns the ID of a reference, or 0 for nil.
+func refId(r *model.Ref) int {
+	if r == nil {
+		return 0
+	}
+	return r.Id
+}
+
+// project returns the project named by an ID or an identifier.
+func (s *Server) project(key string) (*model.Project, bool) {
+	if id, err := strconv.Atoi(key); err == nil {
+		p, ok := s.projects[id]
+		return p, ok
+	}
+	for _, p := range s.projects {
+		if p.Identifier == key {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// visibleProject returns the project of the path variable name, answering
+// 404 when it does not exist or is not visible.
+func (s *Server) visibleProject(r *request, name string) (*model.Project, error) {
+	p, ok := s.project(r.vars[name])
+	if !ok || !s.projectVisible(r, p) {
+		return nil, errNotFound
+	}
+	return p, nil
+}
+
+// ancestors returns the IDs of the ancestors of project id, nearest first.
+func (s *Server) ancestors(id int) []int {
+	ids := []int{}
+	for p := s.projects[id]; p != nil && p.Parent != nil; p = s.projects[p.Parent.Id] {
+		if slices.Contains(ids, p.Parent.Id) {
+			break
+		}
+		ids = append(ids, p.Parent.Id)
+	}
+	return ids
+}
+
+// optInt is an optional number of a request body. It can be cleared with
+// null or an empty string, and numbers may be given as strings.
+type optInt struct {
+	set bool
+	v   int
+
+	// null Whether the number was cleared, unlike a given 0.
+	null bool
+}
+
+func (o *optInt) UnmarshalJSON(b []byte) error {
+	o.set = true
+	o.v = 0
+	o.null = false
+
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case nil:
+		o.null = true
+	case float64:
+		o.v = int(x)
+	case string:
+		if x == "" {
+			o.null = true
+			return nil
+		}
+		n, err := strconv.Atoi(x)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", x)
+		}
+		o.v = n
+	default:
+		return fmt.Errorf("invalid number %s", b)
+	}
+	return nil
+}
+
+// optFloat is an optional decimal of a request body, cleared like optInt.
+type optFloat struct {
+	set bool
+	v   *float64
+}
+
+func (o *optFloat) UnmarshalJSON(b []byte) error {
+	o.set = true
+	o.v = nil
+
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case nil:
+	case float64:
+		o.v = &x
+	case string:
+		if x == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", x)
+		}
+		o.v = &f
+	default:
+		return fmt.Errorf("invalid number %s", b)
+	}
+	return nil
+}
+
+// optDate is an optional date of a request body, cleared like optInt.
+type optDate struct {
+	set bool
+	v   *openapi_types.Date
+}
+
+func (o *optDate) UnmarshalJSON(b []byte) error {
+	o.set = true
+	o.v = nil
+
+	var v *string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v == nil || *v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.DateOnly, *v)
+	if err != nil {
+		return fmt.Errorf("invalid date %q", *v)
+	}
+	o.v = &openapi_types.Date{Time: t}
+	return nil
+}
+
+// idOrKey is a reference given as an ID or as an identifier.
+type idOrKey string
+
+func (k *idOrKey) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case nil:
+		*k = ""
+	case float64:
+		*k = idOrKey(strconv.Itoa(int(x)))
+	case string:
+		*k = idOrKey(x)
+	default:
+		return fmt.Errorf("invalid reference %s", b)
+	}
+	return nil
+}
+
+// customValues is the custom field input of a record, given as a list of
+// custom_fields or as the custom_field_values map.
+type customValues struct {
+	CustomFields []struct {
+		Id    int `json:"id"`
+		Value any `json:"value"`
+	} `json:"custom_fields"`
+	CustomFieldValues map[string]any `json:"custom_field_values"`
+}
+
+// values returns the given values by custom field ID.
+func (c customValues) values() map[int]any {
+	values := map[int]any{}
+	for k, v := range c.CustomFieldValues {
+		if id, err := strconv.Atoi(k); err == nil {
+			values[id] = v
+		}
+	}
+	for _, cf := range c.CustomFields {
+		values[cf.Id] = cf.Value
+	}
+	return values
+}
+
+// normalize converts a JSON value to the strings stored as custom values.
+func normalize(v any) []string {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if x == "" {
+			return nil
+		}
+		return []string{x}
+	case float64:
+		return []string{strconv.FormatFloat(x, 'f', -1, 64)}
+	case bool:
+		if x {
+			return []string{"1"}
+		}
+		return []string{"0"}
+	case []any:
+		values := []string{}
+		for _, e := range x {
+			values = append(values, normalize(e)...)
+		}
+		return values
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+// customFieldsOf returns the definitions of the custom fields of a record
+// type. For issues, trackerId selects the fields of the tracker.
+func (s *Server) customFieldsOf(typ string, trackerId int) []CustomField {
+	fields := []CustomField{}
+	for _, cf := range s.customFields {
+		if cf.CustomizedType != typ {
+			continue
+		}
+		if typ == "issue" && len(cf.Trackers) > 0 && !slices.ContainsFunc(cf.Trackers, func(t model.Ref) bool { return t.Id == trackerId }) {
+			continue
+		}
+		fields = append(fields, cf)
+	}
+	return fields
+}
+
+// validValue reports whether a value is acceptable for the custom field.
+func (s *Server) validValue(cf CustomField, v string) string {
+	switch cf.FieldFormat {
+	case "int":
+		if _, err := strconv.Atoi(v); err != nil {
+			return "is not a number"
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "is invalid"
+		}
+	case "bool":
+		if v != "0" && v != "1" {
+			return "is not included in the list"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return "is not a valid date"
+		}
+	case "list":
+		if !slices.ContainsFunc(cf.PossibleValues, func(p PossibleValue) bool { return p.Value == v }) {
+			return "is not included in the list"
+		}
+	case "user":
+		id, _ := strconv.Atoi(v)
+		if _, ok := s.users[id]; !ok {
+			return "is not included in the list"
+		}
+	case "version":
+		id, _ := strconv.Atoi(v)
+		if _, ok := s.versions[id]; !ok {
+			return "is not included in the list"
+		}
+	}
+	return ""
+}
+
+// applyCustomFields merges the custom field input into the values of a
+// record and validates them. Fields which do not apply are ignored, and new
+// records get the default values.
+func (s *Server) applyCustomFields(current []model.CustomField, fields []CustomField, in customValues, isNew bool, v *validation) []model.CustomField {
+	given := in.values()
+
+	values := []model.CustomField{}
+	for _, cf := range fields {
+		var value []string
+		if raw, ok := given[cf.Id]; ok {
+			value = normalize(raw)
+		} else if i := slices.IndexFunc(current, func(c model.CustomField) bool { return c.Id == cf.Id }); i >= 0 {
+			value = current[i].Values()
+		} else if isNew && cf.DefaultValue != "" {
+			value = []string{cf.DefaultValue}
+		}
+
+		if len(value) == 0 && cf.IsRequired {
+			v.add("%s cannot be blank", cf.Name)
+		}
+		if len(value) > 1 && !cf.Multiple {
+			value = value[:1]
+		}
+		for _, e := range value {
+			if msg := s.validValue(cf, e); msg != "" {
+				v.add("%s %s", cf.Name, msg)
+				break
+			}
+		}
+
+		values = append(values, model.CustomField{Id: cf.Id, Value: value})
+	}
+	return values
+}
+
+// renderCustomFields returns the custom values of a record for the fields,
+// blank values included.
+func renderCustomFields(values []model.CustomField, fields []CustomField) []model.CustomField {
+	rendered := []model.CustomField{}
+	for _, cf := range fields {
+		var value []string
+		if i := slices.IndexFunc(values, func(c model.CustomField) bool { return c.Id == cf.Id }); i >= 0 {
+			value = values[i].Values()
+		}
+
+		out := model.CustomField{Id: cf.Id, Name: cf.Name, Multiple: cf.Multiple}
+		switch {
+		case cf.Multiple && value == nil:
+			out.Value = []string{}
+		case cf.Multiple:
+			out.Value = value
+		case len(value) > 0:
+			out.Value = value[0]
+		default:
+			out.Value = ""
+		}
+		rendered = append(rendered, out)
+	}
+	return rendered
+}
+
+// changedValues returns the journal details of the custom values changed
+// between old and new.
+func changedValues(old, new []model.CustomField) []model.JournalDetail {
+	details := []model.JournalDetail{}
+	for _, n := range new {
+		var before []string
+		if i := slices.IndexFunc(old, func(c model.CustomField) bool { return c.Id == n.Id }); i >= 0 {
+			before = old[i].Values()
+		}
+		after := n.Values()
+		if slices.Equal(before, after) {
+			continue
+		}
+		details = append(details, detail("cf", strconv.Itoa(n.Id), strings.Join(before, ","), strings.Join(after, ",")))
+	}
+	return details
+}
+
+// detail returns a journal detail, with blank values omitted.
+func detail(property, name, old, new string) model.JournalDetail {
+	d := model.JournalDetail{Property: property, Name: name}
+	if old != "" {
+		d.OldValue = &old
+	}
+	if new != "" {
+		d.NewValue = &new
+	}
+	return d
+}
+
+func dateString(d *openapi_types.Date) string {
+	if d == nil {
+		return ""
+	}
+	return d.Format(time.DateOnly)
+}
+
+func floatString(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+func idString(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func dateValue(d *openapi_types.Date) []string {
+	return stringValue(dateString(d))
+}
@@ -0,0 +1,233 @@
+package redminetest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/search", searchIndex)
+	handle(http.MethodGet, "/projects/:project/search", searchIndexProject)
+}
+
+// searchResult is a record matching a search.
+type searchResult struct {
+	Id          int       `json:"id"`
+	Title       string    `json:"title"`
+	Type        string    `json:"type"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	Datetime    time.Time `json:"datetime"`
+}
+
+// searchTypes are the kinds of searched records, selected by the parameters
+// of their names. All are searched when none is selected.
+var searchTypes = []string{"issues", "news", "wiki_pages", "projects"}
+
+var searchTokenPattern = regexp.MustCompile(`"[^"]+"|\S+`)
+
+// searchTokens returns the words and the quoted phrases of a question, in
+// lower case. As in Redmine, words of one character are ignored and only the
+// first five are kept.
+func searchTokens(question string) []string {
+	tokens := []string{}
+	for _, t := range searchTokenPattern.FindAllString(question, -1) {
+		t = strings.ToLower(strings.TrimSpace(strings.Trim(t, `"`)))
+		if len([]rune(t)) > 1 && !slices.Contains(tokens, t) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens[:min(len(tokens), 5)]
+}
+
+// matcher reports whether the texts of a record match the tokens, all of
+// them or any.
+func matcher(tokens []string, allWords bool) func(texts ...string) bool {
+	return func(texts ...string) bool {
+		for _, t := range tokens {
+			found := slices.ContainsFunc(texts, func(text string) bool { return strings.Contains(strings.ToLower(text), t) })
+			if found != allWords {
+				return found
+			}
+		}
+		return allWords
+	}
+}
+
+func searchIndex(s *Server, r *request) (int, any, error) {
+	return s.search(r, nil)
+}
+
+func searchIndexProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.search(r, p)
+}
+
+// searchProjects returns the IDs of the projects searched from project p,
+// nil for all projects, following the scope parameter: all, my_projects,
+// subprojects, or p alone by default.
+func (s *Server) searchProjects(r *request, p *model.Project) []int {
+	switch r.URL.Query().Get("scope") {
+	case "all":
+		return nil
+	case "my_projects":
+		ids := []int{}
+		for _, id := range sortedIds(s.projects) {
+			if len(s.memberRoles(r.userId(), id)) > 0 {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	case "subprojects":
+		if p == nil {
+			return nil
+		}
+		ids := []int{p.Id}
+		for _, id := range sortedIds(s.projects) {
+			if slices.Contains(s.ancestors(id), p.Id) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	if p == nil {
+		return nil
+	}
+	return []int{p.Id}
+}
+
+// search answers the results of the q parameter in the projects of the
+// search from project p, newest first. As in Redmine, the all_words and
+// titles_only flags are set by any non-empty value, and the projects are
+// not searched in a project.
+func (s *Server) search(r *request, p *model.Project) (int, any, error) {
+	q := r.URL.Query()
+	tokens := searchTokens(q.Get("q"))
+	allWords := !q.Has("all_words") || q.Get("all_words") != ""
+	titlesOnly := q.Get("titles_only") != ""
+	openIssues := q.Get("open_issues") != ""
+
+	types := []string{}
+	for _, t := range searchTypes {
+		if q.Get(t) != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		types = searchTypes
+	}
+	if p != nil {
+		types = slices.DeleteFunc(slices.Clone(types), func(t string) bool { return t == "projects" })
+	}
+
+	projects := s.searchProjects(r, p)
+	inScope := func(id int) bool { return projects == nil || slices.Contains(projects, id) }
+	match := matcher(tokens, allWords)
+	base := "http://" + r.Host
+
+	results := []searchResult{}
+	if len(tokens) == 0 {
+		return http.StatusOK, paginate(r, "results", results), nil
+	}
+
+	if slices.Contains(types, "issues") {
+		for _, id := range sortedIds(s.issues) {
+			i := s.issues[id]
+			if !inScope(refId(i.Project)) || !s.issueVisible(r, i) {
+				continue
+			}
+			status := s.issueStatus(i.Status.Id)
+			if openIssues && status.IsClosed {
+				continue
+			}
+			texts := []string{i.Subject}
+			if !titlesOnly {
+				texts = append(texts, i.Description)
+				ip := s.projects[refId(i.Project)]
+				for _, j := range i.Journals {
+					if !j.PrivateNotes || s.allowed(r, ip, "view_private_notes") || refId(j.User) == r.userId() {
+						texts = append(texts, j.Notes)
+					}
+				}
+			}
+			if !match(texts...) {
+				continue
+			}
+			typ := "issue"
+			if status.IsClosed {
+				typ = "issue-closed"
+			}
+			title := fmt.Sprintf("%s #%d (%s): %s", nameOf(s.trackerRef(refId(i.Tracker))), i.Id, status.Name, i.Subject)
+			results = append(results, searchResult{i.Id, title, typ, fmt.Sprintf("%s/issues/%d", base, i.Id), i.Description, *i.CreatedOn})
+		}
+	}
+
+	if slices.Contains(types, "news") {
+		for _, id := range sortedIds(s.news) {
+			n := s.news[id]
+			if !inScope(refId(n.Project)) || !s.newsVisible(r, n) {
+				continue
+			}
+			texts := []string{n.Title}
+			if !titlesOnly {
+				texts = append(texts, n.Summary, n.Description)
+			}
+			if match(texts...) {
+				results = append(results, searchResult{n.Id, n.Title, "news", fmt.Sprintf("%s/news/%d", base, n.Id), n.Description, *n.CreatedOn})
+			}
+		}
+	}
+
+	if slices.Contains(types, "wiki_pages") {
+		keys := make([]string, 0, len(s.wikiPages))
+		for key := range s.wikiPages {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			w := s.wikiPages[key]
+			wp, ok := s.projects[w.projectId]
+			if !ok || !inScope(wp.Id) || !s.projectVisible(r, wp) || !s.allowed(r, wp, "view_wiki_pages") {
+				continue
+			}
+			c := w.current()
+			texts := []string{w.title}
+			if !titlesOnly {
+				texts = append(texts, c.Text)
+			}
+			if match(texts...) {
+				u := fmt.Sprintf("%s/projects/%s/wiki/%s", base, wp.Identifier, url.PathEscape(w.title))
+				results = append(results, searchResult{w.id, "Wiki: " + w.title, "wiki-page", u, c.Text, *c.UpdatedOn})
+			}
+		}
+	}
+
+	if slices.Contains(types, "projects") {
+		for _, id := range sortedIds(s.projects) {
+			sp := s.projects[id]
+			if !inScope(sp.Id) || !s.projectVisible(r, sp) {
+				continue
+			}
+			texts := []string{sp.Name}
+			if !titlesOnly {
+				texts = append(texts, sp.Identifier, sp.Description)
+			}
+			if match(texts...) {
+				results = append(results, searchResult{sp.Id, "Project: " + sp.Name, "project", base + "/projects/" + sp.Identifier, sp.Description, *sp.CreatedOn})
+			}
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b searchResult) int { return b.Datetime.Compare(a.Datetime) })
+	return http.StatusOK, paginate(r, "results", results), nil
+}
@@ -0,0 +1,509 @@
+// Package redminetest provides an in-memory fake Redmine server for tests.
+//
+// The Server implements the REST endpoints of issues, journals, relations,
+// watchers, projects, memberships, issue categories, versions, users, groups,
+// time entries, wiki pages, attachments, news and files, together with the
+// read-only statuses, trackers, enumerations, roles, custom fields and
+// queries, and the search. It follows Redmine's authentication, pagination,
+// filtering and validation behavior closely enough to run clients without a
+// live instance:
+//
+//	srv := redminetest.NewServer(redminetest.DefaultFixtures())
+//	defer srv.Close()
+//
+//	c, _ := redmine.NewClientWithResponses(srv.URL)
+//
+// In a test, Client does the same and closes the server at the end, and
+// Admin authenticates the requests as the administrator.
+//
+// The state is seeded from Fixtures, which can be loaded from JSON or YAML
+// files with LoadFixtures.
+package redminetest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// Server is a fake Redmine server.
+type Server struct {
+	*httptest.Server
+
+	// LoginRequired rejects anonymous requests, like the "Authentication
+	// required" setting of Redmine.
+	LoginRequired bool
+
+	// Now returns the current time. Nil means time.Now.
+	Now func() time.Time
+
+	mu sync.Mutex
+
+	users        map[int]*User
+	groups       map[int]*model.Group
+	roles        map[int]*model.Role
+	statuses     map[int]*model.Status
+	trackers     map[int]*model.Tracker
+	priorities   []Enumeration
+	activities   []Enumeration
+	customFields []CustomField
+	projects     map[int]*model.Project
+	memberships  map[int]*model.Membership
+	categories   map[int]*Category
+	versions     map[int]*model.Version
+	issues       map[int]*model.Issue
+	relations    map[int]*model.Relation
+	timeEntries  map[int]*model.TimeEntry
+	wikiPages    map[string]*wikiPage
+	attachments  map[int]*attachment
+	uploads      map[string]*attachment
+	news         map[int]*News
+	queries      map[int]*Query
+
+	lastId map[string]int
+}
+
+// NewServer starts a server seeded with f. It panics if f is inconsistent.
+func NewServer(f *Fixtures) *Server {
+	s := NewUnstartedServer(f)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a server seeded with f but not started, so that
+// its settings can be changed before calling Start.
+func NewUnstartedServer(f *Fixtures) *Server {
+	s := &Server{
+		users:       map[int]*User{},
+		groups:      map[int]*model.Group{},
+		roles:       map[int]*model.Role{},
+		statuses:    map[int]*model.Status{},
+		trackers:    map[int]*model.Tracker{},
+		projects:    map[int]*model.Project{},
+		memberships: map[int]*model.Membership{},
+		categories:  map[int]*Category{},
+		versions:    map[int]*model.Version{},
+		issues:      map[int]*model.Issue{},
+		relations:   map[int]*model.Relation{},
+		timeEntries: map[int]*model.TimeEntry{},
+		wikiPages:   map[string]*wikiPage{},
+		attachments: map[int]*attachment{},
+		uploads:     map[string]*attachment{},
+		news:        map[int]*News{},
+		queries:     map[int]*Query{},
+		lastId:      map[string]int{},
+	}
+
+	if f != nil {
+		if err := s.Seed(f); err != nil {
+			panic(fmt.Sprintf("redminetest: %v", err))
+		}
+	}
+
+	s.Server = httptest.NewUnstartedServer(s)
+	return s
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC().Truncate(time.Second)
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// nextId allocates the ID of a new record of kind. Users and groups share the
+// "principal" sequence as they do in Redmine.
+func (s *Server) nextId(kind string) int {
+	s.lastId[kind]++
+	return s.lastId[kind]
+}
+
+// useId records that id is taken in the sequence of kind.
+func (s *Server) useId(kind string, id int) {
+	s.lastId[kind] = max(s.lastId[kind], id)
+}
+
+// apiError is an error response.
+type apiError struct {
+	status int
+	errors []string
+}
+
+func (e *apiError) Error() string {
+	if len(e.errors) == 0 {
+		return http.StatusText(e.status)
+	}
+	return strings.Join(e.errors, ", ")
+}
+
+var (
+	errUnauthorized = &apiError{status: http.StatusUnauthorized}
+	errForbidden    = &apiError{status: http.StatusForbidden}
+	errNotFound     = &apiError{status: http.StatusNotFound}
+)
+
+// invalid returns a validation error answered with 422 and the messages.
+func invalid(messages ...string) error {
+	return &apiError{status: http.StatusUnprocessableEntity, errors: messages}
+}
+
+// validation accumulates validation messages.
+type validation []string
+
+func (v *validation) add(format string, args ...any) {
+	*v = append(*v, fmt.Sprintf(format, args...))
+}
+
+func (v validation) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return invalid(v...)
+}
+
+// request is a request being handled.
+type request struct {
+	*http.Request
+
+	// user is the authenticated user, nil for anonymous requests.
+	user *User
+
+	// format is the extension of the path, such as json or txt.
+	format string
+
+	vars map[string]string
+	body map[string]json.RawMessage
+}
+
+// id returns the path variable name as an ID.
+func (r *request) id(name string) (int, error) {
+	id, err := strconv.Atoi(r.vars[name])
+	if err != nil {
+		return 0, errNotFound
+	}
+	return id, nil
+}
+
+// userId returns the ID of the current user, or 0 for anonymous.
+func (r *request) userId() int {
+	if r.user == nil {
+		return 0
+	}
+	return r.user.Id
+}
+
+func (r *request) admin() bool {
+	return r.user != nil && r.user.Admin
+}
+
+// include reports whether the associated data name was requested.
+func (r *request) include(name string) bool {
+	for _, v := range r.URL.Query()["include"] {
+		for _, i := range strings.Split(v, ",") {
+			if strings.TrimSpace(i) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decode decodes the object named key of the JSON body into v.
+func (r *request) decode(key string, v any) error {
+	raw, ok := r.body[key]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return invalid(err.Error())
+	}
+	return nil
+}
+
+// handler handles a request and returns the status and the body of the
+// response. A nil body answers with no content.
+type handler func(s *Server, r *request) (int, any, error)
+
+type route struct {
+	method   string
+	segments []string
+	handle   handler
+}
+
+// match reports whether the route matches the method and the path segments
+// and stores the variables in vars.
+func (rt *route) match(method string, segments []string, vars map[string]string) bool {
+	if rt.method != method || len(rt.segments) != len(segments) {
+		return false
+	}
+
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, ":") {
+			vars[seg[1:]] = segments[i]
+		} else if seg != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var routes []route
+
+// handle registers h for the method and the path pattern, where segments
+// starting with a colon are variables.
+func handle(method, pattern string, h handler) {
+	routes = append(routes, route{
+		method:   method,
+		segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+		handle:   h,
+	})
+}
+
+// splitFormat removes the extension of the last segment of the path.
+func splitFormat(path string) ([]string, string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	last := segments[len(segments)-1]
+	format := ""
+	if i := strings.LastIndex(last, "."); i > 0 {
+		format = last[i+1:]
+		segments[len(segments)-1] = last[:i]
+	}
+	return segments, format
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, hr *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, format := splitFormat(hr.URL.Path)
+	r := &request{Request: hr, format: format, vars: map[string]string{}}
+
+	var rt *route
+	for i := range routes {
+		if routes[i].match(hr.Method, segments, r.vars) {
+			rt = &routes[i]
+			break
+		}
+		clear(r.vars)
+	}
+
+	status, body, err := s.serve(rt, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch v := body.(type) {
+	case nil:
+		w.WriteHeader(status)
+	case download:
+		if v.contentType != "" {
+			w.Header().Set("Content-Type", v.contentType)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(v.content)
+	case string:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, v)
+	default:
+		writeJSON(w, status, v)
+	}
+}
+
+func (s *Server) serve(rt *route, r *request) (int, any, error) {
+	if rt == nil {
+		return 0, nil, errNotFound
+	}
+
+	if err := s.authenticate(r); err != nil {
+		return 0, nil, err
+	}
+
+	if t := mediaType(r); r.Body != nil && (t == "" || t == "application/json") {
+		buf, err := io.ReadAll(r.Body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(buf) > 0 {
+			if err := json.Unmarshal(buf, &r.body); err != nil {
+				return 0, nil, &apiError{status: http.StatusBadRequest, errors: []string{err.Error()}}
+			}
+		}
+	}
+
+	return rt.handle(s, r)
+}
+
+// mediaType returns the media type of the request body.
+func mediaType(r *request) string {
+	t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return t
+}
+
+// writeJSON writes v as compact JSON without a trailing newline, the way
+// Redmine renders it.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *apiError
+	if !errors.As(err, &e) {
+		e = &apiError{status: http.StatusInternalServerError, errors: []string{err.Error()}}
+	}
+
+	switch e.status {
+	case http.StatusUnprocessableEntity:
+		writeJSON(w, e.status, map[string][]string{"errors": e.errors})
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Basic realm="Redmine API"`)
+		w.WriteHeader(e.status)
+	default:
+		w.WriteHeader(e.status)
+	}
+}
+
+// authenticate identifies the user of the request from its API key or basic
+// credentials, and switches to the user named by X-Redmine-Switch-User when
+// the user is an administrator.
+func (s *Server) authenticate(r *request) error {
+	key := r.Header.Get("X-Redmine-API-Key")
+	if key == "" {
+		key = r.URL.Query().Get("key")
+	}
+
+	switch login, password, ok := r.BasicAuth(); {
+	case key != "":
+		r.user = s.userByKey(key)
+		if r.user == nil {
+			return errUnauthorized
+		}
+	case ok:
+		r.user = s.userByLogin(login)
+		if r.user == nil || r.user.Password != password {
+			// The key can be given as the user name with any password.
+			if r.user = s.userByKey(login); r.user == nil {
+				return errUnauthorized
+			}
+		}
+	}
+
+	if r.user != nil && r.user.Status != model.UserStatusActive {
+		return errUnauthorized
+	}
+
+	if r.user == nil && (s.LoginRequired || r.Method != http.MethodGet) {
+		return errUnauthorized
+	}
+
+	if login := r.Header.Get("X-Redmine-Switch-User"); login != "" && r.admin() {
+		u := s.userByLogin(login)
+		if u == nil || u.Status != model.UserStatusActive {
+			return &apiError{status: http.StatusPreconditionFailed}
+		}
+		r.user = u
+	}
+
+	return nil
+}
+
+func (s *Server) userByKey(key string) *User {
+	for _, u := range s.users {
+		if u.APIKey != "" && u.APIKey == key {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) userByLogin(login string) *User {
+	for _, u := range s.users {
+		if u.Login == login {
+			return u
+		}
+	}
+	return nil
+}
+
+// page is the pagination of an index.
+type page struct {
+	offset int
+	limit  int
+	nometa bool
+}
+
+// pagination reads the offset, limit, page and nometa parameters.
+func pagination(r *request) page {
+	q := r.URL.Query()
+
+	p := page{limit: 25}
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		p.limit = min(v, 100)
+	}
+	if v, err := strconv.Atoi(q.Get("offset")); err == nil && v > 0 {
+		p.offset = v
+	} else if v, err := strconv.Atoi(q.Get("page")); err == nil && v > 0 {
+		p.offset = (v - 1) * p.limit
+	}
+	p.nometa = q.Get("nometa") == "1" || r.Header.Get("X-Redmine-Nometa") == "1"
+	return p
+}
+
+// paginate returns the body of an index of items under key.
+func paginate[T any](r *request, key string, items []T) map[string]any {
+	p := pagination(r)
+
+	body := map[string]any{}
+	start := min(p.offset, len(items))
+	end := min(start+p.limit, len(items))
+	body[key] = items[start:end]
+
+	if !p.nometa {
+		body["total_count"] = len(items)
+		body["offset"] = p.offset
+		body["limit"] = p.limit
+	}
+	return body
+}
+
+// sortedIds returns the keys of m in ascending order.
+func sortedIds[T any](m map[int]T) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ref returns a reference to a record, or nil if id is 0.
+func ref(id int, name string) *model.Ref {
+	if id == 0 {
+		return nil
+	}
+	return &model.Ref{Id: id, Name: name}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,390 @@
+package redminetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func newTestServer(t *testing.T) *Server {
+	f := DefaultFixtures()
+	f.Users = append(f.Users, User{
+		User:   model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith", Mail: "jsmith@example.net"},
+		APIKey: "jsmith",
+	})
+	f.Projects = []model.Project{
+		{Id: 1, Name: "Public", Identifier: "public", IsPublic: true},
+		{Id: 2, Name: "Private", Identifier: "private"},
+	}
+	f.Memberships = []model.Membership{
+		{Id: 1, Project: &model.Ref{Id: 2}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 3}}},
+	}
+	for id := 1; id <= 30; id++ {
+		f.Issues = append(f.Issues, model.Issue{Id: id, Project: &model.Ref{Id: 1}, Subject: fmt.Sprintf("Issue %d", id)})
+	}
+	f.Issues = append(f.Issues,
+		model.Issue{Id: 31, Project: &model.Ref{Id: 2}, Subject: "Private project"},
+		model.Issue{Id: 32, Project: &model.Ref{Id: 1}, Subject: "Private issue", IsPrivate: true},
+	)
+	f.WikiPages = []WikiPage{{Project: "public", WikiPage: model.WikiPage{Title: "Wiki", Text: "start"}}}
+
+	srv := NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends a request with the API key and decodes the JSON response.
+func call(t *testing.T, srv *Server, method, path, key string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		req.Header.Set("X-Redmine-API-Key", key)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]any{}
+	buf, _ := io.ReadAll(resp.Body)
+	if len(buf) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(buf, &out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := newTestServer(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{"anonymous read", http.MethodGet, "/issues.json", "", http.StatusOK},
+		{"anonymous write", http.MethodPost, "/issues.json", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/issues.json", "unknown", http.StatusUnauthorized},
+		{"admin only", http.MethodGet, "/users.json", "jsmith", http.StatusForbidden},
+		{"private project", http.MethodGet, "/projects/private.json", "", http.StatusNotFound},
+		{"member", http.MethodGet, "/projects/private.json", "jsmith", http.StatusOK},
+	}
+	for _, c := range cases {
+		if status, _ := call(t, srv, c.method, c.path, c.key, nil); status != c.status {
+			t.Errorf("%s: got %d, want %d", c.name, status, c.status)
+		}
+	}
+
+	srv.LoginRequired = true
+	if status, _ := call(t, srv, http.MethodGet, "/issues.json", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("login required: got %d", status)
+	}
+}
+
+func TestSwitchUser(t *testing.T) {
+	srv := newTestServer(t)
+
+	for login, want := range map[string]int{"jsmith": http.StatusForbidden, "nobody": http.StatusPreconditionFailed} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users.json", nil)
+		req.Header.Set("X-Redmine-API-Key", "admin")
+		req.Header.Set("X-Redmine-Switch-User", login)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: got %d, want %d", login, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	srv := newTestServer(t)
+
+	_, body := call(t, srv, http.MethodGet, "/issues.json?project_id=public&limit=10&offset=25&sort=id", "admin", nil)
+	issues := body["issues"].([]any)
+	if body["total_count"] != 31.0 || body["offset"] != 25.0 || body["limit"] != 10.0 || len(issues) != 6 {
+		t.Errorf("got total %v offset %v limit %v len %d", body["total_count"], body["offset"], body["limit"], len(issues))
+	}
+	if id := issues[0].(map[string]any)["id"]; id != 26.0 {
+		t.Errorf("first id = %v", id)
+	}
+
+	_, body = call(t, srv, http.MethodGet, "/issues.json?nometa=1", "admin", nil)
+	if _, ok := body["total_count"]; ok {
+		t.Errorf("nometa: got %v", body)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	srv := newTestServer(t)
+
+	cases := map[string]int{
+		"/issues.json?subject=~issue+1":          11,
+		"/issues.json?issue_id=1,2,3":            3,
+		"/issues.json?issue_id=><5|7":            3,
+		"/issues.json?status_id=closed":          0,
+		"/issues.json?project_id=private":        1,
+		"/issues.json?assigned_to_id=!*&limit=1": 32,
+	}
+	for path, want := range cases {
+		_, body := call(t, srv, http.MethodGet, path, "admin", nil)
+		if got := body["total_count"]; got != float64(want) {
+			t.Errorf("%s: got %v, want %d", path, got, want)
+		}
+	}
+}
+
+func TestFilterLists(t *testing.T) {
+	f := DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Public", Identifier: "public", IsPublic: true}}
+	f.Issues = nil
+	f.TimeEntries = nil
+	for id := 1; id <= 3; id++ {
+		i := model.Issue{Id: id, Project: &model.Ref{Id: 1}, Subject: fmt.Sprintf("Issue %d", id)}
+		if id == 2 {
+			i.Parent = &struct {
+				Id int `json:"id"`
+			}{Id: 1}
+		}
+		f.Issues = append(f.Issues, i)
+		f.TimeEntries = append(f.TimeEntries, model.TimeEntry{Id: id, Project: &model.Ref{Id: 1}, Issue: &struct {
+			Id int `json:"id"`
+		}{Id: id}, User: &model.Ref{Id: 1}, Activity: &model.Ref{Id: 1}, Hours: 1})
+	}
+	srv := NewServer(f)
+	t.Cleanup(srv.Close)
+
+	cases := map[string]int{
+		"/issues.json?issue_id=1,2":        2,
+		"/issues.json?parent_id=1,3":       1,
+		"/time_entries.json?issue_id=1":    1,
+		"/time_entries.json?issue_id=1,2":  0,
+		"/time_entries.json?issue_id=~1":   2,
+		"/time_entries.json?issue_id=!1,2": 3,
+	}
+	for path, want := range cases {
+		_, body := call(t, srv, http.MethodGet, path, "admin", nil)
+		if got := body["total_count"]; got != float64(want) {
+			t.Errorf("%s: got %v, want %d", path, got, want)
+		}
+	}
+}
+
+func TestIssueVisibility(t *testing.T) {
+	srv := newTestServer(t)
+
+	if status, _ := call(t, srv, http.MethodGet, "/issues/32.json", "jsmith", nil); status != http.StatusNotFound {
+		t.Errorf("private issue: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodGet, "/issues/31.json", "jsmith", nil); status != http.StatusOK {
+		t.Errorf("member: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodGet, "/issues/31.json", "", nil); status != http.StatusNotFound {
+		t.Errorf("anonymous: got %d", status)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	srv := newTestServer(t)
+
+	status, body := call(t, srv, http.MethodPost, "/issues.json", "admin", map[string]any{
+		"issue": map[string]any{"project_id": "public", "due_date": "2024-01-01", "start_date": "2024-02-01"},
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d", status)
+	}
+	errors := fmt.Sprint(body["errors"])
+	for _, msg := range []string{"Subject cannot be blank", "Due date must be greater than start date"} {
+		if !strings.Contains(errors, msg) {
+			t.Errorf("%q not in %s", msg, errors)
+		}
+	}
+
+	status, body = call(t, srv, http.MethodPost, "/issues.json", "admin", map[string]any{
+		"issue": map[string]any{"project_id": "public", "subject": "new"},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("got %d %v", status, body)
+	}
+	if id := body["issue"].(map[string]any)["id"]; id != 33.0 {
+		t.Errorf("id = %v", id)
+	}
+}
+
+func TestIssueJournal(t *testing.T) {
+	srv := newTestServer(t)
+
+	status, _ := call(t, srv, http.MethodPut, "/issues/1.json", "admin", map[string]any{
+		"issue": map[string]any{"subject": "changed", "notes": "why"},
+	})
+	if status != http.StatusNoContent {
+		t.Fatalf("got %d", status)
+	}
+
+	_, body := call(t, srv, http.MethodGet, "/issues/1.json?include=journals", "admin", nil)
+	journals := body["issue"].(map[string]any)["journals"].([]any)
+	if len(journals) != 1 {
+		t.Fatalf("journals = %v", journals)
+	}
+	j := journals[0].(map[string]any)
+	d := j["details"].([]any)[0].(map[string]any)
+	if j["notes"] != "why" || d["name"] != "subject" || d["old_value"] != "Issue 1" || d["new_value"] != "changed" {
+		t.Errorf("journal = %v", j)
+	}
+}
+
+func TestWiki(t *testing.T) {
+	srv := newTestServer(t)
+
+	page := func(version int, text string) map[string]any {
+		return map[string]any{"wiki_page": map[string]any{"text": text, "version": version}}
+	}
+
+	if status, _ := call(t, srv, http.MethodPut, "/projects/public/wiki/Wiki.json", "admin", page(1, "second")); status != http.StatusNoContent {
+		t.Errorf("update: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodPut, "/projects/public/wiki/Wiki.json", "admin", page(1, "stale")); status != http.StatusConflict {
+		t.Errorf("stale update: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodPut, "/projects/public/wiki/New_page.json", "admin", page(0, "new")); status != http.StatusCreated {
+		t.Errorf("create: got %d", status)
+	}
+
+	_, body := call(t, srv, http.MethodGet, "/projects/public/wiki/wiki/1.json", "admin", nil)
+	if v := body["wiki_page"].(map[string]any); v["text"] != "start" || v["version"] != 1.0 {
+		t.Errorf("version 1 = %v", v)
+	}
+	_, body = call(t, srv, http.MethodGet, "/projects/public/wiki.json", "admin", nil)
+	if v := body["wiki_page"].(map[string]any); v["text"] != "second" || v["version"] != 2.0 {
+		t.Errorf("root = %v", v)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	srv := newTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/uploads.json?filename=a.txt", strings.NewReader("content"))
+	req.Header.Set("X-Redmine-API-Key", "admin")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var upload struct {
+		Upload struct {
+			Id    int    `json:"id"`
+			Token string `json:"token"`
+		} `json:"upload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&upload); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	status, _ := call(t, srv, http.MethodPut, "/issues/1.json", "admin", map[string]any{
+		"issue": map[string]any{"uploads": []any{map[string]any{"token": upload.Upload.Token}}},
+	})
+	if status != http.StatusNoContent {
+		t.Fatalf("attach: got %d", status)
+	}
+
+	_, body := call(t, srv, http.MethodGet, "/issues/1.json?include=attachments", "admin", nil)
+	attachments := body["issue"].(map[string]any)["attachments"].([]any)
+	if len(attachments) != 1 {
+		t.Fatalf("attachments = %v", attachments)
+	}
+	a := attachments[0].(map[string]any)
+	if a["id"] != float64(upload.Upload.Id) || a["filename"] != "a.txt" || a["filesize"] != 7.0 {
+		t.Errorf("attachment = %v", a)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, a["content_url"].(string), nil)
+	req.Header.Set("X-Redmine-API-Key", "admin")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if content, _ := io.ReadAll(resp.Body); string(content) != "content" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := newTestServer(t)
+
+	cases := []struct {
+		path string
+		key  string
+		want int
+	}{
+		{"/search.json?q=issue+30", "admin", 1},
+		{"/search.json?q=issue+30&all_words=", "admin", 31},
+		{"/search.json?q=issue+3", "admin", 31},
+		{"/search.json?q=%22private+issue%22", "admin", 1},
+		{"/search.json?q=private", "admin", 3},
+		{"/search.json?q=private&projects=1", "admin", 1},
+		{"/search.json?q=private", "jsmith", 2},
+		{"/projects/public/search.json?q=private", "admin", 1},
+		{"/projects/public/search.json?q=wiki&wiki_pages=1", "admin", 1},
+		{"/search.json?q=a", "admin", 0},
+	}
+	for _, c := range cases {
+		_, body := call(t, srv, http.MethodGet, c.path, c.key, nil)
+		if got := int(body["total_count"].(float64)); got != c.want {
+			t.Errorf("%s as %s: got %d results, want %d", c.path, c.key, got, c.want)
+		}
+	}
+}
+
+func TestNews(t *testing.T) {
+	srv := newTestServer(t)
+
+	news := func(title string) map[string]any {
+		return map[string]any{"news": map[string]any{"title": title, "description": "text"}}
+	}
+
+	if status, _ := call(t, srv, http.MethodPost, "/projects/public/news.json", "admin", news("")); status != http.StatusUnprocessableEntity {
+		t.Errorf("blank title: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodPost, "/projects/public/news.json", "admin", news("Release")); status != http.StatusNoContent {
+		t.Errorf("create: got %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodPost, "/projects/public/news.json", "jsmith", news("Release")); status != http.StatusForbidden {
+		t.Errorf("create without permission: got %d", status)
+	}
+
+	_, body := call(t, srv, http.MethodGet, "/news.json", "jsmith", nil)
+	if items := body["news"].([]any); len(items) != 1 || items[0].(map[string]any)["title"] != "Release" {
+		t.Errorf("news = %v", items)
+	}
+}
@@ -0,0 +1,344 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/time_entries", timelogIndex)
+	handle(http.MethodPost, "/time_entries", timelogCreate)
+	handle(http.MethodGet, "/projects/:project/time_entries", timelogIndexProject)
+	handle(http.MethodPost, "/projects/:project/time_entries", timelogCreateProject)
+	handle(http.MethodPost, "/issues/:issue/time_entries", timelogCreateIssue)
+	handle(http.MethodGet, "/time_entries/:id", timelogShow)
+	handle(http.MethodPut, "/time_entries/:id", timelogUpdate)
+	handle(http.MethodPatch, "/time_entries/:id", timelogUpdate)
+	handle(http.MethodDelete, "/time_entries/:id", timelogDestroy)
+}
+
+// timeEntryFields returns the filterable fields of the time entries. The
+// issue_id filter also matches the subtasks of the issue with `~`.
+func (s *Server) timeEntryFields() map[string]field[*model.TimeEntry] {
+	return map[string]field[*model.TimeEntry]{
+		"id":                     {kindInt, func(e *model.TimeEntry) []string { return intValue(e.Id) }},
+		"issue_id":               {kindTree, func(e *model.TimeEntry) []string { return s.issueAncestors(e.IssueId()) }},
+		"issue.fixed_version_id": {kindInt, func(e *model.TimeEntry) []string { return intValue(s.issueVersionId(e.IssueId())) }},
+		"user_id":                {kindInt, func(e *model.TimeEntry) []string { return intValue(refId(e.User)) }},
+		"activity_id":            {kindInt, func(e *model.TimeEntry) []string { return intValue(refId(e.Activity)) }},
+		"hours":                  {kindFloat, func(e *model.TimeEntry) []string { return floatValue(&e.Hours) }},
+		"comments":               {kindString, func(e *model.TimeEntry) []string { return stringValue(e.Comments) }},
+		"spent_on":               {kindDate, func(e *model.TimeEntry) []string { return dateValue(e.SpentOn) }},
+		"created_on":             {kindTime, func(e *model.TimeEntry) []string { return timeValue(e.CreatedOn) }},
+		"updated_on":             {kindTime, func(e *model.TimeEntry) []string { return timeValue(e.UpdatedOn) }},
+	}
+}
+
+// issueAncestors returns the ID of the issue with id followed by the IDs of
+// its parents, empty if id is 0.
+func (s *Server) issueAncestors(id int) []string {
+	ids := []string{}
+	for id != 0 && !slices.Contains(ids, strconv.Itoa(id)) {
+		ids = append(ids, strconv.Itoa(id))
+		i, ok := s.issues[id]
+		if !ok {
+			break
+		}
+		id = i.ParentId()
+	}
+	return ids
+}
+
+// issueVersionId returns the target version of the issue with id, 0 if
+// none.
+func (s *Server) issueVersionId(id int) int {
+	if i, ok := s.issues[id]; ok {
+		return refId(i.FixedVersion)
+	}
+	return 0
+}
+
+func (s *Server) renderTimeEntry(e *model.TimeEntry) model.TimeEntry {
+	out := *e
+	out.Project = s.projectRef(refId(e.Project))
+	out.User = s.principalRef(refId(e.User))
+	out.Activity = s.activityRef(refId(e.Activity))
+	out.CustomFields = renderCustomFields(e.CustomFields, s.customFieldsOf("time_entry", 0))
+	return out
+}
+
+// timeEntryVisible reports whether the user of r can see time entry e.
+func (s *Server) timeEntryVisible(r *request, e *model.TimeEntry) bool {
+	p, ok := s.projects[refId(e.Project)]
+	return ok && s.projectVisible(r, p) && s.allowed(r, p, "view_time_entries")
+}
+
+func (s *Server) listTimeEntries(r *request, scope *model.Project) (int, any, error) {
+	projects := s.projectScope(r, scope)
+	q := r.URL.Query()
+
+	entries := []*model.TimeEntry{}
+	for _, id := range sortedIds(s.timeEntries) {
+		e := s.timeEntries[id]
+		if projects != nil && !slices.Contains(projects, refId(e.Project)) {
+			continue
+		}
+		if !s.timeEntryVisible(r, e) {
+			continue
+		}
+		if from := q.Get("from"); from != "" && dateString(e.SpentOn) < from {
+			continue
+		}
+		if to := q.Get("to"); to != "" && dateString(e.SpentOn) > to {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	fields := s.timeEntryFields()
+	entries = filter(r, entries, fields)
+	sortBy(r, entries, fields, "spent_on:desc,created_on:desc")
+
+	rendered := []model.TimeEntry{}
+	for _, e := range entries {
+		rendered = append(rendered, s.renderTimeEntry(e))
+	}
+	return http.StatusOK, paginate(r, "time_entries", rendered), nil
+}
+
+func timelogIndex(s *Server, r *request) (int, any, error) {
+	return s.listTimeEntries(r, nil)
+}
+
+func timelogIndexProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "view_time_entries"); err != nil {
+		return 0, nil, err
+	}
+	return s.listTimeEntries(r, p)
+}
+
+// timeEntry returns the time entry of the path variable id, visible to the
+// user of r.
+func (s *Server) timeEntry(r *request) (*model.TimeEntry, *model.Project, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	e, ok := s.timeEntries[id]
+	if !ok || !s.timeEntryVisible(r, e) {
+		return nil, nil, errNotFound
+	}
+	return e, s.projects[refId(e.Project)], nil
+}
+
+func timelogShow(s *Server, r *request) (int, any, error) {
+	e, _, err := s.timeEntry(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"time_entry": s.renderTimeEntry(e)}, nil
+}
+
+type timeEntryInput struct {
+	customValues
+	ProjectId  idOrKey  `json:"project_id"`
+	IssueId    optInt   `json:"issue_id"`
+	UserId     optInt   `json:"user_id"`
+	ActivityId optInt   `json:"activity_id"`
+	Hours      optFloat `json:"hours"`
+	Comments   *string  `json:"comments"`
+	SpentOn    optDate  `json:"spent_on"`
+}
+
+// apply applies the input to time entry e and validates the result. The
+// project of the entry follows its issue.
+func (in *timeEntryInput) apply(s *Server, r *request, e *model.TimeEntry, isNew bool) error {
+	v := validation{}
+
+	if in.ProjectId != "" {
+		if p, ok := s.project(string(in.ProjectId)); ok && s.projectVisible(r, p) {
+			e.Project = &model.Ref{Id: p.Id}
+		} else {
+			e.Project = nil
+		}
+	}
+	if in.IssueId.set {
+		e.Issue = nil
+		if in.IssueId.v != 0 {
+			i, ok := s.issues[in.IssueId.v]
+			switch {
+			case !ok || !s.issueVisible(r, i):
+				v.add("Issue is invalid")
+			case in.ProjectId != "" && refId(e.Project) != refId(i.Project):
+				v.add("Issue is invalid")
+			default:
+				e.Issue = &struct {
+					Id int `json:"id"`
+				}{i.Id}
+				e.Project = &model.Ref{Id: refId(i.Project)}
+			}
+		}
+	}
+	if in.UserId.set {
+		e.User = &model.Ref{Id: in.UserId.v}
+	}
+	if in.ActivityId.set {
+		e.Activity = ref(in.ActivityId.v, "")
+	}
+	blankHours := isNew && !in.Hours.set || in.Hours.set && in.Hours.v == nil
+	if in.Hours.v != nil {
+		e.Hours = *in.Hours.v
+	}
+	if in.Comments != nil {
+		e.Comments = *in.Comments
+	}
+	if in.SpentOn.set {
+		e.SpentOn = in.SpentOn.v
+	}
+	e.CustomFields = s.applyCustomFields(e.CustomFields, s.customFieldsOf("time_entry", 0), in.customValues, isNew, &v)
+
+	p, ok := s.projects[refId(e.Project)]
+	if !ok {
+		v.add("Project cannot be blank")
+		return v.err()
+	}
+
+	if u, ok := s.users[refId(e.User)]; !ok || u.Status != model.UserStatusActive {
+		v.add("User is invalid")
+	} else if in.UserId.set && u.Id != r.userId() && !s.allowed(r, p, "log_time_for_other_users") {
+		v.add("User is invalid")
+	}
+
+	if e.Activity == nil {
+		e.Activity = ref(s.defaultActivity(), "")
+	}
+	if a, ok := findEnumeration(s.activities, refId(e.Activity)); !ok {
+		v.add("Activity cannot be blank")
+	} else if !a.Active {
+		v.add("Activity is not included in the list")
+	}
+
+	switch {
+	case blankHours:
+		v.add("Hours cannot be blank")
+	case e.Hours < 0 || e.Hours >= 1000:
+		v.add("Hours is invalid")
+	}
+	if e.SpentOn == nil {
+		v.add("Date cannot be blank")
+	}
+	if len(e.Comments) > 1024 {
+		v.add("Comment is too long (maximum is 1024 characters)")
+	}
+	return v.err()
+}
+
+func timelogCreate(s *Server, r *request) (int, any, error) {
+	return s.createTimeEntry(r, nil, nil)
+}
+
+func timelogCreateProject(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.createTimeEntry(r, p, nil)
+}
+
+func timelogCreateIssue(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "issue")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.createTimeEntry(r, p, i)
+}
+
+// createTimeEntry logs time in project p, or on issue i, or where the
+// request body says when both are nil.
+func (s *Server) createTimeEntry(r *request, p *model.Project, i *model.Issue) (int, any, error) {
+	in := timeEntryInput{}
+	if err := r.decode("time_entry", &in); err != nil {
+		return 0, nil, err
+	}
+	if p != nil {
+		in.ProjectId = idOrKey(strconv.Itoa(p.Id))
+	}
+	if i != nil {
+		in.IssueId = optInt{set: true, v: i.Id}
+	}
+
+	now := s.now()
+	e := &model.TimeEntry{User: &model.Ref{Id: r.userId()}, SpentOn: &openapi_types.Date{Time: now}, CreatedOn: timePtr(now), UpdatedOn: timePtr(now)}
+	if err := in.apply(s, r, e, true); err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, s.projects[e.Project.Id], "log_time"); err != nil {
+		return 0, nil, err
+	}
+
+	e.Id = s.nextId("time_entry")
+	s.timeEntries[e.Id] = e
+	return http.StatusCreated, map[string]any{"time_entry": s.renderTimeEntry(e)}, nil
+}
+
+// editableTimeEntry returns an error unless the user of r can change time entry e in
+// project p.
+func (s *Server) editableTimeEntry(r *request, e *model.TimeEntry, p *model.Project) error {
+	if s.allowed(r, p, "edit_time_entries") {
+		return nil
+	}
+	if refId(e.User) == r.userId() && s.allowed(r, p, "edit_own_time_entries") {
+		return nil
+	}
+	return errForbidden
+}
+
+func timelogUpdate(s *Server, r *request) (int, any, error) {
+	e, p, err := s.timeEntry(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.editableTimeEntry(r, e, p); err != nil {
+		return 0, nil, err
+	}
+
+	in := timeEntryInput{}
+	if err := r.decode("time_entry", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *e
+	if err := in.apply(s, r, &updated, false); err != nil {
+		return 0, nil, err
+	}
+	if target := s.projects[updated.Project.Id]; target.Id != p.Id {
+		if err := s.require(r, target, "log_time"); err != nil {
+			return 0, nil, err
+		}
+	}
+	updated.UpdatedOn = timePtr(s.now())
+	*e = updated
+	return http.StatusNoContent, nil, nil
+}
+
+func timelogDestroy(s *Server, r *request) (int, any, error) {
+	e, p, err := s.timeEntry(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.editableTimeEntry(r, e, p); err != nil {
+		return 0, nil, err
+	}
+
+	delete(s.timeEntries, e.Id)
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,374 @@
+package redminetest
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/users", usersIndex)
+	handle(http.MethodPost, "/users", usersCreate)
+	handle(http.MethodGet, "/users/:id", usersShow)
+	handle(http.MethodPut, "/users/:id", usersUpdate)
+	handle(http.MethodPatch, "/users/:id", usersUpdate)
+	handle(http.MethodDelete, "/users/:id", usersDestroy)
+	handle(http.MethodGet, "/my/account", myAccount)
+	handle(http.MethodPut, "/my/account", myAccountUpdate)
+}
+
+var (
+	loginPattern = regexp.MustCompile(`(?i)^[a-z0-9_\-@.]*$`)
+	mailPattern  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+var userFields = map[string]field[*User]{
+	"id":         {kindInt, func(u *User) []string { return intValue(u.Id) }},
+	"login":      {kindString, func(u *User) []string { return stringValue(u.Login) }},
+	"firstname":  {kindString, func(u *User) []string { return stringValue(u.Firstname) }},
+	"lastname":   {kindString, func(u *User) []string { return stringValue(u.Lastname) }},
+	"mail":       {kindString, func(u *User) []string { return stringValue(u.Mail) }},
+	"admin":      {kindInt, func(u *User) []string { return boolValue(u.Admin) }},
+	"created_on": {kindTime, func(u *User) []string { return timeValue(u.CreatedOn) }},
+	"updated_on": {kindTime, func(u *User) []string { return timeValue(u.UpdatedOn) }},
+}
+
+// renderUser returns user u as seen by the user of r. Logins, statuses and
+// memberships are only shown to administrators and to the user.
+func (s *Server) renderUser(r *request, u *User) model.User {
+	out := u.User
+	out.CustomFields = renderCustomFields(u.CustomFields, s.customFieldsOf("user", 0))
+	out.Groups = nil
+	out.Memberships = nil
+
+	self := r.admin() || r.userId() == u.Id
+	if !self {
+		out.Login = ""
+		out.Admin = false
+	}
+	if !r.admin() {
+		out.Status = 0
+	}
+
+	if r.include("groups") && self {
+		out.Groups = []model.Ref{}
+		for _, id := range s.groupIds(u.Id) {
+			out.Groups = append(out.Groups, *s.principalRef(id))
+		}
+	}
+	if r.include("memberships") {
+		out.Memberships = s.principalMemberships(r, u.Id)
+	}
+	return out
+}
+
+// principalMemberships returns the memberships of a user or a group in
+// visible projects. The roles given to the groups of a user are inherited.
+func (s *Server) principalMemberships(r *request, id int) []model.Membership {
+	groups := s.groupIds(id)
+
+	memberships := []model.Membership{}
+	for _, pid := range sortedIds(s.projects) {
+		p := s.projects[pid]
+		if !s.projectVisible(r, p) {
+			continue
+		}
+
+		var out *model.Membership
+		for _, mid := range sortedIds(s.memberships) {
+			m := s.memberships[mid]
+			if refId(m.Project) != pid {
+				continue
+			}
+			direct := refId(m.User) == id || refId(m.Group) == id
+			if !direct && !slices.Contains(groups, refId(m.Group)) {
+				continue
+			}
+			if out == nil {
+				out = &model.Membership{Id: mid, Project: s.projectRef(pid), Roles: []model.MembershipRole{}}
+			}
+			for _, role := range m.Roles {
+				out.Roles = append(out.Roles, model.MembershipRole{Id: role.Id, Name: s.roleRef(role.Id).Name, Inherited: !direct || role.Inherited})
+			}
+		}
+		if out != nil {
+			memberships = append(memberships, *out)
+		}
+	}
+	return memberships
+}
+
+func usersIndex(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+
+	q := r.URL.Query()
+	status := model.UserStatusActive
+	if _, ok := q["status"]; ok {
+		status = atoi(q.Get("status"))
+	}
+	name := strings.ToLower(q.Get("name"))
+	groupId := atoi(q.Get("group_id"))
+
+	users := []*User{}
+	for _, id := range sortedIds(s.users) {
+		u := s.users[id]
+		if status != 0 && u.Status != status {
+			continue
+		}
+		if name != "" && !slices.ContainsFunc([]string{u.Login, u.Firstname, u.Lastname, u.Mail, u.Name()}, func(v string) bool {
+			return strings.Contains(strings.ToLower(v), name)
+		}) {
+			continue
+		}
+		if groupId != 0 && !slices.Contains(s.groupIds(u.Id), groupId) {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	users = filter(r, users, map[string]field[*User]{"id": userFields["id"]})
+	sortBy(r, users, userFields, "login")
+
+	rendered := []model.User{}
+	for _, u := range users {
+		rendered = append(rendered, s.renderUser(r, u))
+	}
+	return http.StatusOK, paginate(r, "users", rendered), nil
+}
+
+// user returns the user of the path variable id, where `current` is the
+// user of r. Users other than active ones are only visible to
+// administrators.
+func (s *Server) user(r *request) (*User, error) {
+	if r.vars["id"] == "current" {
+		if r.user == nil {
+			return nil, errUnauthorized
+		}
+		return r.user, nil
+	}
+
+	id, err := r.id("id")
+	if err != nil {
+		return nil, err
+	}
+	u, ok := s.users[id]
+	if !ok || u.Status != model.UserStatusActive && !r.admin() {
+		return nil, errNotFound
+	}
+	return u, nil
+}
+
+func usersShow(s *Server, r *request) (int, any, error) {
+	u, err := s.user(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"user": s.renderUser(r, u)}, nil
+}
+
+type userInput struct {
+	customValues
+	Login            *string `json:"login"`
+	Firstname        *string `json:"firstname"`
+	Lastname         *string `json:"lastname"`
+	Mail             *string `json:"mail"`
+	Password         *string `json:"password"`
+	GeneratePassword *bool   `json:"generate_password"`
+	Admin            *bool   `json:"admin"`
+	Status           *int    `json:"status"`
+	GroupIds         *[]int  `json:"group_ids"`
+}
+
+// apply applies the input to user u and validates the result. Only
+// administrators change logins, passwords, statuses and groups.
+func (in *userInput) apply(s *Server, r *request, u *User, isNew bool) error {
+	v := validation{}
+
+	if in.Firstname != nil {
+		u.Firstname = strings.TrimSpace(*in.Firstname)
+	}
+	if in.Lastname != nil {
+		u.Lastname = strings.TrimSpace(*in.Lastname)
+	}
+	if in.Mail != nil {
+		u.Mail = strings.TrimSpace(*in.Mail)
+	}
+
+	if r.admin() {
+		if in.Login != nil {
+			u.Login = strings.TrimSpace(*in.Login)
+		}
+		if in.Admin != nil {
+			u.Admin = *in.Admin
+		}
+		if in.Status != nil {
+			u.Status = *in.Status
+			if !slices.Contains([]int{model.UserStatusActive, model.UserStatusRegistered, model.UserStatusLocked}, u.Status) {
+				v.add("Status is not included in the list")
+			}
+		}
+		switch {
+		case in.GeneratePassword != nil && *in.GeneratePassword:
+			u.Password = "generated-" + u.Login
+		case in.Password != nil:
+			u.Password = *in.Password
+			if len(u.Password) < 8 {
+				v.add("Password is too short (minimum is 8 characters)")
+			}
+		case isNew:
+			v.add("Password cannot be blank")
+		}
+	}
+
+	u.CustomFields = s.applyCustomFields(u.CustomFields, s.customFieldsOf("user", 0), in.customValues, isNew, &v)
+
+	switch {
+	case u.Login == "":
+		v.add("Login cannot be blank")
+	case len(u.Login) > 60:
+		v.add("Login is too long (maximum is 60 characters)")
+	case !loginPattern.MatchString(u.Login):
+		v.add("Login is invalid")
+	}
+	for _, f := range []struct{ name, value string }{{"First name", u.Firstname}, {"Last name", u.Lastname}} {
+		switch {
+		case f.value == "":
+			v.add("%s cannot be blank", f.name)
+		case len(f.value) > 30:
+			v.add("%s is too long (maximum is 30 characters)", f.name)
+		}
+	}
+	switch {
+	case u.Mail == "":
+		v.add("Email cannot be blank")
+	case !mailPattern.MatchString(u.Mail):
+		v.add("Email is invalid")
+	}
+	for _, other := range s.users {
+		if other.Id == u.Id {
+			continue
+		}
+		if strings.EqualFold(other.Login, u.Login) {
+			v.add("Login has already been taken")
+		}
+		if strings.EqualFold(other.Mail, u.Mail) {
+			v.add("Email has already been taken")
+		}
+	}
+	return v.err()
+}
+
+// setGroups makes user id a member of exactly the groups.
+func (s *Server) setGroups(id int, groups []int) {
+	for gid, g := range s.groups {
+		member := slices.ContainsFunc(g.Users, func(u model.Ref) bool { return u.Id == id })
+		switch want := slices.Contains(groups, gid); {
+		case want && !member:
+			g.Users = append(g.Users, model.Ref{Id: id})
+		case !want && member:
+			g.Users = slices.DeleteFunc(g.Users, func(u model.Ref) bool { return u.Id == id })
+		}
+	}
+}
+
+func usersCreate(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+
+	in := userInput{}
+	if err := r.decode("user", &in); err != nil {
+		return 0, nil, err
+	}
+
+	now := s.now()
+	u := &User{User: model.User{Status: model.UserStatusActive, CreatedOn: timePtr(now), UpdatedOn: timePtr(now)}}
+	if err := in.apply(s, r, u, true); err != nil {
+		return 0, nil, err
+	}
+
+	u.Id = s.nextId("principal")
+	s.users[u.Id] = u
+	if in.GroupIds != nil {
+		s.setGroups(u.Id, *in.GroupIds)
+	}
+	return http.StatusCreated, map[string]any{"user": s.renderUser(r, u)}, nil
+}
+
+func usersUpdate(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+	u, err := s.user(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.updateUser(r, u)
+}
+
+func (s *Server) updateUser(r *request, u *User) (int, any, error) {
+	in := userInput{}
+	if err := r.decode("user", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *u
+	if err := in.apply(s, r, &updated, false); err != nil {
+		return 0, nil, err
+	}
+	updated.UpdatedOn = timePtr(s.now())
+	*u = updated
+
+	if in.GroupIds != nil && r.admin() {
+		s.setGroups(u.Id, *in.GroupIds)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// usersDestroy deletes a user with its memberships. The records of the user,
+// such as issues and journals, keep referring to the deleted ID.
+func usersDestroy(s *Server, r *request) (int, any, error) {
+	if err := requireAdmin(r); err != nil {
+		return 0, nil, err
+	}
+	u, err := s.user(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for id, m := range s.memberships {
+		if refId(m.User) == u.Id {
+			delete(s.memberships, id)
+		}
+	}
+	s.setGroups(u.Id, nil)
+	for _, i := range s.issues {
+		i.Watchers = slices.DeleteFunc(i.Watchers, func(w model.Ref) bool { return w.Id == u.Id })
+	}
+	delete(s.users, u.Id)
+	return http.StatusNoContent, nil, nil
+}
+
+func myAccount(s *Server, r *request) (int, any, error) {
+	if r.user == nil {
+		return 0, nil, errUnauthorized
+	}
+	return http.StatusOK, map[string]any{"user": s.renderUser(r, r.user)}, nil
+}
+
+// myAccountUpdate changes the account of the current user, except the
+// attributes reserved to administrators.
+func myAccountUpdate(s *Server, r *request) (int, any, error) {
+	if r.user == nil {
+		return 0, nil, errUnauthorized
+	}
+
+	self := *r
+	self.user = &User{User: model.User{Id: r.user.Id}}
+	return s.updateUser(&self, r.user)
+}
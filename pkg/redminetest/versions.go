@@ -0,0 +1,231 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects/:project/versions", versionsIndex)
+	handle(http.MethodPost, "/projects/:project/versions", versionsCreate)
+	handle(http.MethodGet, "/versions/:id", versionsShow)
+	handle(http.MethodPut, "/versions/:id", versionsUpdate)
+	handle(http.MethodPatch, "/versions/:id", versionsUpdate)
+	handle(http.MethodDelete, "/versions/:id", versionsDestroy)
+}
+
+var versionSharings = []string{"none", "descendants", "hierarchy", "tree", "system"}
+
+// root returns the ID of the root project of project id.
+func (s *Server) root(id int) int {
+	if ancestors := s.ancestors(id); len(ancestors) > 0 {
+		return ancestors[len(ancestors)-1]
+	}
+	return id
+}
+
+// versionShared reports whether version v can be used by project id.
+func (s *Server) versionShared(v *model.Version, id int) bool {
+	owner := refId(v.Project)
+	switch v.Sharing {
+	case "system":
+		return true
+	case "tree":
+		return s.root(owner) == s.root(id)
+	case "hierarchy":
+		return owner == id || slices.Contains(s.ancestors(id), owner) || slices.Contains(s.ancestors(owner), id)
+	case "descendants":
+		return owner == id || slices.Contains(s.ancestors(id), owner)
+	}
+	return owner == id
+}
+
+func (s *Server) renderVersion(v *model.Version) model.Version {
+	out := *v
+	out.Project = s.projectRef(refId(v.Project))
+	out.CustomFields = renderCustomFields(v.CustomFields, s.customFieldsOf("version", 0))
+
+	estimated, spent := 0.0, 0.0
+	for _, i := range s.issues {
+		if refId(i.FixedVersion) != v.Id {
+			continue
+		}
+		if i.EstimatedHours != nil {
+			estimated += *i.EstimatedHours
+		}
+		spent += s.spentHours(i.Id)
+	}
+	out.EstimatedHours = &estimated
+	out.SpentHours = &spent
+	return out
+}
+
+func versionsIndex(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	versions := []model.Version{}
+	for _, id := range sortedIds(s.versions) {
+		v := s.versions[id]
+		owner, ok := s.projects[refId(v.Project)]
+		if !ok || !s.versionShared(v, p.Id) || !s.projectVisible(r, owner) {
+			continue
+		}
+		versions = append(versions, s.renderVersion(v))
+	}
+	return http.StatusOK, map[string]any{"versions": versions, "total_count": len(versions)}, nil
+}
+
+// version returns the version of the path variable id, visible to the user
+// of r.
+func (s *Server) version(r *request) (*model.Version, *model.Project, error) {
+	id, err := r.id("id")
+	if err != nil {
+		return nil, nil, err
+	}
+	v, ok := s.versions[id]
+	if !ok {
+		return nil, nil, errNotFound
+	}
+	p, ok := s.projects[refId(v.Project)]
+	if !ok || !s.projectVisible(r, p) {
+		return nil, nil, errNotFound
+	}
+	return v, p, nil
+}
+
+func versionsShow(s *Server, r *request) (int, any, error) {
+	v, _, err := s.version(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"version": s.renderVersion(v)}, nil
+}
+
+type versionInput struct {
+	customValues
+	Name          *string `json:"name"`
+	Description   *string `json:"description"`
+	Status        *string `json:"status"`
+	Sharing       *string `json:"sharing"`
+	DueDate       optDate `json:"due_date"`
+	EffectiveDate optDate `json:"effective_date"`
+	WikiPageTitle *string `json:"wiki_page_title"`
+}
+
+func (in *versionInput) apply(s *Server, r *request, v *model.Version, isNew bool) error {
+	val := validation{}
+
+	if in.Name != nil {
+		v.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Description != nil {
+		v.Description = *in.Description
+	}
+	if in.Status != nil {
+		v.Status = *in.Status
+	}
+	if in.Sharing != nil {
+		v.Sharing = *in.Sharing
+	}
+	if in.DueDate.set {
+		v.DueDate = in.DueDate.v
+	}
+	if in.EffectiveDate.set {
+		v.DueDate = in.EffectiveDate.v
+	}
+	if in.WikiPageTitle != nil {
+		v.WikiPageTitle = *in.WikiPageTitle
+	}
+	v.CustomFields = s.applyCustomFields(v.CustomFields, s.customFieldsOf("version", 0), in.customValues, isNew, &val)
+
+	switch {
+	case v.Name == "":
+		val.add("Name cannot be blank")
+	case len(v.Name) > 60:
+		val.add("Name is too long (maximum is 60 characters)")
+	}
+	for _, other := range s.versions {
+		if other.Id != v.Id && refId(other.Project) == refId(v.Project) && strings.EqualFold(other.Name, v.Name) {
+			val.add("Name has already been taken")
+		}
+	}
+	if !slices.Contains([]string{"open", "locked", "closed"}, v.Status) {
+		val.add("Status is not included in the list")
+	}
+	if !slices.Contains(versionSharings, v.Sharing) || v.Sharing == "system" && !r.admin() {
+		val.add("Sharing is not included in the list")
+	}
+	return val.err()
+}
+
+func versionsCreate(s *Server, r *request) (int, any, error) {
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_versions"); err != nil {
+		return 0, nil, err
+	}
+
+	in := versionInput{}
+	if err := r.decode("version", &in); err != nil {
+		return 0, nil, err
+	}
+
+	now := s.now()
+	v := &model.Version{Project: &model.Ref{Id: p.Id}, Status: "open", Sharing: "none", CreatedOn: timePtr(now), UpdatedOn: timePtr(now)}
+	if err := in.apply(s, r, v, true); err != nil {
+		return 0, nil, err
+	}
+	v.Id = s.nextId("version")
+	s.versions[v.Id] = v
+	return http.StatusCreated, map[string]any{"version": s.renderVersion(v)}, nil
+}
+
+func versionsUpdate(s *Server, r *request) (int, any, error) {
+	v, p, err := s.version(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_versions"); err != nil {
+		return 0, nil, err
+	}
+
+	in := versionInput{}
+	if err := r.decode("version", &in); err != nil {
+		return 0, nil, err
+	}
+
+	updated := *v
+	if err := in.apply(s, r, &updated, false); err != nil {
+		return 0, nil, err
+	}
+	updated.UpdatedOn = timePtr(s.now())
+	*v = updated
+	return http.StatusNoContent, nil, nil
+}
+
+func versionsDestroy(s *Server, r *request) (int, any, error) {
+	v, p, err := s.version(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.require(r, p, "manage_versions"); err != nil {
+		return 0, nil, err
+	}
+
+	for _, i := range s.issues {
+		if refId(i.FixedVersion) == v.Id {
+			return 0, nil, invalid("Unable to delete version")
+		}
+	}
+	s.deleteAttachmentsOf("version", v.Id)
+	delete(s.versions, v.Id)
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,142 @@
+package redminetest
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodPost, "/issues/:id/watchers", watchersCreateIssue)
+	handle(http.MethodDelete, "/issues/:id/watchers/:user", watchersDestroyIssue)
+	handle(http.MethodPost, "/watchers", watchersCreate)
+	handle(http.MethodDelete, "/watchers", watchersDestroy)
+}
+
+// watcherIds returns the users of a watcher request body, given as user_id
+// or as the watcher object with user_id or user_ids.
+func watcherIds(r *request) ([]int, error) {
+	var userId int
+	if err := r.decode("user_id", &userId); err != nil {
+		return nil, err
+	}
+
+	var watcher struct {
+		UserId  int   `json:"user_id"`
+		UserIds []int `json:"user_ids"`
+	}
+	if err := r.decode("watcher", &watcher); err != nil {
+		return nil, err
+	}
+
+	ids := watcher.UserIds
+	for _, id := range []int{userId, watcher.UserId} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// watchedIssue returns the issue of a watcher request.
+func (s *Server) watchedIssue(r *request, objectType string, id int) (*model.Issue, *model.Project, error) {
+	if objectType != "issue" {
+		return nil, nil, errNotFound
+	}
+	i, ok := s.issues[id]
+	if !ok || !s.issueVisible(r, i) {
+		return nil, nil, errNotFound
+	}
+	return i, s.projects[refId(i.Project)], nil
+}
+
+func watchersCreateIssue(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.addWatchers(r, i, p)
+}
+
+func watchersCreate(s *Server, r *request) (int, any, error) {
+	var objectType string
+	var objectId int
+	if err := r.decode("object_type", &objectType); err != nil {
+		return 0, nil, err
+	}
+	if err := r.decode("object_id", &objectId); err != nil {
+		return 0, nil, err
+	}
+
+	i, p, err := s.watchedIssue(r, objectType, objectId)
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.addWatchers(r, i, p)
+}
+
+func (s *Server) addWatchers(r *request, i *model.Issue, p *model.Project) (int, any, error) {
+	if err := s.require(r, p, "add_issue_watchers"); err != nil {
+		return 0, nil, err
+	}
+
+	ids, err := watcherIds(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, id := range ids {
+		if _, ok := s.users[id]; !ok && s.groups[id] == nil {
+			return 0, nil, errNotFound
+		}
+	}
+
+	for _, id := range ids {
+		if !slices.ContainsFunc(i.Watchers, func(w model.Ref) bool { return w.Id == id }) {
+			i.Watchers = append(i.Watchers, model.Ref{Id: id})
+		}
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func watchersDestroyIssue(s *Server, r *request) (int, any, error) {
+	i, p, err := s.issue(r, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+	userId, err := r.id("user")
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.removeWatcher(r, i, p, userId)
+}
+
+func watchersDestroy(s *Server, r *request) (int, any, error) {
+	q := r.URL.Query()
+	i, p, err := s.watchedIssue(r, q.Get("object_type"), atoi(q.Get("object_id")))
+	if err != nil {
+		return 0, nil, err
+	}
+	userId, err := strconv.Atoi(q.Get("user_id"))
+	if err != nil {
+		return 0, nil, errNotFound
+	}
+	return s.removeWatcher(r, i, p, userId)
+}
+
+// removeWatcher removes a watcher of an issue. Users can always stop
+// watching themselves.
+func (s *Server) removeWatcher(r *request, i *model.Issue, p *model.Project, userId int) (int, any, error) {
+	if userId != r.userId() {
+		if err := s.require(r, p, "delete_issue_watchers"); err != nil {
+			return 0, nil, err
+		}
+	}
+	if _, ok := s.users[userId]; !ok && s.groups[userId] == nil {
+		return 0, nil, errNotFound
+	}
+
+	i.Watchers = slices.DeleteFunc(i.Watchers, func(w model.Ref) bool { return w.Id == userId })
+	return http.StatusNoContent, nil, nil
+}
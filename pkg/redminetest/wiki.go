@@ -0,0 +1,343 @@
+package redminetest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+func init() {
+	handle(http.MethodGet, "/projects/:project/wiki", wikiShowRoot)
+	handle(http.MethodGet, "/projects/:project/wiki/index", wikiIndex)
+	handle(http.MethodGet, "/projects/:project/wiki/:title", wikiShow)
+	handle(http.MethodGet, "/projects/:project/wiki/:title/:version", wikiShowVersion)
+	handle(http.MethodPut, "/projects/:project/wiki/:title", wikiUpdate)
+	handle(http.MethodPatch, "/projects/:project/wiki/:title", wikiUpdate)
+	handle(http.MethodDelete, "/projects/:project/wiki/:title", wikiDestroy)
+}
+
+// wikiStartPage is the title of the start page of the wikis.
+const wikiStartPage = "Wiki"
+
+// wikiPage is a wiki page with its history, the last version being the
+// current content.
+type wikiPage struct {
+	id        int
+	projectId int
+	title     string
+	parent    string
+	createdOn time.Time
+	versions  []model.WikiPage
+}
+
+func (w *wikiPage) current() *model.WikiPage {
+	return &w.versions[len(w.versions)-1]
+}
+
+// titleize returns the page title of a name, with spaces replaced.
+func titleize(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// wikiKey returns the key of a page in wikiPages. Titles are not case
+// sensitive.
+func wikiKey(projectId int, title string) string {
+	return fmt.Sprintf("%d/%s", projectId, strings.ToLower(titleize(title)))
+}
+
+func (s *Server) seedWikiPage(f WikiPage) error {
+	p, ok := s.project(f.Project)
+	if !ok {
+		return fmt.Errorf("wiki page %s has unknown project %q", f.Title, f.Project)
+	}
+
+	v := f.WikiPage
+	if v.Version == 0 {
+		v.Version = 1
+	}
+	if v.Author == nil {
+		v.Author = &model.Ref{Id: 1}
+	}
+	if v.CreatedOn == nil {
+		v.CreatedOn = timePtr(s.now())
+	}
+	if v.UpdatedOn == nil {
+		v.UpdatedOn = v.CreatedOn
+	}
+
+	key := wikiKey(p.Id, v.Title)
+	for _, a := range v.Attachments {
+		s.seedAttachment(a, "wiki_page", 0, key)
+	}
+	v.Attachments = nil
+
+	w := &wikiPage{id: s.nextId("wiki_page"), projectId: p.Id, title: titleize(v.Title), parent: v.ParentTitle(), createdOn: *v.CreatedOn}
+	w.versions = append(w.versions, v)
+	s.wikiPages[key] = w
+	return nil
+}
+
+// deleteWikiPage deletes a page with its attachments. The children of the
+// page become root pages.
+func (s *Server) deleteWikiPage(key string) {
+	w := s.wikiPages[key]
+	for _, other := range s.wikiPages {
+		if other.projectId == w.projectId && strings.EqualFold(other.parent, w.title) {
+			other.parent = ""
+		}
+	}
+	for aid, a := range s.attachments {
+		if a.container == "wiki_page" && a.wikiKey == key {
+			delete(s.attachments, aid)
+		}
+	}
+	delete(s.wikiPages, key)
+}
+
+// wikiProject returns the project of the path variable project with the
+// permission on its wiki. Pages are served as JSON or plain text only.
+func (s *Server) wikiProject(r *request, permission string) (*model.Project, error) {
+	if r.format != "" && r.format != "json" && r.format != "txt" {
+		return nil, errNotFound
+	}
+	p, err := s.visibleProject(r, "project")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.require(r, p, permission); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// renderWikiPage returns version v of page w, or its text for the txt
+// format.
+func (s *Server) renderWikiPage(r *request, key string, w *wikiPage, v *model.WikiPage) any {
+	if r.format == "txt" {
+		return v.Text
+	}
+
+	out := *v
+	out.Title = w.title
+	out.Parent = nil
+	if w.parent != "" {
+		out.Parent = &model.WikiParent{Title: w.parent}
+	}
+	out.Author = s.principalRef(refId(v.Author))
+	out.CreatedOn = timePtr(w.createdOn)
+	out.Attachments = nil
+	if r.include("attachments") {
+		out.Attachments = s.attachmentsOf(r, "wiki_page", 0, key)
+	}
+	return map[string]any{"wiki_page": out}
+}
+
+func wikiIndex(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "view_wiki_pages")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pages := []model.WikiPage{}
+	for _, w := range s.wikiPages {
+		if w.projectId != p.Id {
+			continue
+		}
+		page := model.WikiPage{Title: w.title, Version: w.current().Version, CreatedOn: timePtr(w.createdOn), UpdatedOn: w.current().UpdatedOn}
+		if w.parent != "" {
+			page.Parent = &model.WikiParent{Title: w.parent}
+		}
+		pages = append(pages, page)
+	}
+	slices.SortFunc(pages, func(a, b model.WikiPage) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+	return http.StatusOK, map[string]any{"wiki_pages": pages}, nil
+}
+
+// wikiPage returns the page of project p with the title.
+func (s *Server) wikiPage(p *model.Project, title string) (string, *wikiPage, error) {
+	key := wikiKey(p.Id, title)
+	w, ok := s.wikiPages[key]
+	if !ok {
+		return "", nil, errNotFound
+	}
+	return key, w, nil
+}
+
+func wikiShowRoot(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "view_wiki_pages")
+	if err != nil {
+		return 0, nil, err
+	}
+	key, w, err := s.wikiPage(p, wikiStartPage)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, s.renderWikiPage(r, key, w, w.current()), nil
+}
+
+func wikiShow(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "view_wiki_pages")
+	if err != nil {
+		return 0, nil, err
+	}
+	key, w, err := s.wikiPage(p, r.vars["title"])
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, s.renderWikiPage(r, key, w, w.current()), nil
+}
+
+func wikiShowVersion(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "view_wiki_edits")
+	if err != nil {
+		return 0, nil, err
+	}
+	key, w, err := s.wikiPage(p, r.vars["title"])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version, err := strconv.Atoi(r.vars["version"])
+	if err != nil {
+		return 0, nil, errNotFound
+	}
+	i := slices.IndexFunc(w.versions, func(v model.WikiPage) bool { return v.Version == version })
+	if i < 0 {
+		return 0, nil, errNotFound
+	}
+	return http.StatusOK, s.renderWikiPage(r, key, w, &w.versions[i]), nil
+}
+
+// wikiUpdate creates or updates a page. A version other than the current one
+// is a conflict. Saving the same text only changes the parent.
+func wikiUpdate(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "edit_wiki_pages")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var in struct {
+		Text        *string       `json:"text"`
+		Comments    string        `json:"comments"`
+		Version     optInt        `json:"version"`
+		ParentTitle *string       `json:"parent_title"`
+		Uploads     []uploadInput `json:"uploads"`
+	}
+	if err := r.decode("wiki_page", &in); err != nil {
+		return 0, nil, err
+	}
+
+	title := titleize(r.vars["title"])
+	key := wikiKey(p.Id, title)
+	w, exists := s.wikiPages[key]
+	if exists && in.Version.set && in.Version.v != w.current().Version {
+		return 0, nil, &apiError{status: http.StatusConflict}
+	}
+
+	v := validation{}
+	text := ""
+	if exists {
+		text = w.current().Text
+	}
+	if in.Text != nil {
+		text = *in.Text
+	}
+	if text == "" {
+		v.add("Text cannot be blank")
+	}
+	if len(in.Comments) > 1024 {
+		v.add("Comment is too long (maximum is 1024 characters)")
+	}
+	if !exists && (title == "" || len(title) > 255) {
+		v.add("Title is invalid")
+	}
+
+	parent := ""
+	if exists {
+		parent = w.parent
+	}
+	if in.ParentTitle != nil {
+		parent = ""
+		if *in.ParentTitle != "" {
+			parentKey, pw, err := s.wikiPage(p, *in.ParentTitle)
+			if err != nil || parentKey == key || s.wikiDescends(pw, title) {
+				v.add("Parent page is invalid")
+			} else {
+				parent = pw.title
+			}
+		}
+	}
+	for _, u := range in.Uploads {
+		if _, ok := s.uploads[u.Token]; !ok {
+			v.add("Attachments is invalid")
+			break
+		}
+	}
+	if err := v.err(); err != nil {
+		return 0, nil, err
+	}
+
+	now := s.now()
+	if !exists {
+		w = &wikiPage{id: s.nextId("wiki_page"), projectId: p.Id, title: title, createdOn: now}
+		s.wikiPages[key] = w
+	}
+	w.parent = parent
+	if !exists || text != w.current().Text {
+		version := 1
+		if exists {
+			version = w.current().Version + 1
+		}
+		w.versions = append(w.versions, model.WikiPage{
+			Version:   version,
+			Text:      text,
+			Author:    &model.Ref{Id: r.userId()},
+			Comments:  in.Comments,
+			UpdatedOn: timePtr(now),
+		})
+	}
+	for _, u := range in.Uploads {
+		s.attach(u, "wiki_page", 0, key)
+	}
+
+	if !exists {
+		return http.StatusCreated, s.renderWikiPage(r, key, w, w.current()), nil
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// wikiDescends reports whether page w is the page titled title or one of
+// its descendants.
+func (s *Server) wikiDescends(w *wikiPage, title string) bool {
+	for seen := map[string]bool{}; w != nil && !seen[w.title]; w = s.wikiPages[wikiKey(w.projectId, w.parent)] {
+		if strings.EqualFold(w.title, title) {
+			return true
+		}
+		seen[w.title] = true
+		if w.parent == "" {
+			break
+		}
+	}
+	return false
+}
+
+func wikiDestroy(s *Server, r *request) (int, any, error) {
+	p, err := s.wikiProject(r, "delete_wiki_pages")
+	if err != nil {
+		return 0, nil, err
+	}
+	key, _, err := s.wikiPage(p, r.vars["title"])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.deleteWikiPage(key)
+	return http.StatusNoContent, nil, nil
+}
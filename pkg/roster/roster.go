@@ -0,0 +1,191 @@
+// Package roster provisions the users and the group memberships of a
+// Redmine server from a declarative roster, such as the export of an HR
+// system, in two steps: Plan compares the roster with the users and groups
+// of the server, Apply sends the planned changes and reports each of them.
+//
+// The roster is authoritative for the users it lists and for the members
+// of the groups it manages. The protected accounts are never changed.
+package roster
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/csvexport"
+)
+
+// Roster is the expected state of the users and the groups.
+type Roster struct {
+	// Users The users, identified by their logins.
+	Users []User `yaml:"users" json:"users"`
+
+	// Groups The names of the groups whose members are synchronized, the
+	// groups of the users if empty.
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+
+	// Protected The logins of the accounts never created, changed, locked
+	// or moved between groups, such as the administrators and the bots.
+	Protected []string `yaml:"protected,omitempty" json:"protected,omitempty"`
+
+	// LockMissing Whether to lock the users of the server missing from the
+	// roster. The administrators and the user of the synchronization are
+	// protected instead.
+	LockMissing bool `yaml:"lock_missing,omitempty" json:"lock_missing,omitempty"`
+}
+
+// User is a user of a roster. The empty fields of an existing user are left
+// unchanged.
+type User struct {
+	Login     string `yaml:"login" json:"login"`
+	Firstname string `yaml:"firstname,omitempty" json:"firstname,omitempty"`
+	Lastname  string `yaml:"lastname,omitempty" json:"lastname,omitempty"`
+	Mail      string `yaml:"mail,omitempty" json:"mail,omitempty"`
+
+	// Admin Whether the user is an administrator, unchanged if nil.
+	Admin *bool `yaml:"admin,omitempty" json:"admin,omitempty"`
+
+	// Locked Whether the user is locked. The other users are active.
+	Locked bool `yaml:"locked,omitempty" json:"locked,omitempty"`
+
+	// Groups The names of the groups of the user.
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// Load reads a roster from a YAML file, or a CSV file if its extension is
+// .csv.
+func Load(path string) (*Roster, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r *Roster
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		r, err = ParseCSV(f)
+	} else {
+		var buf []byte
+		if buf, err = io.ReadAll(f); err == nil {
+			r, err = Parse(buf)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Parse parses a YAML roster and validates it.
+func Parse(buf []byte) (*Roster, error) {
+	r := &Roster{}
+	if err := yaml.Unmarshal(buf, r); err != nil {
+		return nil, err
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ParseCSV parses a CSV roster, with a header naming the columns login,
+// firstname, lastname, mail, admin, locked and groups, the groups
+// separated by "|". Only login is required. The charset is detected.
+func ParseCSV(r io.Reader) (*Roster, error) {
+	decoded, _, err := csvexport.Decode(r, "")
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(decoded)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty roster")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["login"]; !ok {
+		return nil, errors.New("no login column")
+	}
+
+	roster := &Roster{}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		u := User{Login: get("login"), Firstname: get("firstname"), Lastname: get("lastname"), Mail: get("mail")}
+		if v := get("admin"); v != "" {
+			admin, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, admin: %w", line, err)
+			}
+			u.Admin = &admin
+		}
+		if v := get("locked"); v != "" {
+			if u.Locked, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("line %d, locked: %w", line, err)
+			}
+		}
+		for _, g := range strings.Split(get("groups"), "|") {
+			if g = strings.TrimSpace(g); g != "" {
+				u.Groups = append(u.Groups, g)
+			}
+		}
+		roster.Users = append(roster.Users, u)
+	}
+
+	if err := roster.Validate(); err != nil {
+		return nil, err
+	}
+	return roster, nil
+}
+
+// Validate checks that the users have unique logins.
+func (r *Roster) Validate() error {
+	logins := map[string]bool{}
+	for i, u := range r.Users {
+		login := strings.ToLower(u.Login)
+		if login == "" {
+			return fmt.Errorf("user %d: no login", i+1)
+		}
+		if logins[login] {
+			return fmt.Errorf("duplicate login %q", u.Login)
+		}
+		logins[login] = true
+	}
+	return nil
+}
+
+// IsProtected reports whether the account with login is protected.
+func (r *Roster) IsProtected(login string) bool {
+	for _, p := range r.Protected {
+		if strings.EqualFold(p, login) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package roster
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+const roster = `
+protected: [admin, ci]
+lock_missing: true
+users:
+  - login: jsmith
+    firstname: John
+    lastname: Smith
+    mail: john.smith@example.net
+    groups: [Developers]
+  - login: alice
+    firstname: Alice
+    lastname: Doe
+    mail: alice@example.net
+    groups: [developers, QA]
+  - login: bob
+    locked: true
+  - login: ci
+    groups: [QA]
+`
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Users = append(f.Users,
+		redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith", Mail: "jsmith@example.net"}},
+		redminetest.User{User: model.User{Id: 3, Login: "bob", Firstname: "Bob", Lastname: "Roe", Mail: "bob@example.net"}},
+		redminetest.User{User: model.User{Id: 4, Login: "carol", Firstname: "Carol", Lastname: "Poe", Mail: "carol@example.net"}},
+		redminetest.User{User: model.User{Id: 5, Login: "ci", Firstname: "CI", Lastname: "Bot", Mail: "ci@example.net"}},
+		redminetest.User{User: model.User{Id: 6, Login: "dave", Firstname: "Dave", Lastname: "Old", Mail: "dave@example.net", Status: model.UserStatusLocked}},
+	)
+	f.Groups = []model.Group{
+		{Id: 10, Name: "Developers", Users: []model.Ref{{Id: 2}, {Id: 4}}},
+		{Id: 11, Name: "QA", Users: []model.Ref{{Id: 5}, {Id: 3}}},
+	}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Users) != 4 || !r.LockMissing || !r.IsProtected("CI") || r.IsProtected("bob") || !r.Users[2].Locked {
+		t.Errorf("roster = %+v", r)
+	}
+
+	if _, err := Parse([]byte("users: [{login: a}, {login: A}]")); err == nil || !strings.Contains(err.Error(), "duplicate login") {
+		t.Errorf("duplicate: %v", err)
+	}
+	if _, err := Parse([]byte("users: [{mail: a@example.net}]")); err == nil || !strings.Contains(err.Error(), "no login") {
+		t.Errorf("no login: %v", err)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	r, err := ParseCSV(strings.NewReader("Login,Firstname,Lastname,Mail,Admin,Locked,Groups\n" +
+		"jsmith,John,Smith,john@example.net,,,Developers | QA\n" +
+		"bob,,,,true,yes,\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3, locked") {
+		t.Errorf("invalid locked: %v", err)
+	}
+
+	r, err = ParseCSV(strings.NewReader("login,groups,admin\njsmith,Developers|QA,\nbob,,true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Users) != 2 || strings.Join(r.Users[0].Groups, ",") != "Developers,QA" || r.Users[0].Admin != nil || !*r.Users[1].Admin {
+		t.Errorf("roster = %+v", r)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("name\nx\n")); err == nil {
+		t.Error("no login column")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	r, err := Parse([]byte(roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Syncer{Client: c, Auth: auth}
+	plan, err := s.Plan(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, a := range plan.Actions {
+		got = append(got, a.Kind+" "+a.Describe())
+	}
+	want := []string{
+		"update jsmith mail=jsmith@example.net->john.smith@example.net",
+		"create alice firstname=Alice lastname=Doe mail=alice@example.net",
+		"update bob status=active->locked",
+		"lock carol status=active->locked",
+		"add_member alice to Developers",
+		"remove_member carol from Developers",
+		"add_member alice to QA",
+		"remove_member bob from QA",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan =\n%s", strings.Join(got, "\n"))
+	}
+	if strings.Join(plan.Protected, ",") != "ci,admin" {
+		t.Errorf("protected = %v", plan.Protected)
+	}
+
+	at := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return at }
+	report := s.Apply(ctx, plan)
+	if report.Applied != len(want) || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	users, err := apiutil.Users(ctx, c, &redmine.UsersIndexParams{Query: &redmine.UsersIndexParams_Query{Status: new(string)}}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := map[string]int{}
+	for _, u := range users {
+		status[u.Login] = u.Status
+	}
+	if status["alice"] != model.UserStatusActive || status["bob"] != model.UserStatusLocked || status["carol"] != model.UserStatusLocked || status["admin"] != model.UserStatusActive {
+		t.Errorf("statuses = %v", status)
+	}
+	for id, want := range map[int][]int{10: {2, report.Actions[1].UserId}, 11: {5, report.Actions[1].UserId}} {
+		g, err := apiutil.Group(ctx, c, id, []string{"users"}, auth...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members := []int{}
+		for _, u := range g.Users {
+			members = append(members, u.Id)
+		}
+		if len(members) != len(want) || members[0] != want[0] || members[1] != want[1] {
+			t.Errorf("group %d = %v", id, members)
+		}
+	}
+
+	if plan, err := s.Plan(ctx, r); err != nil || len(plan.Actions) != 0 {
+		t.Errorf("second plan = %+v, %v", plan, err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Audit(&buf); err != nil {
+		t.Fatal(err)
+	}
+	audit := buf.String()
+	if !strings.HasPrefix(audit, "2025-01-06T09:00:00Z started, 8 actions\n2025-01-06T09:00:00Z update jsmith mail=") ||
+		!strings.Contains(audit, "2025-01-06T09:00:00Z protected ci: skipped\n") || !strings.HasSuffix(audit, "finished, 8 applied, 0 failed\n") {
+		t.Errorf("audit =\n%s", audit)
+	}
+}
+
+func TestPlanLockMissing(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	admin := true
+	if err := apiutil.UpdateUser(ctx, c, 3, apiutil.UserFields{Admin: &admin}, auth...); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Syncer{Client: c, Auth: auth}
+	plan, err := s.Plan(ctx, &Roster{Users: []User{{Login: "jsmith"}}, LockMissing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, a := range plan.Actions {
+		got = append(got, a.Kind+" "+a.Login)
+	}
+	if strings.Join(got, ",") != "lock carol,lock ci" {
+		t.Errorf("plan = %v", got)
+	}
+	if strings.Join(plan.Protected, ",") != "admin,bob" {
+		t.Errorf("protected = %v", plan.Protected)
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	s := &Syncer{Client: c, Auth: auth}
+
+	if _, err := s.Plan(ctx, &Roster{Users: []User{{Login: "jsmith", Groups: []string{"Ops"}}}}); err == nil || err.Error() != `group "Ops" not found` {
+		t.Errorf("unknown group: %v", err)
+	}
+
+	plan, err := s.Plan(ctx, &Roster{Users: []User{{Login: "bad login", Groups: []string{"QA"}}}, Groups: []string{"QA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := s.Apply(ctx, plan)
+	if report.Failed != 2 || report.Applied != 2 || !strings.Contains(report.Actions[0].Error, "Login is invalid") || report.Actions[1].Error != `user "bad login" not created` {
+		t.Errorf("report = %+v", report)
+	}
+}
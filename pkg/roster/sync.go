@@ -0,0 +1,437 @@
+package roster
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Kinds of actions. A plan lists the users to create, update and lock
+// first, then the members to add and remove, group by group.
+const (
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionLock         = "lock"
+	ActionAddMember    = "add_member"
+	ActionRemoveMember = "remove_member"
+)
+
+// Fields of FieldChange.
+const (
+	FieldFirstname = "firstname"
+	FieldLastname  = "lastname"
+	FieldMail      = "mail"
+	FieldAdmin     = "admin"
+	FieldStatus    = "status"
+)
+
+// FieldChange is the change of a field of a user.
+type FieldChange struct {
+	Field string `json:"field"`
+
+	// From The current value, "" for a new user. The statuses are active,
+	// registered or locked.
+	From string `json:"from"`
+
+	To string `json:"to"`
+}
+
+// Action is a change of the server planned to match the roster.
+type Action struct {
+	Kind  string `json:"action"`
+	Login string `json:"login"`
+
+	// UserId The ID of the user, 0 for a user to create.
+	UserId int `json:"user_id,omitempty"`
+
+	// Group The name of the group of a membership change.
+	Group string `json:"group,omitempty"`
+
+	// GroupId The ID of the group of a membership change.
+	GroupId int `json:"group_id,omitempty"`
+
+	// Changes The fields created or changed.
+	Changes []FieldChange `json:"changes,omitempty"`
+
+	// Applied Whether the action was sent and accepted.
+	Applied bool `json:"applied"`
+
+	// Error The error of the action, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// At The date and time when the action was applied or failed.
+	At *time.Time `json:"at,omitempty"`
+}
+
+// Plan is the list of the actions making the server match a roster.
+type Plan struct {
+	Actions []Action `json:"actions"`
+
+	// Protected The protected logins of the roster or the server, left
+	// as they are.
+	Protected []string `json:"protected,omitempty"`
+}
+
+// Report is the audit report of the application of a plan.
+type Report struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	// Applied The number of actions applied.
+	Applied int `json:"applied"`
+
+	// Failed The number of actions failed.
+	Failed int `json:"failed"`
+
+	Actions   []Action `json:"actions"`
+	Protected []string `json:"protected,omitempty"`
+}
+
+// Syncer plans and applies the changes of the users and groups of a server.
+// It requires an administrator.
+type Syncer struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	now func() time.Time
+}
+
+// Plan compares r with the users of the server, of any status, and the
+// members of the groups managed by r. The error is for a roster naming a
+// group missing from the server or a failed request.
+func (s *Syncer) Plan(ctx context.Context, r *Roster) (*Plan, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	// An empty status selects users of any status.
+	all := ""
+	users, err := apiutil.Users(ctx, s.Client, &redmine.UsersIndexParams{Query: &redmine.UsersIndexParams_Query{Status: &all}}, s.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	byLogin := map[string]*model.User{}
+	byId := map[int]*model.User{}
+	for i := range users {
+		byLogin[strings.ToLower(users[i].Login)] = &users[i]
+		byId[users[i].Id] = &users[i]
+	}
+
+	plan := &Plan{Actions: []Action{}}
+	protected := map[string]bool{}
+	protect := func(login string) {
+		if !protected[strings.ToLower(login)] {
+			protected[strings.ToLower(login)] = true
+			plan.Protected = append(plan.Protected, login)
+		}
+	}
+
+	listed := map[string]bool{}
+	for _, u := range r.Users {
+		listed[strings.ToLower(u.Login)] = true
+		if r.IsProtected(u.Login) {
+			protect(u.Login)
+			continue
+		}
+		current := byLogin[strings.ToLower(u.Login)]
+		if current == nil {
+			plan.Actions = append(plan.Actions, Action{Kind: ActionCreate, Login: u.Login, Changes: diff(&model.User{}, u, true)})
+		} else if changes := diff(current, u, false); len(changes) > 0 {
+			plan.Actions = append(plan.Actions, Action{Kind: ActionUpdate, Login: current.Login, UserId: current.Id, Changes: changes})
+		}
+	}
+
+	if r.LockMissing {
+		me, err := apiutil.CurrentUser(ctx, s.Client, s.Auth...)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if listed[strings.ToLower(u.Login)] {
+				continue
+			}
+			// Locking them could shut everyone out of the server.
+			if r.IsProtected(u.Login) || u.Admin || u.Id == me.Id {
+				protect(u.Login)
+				continue
+			}
+			if u.Status != model.UserStatusLocked {
+				plan.Actions = append(plan.Actions, Action{
+					Kind:    ActionLock,
+					Login:   u.Login,
+					UserId:  u.Id,
+					Changes: []FieldChange{{Field: FieldStatus, From: statusName(u.Status), To: statusName(model.UserStatusLocked)}},
+				})
+			}
+		}
+	}
+
+	members, err := s.planMembers(ctx, r, byLogin, byId, protect)
+	if err != nil {
+		return nil, err
+	}
+	plan.Actions = append(plan.Actions, members...)
+	return plan, nil
+}
+
+// planMembers returns the additions and removals of the members of the
+// groups managed by r, sorted by group and login.
+func (s *Syncer) planMembers(ctx context.Context, r *Roster, byLogin map[string]*model.User, byId map[int]*model.User, protect func(string)) ([]Action, error) {
+	names := r.Groups
+	if len(names) == 0 {
+		for _, u := range r.Users {
+			names = append(names, u.Groups...)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	groups, err := apiutil.Groups(ctx, s.Client, s.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	managed := []model.Group{}
+	for _, name := range names {
+		i := slices.IndexFunc(groups, func(g model.Group) bool { return strings.EqualFold(g.Name, name) })
+		if i < 0 {
+			return nil, fmt.Errorf("group %q not found", name)
+		}
+		if !slices.ContainsFunc(managed, func(g model.Group) bool { return g.Id == groups[i].Id }) {
+			managed = append(managed, groups[i])
+		}
+	}
+	slices.SortFunc(managed, func(a, b model.Group) int { return cmp.Compare(a.Name, b.Name) })
+
+	actions := []Action{}
+	for _, g := range managed {
+		group, err := apiutil.Group(ctx, s.Client, g.Id, []string{"users"}, s.Auth...)
+		if err != nil {
+			return nil, err
+		}
+
+		current := map[string]int{}
+		for _, m := range group.Users {
+			if u := byId[m.Id]; u != nil {
+				current[strings.ToLower(u.Login)] = u.Id
+			}
+		}
+
+		changes := []Action{}
+		wanted := map[string]bool{}
+		for _, u := range r.Users {
+			if !slices.ContainsFunc(u.Groups, func(name string) bool { return strings.EqualFold(name, g.Name) }) {
+				continue
+			}
+			login := strings.ToLower(u.Login)
+			wanted[login] = true
+			if _, ok := current[login]; ok || r.IsProtected(u.Login) {
+				continue
+			}
+			a := Action{Kind: ActionAddMember, Login: u.Login, Group: g.Name, GroupId: g.Id}
+			if existing := byLogin[login]; existing != nil {
+				a.UserId = existing.Id
+			}
+			changes = append(changes, a)
+		}
+		for login, id := range current {
+			if wanted[login] {
+				continue
+			}
+			if r.IsProtected(login) {
+				protect(byId[id].Login)
+				continue
+			}
+			changes = append(changes, Action{Kind: ActionRemoveMember, Login: byId[id].Login, UserId: id, Group: g.Name, GroupId: g.Id})
+		}
+		slices.SortFunc(changes, func(a, b Action) int {
+			return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Login, b.Login))
+		})
+		actions = append(actions, changes...)
+	}
+	return actions, nil
+}
+
+// diff returns the fields of current to change to match u.
+func diff(current *model.User, u User, isNew bool) []FieldChange {
+	changes := []FieldChange{}
+	text := func(field, from, to string) {
+		if to != "" && to != from {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	text(FieldFirstname, current.Firstname, u.Firstname)
+	text(FieldLastname, current.Lastname, u.Lastname)
+	text(FieldMail, current.Mail, u.Mail)
+	if u.Admin != nil && (*u.Admin != current.Admin || isNew && *u.Admin) {
+		changes = append(changes, FieldChange{Field: FieldAdmin, From: strconv.FormatBool(current.Admin), To: strconv.FormatBool(*u.Admin)})
+	}
+
+	status := model.UserStatusActive
+	if u.Locked {
+		status = model.UserStatusLocked
+	}
+	if isNew && u.Locked || !isNew && current.Status != status {
+		from := ""
+		if !isNew {
+			from = statusName(current.Status)
+		}
+		changes = append(changes, FieldChange{Field: FieldStatus, From: from, To: statusName(status)})
+	}
+	return changes
+}
+
+var statuses = map[int]string{
+	model.UserStatusActive:     "active",
+	model.UserStatusRegistered: "registered",
+	model.UserStatusLocked:     "locked",
+}
+
+func statusName(status int) string {
+	if name, ok := statuses[status]; ok {
+		return name
+	}
+	return strconv.Itoa(status)
+}
+
+// Apply applies the actions of plan in order and returns the audit report.
+// A failed action does not stop the others, but the memberships of a user
+// whose creation failed fail too. The new users get a generated password to
+// change at their first login.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan) *Report {
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+
+	report := &Report{Started: now(), Actions: slices.Clone(plan.Actions), Protected: plan.Protected}
+	created := map[string]int{}
+	for i := range report.Actions {
+		a := &report.Actions[i]
+		err := s.apply(ctx, a, created)
+		at := now()
+		a.At = &at
+		if err != nil {
+			a.Error = err.Error()
+			report.Failed++
+			continue
+		}
+		a.Applied = true
+		report.Applied++
+	}
+	report.Finished = now()
+	return report
+}
+
+func (s *Syncer) apply(ctx context.Context, a *Action, created map[string]int) error {
+	switch a.Kind {
+	case ActionCreate:
+		fields := userFields(a.Changes)
+		fields.Login = a.Login
+		generate := true
+		fields.GeneratePassword = &generate
+		fields.MustChangePasswd = &generate
+		u, err := apiutil.CreateUser(ctx, s.Client, fields, s.Auth...)
+		if err != nil {
+			return err
+		}
+		a.UserId = u.Id
+		created[strings.ToLower(a.Login)] = u.Id
+		return nil
+	case ActionUpdate, ActionLock:
+		return apiutil.UpdateUser(ctx, s.Client, a.UserId, userFields(a.Changes), s.Auth...)
+	case ActionAddMember:
+		if a.UserId == 0 {
+			a.UserId = created[strings.ToLower(a.Login)]
+		}
+		if a.UserId == 0 {
+			return fmt.Errorf("user %q not created", a.Login)
+		}
+		return apiutil.AddGroupUsers(ctx, s.Client, a.GroupId, []int{a.UserId}, s.Auth...)
+	case ActionRemoveMember:
+		return apiutil.RemoveGroupUser(ctx, s.Client, a.GroupId, a.UserId, s.Auth...)
+	}
+	return fmt.Errorf("unknown action %q", a.Kind)
+}
+
+// userFields returns the fields sent to apply changes.
+func userFields(changes []FieldChange) apiutil.UserFields {
+	f := apiutil.UserFields{}
+	for _, c := range changes {
+		to := c.To
+		switch c.Field {
+		case FieldFirstname:
+			f.Firstname = &to
+		case FieldLastname:
+			f.Lastname = &to
+		case FieldMail:
+			f.Mail = &to
+		case FieldAdmin:
+			admin := to == "true"
+			f.Admin = &admin
+		case FieldStatus:
+			for status, name := range statuses {
+				if name == to {
+					f.Status = &status
+				}
+			}
+		}
+	}
+	return f
+}
+
+// Audit writes the report as text, one line per action with its time and
+// outcome.
+func (r *Report) Audit(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s started, %d actions\n", r.Started.Format(time.RFC3339), len(r.Actions))
+	for _, a := range r.Actions {
+		at := r.Started
+		if a.At != nil {
+			at = *a.At
+		}
+		outcome := "ok"
+		if !a.Applied {
+			outcome = "FAILED: " + a.Error
+		}
+		fmt.Fprintf(b, "%s %s %s: %s\n", at.Format(time.RFC3339), a.Kind, a.Describe(), outcome)
+	}
+	for _, login := range r.Protected {
+		fmt.Fprintf(b, "%s protected %s: skipped\n", r.Started.Format(time.RFC3339), login)
+	}
+	fmt.Fprintf(b, "%s finished, %d applied, %d failed\n", r.Finished.Format(time.RFC3339), r.Applied, r.Failed)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Describe returns the subject and the changes of the action, such as
+// "jsmith mail=john@example.net" or "jsmith to Developers".
+func (a Action) Describe() string {
+	parts := []string{a.Login}
+	switch a.Kind {
+	case ActionAddMember:
+		parts = append(parts, "to", a.Group)
+	case ActionRemoveMember:
+		parts = append(parts, "from", a.Group)
+	}
+	for _, c := range a.Changes {
+		if c.From == "" {
+			parts = append(parts, c.Field+"="+c.To)
+		} else {
+			parts = append(parts, c.Field+"="+c.From+"->"+c.To)
+		}
+	}
+	return strings.Join(parts, " ")
+}
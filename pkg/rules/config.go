@@ -0,0 +1,233 @@
+// Package rules runs housekeeping rules over the issues: each rule selects
+// issues with a query of the issue list and conditions on their fields,
+// custom fields, age and journals, then changes them, adding a note,
+// setting the status, the assignee or the priority, or adding watchers.
+// The rules are written in YAML:
+//
+//	rules:
+//	  - name: stale-feedback
+//	    query: {project_id: web, status_id: "4"}
+//	    when:
+//	      tracker: [Bug]
+//	      no_notes_for: 14d
+//	    actions:
+//	      note: Closed without feedback.
+//	      status: Closed
+//
+// Every change made by a rule carries a marker in its note, "[rule:
+// <name>]", so that a rule fires once on an issue, or again only after
+// its repeat_after delay.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Config is a set of rules, run in order.
+type Config struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule is a condition on issues and the actions applied to the issues
+// matching it.
+type Rule struct {
+	// Name The unique name of the rule, written in the markers.
+	Name string `yaml:"name" json:"name"`
+
+	// Query The filters of the issue list selecting the issues, as the
+	// query parameters of the API, such as project_id or status_id. The
+	// open issues by default.
+	Query map[string]string `yaml:"query,omitempty" json:"query,omitempty"`
+
+	When    Condition `yaml:"when,omitempty" json:"when,omitempty"`
+	Actions Actions   `yaml:"actions" json:"actions"`
+
+	// RepeatAfter The delay after which the rule fires again on an issue,
+	// never if zero.
+	RepeatAfter Duration `yaml:"repeat_after,omitempty" json:"repeat_after,omitempty"`
+}
+
+// Condition is a condition on the issues. All the set fields must match.
+// The statuses, trackers and priorities are given by ID or name, the
+// users by ID, login or name.
+type Condition struct {
+	Status   List `yaml:"status,omitempty" json:"status,omitempty"`
+	Tracker  List `yaml:"tracker,omitempty" json:"tracker,omitempty"`
+	Priority List `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Author   List `yaml:"author,omitempty" json:"author,omitempty"`
+
+	// AssignedTo The assignees, "none" for the unassigned issues.
+	AssignedTo List `yaml:"assigned_to,omitempty" json:"assigned_to,omitempty"`
+
+	// CustomFields The values of the custom fields by ID, "" for no value.
+	CustomFields map[int]List `yaml:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+
+	// Subject A regular expression the subject matches.
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty"`
+
+	// OlderThan The minimum time since the creation.
+	OlderThan Duration `yaml:"older_than,omitempty" json:"older_than,omitempty"`
+
+	// IdleFor The minimum time since the last update.
+	IdleFor Duration `yaml:"idle_for,omitempty" json:"idle_for,omitempty"`
+
+	// NoNotesFor The minimum time since the last note, or the creation
+	// if none.
+	NoNotesFor Duration `yaml:"no_notes_for,omitempty" json:"no_notes_for,omitempty"`
+}
+
+// Actions are the changes applied to the issues.
+type Actions struct {
+	// Note The note added.
+	Note string `yaml:"note,omitempty" json:"note,omitempty"`
+
+	// PrivateNote Whether the note is private.
+	PrivateNote bool `yaml:"private_note,omitempty" json:"private_note,omitempty"`
+
+	// Status The ID or name of the new status.
+	Status string `yaml:"status,omitempty" json:"status,omitempty"`
+
+	// AssignTo The ID or login of the new assignee, "author" for the
+	// author or "none" to unassign.
+	AssignTo string `yaml:"assign_to,omitempty" json:"assign_to,omitempty"`
+
+	// Priority The ID or name of the new priority, "bump" for the next
+	// higher one.
+	Priority string `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Watchers The IDs or logins of the users added to the watchers.
+	Watchers []string `yaml:"watchers,omitempty" json:"watchers,omitempty"`
+}
+
+// List is a list of values, written as a list or a single value.
+type List []string
+
+// UnmarshalYAML accepts a scalar as a list of one value.
+func (l *List) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = List{n.Value}
+		return nil
+	}
+	var values []string
+	if err := n.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// Duration is a duration written as in the time package, with the units
+// d for days and w for weeks too, such as 14d.
+type Duration time.Duration
+
+// ParseDuration parses a duration with days and weeks.
+func ParseDuration(s string) (Duration, error) {
+	for unit, hours := range map[string]int{"d": 24, "w": 7 * 24} {
+		if n, ok := strings.CutSuffix(s, unit); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return Duration(v * float64(hours) * float64(time.Hour)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	return Duration(d), err
+}
+
+// UnmarshalYAML parses a duration with ParseDuration.
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	v, err := ParseDuration(n.Value)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON writes the duration as in the time package.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads rules from a YAML file.
+func Load(path string) (*Config, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses rules and validates them.
+func Parse(buf []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.Unmarshal(buf, c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that the rules have unique names, valid subject
+// expressions and at least one action.
+func (c *Config) Validate() error {
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("no rules")
+	}
+	names := map[string]bool{}
+	for i, r := range c.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule %d: no name", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("duplicate rule %q", r.Name)
+		}
+		names[r.Name] = true
+		if _, err := regexp.Compile(r.When.Subject); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		a := r.Actions
+		if a.Note == "" && a.Status == "" && a.AssignTo == "" && a.Priority == "" && len(a.Watchers) == 0 {
+			return fmt.Errorf("rule %q: no actions", r.Name)
+		}
+	}
+	return nil
+}
+
+// query returns the query of the issue list of the rule.
+func (r *Rule) query() (*redmine.IssuesIndexParams_Query, error) {
+	q := &redmine.IssuesIndexParams_Query{}
+	if len(r.Query) == 0 {
+		return q, nil
+	}
+	buf, err := json.Marshal(r.Query)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, q); err != nil {
+		return nil, fmt.Errorf("rule %q: query: %w", r.Name, err)
+	}
+	return q, nil
+}
+
+// Marker returns the marker of the notes of the changes made by the rule.
+func (r *Rule) Marker() string {
+	return "[rule: " + r.Name + "]"
+}
@@ -0,0 +1,449 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Fields of FieldChange.
+const (
+	FieldNote       = "note"
+	FieldStatus     = "status"
+	FieldAssignedTo = "assigned_to"
+	FieldPriority   = "priority"
+	FieldWatchers   = "watchers"
+)
+
+// FieldChange is the change of a field of an issue by a rule.
+type FieldChange struct {
+	Field string `json:"field"`
+
+	// From The current value, a name, "" if none.
+	From string `json:"from"`
+
+	To string `json:"to"`
+}
+
+// Result is the outcome of a rule on an issue matching its condition.
+type Result struct {
+	Rule    string `json:"rule"`
+	Id      int    `json:"id"`
+	Subject string `json:"subject"`
+
+	// Changes The changes of the actions of the rule.
+	Changes []FieldChange `json:"changes,omitempty"`
+
+	// Applied Whether the changes were sent and accepted. It is false in
+	// simulation mode and when the rule already fired.
+	Applied bool `json:"applied"`
+
+	// FiredOn The date and time of the last change made by the rule on the
+	// issue, when it is not due to fire again.
+	FiredOn *time.Time `json:"fired_on,omitempty"`
+
+	// Error The error of the changes, if they failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Engine runs rules over the issues of a server.
+type Engine struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Simulate Whether to only report the matches, without changing the
+	// issues.
+	Simulate bool
+
+	// Now returns the current time. Nil means time.Now.
+	Now func() time.Time
+
+	statuses   []model.Status
+	priorities []model.Ref
+	users      []model.User
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+// Run runs the rules of c once, in order, and returns a result for each
+// issue matching a rule. A failed change does not stop the others: the
+// error is that of a query or of the resolution of a name.
+func (e *Engine) Run(ctx context.Context, c *Config) ([]Result, error) {
+	results := []Result{}
+	for i := range c.Rules {
+		rs, err := e.run(ctx, &c.Rules[i])
+		if err != nil {
+			return results, fmt.Errorf("rule %q: %w", c.Rules[i].Name, err)
+		}
+		results = append(results, rs...)
+	}
+	return results, nil
+}
+
+// Schedule runs the rules of c every interval until ctx is done, starting
+// at once, and passes the results of each run to report. A failed run is
+// reported with its error and the next one still happens.
+func (e *Engine) Schedule(ctx context.Context, c *Config, interval time.Duration, report func([]Result, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report(e.Run(ctx, c))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) run(ctx context.Context, r *Rule) ([]Result, error) {
+	q, err := r.query()
+	if err != nil {
+		return nil, err
+	}
+	// Parse checks the expression, but the rules may be built without it.
+	subject, err := regexp.Compile(r.When.Subject)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := apiutil.Issues(ctx, e.Client, &redmine.IssuesIndexParams{Query: q}, e.Auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, listed := range issues {
+		ok, err := e.match(ctx, &r.When, &listed, subject)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// The journals tell the last note and whether the rule fired.
+		issue, err := apiutil.Issue(ctx, e.Client, listed.Id, []string{"journals", "watchers"}, e.Auth...)
+		if err != nil {
+			return nil, err
+		}
+		if r.When.NoNotesFor != 0 && e.now().Sub(lastNote(issue)) < time.Duration(r.When.NoNotesFor) {
+			continue
+		}
+
+		result := Result{Rule: r.Name, Id: issue.Id, Subject: issue.Subject}
+		if fired := firedOn(issue, r.Marker()); fired != nil && (r.RepeatAfter == 0 || e.now().Sub(*fired) < time.Duration(r.RepeatAfter)) {
+			result.FiredOn = fired
+			results = append(results, result)
+			continue
+		}
+
+		fields, watchers, changes, err := e.plan(ctx, r, issue)
+		if err != nil {
+			return nil, err
+		}
+		result.Changes = changes
+		if !e.Simulate {
+			if err := e.apply(ctx, issue.Id, fields, watchers); err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Applied = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// match reports whether the issue of the list matches the condition,
+// except for the notes, which are not listed.
+func (e *Engine) match(ctx context.Context, c *Condition, i *model.Issue, subject *regexp.Regexp) (bool, error) {
+	status := model.Ref{}
+	if i.Status != nil {
+		status = model.Ref{Id: i.Status.Id, Name: i.Status.Name}
+	}
+	if !matchRef(c.Status, &status) || !matchRef(c.Tracker, i.Tracker) || !matchRef(c.Priority, i.Priority) {
+		return false, nil
+	}
+	for _, users := range []struct {
+		values List
+		ref    *model.Ref
+	}{{c.Author, i.Author}, {c.AssignedTo, i.AssignedTo}} {
+		if ok, err := e.matchUser(ctx, users.values, users.ref); err != nil || !ok {
+			return false, err
+		}
+	}
+
+	for id, values := range c.CustomFields {
+		current := []string{}
+		if j := slices.IndexFunc(i.CustomFields, func(cf model.CustomField) bool { return cf.Id == id }); j >= 0 {
+			current = i.CustomFields[j].Values()
+		}
+		if len(current) == 0 {
+			current = []string{""}
+		}
+		if !slices.ContainsFunc(current, func(v string) bool { return slices.Contains(values, v) }) {
+			return false, nil
+		}
+	}
+
+	if !subject.MatchString(i.Subject) {
+		return false, nil
+	}
+	now := e.now()
+	if c.OlderThan != 0 && (i.CreatedOn == nil || now.Sub(*i.CreatedOn) < time.Duration(c.OlderThan)) {
+		return false, nil
+	}
+	if c.IdleFor != 0 && (i.UpdatedOn == nil || now.Sub(*i.UpdatedOn) < time.Duration(c.IdleFor)) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// matchRef reports whether ref has one of the IDs or names of values, true
+// if values is empty.
+func matchRef(values List, ref *model.Ref) bool {
+	if len(values) == 0 {
+		return true
+	}
+	if ref == nil {
+		return false
+	}
+	return slices.ContainsFunc(values, func(v string) bool {
+		return v == strconv.Itoa(ref.Id) || strings.EqualFold(v, ref.Name)
+	})
+}
+
+func (e *Engine) matchUser(ctx context.Context, values List, ref *model.Ref) (bool, error) {
+	if len(values) == 0 {
+		return true, nil
+	}
+	if ref == nil {
+		return slices.Contains(values, "none"), nil
+	}
+	if matchRef(values, ref) {
+		return true, nil
+	}
+	if err := e.loadUsers(ctx); err != nil {
+		return false, err
+	}
+	i := slices.IndexFunc(e.users, func(u model.User) bool { return u.Id == ref.Id })
+	return i >= 0 && slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, e.users[i].Login) }), nil
+}
+
+// lastNote returns the time of the last note of the issue, its creation
+// if none.
+func lastNote(i *model.Issue) time.Time {
+	last := time.Time{}
+	if i.CreatedOn != nil {
+		last = *i.CreatedOn
+	}
+	for _, j := range i.Journals {
+		if j.Notes != "" && j.CreatedOn.After(last) {
+			last = j.CreatedOn
+		}
+	}
+	return last
+}
+
+// firedOn returns the time of the last journal with marker, nil if none.
+func firedOn(i *model.Issue, marker string) *time.Time {
+	var fired *time.Time
+	for _, j := range i.Journals {
+		if strings.Contains(j.Notes, marker) && (fired == nil || j.CreatedOn.After(*fired)) {
+			fired = &j.CreatedOn
+		}
+	}
+	return fired
+}
+
+// plan returns the fields sent to apply the actions of r to the issue, the
+// watchers to add and the changes. The note always carries the marker.
+func (e *Engine) plan(ctx context.Context, r *Rule, i *model.Issue) (apiutil.IssueFields, []int, []FieldChange, error) {
+	a := r.Actions
+	fields := apiutil.IssueFields{}
+	changes := []FieldChange{}
+
+	if a.Status != "" {
+		if err := e.loadStatuses(ctx); err != nil {
+			return fields, nil, nil, err
+		}
+		status, err := find("status", a.Status, e.statuses, func(s model.Status) model.Ref { return model.Ref{Id: s.Id, Name: s.Name} })
+		if err != nil {
+			return fields, nil, nil, err
+		}
+		if i.Status == nil || i.Status.Id != status.Id {
+			fields.StatusId = &status.Id
+			from := ""
+			if i.Status != nil {
+				from = i.Status.Name
+			}
+			changes = append(changes, FieldChange{Field: FieldStatus, From: from, To: status.Name})
+		}
+	}
+
+	if a.Priority != "" {
+		if e.priorities == nil {
+			priorities, err := apiutil.Priorities(ctx, e.Client, e.Auth...)
+			if err != nil {
+				return fields, nil, nil, err
+			}
+			e.priorities = priorities
+		}
+		priority, err := e.priority(a.Priority, i.Priority)
+		if err != nil {
+			return fields, nil, nil, err
+		}
+		if priority != nil && (i.Priority == nil || i.Priority.Id != priority.Id) {
+			fields.PriorityId = &priority.Id
+			changes = append(changes, FieldChange{Field: FieldPriority, From: refName(i.Priority), To: priority.Name})
+		}
+	}
+
+	if a.AssignTo != "" {
+		assignee, err := e.assignee(ctx, a.AssignTo, i)
+		if err != nil {
+			return fields, nil, nil, err
+		}
+		current := 0
+		if i.AssignedTo != nil {
+			current = i.AssignedTo.Id
+		}
+		if assignee.Id != current {
+			if assignee.Id == 0 {
+				fields.Clear = append(fields.Clear, "assigned_to_id")
+			} else {
+				fields.AssignedToId = &assignee.Id
+			}
+			changes = append(changes, FieldChange{Field: FieldAssignedTo, From: refName(i.AssignedTo), To: assignee.Name})
+		}
+	}
+
+	watchers := []int{}
+	added := []string{}
+	for _, w := range a.Watchers {
+		if err := e.loadUsers(ctx); err != nil {
+			return fields, nil, nil, err
+		}
+		u, err := find("user", w, e.users, userRef)
+		if err != nil {
+			return fields, nil, nil, err
+		}
+		if !slices.ContainsFunc(i.Watchers, func(r model.Ref) bool { return r.Id == u.Id }) && !slices.Contains(watchers, u.Id) {
+			watchers = append(watchers, u.Id)
+			added = append(added, u.Name)
+		}
+	}
+	if len(added) > 0 {
+		changes = append(changes, FieldChange{Field: FieldWatchers, To: strings.Join(added, ", ")})
+	}
+
+	note := strings.TrimSpace(a.Note + "\n\n" + r.Marker())
+	fields.Notes = &note
+	if a.PrivateNote {
+		fields.PrivateNotes = &a.PrivateNote
+	}
+	if a.Note != "" {
+		changes = append(changes, FieldChange{Field: FieldNote, To: a.Note})
+	}
+	return fields, watchers, changes, nil
+}
+
+// priority returns the priority named v, or the one above current for
+// "bump", nil if current is the highest.
+func (e *Engine) priority(v string, current *model.Ref) (*model.Ref, error) {
+	if v != "bump" {
+		return find("priority", v, e.priorities, func(r model.Ref) model.Ref { return r })
+	}
+	if current == nil {
+		return nil, nil
+	}
+	i := slices.IndexFunc(e.priorities, func(r model.Ref) bool { return r.Id == current.Id })
+	if i < 0 || i+1 >= len(e.priorities) {
+		return nil, nil
+	}
+	return &e.priorities[i+1], nil
+}
+
+// assignee returns the user of v, with ID 0 for none.
+func (e *Engine) assignee(ctx context.Context, v string, i *model.Issue) (*model.Ref, error) {
+	switch v {
+	case "none":
+		return &model.Ref{}, nil
+	case "author":
+		if i.Author == nil {
+			return &model.Ref{}, nil
+		}
+		return i.Author, nil
+	}
+	if err := e.loadUsers(ctx); err != nil {
+		return nil, err
+	}
+	return find("user", v, e.users, userRef)
+}
+
+func (e *Engine) apply(ctx context.Context, id int, fields apiutil.IssueFields, watchers []int) error {
+	if err := apiutil.UpdateIssue(ctx, e.Client, id, fields, e.Auth...); err != nil {
+		return err
+	}
+	if len(watchers) > 0 {
+		return apiutil.AddWatchers(ctx, e.Client, id, watchers, e.Auth...)
+	}
+	return nil
+}
+
+func (e *Engine) loadStatuses(ctx context.Context) error {
+	if e.statuses != nil {
+		return nil
+	}
+	statuses, err := apiutil.Statuses(ctx, e.Client, e.Auth...)
+	e.statuses = statuses
+	return err
+}
+
+func (e *Engine) loadUsers(ctx context.Context) error {
+	if e.users != nil {
+		return nil
+	}
+	users, err := apiutil.Users(ctx, e.Client, nil, e.Auth...)
+	e.users = users
+	return err
+}
+
+// find returns the item whose ID or name is v, case-insensitively. The
+// users are named by their logins.
+func find[T any](kind, v string, items []T, ref func(T) model.Ref) (*model.Ref, error) {
+	for _, item := range items {
+		r := ref(item)
+		if v == strconv.Itoa(r.Id) || strings.EqualFold(v, r.Name) {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %q not found", kind, v)
+}
+
+func userRef(u model.User) model.Ref {
+	return model.Ref{Id: u.Id, Name: u.Login}
+}
+
+func refName(r *model.Ref) string {
+	if r == nil {
+		return ""
+	}
+	return r.Name
+}
@@ -0,0 +1,235 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+const config = `
+rules:
+  - name: stale
+    query: {status_id: "4"}
+    when:
+      tracker: Bug
+      idle_for: 4w
+      no_notes_for: 30d
+    actions:
+      note: Closed without feedback.
+      status: closed
+  - name: remind
+    when:
+      status: [Feedback]
+      tracker: [1]
+    actions:
+      note: Any news?
+  - name: triage
+    query: {project_id: web}
+    when:
+      status: New
+      assigned_to: none
+      older_than: 7d
+      custom_fields: {1: ""}
+    actions:
+      assign_to: jsmith
+      priority: bump
+      watchers: [alice, jsmith]
+`
+
+var now = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Web", Identifier: "web"}}
+	f.Users = append(f.Users,
+		redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith", Mail: "jsmith@example.net"}},
+		redminetest.User{User: model.User{Id: 3, Login: "alice", Firstname: "Alice", Lastname: "Doe", Mail: "alice@example.net"}},
+	)
+	f.Memberships = []model.Membership{
+		{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 3}, Roles: []model.MembershipRole{{Id: 2}}},
+	}
+	f.CustomFields = []redminetest.CustomField{{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"}}
+
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+	issue := func(id, tracker, status int, updated time.Time) model.Issue {
+		return model.Issue{
+			Id:        id,
+			Project:   &model.Ref{Id: 1},
+			Tracker:   &model.Ref{Id: tracker},
+			Status:    &model.Status{Id: status},
+			Priority:  &model.Ref{Id: 2},
+			Author:    &model.Ref{Id: 1},
+			Subject:   "Issue " + string(rune('0'+id)),
+			CreatedOn: &old,
+			UpdatedOn: &updated,
+		}
+	}
+	f.Issues = []model.Issue{issue(1, 1, 4, old), issue(2, 1, 4, recent), issue(3, 2, 4, old), issue(4, 1, 1, old)}
+	f.Issues[0].AssignedTo = &model.Ref{Id: 2}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.Rules[0]
+	if len(c.Rules) != 3 || r.When.Tracker[0] != "Bug" || r.When.IdleFor != Duration(28*24*time.Hour) || r.Marker() != "[rule: stale]" {
+		t.Errorf("rules = %+v", c)
+	}
+	if c.Rules[2].When.CustomFields[1][0] != "" {
+		t.Errorf("custom fields = %+v", c.Rules[2].When.CustomFields)
+	}
+
+	invalid := map[string]string{
+		"rules: []":                     "no rules",
+		"rules: [{actions: {note: a}}]": "no name",
+		"rules: [{name: a, actions: {note: a}}, {name: a, actions: {note: b}}]": "duplicate",
+		"rules: [{name: a}]": "no actions",
+		"rules: [{name: a, when: {subject: '('}, actions: {note: a}}]": "missing closing",
+		"rules: [{name: a, when: {idle_for: 3x}, actions: {note: a}}]": "unknown unit",
+	}
+	for src, want := range invalid {
+		if _, err := Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
+
+func describe(results []Result) string {
+	lines := []string{}
+	for _, r := range results {
+		changes := []string{}
+		for _, c := range r.Changes {
+			changes = append(changes, c.Field+":"+c.From+"->"+c.To)
+		}
+		line := r.Rule + " #" + string(rune('0'+r.Id)) + " " + strings.Join(changes, ", ")
+		if r.Applied {
+			line += " applied"
+		}
+		if r.FiredOn != nil {
+			line += " fired"
+		}
+		lines = append(lines, line+r.Error)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestRun(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	config, err := Parse([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Engine{Client: c, Auth: auth, Simulate: true, Now: func() time.Time { return now }}
+	results, err := e.Run(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "stale #1 status:Feedback->Closed, note:->Closed without feedback.\n" +
+		"remind #2 note:->Any news?\n" +
+		"remind #1 note:->Any news?\n" +
+		"triage #4 priority:Normal->High, assigned_to:->jsmith, watchers:->alice, jsmith"
+	if got := describe(results); got != want {
+		t.Errorf("simulation =\n%s", got)
+	}
+	if i, _ := apiutil.Issue(ctx, c, 1, nil, auth...); i.Status.Id != 4 {
+		t.Errorf("simulation changed issue 1: %+v", i.Status)
+	}
+
+	e.Simulate = false
+	results, err = e.Run(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "stale #1 status:Feedback->Closed, note:->Closed without feedback. applied\n" +
+		"remind #2 note:->Any news? applied\n" +
+		"triage #4 priority:Normal->High, assigned_to:->jsmith, watchers:->alice, jsmith applied"
+	if got := describe(results); got != want {
+		t.Errorf("run =\n%s", got)
+	}
+
+	i, err := apiutil.Issue(ctx, c, 1, []string{"journals"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Status.Id != 5 || len(i.Journals) != 1 || i.Journals[0].Notes != "Closed without feedback.\n\n[rule: stale]" {
+		t.Errorf("issue 1 = %+v, %+v", i.Status, i.Journals)
+	}
+	i, err = apiutil.Issue(ctx, c, 4, []string{"watchers"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.AssignedTo == nil || i.AssignedTo.Id != 2 || i.Priority.Id != 3 || len(i.Watchers) != 2 {
+		t.Errorf("issue 4 = %+v", i)
+	}
+
+	results, err = e.Run(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(results); got != "remind #2  fired" {
+		t.Errorf("second run =\n%s", got)
+	}
+
+	// The reminder fires again once its delay is over.
+	config.Rules[1].RepeatAfter = Duration(time.Hour)
+	e.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	results, err = e.Run(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(results); got != "remind #2 note:->Any news? applied" {
+		t.Errorf("repeat =\n%s", got)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	config, err := Parse([]byte("rules: [{name: a, actions: {status: Done}}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{Client: c, Auth: auth}
+	if _, err := e.Run(context.Background(), config); err == nil || err.Error() != `rule "a": status "Done" not found` {
+		t.Errorf("unknown status: %v", err)
+	}
+
+	config = &Config{Rules: []Rule{{Name: "b", When: Condition{Subject: "("}, Actions: Actions{Note: "n"}}}}
+	if _, err := e.Run(context.Background(), config); err == nil || !strings.Contains(err.Error(), "missing closing") {
+		t.Errorf("invalid subject: %v", err)
+	}
+}
+
+func TestRunUnassign(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	config, err := Parse([]byte(`rules: [{name: a, query: {issue_id: "1"}, actions: {assign_to: none}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{Client: c, Auth: auth}
+	results, err := e.Run(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(results); got != "a #1 assigned_to:John Smith-> applied" {
+		t.Errorf("run =\n%s", got)
+	}
+	if i, err := apiutil.Issue(ctx, c, 1, nil, auth...); err != nil || i.AssignedTo != nil {
+		t.Errorf("issue 1 = %+v, %v", i, err)
+	}
+}
@@ -0,0 +1,279 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Created is an issue created from a template.
+type Created struct {
+	Key     string `json:"key,omitempty"`
+	Id      int    `json:"id"`
+	Subject string `json:"subject"`
+
+	// Parent The ID of the parent issue, 0 for a top-level issue.
+	Parent int `json:"parent,omitempty"`
+}
+
+// Instantiator creates the issues of templates.
+type Instantiator struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Project The ID or identifier of the project of the issues, over the
+	// one of the template.
+	Project string
+
+	// Vars The values of the variables, over the defaults of the template.
+	Vars map[string]string
+
+	trackers   []model.Tracker
+	priorities []model.Ref
+	users      []model.User
+}
+
+// planned is an issue of a template ready to be created.
+type planned struct {
+	issue    *Issue
+	fields   apiutil.IssueFields
+	watchers []int
+
+	// parent The index of the parent issue in the plan, -1 if none.
+	parent int
+}
+
+// Instantiate creates the issues of t, parents first, then adds their
+// watchers and their relations. The variables and the names of trackers,
+// priorities and users are all resolved before the first issue is created.
+// When a step fails, the issues already created are deleted.
+func (in *Instantiator) Instantiate(ctx context.Context, t *Template) ([]Created, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan, err := in.plan(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	created := []Created{}
+	ids := map[string]int{}
+	for _, p := range plan {
+		c, err := in.create(ctx, p, created)
+		if c != nil {
+			created = append(created, *c)
+		}
+		if err != nil {
+			return nil, in.rollback(ctx, created, fmt.Errorf("%s: %w", *p.fields.Subject, err))
+		}
+		if p.issue.Key != "" {
+			ids[p.issue.Key] = c.Id
+		}
+	}
+
+	for n, p := range plan {
+		for _, r := range p.issue.Relations {
+			if _, err := apiutil.CreateRelation(ctx, in.Client, created[n].Id, ids[r.Issue], r.Type, in.Auth...); err != nil {
+				err = fmt.Errorf("%s: relation %s %s: %w", created[n].Subject, r.Type, r.Issue, err)
+				return nil, in.rollback(ctx, created, err)
+			}
+		}
+	}
+	return created, nil
+}
+
+// plan expands the variables of the issues of t and resolves their names.
+func (in *Instantiator) plan(ctx context.Context, t *Template) ([]planned, error) {
+	project := in.Project
+	if project == "" {
+		project = t.Project
+	}
+	if project == "" {
+		return nil, errors.New("no project")
+	}
+
+	vars := maps.Clone(t.Variables)
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	maps.Copy(vars, in.Vars)
+
+	plan := []planned{}
+	var add func(issues []Issue, parent int) error
+	add = func(issues []Issue, parent int) error {
+		for n := range issues {
+			i := &issues[n]
+			p, err := in.planIssue(ctx, i, project, vars)
+			if err != nil {
+				if i.Key != "" {
+					return fmt.Errorf("issue %q: %w", i.Key, err)
+				}
+				return fmt.Errorf("issue %q: %w", i.Subject, err)
+			}
+			p.parent = parent
+			plan = append(plan, *p)
+			if err := add(i.Children, len(plan)-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(t.Issues, -1); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (in *Instantiator) planIssue(ctx context.Context, i *Issue, project string, vars map[string]string) (*planned, error) {
+	p := &planned{issue: i, fields: apiutil.IssueFields{ProjectId: project, EstimatedHours: i.EstimatedHours}}
+
+	subject, err := expand(i.Subject, vars)
+	if err != nil {
+		return nil, err
+	}
+	p.fields.Subject = &subject
+	if i.Description != "" {
+		description, err := expand(i.Description, vars)
+		if err != nil {
+			return nil, err
+		}
+		p.fields.Description = &description
+	}
+
+	for _, cf := range i.CustomFields {
+		v, err := expandValue(cf.Value, vars)
+		if err != nil {
+			return nil, err
+		}
+		p.fields.CustomFields = append(p.fields.CustomFields, apiutil.CustomFieldValue{Id: cf.Id, Value: v})
+	}
+
+	if i.Tracker != "" {
+		id, err := in.resolveTracker(ctx, i.Tracker)
+		if err != nil {
+			return nil, err
+		}
+		p.fields.TrackerId = &id
+	}
+	if i.Priority != "" {
+		id, err := in.resolvePriority(ctx, i.Priority)
+		if err != nil {
+			return nil, err
+		}
+		p.fields.PriorityId = &id
+	}
+	if i.Assignee != "" {
+		assignee, err := expand(i.Assignee, vars)
+		if err != nil {
+			return nil, err
+		}
+		id, err := in.resolveUser(ctx, assignee)
+		if err != nil {
+			return nil, err
+		}
+		p.fields.AssignedToId = &id
+	}
+	for _, w := range i.Watchers {
+		login, err := expand(w, vars)
+		if err != nil {
+			return nil, err
+		}
+		id, err := in.resolveUser(ctx, login)
+		if err != nil {
+			return nil, err
+		}
+		p.watchers = append(p.watchers, id)
+	}
+	return p, nil
+}
+
+// create creates the issue of p, under its parent in created, and adds its
+// watchers. The issue is returned when created, even if adding the watchers
+// fails.
+func (in *Instantiator) create(ctx context.Context, p planned, created []Created) (*Created, error) {
+	fields := p.fields
+	if p.parent >= 0 {
+		fields.ParentIssueId = &created[p.parent].Id
+	}
+
+	issue, err := apiutil.CreateIssue(ctx, in.Client, fields, in.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Created{Key: p.issue.Key, Id: issue.Id, Subject: *fields.Subject}
+	if fields.ParentIssueId != nil {
+		c.Parent = *fields.ParentIssueId
+	}
+
+	if len(p.watchers) > 0 {
+		if err := apiutil.AddWatchers(ctx, in.Client, issue.Id, p.watchers, in.Auth...); err != nil {
+			return c, fmt.Errorf("watchers: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// rollback deletes the created issues, subtasks first, and returns err with
+// the errors of the deletions.
+func (in *Instantiator) rollback(ctx context.Context, created []Created, err error) error {
+	errs := []error{err}
+	for _, c := range slices.Backward(created) {
+		if err := apiutil.DeleteIssue(ctx, in.Client, c.Id, in.Auth...); err != nil && !apiutil.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("rollback of #%d: %w", c.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (in *Instantiator) resolveTracker(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if in.trackers == nil {
+		trackers, err := apiutil.Trackers(ctx, in.Client, in.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		in.trackers = trackers
+	}
+	return apiutil.FindByName("tracker", v, in.trackers, func(t model.Tracker) (int, string) { return t.Id, t.Name })
+}
+
+func (in *Instantiator) resolvePriority(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if in.priorities == nil {
+		priorities, err := apiutil.Priorities(ctx, in.Client, in.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		in.priorities = priorities
+	}
+	return apiutil.FindByName("priority", v, in.priorities, func(r model.Ref) (int, string) { return r.Id, r.Name })
+}
+
+func (in *Instantiator) resolveUser(ctx context.Context, v string) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+	if in.users == nil {
+		users, err := apiutil.Users(ctx, in.Client, nil, in.Auth...)
+		if err != nil {
+			return 0, err
+		}
+		in.users = users
+	}
+	return apiutil.FindByName("user", v, in.users, func(u model.User) (int, string) { return u.Id, u.Login })
+}
@@ -0,0 +1,231 @@
+// Package template creates a set of related issues, such as an onboarding
+// or a release checklist, from a template written in YAML.
+//
+// A template describes issues with their subtasks, watchers and relations
+// to each other:
+//
+//	project: hr
+//	variables:
+//	  team: IT
+//	issues:
+//	  - key: onboarding
+//	    tracker: Task
+//	    subject: Onboarding of ${name}
+//	    watchers: [jsmith]
+//	    children:
+//	      - key: laptop
+//	        subject: Order a laptop for ${name}
+//	        custom_fields:
+//	          - id: 2
+//	            value: ${team}
+//	      - key: accounts
+//	        subject: Create the accounts of ${name}
+//	        relations:
+//	          - type: follows
+//	            issue: laptop
+//
+// The variables ${name} of the strings are replaced with the values given
+// to Instantiator, or with the defaults of the template; $$ is a dollar
+// sign.
+package template
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template is a set of issues to create together.
+type Template struct {
+	// Name The name of the template.
+	Name string `yaml:"name,omitempty"`
+
+	// Project The ID or identifier of the project of the issues, when not
+	// given to Instantiator.
+	Project string `yaml:"project,omitempty"`
+
+	// Variables The default values of the variables.
+	Variables map[string]string `yaml:"variables,omitempty"`
+
+	// Issues The top-level issues.
+	Issues []Issue `yaml:"issues"`
+}
+
+// Issue is an issue of a template.
+type Issue struct {
+	// Key The name of the issue in the relations of the template.
+	Key string `yaml:"key,omitempty"`
+
+	// Tracker The ID or name of the tracker, the default of the project if
+	// empty.
+	Tracker string `yaml:"tracker,omitempty"`
+
+	// Priority The ID or name of the priority, the default one if empty.
+	Priority string `yaml:"priority,omitempty"`
+
+	// Assignee The ID or login of the assignee.
+	Assignee string `yaml:"assignee,omitempty"`
+
+	Subject     string `yaml:"subject"`
+	Description string `yaml:"description,omitempty"`
+
+	// EstimatedHours The estimated time to complete the issue.
+	EstimatedHours *float64 `yaml:"estimated_hours,omitempty"`
+
+	CustomFields []CustomField `yaml:"custom_fields,omitempty"`
+
+	// Watchers The IDs or logins of the watchers.
+	Watchers []string `yaml:"watchers,omitempty"`
+
+	// Children The subtasks of the issue.
+	Children []Issue `yaml:"children,omitempty"`
+
+	// Relations The relations from the issue to other issues of the
+	// template.
+	Relations []Relation `yaml:"relations,omitempty"`
+}
+
+// CustomField is the value of a custom field of an issue.
+type CustomField struct {
+	// Id The ID of the custom field.
+	Id int `yaml:"id"`
+
+	// Value The value of the custom field, a string or a list of strings.
+	Value any `yaml:"value"`
+}
+
+// Relation is a relation from an issue to another issue of the template.
+type Relation struct {
+	// Type The type of the relation: relates, duplicates, blocks, precedes,
+	// copied_to or their reverse.
+	Type string `yaml:"type"`
+
+	// Issue The key of the related issue.
+	Issue string `yaml:"issue"`
+}
+
+// Load reads the template in the YAML file path.
+func Load(path string) (*Template, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse parses a template in YAML and checks its keys and relations.
+func Parse(buf []byte) (*Template, error) {
+	t := &Template{}
+	dec := yaml.NewDecoder(strings.NewReader(string(buf)))
+	dec.KnownFields(true)
+	if err := dec.Decode(t); err != nil {
+		return nil, err
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// walk calls fn for each issue of issues and their children, parents first.
+func walk(issues []Issue, fn func(i *Issue) error) error {
+	for n := range issues {
+		if err := fn(&issues[n]); err != nil {
+			return err
+		}
+		if err := walk(issues[n].Children, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the template has issues with a subject, that their
+// keys are unique and that their relations are to issues of the template.
+func (t *Template) Validate() error {
+	if len(t.Issues) == 0 {
+		return fmt.Errorf("template has no issues")
+	}
+
+	keys := map[string]bool{}
+	err := walk(t.Issues, func(i *Issue) error {
+		if i.Subject == "" {
+			return fmt.Errorf("issue %q has no subject", i.Key)
+		}
+		if i.Key == "" {
+			return nil
+		}
+		if keys[i.Key] {
+			return fmt.Errorf("duplicate issue key %q", i.Key)
+		}
+		keys[i.Key] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return walk(t.Issues, func(i *Issue) error {
+		for _, r := range i.Relations {
+			if r.Type == "" {
+				return fmt.Errorf("issue %q: relation to %q has no type", i.Key, r.Issue)
+			}
+			if !keys[r.Issue] {
+				return fmt.Errorf("issue %q: relation to unknown issue %q", i.Key, r.Issue)
+			}
+			if r.Issue == i.Key {
+				return fmt.Errorf("issue %q: relation to itself", i.Key)
+			}
+		}
+		return nil
+	})
+}
+
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expand replaces the variables of s with their values in vars.
+func expand(s string, vars map[string]string) (string, error) {
+	var err error
+	s = variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$$" {
+			return "$"
+		}
+		name := m[2 : len(m)-1]
+		v, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("undefined variable %q", name)
+		}
+		return v
+	})
+	return s, err
+}
+
+// expandValue replaces the variables of the strings of a custom field
+// value.
+func expandValue(v any, vars map[string]string) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return expand(v, vars)
+	case []any:
+		out := []string{}
+		for _, item := range v {
+			s, err := expand(fmt.Sprint(item), vars)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	case nil:
+		return "", nil
+	default:
+		return expand(fmt.Sprint(v), vars)
+	}
+}
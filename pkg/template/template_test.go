@@ -0,0 +1,146 @@
+package template
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+const onboarding = `
+name: Onboarding
+project: hr
+variables:
+  team: IT
+issues:
+  - key: onboarding
+    tracker: support
+    subject: Onboarding of ${name}
+    description: |
+      Welcome ${name}, it costs $$0.
+    watchers: [jsmith]
+    children:
+      - key: laptop
+        subject: Order a laptop for ${name}
+        priority: High
+        assignee: jsmith
+        custom_fields:
+          - id: 1
+            value: ${team}
+      - key: accounts
+        subject: Create the accounts of ${name}
+        relations:
+          - type: follows
+            issue: laptop
+`
+
+func fixtures() *redminetest.Fixtures {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "HR", Identifier: "hr"}}
+	f.Users = append(f.Users, redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith"}})
+	f.Memberships = []model.Membership{{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}}}
+	f.CustomFields = []redminetest.CustomField{{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"}}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	tmpl, err := Parse([]byte(onboarding))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name != "Onboarding" || len(tmpl.Issues[0].Children) != 2 || tmpl.Issues[0].Children[1].Relations[0].Issue != "laptop" {
+		t.Errorf("template = %+v", tmpl)
+	}
+
+	invalid := map[string]string{
+		"issues: []":                 "no issues",
+		"issues: [{subject: a}, {}]": "no subject",
+		"issues: [{key: a, subject: a}, {key: a, subject: b}]":                  "duplicate",
+		"issues: [{key: a, subject: a, relations: [{type: blocks, issue: b}]}]": "unknown issue",
+		"issues: [{subject: a, tracker_id: 1}]":                                 "not found",
+	}
+	for src, want := range invalid {
+		if _, err := Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	tmpl, err := Parse([]byte(onboarding))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &Instantiator{Client: c, Auth: auth, Vars: map[string]string{"name": "Alice"}}
+	created, err := in.Instantiate(ctx, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 3 || created[1].Parent != created[0].Id || created[2].Key != "accounts" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	root, err := apiutil.Issue(ctx, c, created[0].Id, []string{"watchers"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Subject != "Onboarding of Alice" || root.Description != "Welcome Alice, it costs $0.\n" || root.Tracker.Id != 3 {
+		t.Errorf("root = %+v", root)
+	}
+	if len(root.Watchers) != 1 || root.Watchers[0].Id != 2 {
+		t.Errorf("watchers = %+v", root.Watchers)
+	}
+
+	laptop, _ := apiutil.Issue(ctx, c, created[1].Id, nil, auth...)
+	if laptop.Priority.Id != 3 || laptop.AssignedTo.Id != 2 || len(laptop.CustomFields) != 1 || laptop.CustomFields[0].Value != "IT" {
+		t.Errorf("laptop = %+v", laptop)
+	}
+
+	relations, err := apiutil.Relations(ctx, c, created[2].Id, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relations) != 1 || relations[0].IssueId != created[1].Id || relations[0].IssueToId != created[2].Id || relations[0].RelationType != "precedes" {
+		t.Errorf("relations = %+v", relations)
+	}
+}
+
+func TestInstantiateRollback(t *testing.T) {
+	c := redminetest.Client(t, fixtures())
+	ctx := context.Background()
+	tmpl, err := Parse([]byte(onboarding))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An undefined variable fails before any issue is created.
+	in := &Instantiator{Client: c, Auth: auth}
+	if _, err := in.Instantiate(ctx, tmpl); err == nil || !strings.Contains(err.Error(), `undefined variable "name"`) {
+		t.Errorf("undefined variable: %v", err)
+	}
+
+	// A watcher not found by the server fails after the first issues are
+	// created.
+	tmpl.Issues[0].Children[1].Watchers = []string{"99"}
+	in.Vars = map[string]string{"name": "Bob"}
+	if _, err := in.Instantiate(ctx, tmpl); err == nil || !strings.Contains(err.Error(), "watchers") {
+		t.Errorf("watcher: %v", err)
+	}
+
+	issues, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("issues left: %+v", issues)
+	}
+}
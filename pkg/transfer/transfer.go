@@ -0,0 +1,579 @@
+// Package transfer copies or moves issues to another project, on the same
+// Redmine server or on another one, with their attachments, watchers,
+// relations and subtasks.
+//
+// The copies are created in the target project. The tracker, status,
+// priority, category, version and custom fields are mapped by name, and
+// the users by login when the target is another server. A value that the
+// target lacks is left out of the copy and listed in the report. The
+// author and the dates of creation are those of the copy; the journals can
+// be carried over as notes.
+//
+// A move on the same server changes the project of the issue, which keeps
+// its ID, journals and logged time. A move to another server copies the
+// issue and deletes it: the time logged on it is lost with it.
+package transfer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// Field names of Drop.
+const (
+	FieldTracker      = "tracker"
+	FieldStatus       = "status"
+	FieldPriority     = "priority"
+	FieldCategory     = "category"
+	FieldFixedVersion = "fixed_version"
+	FieldAssignedTo   = "assigned_to"
+	FieldWatcher      = "watcher"
+	FieldCustomField  = "custom_field"
+	FieldRelation     = "relation"
+)
+
+// ErrTimeLogged is returned by Move when time is logged on the issues to
+// delete from the source server and DeleteTimeEntries is not set.
+var ErrTimeLogged = errors.New("time is logged on the issues")
+
+// Copied is an issue copied to the target project, or moved there with the
+// same ID.
+type Copied struct {
+	// Source The ID of the source issue.
+	Source int `json:"source"`
+
+	// Target The ID of the copy.
+	Target int `json:"target"`
+
+	Subject string `json:"subject"`
+
+	// Parent The ID of the parent of the copy, 0 for the copied issue.
+	Parent int `json:"parent,omitempty"`
+}
+
+// Drop is a value of a source issue left out of its copy because the
+// target lacks it.
+type Drop struct {
+	// Issue The ID of the source issue.
+	Issue int `json:"issue"`
+
+	// Field The kind of the value, such as tracker or watcher.
+	Field string `json:"field"`
+
+	// Value The name of the value: the name of the tracker, the login of the
+	// user, the name of the custom field or the relation.
+	Value string `json:"value"`
+}
+
+// Report is the outcome of a copy or a move.
+type Report struct {
+	// Issues The issues copied, parents first.
+	Issues []Copied `json:"issues"`
+
+	// Dropped The values left out of the copies.
+	Dropped []Drop `json:"dropped,omitempty"`
+
+	// Deleted Whether the source issue was deleted, once moved to another
+	// server.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Copier copies issues to a target project.
+type Copier struct {
+	// Source The client of the server of the issues.
+	Source redmine.ClientWithResponsesInterface
+
+	// SourceAuth The request editors authenticating the requests to Source.
+	SourceAuth []redmine.RequestEditorFn
+
+	// Target The client of the server of the target project, Source if nil.
+	Target redmine.ClientWithResponsesInterface
+
+	// TargetAuth The request editors authenticating the requests to Target,
+	// SourceAuth if Target is nil.
+	TargetAuth []redmine.RequestEditorFn
+
+	// Project The ID or identifier of the target project.
+	Project string
+
+	// Journals Whether to add the notes of the journals to the copies.
+	Journals bool
+
+	// DeleteTimeEntries Whether Move deletes the issues moved to another
+	// server even if time is logged on them, losing that time.
+	DeleteTimeEntries bool
+
+	mapping *targetProject
+	logins  map[int]string
+}
+
+// targetProject is what the names of the source issues map to.
+type targetProject struct {
+	trackers     []model.Ref
+	statuses     []model.Status
+	priorities   []model.Ref
+	categories   []model.Ref
+	versions     []model.Version
+	customFields []model.Ref
+
+	// assignable The IDs of the users and groups members of the project.
+	assignable []int
+
+	// users The users of the target server, nil on the same server.
+	users []model.User
+}
+
+// issue is a source issue to copy.
+type issue struct {
+	*model.Issue
+
+	// parent The index of the parent issue in the copy, -1 if none.
+	parent int
+}
+
+// Copy copies the issue with id and its subtasks to the target project.
+// The relations between the copied issues are copied as well; those to
+// other issues only on the same server. When a step fails, the copies
+// already created are deleted.
+func (cp *Copier) Copy(ctx context.Context, id int) (*Report, error) {
+	if cp.Project == "" {
+		return nil, errors.New("no target project")
+	}
+	if err := cp.load(ctx); err != nil {
+		return nil, err
+	}
+
+	issues, err := cp.fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{}
+	ids := map[int]int{}
+	for _, i := range issues {
+		c, err := cp.create(ctx, r, i)
+		if c != nil {
+			r.Issues = append(r.Issues, *c)
+		}
+		if err != nil {
+			return nil, cp.rollback(ctx, r.Issues, fmt.Errorf("#%d: %w", i.Id, err))
+		}
+		ids[i.Id] = c.Target
+	}
+
+	if err := cp.relate(ctx, r, issues, ids); err != nil {
+		return nil, cp.rollback(ctx, r.Issues, err)
+	}
+	return r, nil
+}
+
+// Move moves the issue with id and its subtasks to the target project.
+//
+// On the same server, the project of the issue is changed and the server
+// moves the subtasks and the logged time with it. The server clears the
+// tracker, category, version and assignee that the target project lacks;
+// they are listed in the report.
+//
+// On another server, the issue is copied as Copy does, then deleted with
+// its subtasks and the time logged on them, which the copies lack. Move
+// returns ErrTimeLogged instead, unless DeleteTimeEntries is set.
+func (cp *Copier) Move(ctx context.Context, id int) (*Report, error) {
+	if cp.Target == nil {
+		return cp.changeProject(ctx, id)
+	}
+
+	if !cp.DeleteTimeEntries {
+		// The ~ operator selects the issue and its subtasks.
+		tree := "~" + strconv.Itoa(id)
+		q := redmine.TimelogIndexParams_Query{IssueId: &tree}
+		entries, err := apiutil.TimeEntries(ctx, cp.Source, &redmine.TimelogIndexParams{Query: &q}, cp.SourceAuth...)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return nil, ErrTimeLogged
+		}
+	}
+
+	r, err := cp.Copy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := apiutil.DeleteIssue(ctx, cp.Source, id, cp.SourceAuth...); err != nil {
+		return r, fmt.Errorf("copied to #%d, but deleting #%d failed: %w", r.Issues[0].Target, id, err)
+	}
+	r.Deleted = true
+	return r, nil
+}
+
+// changeProject moves the issue with id to the target project on the same
+// server, and reports the values the server cleared.
+func (cp *Copier) changeProject(ctx context.Context, id int) (*Report, error) {
+	if cp.Project == "" {
+		return nil, errors.New("no target project")
+	}
+
+	issues, err := cp.fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := apiutil.UpdateIssue(ctx, cp.Source, id, apiutil.IssueFields{ProjectId: cp.Project}, cp.SourceAuth...); err != nil {
+		return nil, err
+	}
+
+	r := &Report{}
+	for _, i := range issues {
+		c := Copied{Source: i.Id, Target: i.Id, Subject: i.Subject}
+		if i.parent >= 0 {
+			c.Parent = issues[i.parent].Id
+		}
+		r.Issues = append(r.Issues, c)
+
+		moved, err := apiutil.Issue(ctx, cp.Source, i.Id, nil, cp.SourceAuth...)
+		if err != nil {
+			return r, fmt.Errorf("#%d: %w", i.Id, err)
+		}
+		cleared := func(field string, before, after *model.Ref) {
+			if before != nil && (after == nil || after.Name != before.Name) {
+				r.Dropped = append(r.Dropped, Drop{Issue: i.Id, Field: field, Value: before.Name})
+			}
+		}
+		cleared(FieldTracker, i.Tracker, moved.Tracker)
+		cleared(FieldCategory, i.Category, moved.Category)
+		cleared(FieldFixedVersion, i.FixedVersion, moved.FixedVersion)
+		cleared(FieldAssignedTo, i.AssignedTo, moved.AssignedTo)
+	}
+	return r, nil
+}
+
+// fetch returns the issue with id and its subtasks, parents first.
+func (cp *Copier) fetch(ctx context.Context, id int) ([]issue, error) {
+	include := []string{"attachments", "children", "relations", "watchers"}
+	if cp.Journals {
+		include = append(include, "journals")
+	}
+
+	issues := []issue{}
+	var add func(id, parent int) error
+	add = func(id, parent int) error {
+		i, err := apiutil.Issue(ctx, cp.Source, id, include, cp.SourceAuth...)
+		if err != nil {
+			return fmt.Errorf("#%d: %w", id, err)
+		}
+		issues = append(issues, issue{Issue: i, parent: parent})
+		n := len(issues) - 1
+		for _, c := range i.Children {
+			if err := add(c.Id, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(id, -1); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// create creates the copy of i under the copy of its parent, uploads its
+// attachments, adds its watchers and its notes. The copy is returned when
+// created, even if a later step fails.
+func (cp *Copier) create(ctx context.Context, r *Report, i issue) (*Copied, error) {
+	fields, err := cp.fields(ctx, r, i.Issue)
+	if err != nil {
+		return nil, err
+	}
+	if i.parent >= 0 {
+		fields.ParentIssueId = &r.Issues[i.parent].Target
+	}
+	for _, a := range i.Attachments {
+		u, err := cp.upload(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", a.Filename, err)
+		}
+		fields.Uploads = append(fields.Uploads, *u)
+	}
+
+	created, err := apiutil.CreateProjectIssue(ctx, cp.target(), cp.Project, fields, cp.targetAuth()...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Copied{Source: i.Id, Target: created.Id, Subject: i.Subject}
+	if fields.ParentIssueId != nil {
+		c.Parent = *fields.ParentIssueId
+	}
+
+	watchers := []int{}
+	for _, w := range i.Watchers {
+		id, ok, err := cp.user(ctx, w.Id)
+		if err != nil {
+			return c, fmt.Errorf("watchers: %w", err)
+		}
+		if !ok {
+			r.Dropped = append(r.Dropped, Drop{Issue: i.Id, Field: FieldWatcher, Value: cmp.Or(cp.logins[w.Id], w.Name)})
+			continue
+		}
+		watchers = append(watchers, id)
+	}
+	if len(watchers) > 0 {
+		if err := apiutil.AddWatchers(ctx, cp.target(), created.Id, watchers, cp.targetAuth()...); err != nil {
+			return c, fmt.Errorf("watchers: %w", err)
+		}
+	}
+
+	for _, j := range i.Journals {
+		if j.Notes == "" {
+			continue
+		}
+		notes := fmt.Sprintf("%s wrote on %s:\n\n%s", refName(j.User), j.CreatedOn.Format("2006-01-02 15:04"), j.Notes)
+		f := apiutil.IssueFields{Notes: &notes}
+		if j.PrivateNotes {
+			f.PrivateNotes = &j.PrivateNotes
+		}
+		if err := apiutil.UpdateIssue(ctx, cp.target(), created.Id, f, cp.targetAuth()...); err != nil {
+			return c, fmt.Errorf("journal %d: %w", j.Id, err)
+		}
+	}
+	return c, nil
+}
+
+// fields returns the fields of the copy of i, mapped to the target
+// project, and adds the values left out to r.
+func (cp *Copier) fields(ctx context.Context, r *Report, i *model.Issue) (apiutil.IssueFields, error) {
+	t := cp.mapping
+	subject, description := i.Subject, i.Description
+	f := apiutil.IssueFields{
+		Subject:        &subject,
+		StartDate:      i.StartDate,
+		DueDate:        i.DueDate,
+		DoneRatio:      &i.DoneRatio,
+		EstimatedHours: i.EstimatedHours,
+	}
+	if description != "" {
+		f.Description = &description
+	}
+	if i.IsPrivate {
+		f.IsPrivate = &i.IsPrivate
+	}
+
+	drop := func(field, value string) {
+		r.Dropped = append(r.Dropped, Drop{Issue: i.Id, Field: field, Value: value})
+	}
+	mapRef := func(field string, ref *model.Ref, items []model.Ref) *int {
+		if ref == nil {
+			return nil
+		}
+		if id, err := apiutil.FindByName(field, ref.Name, items, func(r model.Ref) (int, string) { return r.Id, r.Name }); err == nil {
+			return &id
+		}
+		drop(field, ref.Name)
+		return nil
+	}
+	f.TrackerId = mapRef(FieldTracker, i.Tracker, t.trackers)
+	f.PriorityId = mapRef(FieldPriority, i.Priority, t.priorities)
+	f.CategoryId = mapRef(FieldCategory, i.Category, t.categories)
+	if i.Status != nil {
+		if id, err := apiutil.FindByName(FieldStatus, i.Status.Name, t.statuses, func(s model.Status) (int, string) { return s.Id, s.Name }); err == nil {
+			f.StatusId = &id
+		} else {
+			drop(FieldStatus, i.Status.Name)
+		}
+	}
+	if i.FixedVersion != nil {
+		if id, err := apiutil.FindByName(FieldFixedVersion, i.FixedVersion.Name, t.versions, func(v model.Version) (int, string) { return v.Id, v.Name }); err == nil {
+			f.FixedVersionId = &id
+		} else {
+			drop(FieldFixedVersion, i.FixedVersion.Name)
+		}
+	}
+
+	if i.AssignedTo != nil {
+		id, ok, err := cp.user(ctx, i.AssignedTo.Id)
+		if err != nil {
+			return f, err
+		}
+		if ok && slices.Contains(t.assignable, id) {
+			f.AssignedToId = &id
+		} else {
+			drop(FieldAssignedTo, cmp.Or(cp.logins[i.AssignedTo.Id], i.AssignedTo.Name))
+		}
+	}
+
+	for _, cf := range i.CustomFields {
+		values := cf.Values()
+		if len(values) == 0 || len(values) == 1 && values[0] == "" {
+			continue
+		}
+		id, err := apiutil.FindByName(FieldCustomField, cf.Name, t.customFields, func(r model.Ref) (int, string) { return r.Id, r.Name })
+		if err != nil {
+			drop(FieldCustomField, cf.Name)
+			continue
+		}
+		var v any = values[0]
+		if cf.Multiple {
+			v = values
+		}
+		f.CustomFields = append(f.CustomFields, apiutil.CustomFieldValue{Id: id, Value: v})
+	}
+	return f, nil
+}
+
+// upload uploads the content of the source attachment a to the target.
+func (cp *Copier) upload(ctx context.Context, a model.Attachment) (*apiutil.Upload, error) {
+	content, err := apiutil.AttachmentContent(ctx, cp.Source, a.Id, cp.SourceAuth...)
+	if err != nil {
+		return nil, err
+	}
+	u, err := apiutil.UploadFile(ctx, cp.target(), a.Filename, a.ContentType, bytes.NewReader(content), cp.targetAuth()...)
+	if err != nil {
+		return nil, err
+	}
+	u.Description = a.Description
+	return u, nil
+}
+
+// relate copies the relations of the source issues. A relation between two
+// copied issues is copied once between the copies; a relation to another
+// issue is copied on the same server and dropped otherwise.
+func (cp *Copier) relate(ctx context.Context, r *Report, issues []issue, ids map[int]int) error {
+	done := map[int]bool{}
+	for _, i := range issues {
+		for _, rel := range i.Relations {
+			if done[rel.Id] {
+				continue
+			}
+			done[rel.Id] = true
+
+			from, fromOk := ids[rel.IssueId]
+			to, toOk := ids[rel.IssueToId]
+			if !fromOk || !toOk {
+				if cp.Target != nil {
+					r.Dropped = append(r.Dropped, Drop{Issue: i.Id, Field: FieldRelation, Value: fmt.Sprintf("#%d %s #%d", rel.IssueId, rel.RelationType, rel.IssueToId)})
+					continue
+				}
+				from, to = cmp.Or(from, rel.IssueId), cmp.Or(to, rel.IssueToId)
+			}
+
+			if _, err := apiutil.CreateRelation(ctx, cp.target(), from, to, rel.RelationType, cp.targetAuth()...); err != nil {
+				return fmt.Errorf("#%d: relation %s #%d: %w", rel.IssueId, rel.RelationType, rel.IssueToId, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rollback deletes the copies, subtasks first, and returns err with the
+// errors of the deletions.
+func (cp *Copier) rollback(ctx context.Context, copied []Copied, err error) error {
+	errs := []error{err}
+	for _, c := range slices.Backward(copied) {
+		if err := apiutil.DeleteIssue(ctx, cp.target(), c.Target, cp.targetAuth()...); err != nil && !apiutil.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("rollback of #%d: %w", c.Target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (cp *Copier) target() redmine.ClientWithResponsesInterface {
+	if cp.Target == nil {
+		return cp.Source
+	}
+	return cp.Target
+}
+
+func (cp *Copier) targetAuth() []redmine.RequestEditorFn {
+	if cp.Target == nil {
+		return cp.SourceAuth
+	}
+	return cp.TargetAuth
+}
+
+// load fetches the trackers, statuses, priorities, categories, versions,
+// custom fields and members of the target project.
+func (cp *Copier) load(ctx context.Context) error {
+	if cp.mapping != nil {
+		return nil
+	}
+	c, auth := cp.target(), cp.targetAuth()
+
+	p, err := apiutil.Project(ctx, c, cp.Project, []string{"trackers", "issue_categories", "issue_custom_fields"}, auth...)
+	if err != nil {
+		return fmt.Errorf("project %s: %w", cp.Project, err)
+	}
+	t := &targetProject{trackers: p.Trackers, categories: p.IssueCategories, customFields: p.IssueCustomFields}
+	if t.statuses, err = apiutil.Statuses(ctx, c, auth...); err != nil {
+		return err
+	}
+	if t.priorities, err = apiutil.Priorities(ctx, c, auth...); err != nil {
+		return err
+	}
+	if t.versions, err = apiutil.Versions(ctx, c, cp.Project, auth...); err != nil {
+		return err
+	}
+
+	members, err := apiutil.Memberships(ctx, c, cp.Project, auth...)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.User != nil {
+			t.assignable = append(t.assignable, m.User.Id)
+		}
+		if m.Group != nil {
+			t.assignable = append(t.assignable, m.Group.Id)
+		}
+	}
+
+	if cp.Target != nil {
+		if t.users, err = apiutil.Users(ctx, c, nil, auth...); err != nil {
+			return err
+		}
+	}
+	cp.mapping = t
+	cp.logins = map[int]string{}
+	return nil
+}
+
+// user returns the ID on the target of the source user with id, and
+// whether it exists there. On another server, the user is matched by login.
+func (cp *Copier) user(ctx context.Context, id int) (int, bool, error) {
+	if cp.Target == nil {
+		return id, true, nil
+	}
+
+	login, ok := cp.logins[id]
+	if !ok {
+		u, err := apiutil.User(ctx, cp.Source, id, nil, cp.SourceAuth...)
+		if err != nil && !apiutil.IsNotFound(err) {
+			return 0, false, fmt.Errorf("user %d: %w", id, err)
+		}
+		if u != nil {
+			login = u.Login
+		}
+		cp.logins[id] = login
+	}
+	if login == "" {
+		return 0, false, nil
+	}
+	i := slices.IndexFunc(cp.mapping.users, func(u model.User) bool { return u.Login == login })
+	if i < 0 {
+		return 0, false, nil
+	}
+	return cp.mapping.users[i].Id, true, nil
+}
+
+func refName(r *model.Ref) string {
+	if r == nil {
+		return "Anonymous"
+	}
+	return r.Name
+}
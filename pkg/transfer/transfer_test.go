@@ -0,0 +1,246 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+// newSource returns a server with the issue #1, its subtasks #2 and #4,
+// and the issue #3 related to #1, in the project web. Time is logged on #4.
+func newSource(t *testing.T) *redmine.ClientWithResponses {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{
+		{Id: 1, Name: "Web", Identifier: "web"},
+		{Id: 2, Name: "Mobile", Identifier: "mobile"},
+	}
+	f.Users = append(f.Users,
+		redminetest.User{User: model.User{Id: 2, Login: "jsmith", Firstname: "John", Lastname: "Smith"}},
+		redminetest.User{User: model.User{Id: 3, Login: "guest", Firstname: "Guest", Lastname: "User"}},
+	)
+	f.Memberships = []model.Membership{{Id: 1, Project: &model.Ref{Id: 1}, User: &model.Ref{Id: 2}, Roles: []model.MembershipRole{{Id: 2}}}}
+	f.CustomFields = []redminetest.CustomField{
+		{Id: 1, Name: "Team", CustomizedType: "issue", FieldFormat: "string"},
+		{Id: 2, Name: "Legacy", CustomizedType: "issue", FieldFormat: "string"},
+	}
+	f.Categories = []redminetest.Category{{Id: 1, Project: &model.Ref{Id: 1}, Name: "UI"}}
+	f.Versions = []model.Version{{Id: 1, Project: &model.Ref{Id: 1}, Name: "1.0"}}
+	f.Issues = []model.Issue{
+		{Id: 1, Project: &model.Ref{Id: 1}, Status: &model.Status{Id: 2}, Category: &model.Ref{Id: 1}, FixedVersion: &model.Ref{Id: 1}, AssignedTo: &model.Ref{Id: 2}, Subject: "Login fails", Description: "Steps.",
+			CustomFields: []model.CustomField{{Id: 1, Value: "web"}, {Id: 2, Value: "x"}},
+			Watchers:     []model.Ref{{Id: 2}, {Id: 3}},
+			Journals:     []model.Journal{{Id: 1, User: &model.Ref{Id: 2}, Notes: "Seen on Firefox.", CreatedOn: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)}},
+			Relations:    []model.Relation{{Id: 1, IssueToId: 3, RelationType: "relates"}}},
+		{Id: 2, Project: &model.Ref{Id: 1}, Parent: &struct {
+			Id int `json:"id"`
+		}{Id: 1}, Subject: "Check the form",
+			Relations: []model.Relation{{Id: 2, IssueToId: 4, RelationType: "blocks"}}},
+		{Id: 3, Project: &model.Ref{Id: 1}, Subject: "Session expires"},
+		{Id: 4, Project: &model.Ref{Id: 1}, Parent: &struct {
+			Id int `json:"id"`
+		}{Id: 1}, Subject: "Check the server"},
+	}
+	f.TimeEntries = []model.TimeEntry{{Id: 1, Project: &model.Ref{Id: 1}, Issue: &struct {
+		Id int `json:"id"`
+	}{Id: 4}, User: &model.Ref{Id: 1}, Hours: 2, SpentOn: date(2025, 1, 3)}}
+	c := redminetest.Client(t, f)
+
+	ctx := context.Background()
+	u, err := apiutil.UploadFile(ctx, c, "trace.log", "text/plain", strings.NewReader("trace"), auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiutil.UpdateIssue(ctx, c, 1, apiutil.IssueFields{Uploads: []apiutil.Upload{*u}}, auth...); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCopyToServer(t *testing.T) {
+	src := newSource(t)
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 5, Name: "Ops", Identifier: "ops"}}
+	f.Users = append(f.Users, redminetest.User{User: model.User{Id: 7, Login: "jsmith", Firstname: "John", Lastname: "Smith"}})
+	f.Memberships = []model.Membership{{Id: 1, Project: &model.Ref{Id: 5}, User: &model.Ref{Id: 7}, Roles: []model.MembershipRole{{Id: 2}}}}
+	f.CustomFields = []redminetest.CustomField{{Id: 4, Name: "Team", CustomizedType: "issue", FieldFormat: "string"}}
+	f.Versions = []model.Version{{Id: 3, Project: &model.Ref{Id: 5}, Name: "1.0"}}
+	dst := redminetest.Client(t, f)
+	ctx := context.Background()
+
+	cp := &Copier{Source: src, SourceAuth: auth, Target: dst, TargetAuth: auth, Project: "ops", Journals: true}
+	r, err := cp.Copy(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 3 || r.Issues[0].Source != 1 || r.Issues[1].Parent != r.Issues[0].Target || r.Issues[2].Source != 4 || r.Deleted {
+		t.Fatalf("report = %+v", r)
+	}
+	want := []Drop{
+		{Issue: 1, Field: FieldCategory, Value: "UI"},
+		{Issue: 1, Field: FieldCustomField, Value: "Legacy"},
+		{Issue: 1, Field: FieldWatcher, Value: "guest"},
+		{Issue: 1, Field: FieldRelation, Value: "#1 relates #3"},
+	}
+	if !slices.Equal(r.Dropped, want) {
+		t.Errorf("dropped = %+v", r.Dropped)
+	}
+
+	root, err := apiutil.Issue(ctx, dst, r.Issues[0].Target, []string{"attachments", "journals", "watchers"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Project.Id != 5 || root.Status.Id != 2 || root.FixedVersion == nil || root.FixedVersion.Id != 3 || root.AssignedTo == nil || root.AssignedTo.Id != 7 || root.Category != nil {
+		t.Errorf("root = %+v", root)
+	}
+	if len(root.CustomFields) != 1 || root.CustomFields[0].Id != 4 || root.CustomFields[0].Value != "web" {
+		t.Errorf("custom fields = %+v", root.CustomFields)
+	}
+	if len(root.Watchers) != 1 || root.Watchers[0].Id != 7 {
+		t.Errorf("watchers = %+v", root.Watchers)
+	}
+	if len(root.Journals) == 0 || root.Journals[len(root.Journals)-1].Notes != "John Smith wrote on 2025-01-02 10:00:\n\nSeen on Firefox." {
+		t.Errorf("journals = %+v", root.Journals)
+	}
+	if len(root.Attachments) != 1 || root.Attachments[0].Filename != "trace.log" {
+		t.Fatalf("attachments = %+v", root.Attachments)
+	}
+	if content, err := apiutil.AttachmentContent(ctx, dst, root.Attachments[0].Id, auth...); err != nil || string(content) != "trace" {
+		t.Errorf("content = %q, %v", content, err)
+	}
+
+	relations, err := apiutil.Relations(ctx, dst, r.Issues[1].Target, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relations) != 1 || relations[0].IssueId != r.Issues[1].Target || relations[0].IssueToId != r.Issues[2].Target || relations[0].RelationType != "blocks" {
+		t.Errorf("relations = %+v", relations)
+	}
+
+	if _, err := apiutil.Issue(ctx, src, 1, nil, auth...); err != nil {
+		t.Errorf("source of the copy: %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	c := newSource(t)
+	ctx := context.Background()
+
+	cp := &Copier{Source: c, SourceAuth: auth, Project: "mobile"}
+	r, err := cp.Move(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 3 || r.Issues[0].Target != 1 || r.Issues[1].Parent != 1 || r.Deleted {
+		t.Fatalf("report = %+v", r)
+	}
+	want := []Drop{
+		{Issue: 1, Field: FieldCategory, Value: "UI"},
+		{Issue: 1, Field: FieldFixedVersion, Value: "1.0"},
+		{Issue: 1, Field: FieldAssignedTo, Value: "John Smith"},
+	}
+	if !slices.Equal(r.Dropped, want) {
+		t.Errorf("dropped = %+v", r.Dropped)
+	}
+
+	root, err := apiutil.Issue(ctx, c, 1, []string{"journals", "relations", "watchers"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Project.Id != 2 || len(root.Watchers) != 2 || len(root.CustomFields) != 2 || root.Journals[0].Notes != "Seen on Firefox." {
+		t.Errorf("root = %+v", root)
+	}
+	if len(root.Relations) != 1 || root.Relations[0].IssueToId != 3 {
+		t.Errorf("relations = %+v", root.Relations)
+	}
+	if sub, err := apiutil.Issue(ctx, c, 4, nil, auth...); err != nil || sub.Project.Id != 2 {
+		t.Errorf("subtask = %+v, %v", sub, err)
+	}
+
+	entries, err := apiutil.TimeEntries(ctx, c, nil, auth...)
+	if err != nil || len(entries) != 1 || entries[0].Project.Id != 2 {
+		t.Errorf("time entries = %+v, %v", entries, err)
+	}
+}
+
+func TestMoveToServer(t *testing.T) {
+	src := newSource(t)
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 5, Name: "Ops", Identifier: "ops"}}
+	dst := redminetest.Client(t, f)
+	ctx := context.Background()
+
+	cp := &Copier{Source: src, SourceAuth: auth, Target: dst, TargetAuth: auth, Project: "ops"}
+	if _, err := cp.Move(ctx, 1); !errors.Is(err, ErrTimeLogged) {
+		t.Fatalf("move with logged time: %v", err)
+	}
+	if issues, err := apiutil.Issues(ctx, dst, nil, auth...); err != nil || len(issues) != 0 {
+		t.Errorf("copies = %+v, %v", issues, err)
+	}
+
+	cp.DeleteTimeEntries = true
+	r, err := cp.Move(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 3 || !r.Deleted {
+		t.Fatalf("report = %+v", r)
+	}
+	if _, err := apiutil.Issue(ctx, src, 4, nil, auth...); !apiutil.IsNotFound(err) {
+		t.Errorf("subtask not deleted: %v", err)
+	}
+	if entries, err := apiutil.TimeEntries(ctx, src, nil, auth...); err != nil || len(entries) != 0 {
+		t.Errorf("time entries = %+v, %v", entries, err)
+	}
+}
+
+func TestCopyRollback(t *testing.T) {
+	c := newSource(t)
+	ctx := context.Background()
+
+	cp := &Copier{Source: c, SourceAuth: auth, Project: "unknown"}
+	if _, err := cp.Copy(ctx, 1); !apiutil.IsNotFound(err) {
+		t.Errorf("unknown project: %v", err)
+	}
+
+	// The dates of the subtask #4, set by the fixtures, are rejected on the
+	// creation of its copy, after those of #1 and #2.
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Web", Identifier: "web"}}
+	f.Issues = []model.Issue{
+		{Id: 1, Project: &model.Ref{Id: 1}, Subject: "Login fails"},
+		{Id: 2, Project: &model.Ref{Id: 1}, Parent: &struct {
+			Id int `json:"id"`
+		}{Id: 1}, Subject: "Check the form"},
+		{Id: 4, Project: &model.Ref{Id: 1}, Parent: &struct {
+			Id int `json:"id"`
+		}{Id: 1}, Subject: "Check the server", StartDate: date(2025, 2, 1), DueDate: date(2025, 1, 1)},
+	}
+	c = redminetest.Client(t, f)
+
+	cp = &Copier{Source: c, SourceAuth: auth, Project: "web"}
+	if _, err := cp.Copy(ctx, 1); err == nil || !strings.Contains(err.Error(), "#4: ") {
+		t.Errorf("invalid subtask: %v", err)
+	}
+	issues, err := apiutil.Issues(ctx, c, &redmine.IssuesIndexParams{}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 3 {
+		t.Errorf("issues = %+v", issues)
+	}
+}
+
+func date(y int, m time.Month, d int) *openapi_types.Date {
+	return &openapi_types.Date{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
@@ -0,0 +1,90 @@
+// Package watch emits events for the changes of issues by polling a Redmine
+// server, which has no webhooks.
+//
+// A Watcher lists the issues updated since its previous poll and compares
+// them with their last-seen state and their new journals. The resulting
+// events are sent to a channel, and a Forwarder can POST them as JSON to
+// HTTP endpoints. Both persist their checkpoints so that a restart neither
+// loses nor repeats events.
+package watch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+)
+
+// EventType is the kind of an event.
+type EventType string
+
+const (
+	// IssueCreated is emitted when an issue is created.
+	IssueCreated EventType = "issue_created"
+
+	// StatusChanged is emitted when the status of an issue changes.
+	StatusChanged EventType = "status_changed"
+
+	// Assigned is emitted when the assignee of an issue changes.
+	Assigned EventType = "assigned"
+
+	// NoteAdded is emitted when a note is added to an issue.
+	NoteAdded EventType = "note_added"
+
+	// AttachmentAdded is emitted when a file is attached to an issue.
+	AttachmentAdded EventType = "attachment_added"
+
+	// Closed is emitted when an issue moves from an open to a closed status.
+	// It follows the StatusChanged event of the same change.
+	Closed EventType = "closed"
+)
+
+// Event is a change of an issue.
+type Event struct {
+	// Id The unique ID of the event. The same change always has the same ID,
+	// so receivers can discard duplicates.
+	Id string `json:"id"`
+
+	// Type The kind of the event.
+	Type EventType `json:"type"`
+
+	// IssueId The ID of the issue.
+	IssueId int `json:"issue_id"`
+
+	// Project The project of the issue.
+	Project *model.Ref `json:"project,omitempty"`
+
+	// Subject The subject of the issue.
+	Subject string `json:"subject"`
+
+	// User The user who made the change, if known.
+	User *model.Ref `json:"user,omitempty"`
+
+	// JournalId The ID of the journal recording the change, if any.
+	JournalId int `json:"journal_id,omitempty"`
+
+	// OldValue The status or assignee before the change.
+	OldValue *model.Ref `json:"old_value,omitempty"`
+
+	// NewValue The status or assignee after the change.
+	NewValue *model.Ref `json:"new_value,omitempty"`
+
+	// Notes The added note.
+	Notes string `json:"notes,omitempty"`
+
+	// Attachment The added attachment.
+	Attachment *model.Attachment `json:"attachment,omitempty"`
+
+	// CreatedOn The date and time of the change.
+	CreatedOn time.Time `json:"created_on"`
+}
+
+func newEvent(t EventType, issue *model.Issue, source string) Event {
+	return Event{
+		Id:      fmt.Sprintf("%d:%s:%s", issue.Id, source, t),
+		Type:    t,
+		IssueId: issue.Id,
+		Project: issue.Project,
+		Subject: issue.Subject,
+	}
+}
@@ -0,0 +1,246 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultRetries is the number of retries when Forwarder.Retries is zero.
+	DefaultRetries = 3
+
+	// DefaultBackoff is the first delay between retries when
+	// Forwarder.Backoff is zero. The delay doubles on each retry.
+	DefaultBackoff = time.Second
+)
+
+// DeliveryError is the failure to POST an event to an endpoint.
+type DeliveryError struct {
+	Endpoint string
+	Event    Event
+
+	// StatusCode The status code of the last response, or 0 if the request
+	// failed before a response.
+	StatusCode int
+
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("deliver %s to %s: %v", e.Event.Id, e.Endpoint, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// permanent reports whether retrying the delivery cannot succeed.
+func (e *DeliveryError) permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout &&
+		e.StatusCode != http.StatusTooManyRequests
+}
+
+// Forwarder POSTs events as JSON to HTTP endpoints.
+//
+// Each endpoint has its own queue of pending events, delivered in order.
+// An event stays queued until the endpoint answers with a 2xx status or
+// rejects it with a 4xx status, so an endpoint that is down receives the
+// queued events once it is back.
+type Forwarder struct {
+	// Endpoints The URLs the events are POSTed to.
+	Endpoints []string
+
+	// Client The HTTP client. Nil means http.DefaultClient.
+	Client *http.Client
+
+	// Retries The number of retries of a failed delivery before the
+	// endpoint is skipped until the next event or RetryInterval.
+	Retries int
+
+	// Backoff The first delay between retries.
+	Backoff time.Duration
+
+	// RetryInterval The time after which the pending events of skipped
+	// endpoints are retried. Zero means DefaultInterval.
+	RetryInterval time.Duration
+
+	// StatePath The file where the pending events are persisted.
+	// Empty keeps them in memory only.
+	StatePath string
+
+	// OnError is called with the failed deliveries. Permanent failures are
+	// dropped from the queue.
+	OnError func(*DeliveryError)
+
+	pending map[string][]Event
+}
+
+func (f *Forwarder) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
+
+// Run forwards the events received from events until ctx is done or events
+// is closed, in which case the pending events are delivered before returning.
+func (f *Forwarder) Run(ctx context.Context, events <-chan Event) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+
+	interval := f.RetryInterval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if err := f.flush(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case e, ok := <-events:
+			if !ok {
+				return f.flush(ctx)
+			}
+			if err := f.Enqueue(e); err != nil {
+				return err
+			}
+		}
+
+		if err := f.flush(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *Forwarder) load() error {
+	if f.pending != nil {
+		return nil
+	}
+
+	f.pending = map[string][]Event{}
+	if f.StatePath == "" {
+		return nil
+	}
+	return loadJSON(f.StatePath, &f.pending)
+}
+
+func (f *Forwarder) save() error {
+	if f.StatePath == "" {
+		return nil
+	}
+	return saveJSON(f.StatePath, f.pending)
+}
+
+// Enqueue adds e to the queue of every endpoint and persists the queues.
+func (f *Forwarder) Enqueue(e Event) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+
+	for _, endpoint := range f.Endpoints {
+		f.pending[endpoint] = append(f.pending[endpoint], e)
+	}
+	return f.save()
+}
+
+// Pending returns the number of events waiting to be delivered to endpoint.
+func (f *Forwarder) Pending(endpoint string) int {
+	return len(f.pending[endpoint])
+}
+
+// flush delivers the pending events of every endpoint. An endpoint is skipped
+// at its first event that cannot be delivered after the retries.
+func (f *Forwarder) flush(ctx context.Context) error {
+	for _, endpoint := range f.Endpoints {
+		for len(f.pending[endpoint]) > 0 {
+			e := f.pending[endpoint][0]
+
+			err := f.deliver(ctx, endpoint, e)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && f.OnError != nil {
+				f.OnError(err)
+			}
+			if err != nil && !err.permanent() {
+				break
+			}
+
+			f.pending[endpoint] = f.pending[endpoint][1:]
+			if err := f.save(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Forwarder) deliver(ctx context.Context, endpoint string, e Event) *DeliveryError {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return &DeliveryError{Endpoint: endpoint, Event: e, Err: err}
+	}
+
+	retries := f.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+
+	backoff := f.Backoff
+	if backoff == 0 {
+		backoff = DefaultBackoff
+	}
+
+	var last *DeliveryError
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return &DeliveryError{Endpoint: endpoint, Event: e, Err: ctx.Err()}
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		last = f.post(ctx, endpoint, e, body)
+		if last == nil || last.permanent() {
+			return last
+		}
+	}
+	return last
+}
+
+func (f *Forwarder) post(ctx context.Context, endpoint string, e Event, body []byte) *DeliveryError {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return &DeliveryError{Endpoint: endpoint, Event: e, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Redmine-Event", string(e.Type))
+	req.Header.Set("X-Redmine-Event-Id", e.Id)
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return &DeliveryError{Endpoint: endpoint, Event: e, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &DeliveryError{Endpoint: endpoint, Event: e, StatusCode: resp.StatusCode, Err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	return nil
+}
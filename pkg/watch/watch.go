@@ -0,0 +1,492 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// DefaultInterval is the time between two polls when Watcher.Interval is zero.
+const DefaultInterval = time.Minute
+
+// snapshot is the last-seen state of an issue.
+type snapshot struct {
+	UpdatedOn   *time.Time `json:"updated_on,omitempty"`
+	Status      *model.Ref `json:"status,omitempty"`
+	AssignedTo  *model.Ref `json:"assigned_to,omitempty"`
+	JournalId   int        `json:"journal_id,omitempty"`
+	Attachments []int      `json:"attachments,omitempty"`
+}
+
+// state is the checkpoint of a Watcher.
+type state struct {
+	// UpdatedOn The updated_on of the most recently updated issue seen.
+	UpdatedOn time.Time `json:"updated_on"`
+
+	Issues map[int]*snapshot `json:"issues"`
+}
+
+// Watcher polls a Redmine server for changed issues.
+type Watcher struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Projects The IDs or identifiers of the watched projects, including
+	// their subprojects. Empty means every visible issue.
+	Projects []string
+
+	// Interval The time between two polls of Run.
+	Interval time.Duration
+
+	// StatePath The file where the checkpoint is persisted. Empty keeps it in
+	// memory only, so that a new Watcher starts from the current state.
+	StatePath string
+
+	// OnError is called with the errors of Run, which keeps polling.
+	// Nil makes Run return the first error.
+	OnError func(error)
+
+	// Now returns the current time. Nil means time.Now.
+	Now func() time.Time
+
+	state    *state
+	statuses map[int]model.Status
+}
+
+func (w *Watcher) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+// Run polls every Interval and sends the events to events until ctx is
+// done. The checkpoint is saved once every event of a poll has been received.
+func (w *Watcher) Run(ctx context.Context, events chan<- Event) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	for {
+		if err := w.step(ctx, events); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError == nil {
+				return err
+			}
+			w.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *Watcher) step(ctx context.Context, events chan<- Event) error {
+	evs, next, err := w.poll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range evs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case events <- e:
+		}
+	}
+
+	return w.commit(next)
+}
+
+// Poll returns the events since the previous poll and saves the checkpoint.
+// The first poll of a new checkpoint only records the current state and
+// returns no event.
+func (w *Watcher) Poll(ctx context.Context) ([]Event, error) {
+	evs, next, err := w.poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return evs, w.commit(next)
+}
+
+func (w *Watcher) commit(next *state) error {
+	w.state = next
+	if w.StatePath == "" {
+		return nil
+	}
+	return saveJSON(w.StatePath, next)
+}
+
+func (w *Watcher) load(ctx context.Context) error {
+	if w.state == nil {
+		s := &state{Issues: map[int]*snapshot{}}
+		if w.StatePath != "" {
+			if err := loadJSON(w.StatePath, s); err != nil {
+				return err
+			}
+		}
+		w.state = s
+	}
+
+	if w.statuses == nil {
+		statuses, err := apiutil.Statuses(ctx, w.Client, w.Auth...)
+		if err != nil {
+			return err
+		}
+
+		w.statuses = map[int]model.Status{}
+		for _, s := range statuses {
+			w.statuses[s.Id] = s
+		}
+	}
+
+	return nil
+}
+
+// projects returns the project filters, where "" means no filter.
+func (w *Watcher) projects() []string {
+	if len(w.Projects) == 0 {
+		return []string{""}
+	}
+	return w.Projects
+}
+
+// changed returns the issues updated at or after since, or the most recently
+// updated issue of each project if since is zero, ordered by updated_on.
+func (w *Watcher) changed(ctx context.Context, since time.Time) ([]model.Issue, error) {
+	byId := map[int]model.Issue{}
+	for _, project := range w.projects() {
+		all := "*"
+		q := redmine.IssuesIndexParams_Query{StatusId: &all}
+		if project != "" {
+			q.ProjectId = &project
+		}
+
+		var issues []model.Issue
+		if since.IsZero() {
+			q.Set("sort", "updated_on:desc")
+			resp, err := w.Client.IssuesIndexWithResponse(ctx, &redmine.IssuesIndexParams{Query: &q, Pagination: apiutil.Page(0, 1)}, w.Auth...)
+			if err != nil {
+				return nil, err
+			}
+			if err := apiutil.Check(resp, resp.Body); err != nil {
+				return nil, err
+			}
+
+			var page struct {
+				Issues []model.Issue `json:"issues"`
+			}
+			if err := json.Unmarshal(resp.Body, &page); err != nil {
+				return nil, err
+			}
+			issues = page.Issues
+		} else {
+			expr := ">=" + since.UTC().Format(time.RFC3339)
+			q.UpdatedOn = &expr
+
+			var err error
+			issues, err = apiutil.Issues(ctx, w.Client, &redmine.IssuesIndexParams{Query: &q}, w.Auth...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, i := range issues {
+			byId[i.Id] = i
+		}
+	}
+
+	issues := make([]model.Issue, 0, len(byId))
+	for _, i := range byId {
+		issues = append(issues, i)
+	}
+	sort.Slice(issues, func(a, b int) bool {
+		ta, tb := issues[a].UpdatedOn, issues[b].UpdatedOn
+		if ta != nil && tb != nil && !ta.Equal(*tb) {
+			return ta.Before(*tb)
+		}
+		return issues[a].Id < issues[b].Id
+	})
+
+	return issues, nil
+}
+
+func (w *Watcher) poll(ctx context.Context) ([]Event, *state, error) {
+	if err := w.load(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	prev := w.state
+	next := &state{UpdatedOn: prev.UpdatedOn, Issues: make(map[int]*snapshot, len(prev.Issues))}
+	for id, s := range prev.Issues {
+		next.Issues[id] = s
+	}
+
+	baseline := prev.UpdatedOn.IsZero()
+
+	issues, err := w.changed(ctx, prev.UpdatedOn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := []Event{}
+	for _, i := range issues {
+		snap := prev.Issues[i.Id]
+		if snap != nil && apiutil.TimeEqual(snap.UpdatedOn, i.UpdatedOn) {
+			continue
+		}
+
+		// The issue index cannot include journals.
+		full, err := apiutil.Issue(ctx, w.Client, i.Id, []string{"attachments", "journals"}, w.Auth...)
+		if apiutil.IsNotFound(err) || apiutil.IsForbidden(err) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		if !baseline {
+			events = append(events, w.diff(full, snap, prev.UpdatedOn)...)
+		}
+
+		next.Issues[i.Id] = newSnapshot(full)
+		if full.UpdatedOn != nil && full.UpdatedOn.After(next.UpdatedOn) {
+			next.UpdatedOn = *full.UpdatedOn
+		}
+	}
+
+	if next.UpdatedOn.IsZero() {
+		// No visible issue yet: start from now.
+		next.UpdatedOn = w.now()
+	}
+
+	return events, next, nil
+}
+
+func newSnapshot(i *model.Issue) *snapshot {
+	s := &snapshot{UpdatedOn: i.UpdatedOn, AssignedTo: i.AssignedTo}
+	if i.Status != nil {
+		s.Status = &model.Ref{Id: i.Status.Id, Name: i.Status.Name}
+	}
+	for _, j := range i.Journals {
+		s.JournalId = max(s.JournalId, j.Id)
+	}
+	for _, a := range i.Attachments {
+		s.Attachments = append(s.Attachments, a.Id)
+	}
+	return s
+}
+
+func (w *Watcher) isClosed(r *model.Ref) bool {
+	return r != nil && w.statuses[r.Id].IsClosed
+}
+
+func (w *Watcher) statusRef(v *string) *model.Ref {
+	id := parseId(v)
+	if id == 0 {
+		return nil
+	}
+	return &model.Ref{Id: id, Name: w.statuses[id].Name}
+}
+
+func parseId(v *string) int {
+	if v == nil {
+		return 0
+	}
+	id, _ := strconv.Atoi(*v)
+	return id
+}
+
+// userRef returns a reference to the user with the ID in v, named after
+// whichever of the known references has that ID.
+func userRef(v *string, known ...*model.Ref) *model.Ref {
+	id := parseId(v)
+	if id == 0 {
+		return nil
+	}
+	for _, k := range known {
+		if k != nil && k.Id == id {
+			return &model.Ref{Id: id, Name: k.Name}
+		}
+	}
+	return &model.Ref{Id: id}
+}
+
+// diff returns the events of an issue changed since its snapshot. A nil
+// snapshot means the issue has not been seen, in which case the journals
+// created at or after since are new.
+func (w *Watcher) diff(issue *model.Issue, snap *snapshot, since time.Time) []Event {
+	events := []Event{}
+	emitted := map[EventType]bool{}
+	attached := map[int]bool{}
+
+	emit := func(e Event) {
+		events = append(events, e)
+		emitted[e.Type] = true
+	}
+
+	attachment := func(id int, user *model.Ref, journalId int, at time.Time) {
+		if attached[id] {
+			return
+		}
+		attached[id] = true
+
+		for _, a := range issue.Attachments {
+			if a.Id == id {
+				e := newEvent(AttachmentAdded, issue, fmt.Sprintf("a%d", id))
+				e.User = user
+				e.JournalId = journalId
+				e.Attachment = &a
+				e.CreatedOn = at
+				if a.CreatedOn != nil && e.CreatedOn.IsZero() {
+					e.CreatedOn = *a.CreatedOn
+				}
+				emit(e)
+				return
+			}
+		}
+	}
+
+	created := snap == nil && issue.CreatedOn != nil && !issue.CreatedOn.Before(since)
+	if created {
+		e := newEvent(IssueCreated, issue, "new")
+		e.User = issue.Author
+		e.NewValue = issue.AssignedTo
+		e.CreatedOn = *issue.CreatedOn
+		emit(e)
+
+		// Files attached on creation are part of the new issue.
+		for _, a := range issue.Attachments {
+			if a.CreatedOn == nil || !a.CreatedOn.After(*issue.CreatedOn) {
+				attached[a.Id] = true
+			}
+		}
+	}
+
+	for _, j := range issue.Journals {
+		if snap != nil && j.Id <= snap.JournalId {
+			continue
+		}
+		if snap == nil && !created && j.CreatedOn.Before(since) {
+			continue
+		}
+
+		source := fmt.Sprintf("j%d", j.Id)
+		for _, d := range j.Details {
+			switch {
+			case d.Property == "attr" && d.Name == "status_id":
+				e := newEvent(StatusChanged, issue, source)
+				e.User = j.User
+				e.JournalId = j.Id
+				e.OldValue = w.statusRef(d.OldValue)
+				e.NewValue = w.statusRef(d.NewValue)
+				e.CreatedOn = j.CreatedOn
+				emit(e)
+
+				if w.isClosed(e.NewValue) && !w.isClosed(e.OldValue) {
+					e.Id = fmt.Sprintf("%d:%s:%s", issue.Id, source, Closed)
+					e.Type = Closed
+					emit(e)
+				}
+			case d.Property == "attr" && d.Name == "assigned_to_id":
+				var old *model.Ref
+				if snap != nil {
+					old = snap.AssignedTo
+				}
+
+				e := newEvent(Assigned, issue, source)
+				e.User = j.User
+				e.JournalId = j.Id
+				e.OldValue = userRef(d.OldValue, old)
+				e.NewValue = userRef(d.NewValue, issue.AssignedTo)
+				e.CreatedOn = j.CreatedOn
+				emit(e)
+			case d.Property == "attachment" && d.NewValue != nil:
+				id, _ := strconv.Atoi(d.Name)
+				attachment(id, j.User, j.Id, j.CreatedOn)
+			}
+		}
+
+		if j.Notes != "" {
+			e := newEvent(NoteAdded, issue, source)
+			e.User = j.User
+			e.JournalId = j.Id
+			e.Notes = j.Notes
+			e.CreatedOn = j.CreatedOn
+			emit(e)
+		}
+	}
+
+	if snap == nil {
+		return events
+	}
+
+	// Changes without journal, e.g. hidden from the current user, are found
+	// by comparing with the snapshot.
+	var updated time.Time
+	if issue.UpdatedOn != nil {
+		updated = *issue.UpdatedOn
+	}
+	source := fmt.Sprintf("u%d", updated.Unix())
+
+	var status *model.Ref
+	if issue.Status != nil {
+		status = &model.Ref{Id: issue.Status.Id, Name: issue.Status.Name}
+	}
+	if !emitted[StatusChanged] && idOf(status) != idOf(snap.Status) {
+		e := newEvent(StatusChanged, issue, source)
+		e.OldValue = snap.Status
+		e.NewValue = status
+		e.CreatedOn = updated
+		emit(e)
+
+		if w.isClosed(status) && !w.isClosed(snap.Status) {
+			e.Id = fmt.Sprintf("%d:%s:%s", issue.Id, source, Closed)
+			e.Type = Closed
+			emit(e)
+		}
+	}
+
+	if !emitted[Assigned] && idOf(issue.AssignedTo) != idOf(snap.AssignedTo) {
+		e := newEvent(Assigned, issue, source)
+		e.OldValue = snap.AssignedTo
+		e.NewValue = issue.AssignedTo
+		e.CreatedOn = updated
+		emit(e)
+	}
+
+	seen := map[int]bool{}
+	for _, id := range snap.Attachments {
+		seen[id] = true
+	}
+	for _, a := range issue.Attachments {
+		if !seen[a.Id] {
+			attachment(a.Id, a.Author, 0, time.Time{})
+		}
+	}
+
+	return events
+}
+
+// idOf returns the ID of r, or 0 if r is nil.
+func idOf(r *model.Ref) int {
+	if r == nil {
+		return 0
+	}
+	return r.Id
+}
@@ -0,0 +1,203 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+type fakeServer struct {
+	mu     sync.Mutex
+	issues map[int]string
+	since  []string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/issue_statuses.json":
+		fmt.Fprint(w, `{"issue_statuses":[{"id":1,"name":"New"},{"id":5,"name":"Closed","is_closed":true}]}`)
+	case r.URL.Path == "/issues.json":
+		f.since = append(f.since, r.URL.Query().Get("updated_on"))
+		items := []string{}
+		for _, v := range f.issues {
+			items = append(items, v)
+		}
+		fmt.Fprintf(w, `{"issues":[%s],"total_count":%d}`, strings.Join(items, ","), len(items))
+	case strings.HasPrefix(r.URL.Path, "/issues/"):
+		var id int
+		fmt.Sscanf(r.URL.Path, "/issues/%d.json", &id)
+		fmt.Fprintf(w, `{"issue":%s}`, f.issues[id])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeServer) set(id int, v string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.issues[id] = v
+}
+
+func newWatcher(t *testing.T, f *fakeServer) *Watcher {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	c, err := redmine.NewClientWithResponses(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Watcher{Client: c, StatePath: filepath.Join(t.TempDir(), "state.json")}
+}
+
+func eventTypes(events []Event) string {
+	types := []string{}
+	for _, e := range events {
+		types = append(types, fmt.Sprintf("%d:%s", e.IssueId, e.Type))
+	}
+	return strings.Join(types, ",")
+}
+
+func TestWatcherPoll(t *testing.T) {
+	f := &fakeServer{issues: map[int]string{
+		1: `{"id":1,"subject":"one","status":{"id":1,"name":"New"},"created_on":"2025-01-01T00:00:00Z","updated_on":"2025-01-01T00:00:00Z"}`,
+	}}
+	w := newWatcher(t, f)
+
+	events, err := w.Poll(context.TODO())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("baseline = %v, %v", events, err)
+	}
+
+	f.set(1, `{"id":1,"subject":"one","status":{"id":5,"name":"Closed"},"assigned_to":{"id":3,"name":"Alice"},
+		"created_on":"2025-01-01T00:00:00Z","updated_on":"2025-01-02T00:00:00Z",
+		"attachments":[{"id":9,"filename":"a.log"}],
+		"journals":[{"id":20,"user":{"id":2,"name":"Bob"},"notes":"done","created_on":"2025-01-02T00:00:00Z","details":[
+			{"property":"attr","name":"status_id","old_value":"1","new_value":"5"},
+			{"property":"attr","name":"assigned_to_id","new_value":"3"},
+			{"property":"attachment","name":"9","new_value":"a.log"}]}]}`)
+	f.set(2, `{"id":2,"subject":"two","author":{"id":2,"name":"Bob"},"status":{"id":1,"name":"New"},"created_on":"2025-01-03T00:00:00Z","updated_on":"2025-01-03T00:00:00Z"}`)
+
+	// A new watcher resumes from the persisted checkpoint.
+	w = &Watcher{Client: w.Client, StatePath: w.StatePath}
+	events, err = w.Poll(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventTypes(events); got != "1:status_changed,1:closed,1:assigned,1:attachment_added,1:note_added,2:issue_created" {
+		t.Errorf("events = %s", got)
+	}
+	if f.since[len(f.since)-1] != ">=2025-01-01T00:00:00Z" {
+		t.Errorf("updated_on = %v", f.since)
+	}
+
+	e := events[0]
+	if e.Id != "1:j20:status_changed" || e.User.Name != "Bob" || e.OldValue.Name != "New" || e.NewValue.Name != "Closed" {
+		t.Errorf("status event = %+v", e)
+	}
+	if e := events[2]; e.NewValue.Name != "Alice" {
+		t.Errorf("assigned event = %+v", e)
+	}
+	if e := events[3]; e.Attachment == nil || e.Attachment.Filename != "a.log" {
+		t.Errorf("attachment event = %+v", e)
+	}
+
+	events, err = w.Poll(context.TODO())
+	if err != nil || len(events) != 0 {
+		t.Errorf("unchanged = %v, %v", events, err)
+	}
+
+	// A change without journal is found from the snapshot.
+	f.set(2, `{"id":2,"subject":"two","status":{"id":5,"name":"Closed"},"created_on":"2025-01-03T00:00:00Z","updated_on":"2025-01-04T00:00:00Z"}`)
+	events, err = w.Poll(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventTypes(events); got != "2:status_changed,2:closed" {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestForwarder(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	received := []Event{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil || r.Header.Get("X-Redmine-Event") != string(e.Type) {
+			t.Errorf("body = %s, err = %v", body, err)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	statePath := filepath.Join(t.TempDir(), "pending.json")
+	fw := &Forwarder{Endpoints: []string{srv.URL}, Backoff: time.Millisecond, StatePath: statePath}
+
+	events := make(chan Event, 2)
+	events <- Event{Id: "1:new:issue_created", Type: IssueCreated, IssueId: 1}
+	events <- Event{Id: "1:j2:note_added", Type: NoteAdded, IssueId: 1}
+	close(events)
+
+	if err := fw.Run(context.TODO(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 3 || len(received) != 2 || received[0].Type != IssueCreated || received[1].Type != NoteAdded {
+		t.Errorf("calls = %d, received = %+v", calls, received)
+	}
+	if fw.Pending(srv.URL) != 0 {
+		t.Errorf("pending = %d", fw.Pending(srv.URL))
+	}
+}
+
+func TestForwarderPersistsPending(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	statePath := filepath.Join(t.TempDir(), "pending.json")
+	fw := &Forwarder{Endpoints: []string{srv.URL}, Retries: 1, Backoff: time.Millisecond, StatePath: statePath}
+
+	events := make(chan Event, 1)
+	events <- Event{Id: "1:new:issue_created", Type: IssueCreated, IssueId: 1}
+	close(events)
+
+	failures := 0
+	fw.OnError = func(*DeliveryError) { failures++ }
+	if err := fw.Run(context.TODO(), events); err != nil {
+		t.Fatal(err)
+	}
+	if failures == 0 {
+		t.Error("expected a delivery error")
+	}
+
+	fw = &Forwarder{Endpoints: []string{srv.URL}, StatePath: statePath}
+	if err := fw.load(); err != nil || fw.Pending(srv.URL) != 1 {
+		t.Errorf("pending = %d, %v", fw.Pending(srv.URL), err)
+	}
+}
@@ -0,0 +1,155 @@
+package wiki
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// edit is a step of the edit script from a to b: a[a] is kept as b[b],
+// a[a] is deleted, or b[b] is inserted.
+type edit struct {
+	kind opKind
+	a, b int
+}
+
+// splitLines splits text into lines without their line breaks.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script from a to b, by the algorithm
+// of Myers.
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		trace = append(trace, slices.Clone(v))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{opEqual, x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{opInsert, x, prevY})
+			} else {
+				edits = append(edits, edit{opDelete, prevX, y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(edits)
+	return edits
+}
+
+// diffContext is the number of unchanged lines around the changes of a
+// unified diff.
+const diffContext = 3
+
+// unifiedDiff returns the differences from text a to text b in the unified
+// format, or "" when they have the same lines.
+func unifiedDiff(nameA, nameB, a, b string) string {
+	linesA, linesB := splitLines(a), splitLines(b)
+	edits := diffLines(linesA, linesB)
+
+	out := strings.Builder{}
+	for i := 0; i < len(edits); {
+		if edits[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		// The hunk spans the changes separated by fewer than twice the
+		// context lines.
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].kind != opEqual {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(edits))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		hunk := strings.Builder{}
+		countA, countB := 0, 0
+		for _, e := range edits[start:end] {
+			switch e.kind {
+			case opEqual:
+				hunk.WriteString(" " + linesA[e.a] + "\n")
+				countA++
+				countB++
+			case opDelete:
+				hunk.WriteString("-" + linesA[e.a] + "\n")
+				countA++
+			case opInsert:
+				hunk.WriteString("+" + linesB[e.b] + "\n")
+				countB++
+			}
+		}
+		startA, startB := edits[start].a+1, edits[start].b+1
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n%s", startA, countA, startB, countB, hunk.String())
+		i = end
+	}
+	return out.String()
+}
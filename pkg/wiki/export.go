@@ -0,0 +1,126 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// ErrAttachmentName is returned when an attachment would be exported over the
+// file of its page.
+var ErrAttachmentName = errors.New("wiki: attachment named as the page file")
+
+// Exporter writes the wiki of a project to a directory tree.
+type Exporter struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Project The ID or identifier of the project.
+	Project string
+
+	// Format The text formatting of the wiki.
+	Format Format
+}
+
+// pageDirs returns the directory of each page keyed by titleKey, following
+// the parents of the pages. A page whose parent is unknown, or part of a
+// cycle, is a root page.
+func pageDirs(pages []model.WikiPage) map[string]string {
+	parents := map[string]string{}
+	titles := map[string]string{}
+	for _, p := range pages {
+		titles[titleKey(p.Title)] = p.Title
+		parents[titleKey(p.Title)] = titleKey(p.ParentTitle())
+	}
+
+	dirs := map[string]string{}
+	for key, title := range titles {
+		dir := title
+		seen := map[string]bool{key: true}
+		for parent := parents[key]; parent != "" && !seen[parent]; parent = parents[parent] {
+			if _, ok := titles[parent]; !ok {
+				break
+			}
+			seen[parent] = true
+			dir = path.Join(titles[parent], dir)
+		}
+		dirs[key] = dir
+	}
+	return dirs
+}
+
+// Export writes the pages of the wiki to dir with their attachments, and
+// returns them sorted by directory. The files of the pages deleted from the
+// wiki since a previous export are left as is. A page with an attachment
+// named as its file, index.md or index.textile, fails with ErrAttachmentName.
+func (e *Exporter) Export(ctx context.Context, dir string) ([]Page, error) {
+	index, err := apiutil.WikiPages(ctx, e.Client, e.Project, e.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	dirs := pageDirs(index)
+
+	pages := []Page{}
+	for _, entry := range index {
+		w, err := apiutil.WikiPage(ctx, e.Client, e.Project, entry.Title, []string{"attachments"}, e.Auth...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Title, err)
+		}
+
+		p := Page{
+			Title:     w.Title,
+			Parent:    w.ParentTitle(),
+			Version:   w.Version,
+			UpdatedOn: w.UpdatedOn,
+			Comments:  w.Comments,
+			Dir:       dirs[titleKey(entry.Title)],
+		}
+		if w.Author != nil {
+			p.Author = w.Author.Name
+		}
+		p.Text = exportLinks(w.Text, e.Format, p.Dir, dirs)
+
+		pageDir := filepath.Join(dir, filepath.FromSlash(p.Dir))
+		if err := os.MkdirAll(pageDir, 0o755); err != nil {
+			return nil, err
+		}
+		for _, a := range w.Attachments {
+			name := filepath.Base(a.Filename)
+			if strings.EqualFold(name, e.Format.IndexFile()) {
+				return nil, fmt.Errorf("%s: %s: %w", entry.Title, a.Filename, ErrAttachmentName)
+			}
+			content, err := apiutil.AttachmentContent(ctx, e.Client, a.Id, e.Auth...)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", entry.Title, a.Filename, err)
+			}
+			if err := os.WriteFile(filepath.Join(pageDir, name), content, 0o644); err != nil {
+				return nil, err
+			}
+			p.Attachments = append(p.Attachments, name)
+		}
+
+		buf, err := p.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(pageDir, e.Format.IndexFile()), buf, 0o644); err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Dir < pages[j].Dir })
+	return pages, nil
+}
@@ -0,0 +1,132 @@
+package wiki
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// wikiLinkPattern matches the links to wiki pages: [[Title]],
+// [[Title#anchor]], [[Title|text]] and [[project:Title]], or their escaped
+// form starting with !.
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]+))?\]\]`)
+
+// Titleize returns the title of the page named name, with its spaces
+// replaced by underscores as Redmine does.
+func Titleize(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// titleKey returns the key of a title in the maps of pages. Titles are not
+// case sensitive.
+func titleKey(title string) string {
+	return strings.ToLower(Titleize(title))
+}
+
+// relativePath returns the path to target from the directory dir, both
+// relative to the root of the tree.
+func relativePath(dir, target string) string {
+	from := strings.Split(dir, "/")
+	to := strings.Split(target, "/")
+	if dir == "" {
+		from = nil
+	}
+	if target == "" {
+		to = nil
+	}
+
+	i := 0
+	for i < len(from) && i < len(to) && from[i] == to[i] {
+		i++
+	}
+	parts := []string{}
+	for range from[i:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, to[i:]...)
+	return strings.Join(parts, "/")
+}
+
+// escapePath escapes the segments of a slash-separated path for a link.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// exportLinks rewrites the links of text to the pages of dirs, keyed by
+// titleKey, into relative links to their files from the page in dir. The
+// links to other projects and to unknown pages are kept.
+func exportLinks(text string, f Format, dir string, dirs map[string]string) string {
+	return wikiLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		m := wikiLinkPattern.FindStringSubmatch(link)
+		if m[1] != "" || strings.Contains(m[2], ":") {
+			return link
+		}
+
+		title, anchor, _ := strings.Cut(m[2], "#")
+		target, ok := dirs[titleKey(title)]
+		if !ok {
+			return link
+		}
+
+		href := escapePath(path.Join(relativePath(dir, target), f.IndexFile()))
+		if anchor != "" {
+			href += "#" + anchor
+		}
+		text := m[3]
+		if text == "" {
+			text = strings.TrimSpace(m[2])
+		}
+
+		if f.markdown() {
+			return fmt.Sprintf("[%s](%s)", text, href)
+		}
+		return fmt.Sprintf(`"%s":%s`, text, href)
+	})
+}
+
+// fileLinkPattern returns the pattern of the links to the files of the
+// pages, as written by exportLinks: the text, the path of the file and the
+// anchor. The Markdown images, starting with !, are matched to be kept.
+func fileLinkPattern(f Format) *regexp.Regexp {
+	index := regexp.QuoteMeta(f.IndexFile())
+	if f.markdown() {
+		return regexp.MustCompile(`(!?)\[([^\]\n]*)\]\(((?:[^\s()#]*/)?` + index + `)(?:#([^\s()]*))?\)`)
+	}
+	return regexp.MustCompile(`()"([^"\n]+)":((?:[^\s"#]*/)?` + index + `)(?:#([\w-]+))?`)
+}
+
+// importLinks rewrites the relative links of text to the files of the
+// pages of titles, keyed by directory, back into wiki links from the page
+// in dir. The other links are kept.
+func importLinks(text string, f Format, dir string, titles map[string]string) string {
+	pattern := fileLinkPattern(f)
+	return pattern.ReplaceAllStringFunc(text, func(link string) string {
+		m := pattern.FindStringSubmatch(link)
+		if m[1] != "" {
+			return link
+		}
+
+		target, err := url.PathUnescape(m[3])
+		if err != nil {
+			return link
+		}
+		title, ok := titles[path.Clean(path.Join(dir, path.Dir(target)))]
+		if !ok {
+			return link
+		}
+
+		if m[4] != "" {
+			title += "#" + m[4]
+		}
+		if titleKey(m[2]) == titleKey(title) {
+			return "[[" + m[2] + "]]"
+		}
+		return "[[" + title + "|" + m[2] + "]]"
+	})
+}
@@ -0,0 +1,220 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// ErrConflict is returned when the local and the server changes of a page
+// overlap.
+var ErrConflict = errors.New("wiki: conflicting changes")
+
+// Conflict is a region of the base text changed differently by both sides.
+type Conflict struct {
+	// BaseLine The line of the base text where the region starts, from 1.
+	BaseLine int `json:"base_line"`
+
+	Base   []string `json:"base"`
+	Local  []string `json:"local"`
+	Server []string `json:"server"`
+}
+
+// chunk is a change of the base text: its lines [start, end) are replaced
+// with lines.
+type chunk struct {
+	start, end int
+	lines      []string
+}
+
+// chunks returns the changes from base to other.
+func chunks(base, other []string) []chunk {
+	var out []chunk
+	var c *chunk
+	for _, e := range diffLines(base, other) {
+		if e.kind == opEqual {
+			if c != nil {
+				out = append(out, *c)
+				c = nil
+			}
+			continue
+		}
+		if c == nil {
+			c = &chunk{start: e.a, end: e.a}
+		}
+		if e.kind == opDelete {
+			c.end = e.a + 1
+		} else {
+			c.lines = append(c.lines, other[e.b])
+		}
+	}
+	if c != nil {
+		out = append(out, *c)
+	}
+	return out
+}
+
+// apply returns the lines [start, end) of base with the changes of cs,
+// which are within the range.
+func apply(base []string, start, end int, cs []chunk) []string {
+	out := []string{}
+	i := start
+	for _, c := range cs {
+		out = append(out, base[i:c.start]...)
+		out = append(out, c.lines...)
+		i = c.end
+	}
+	return append(out, base[i:end]...)
+}
+
+// Merge3 merges the changes from base to local and from base to server,
+// line by line. The changes which touch or overlap each other are
+// conflicts, unless both sides made the same change. The merged text has
+// the conflicts between markers, as diff3 does.
+func Merge3(base, local, server string) (string, []Conflict) {
+	baseLines := splitLines(base)
+	localChunks := chunks(baseLines, splitLines(local))
+	serverChunks := chunks(baseLines, splitLines(server))
+
+	out := []string{}
+	var conflicts []Conflict
+	pos := 0
+	for len(localChunks) > 0 || len(serverChunks) > 0 {
+		// The group starts with the first chunk and takes every chunk of
+		// either side touching the range.
+		var group [2][]chunk
+		side := 0
+		if len(localChunks) == 0 || len(serverChunks) > 0 && serverChunks[0].start < localChunks[0].start {
+			side = 1
+		}
+		sides := [2]*[]chunk{&localChunks, &serverChunks}
+		first := (*sides[side])[0]
+		start, end := first.start, first.end
+		group[side] = append(group[side], first)
+		*sides[side] = (*sides[side])[1:]
+		for grown := true; grown; {
+			grown = false
+			for s, cs := range sides {
+				for len(*cs) > 0 && (*cs)[0].start <= end {
+					c := (*cs)[0]
+					group[s] = append(group[s], c)
+					end = max(end, c.end)
+					*cs = (*cs)[1:]
+					grown = true
+				}
+			}
+		}
+
+		out = append(out, baseLines[pos:start]...)
+		pos = end
+		localLines := apply(baseLines, start, end, group[0])
+		serverLines := apply(baseLines, start, end, group[1])
+		switch {
+		case len(group[1]) == 0:
+			out = append(out, localLines...)
+		case len(group[0]) == 0, slices.Equal(localLines, serverLines):
+			out = append(out, serverLines...)
+		default:
+			c := Conflict{BaseLine: start + 1, Base: slices.Clone(baseLines[start:end]), Local: localLines, Server: serverLines}
+			conflicts = append(conflicts, c)
+			out = append(out, "<<<<<<< local")
+			out = append(out, c.Local...)
+			out = append(out, "||||||| base")
+			out = append(out, c.Base...)
+			out = append(out, "=======")
+			out = append(out, c.Server...)
+			out = append(out, ">>>>>>> server")
+		}
+	}
+	out = append(out, baseLines[pos:]...)
+
+	if len(out) == 0 {
+		return "", conflicts
+	}
+	return strings.Join(out, "\n") + "\n", conflicts
+}
+
+// MergeResult is a three-way merge of a page.
+type MergeResult struct {
+	// Text The merged text, with the conflicts between markers.
+	Text string `json:"text"`
+
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+
+	// Version The current version of the page on the server, which the
+	// merged text is based on.
+	Version int `json:"version"`
+}
+
+// Merger saves the local edits of wiki pages, merging them with the
+// changes saved on the server in between.
+type Merger struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Project The ID or identifier of the project.
+	Project string
+}
+
+// Merge merges the local text of the page title, edited from version base,
+// with its current text on the server.
+func (m *Merger) Merge(ctx context.Context, title string, base int, local string) (*MergeResult, error) {
+	ancestor, err := apiutil.WikiPageVersion(ctx, m.Client, m.Project, title, base, m.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	current, err := apiutil.WikiPage(ctx, m.Client, m.Project, title, nil, m.Auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	text, conflicts := Merge3(normalizeText(ancestor.Text)+"\n", normalizeText(local)+"\n", normalizeText(current.Text)+"\n")
+	return &MergeResult{Text: text, Conflicts: conflicts, Version: current.Version}, nil
+}
+
+// maxMergeAttempts is the number of times Save merges a page saved again
+// on the server while merging.
+const maxMergeAttempts = 3
+
+// Save saves the text of the page title, edited from version base. When the
+// page has been changed on the server since, the changes are merged and the
+// merged text is saved, unless they conflict: the result then has the
+// conflicts and the error is ErrConflict. The result is nil when the text
+// is saved without merge.
+func (m *Merger) Save(ctx context.Context, title string, base int, fields apiutil.WikiPageFields) (*MergeResult, error) {
+	local := ""
+	if fields.Text != nil {
+		local = *fields.Text
+	}
+
+	fields.Version = &base
+	err := apiutil.SaveWikiPage(ctx, m.Client, m.Project, title, fields, m.Auth...)
+	if !apiutil.IsConflict(err) {
+		return nil, err
+	}
+
+	for range maxMergeAttempts {
+		r, err := m.Merge(ctx, title, base, local)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Conflicts) > 0 {
+			return r, ErrConflict
+		}
+
+		fields.Text = &r.Text
+		fields.Version = &r.Version
+		err = apiutil.SaveWikiPage(ctx, m.Client, m.Project, title, fields, m.Auth...)
+		if !apiutil.IsConflict(err) {
+			return r, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,108 @@
+// Package wiki exports the wiki of a Redmine project to a directory tree of
+// pages with front matter, and publishes such a tree back to the server.
+//
+// The page titled T whose parent is P is exported to the directory P/T, as
+// index.md or index.textile next to its attachments. The links between the
+// pages are rewritten to relative file paths so that the tree can be
+// browsed offline, and back to wiki links when it is published.
+package wiki
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the text formatting of a wiki, as set in the settings of the
+// Redmine server.
+type Format string
+
+const (
+	FormatTextile    Format = "textile"
+	FormatMarkdown   Format = "markdown"
+	FormatCommonMark Format = "common_mark"
+)
+
+// markdown reports whether f is one of the Markdown formattings.
+func (f Format) markdown() bool {
+	return f == FormatMarkdown || f == FormatCommonMark
+}
+
+// IndexFile returns the name of the files of the pages, index.textile for
+// Textile and index.md otherwise. Empty means FormatTextile, the default of
+// Redmine.
+func (f Format) IndexFile() string {
+	if f == "" || f == FormatTextile {
+		return "index.textile"
+	}
+	return "index.md"
+}
+
+// Page is an exported wiki page.
+type Page struct {
+	Title     string     `json:"title" yaml:"title"`
+	Parent    string     `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Version   int        `json:"version,omitempty" yaml:"version,omitempty"`
+	Author    string     `json:"author,omitempty" yaml:"author,omitempty"`
+	UpdatedOn *time.Time `json:"updated_on,omitempty" yaml:"updated_on,omitempty"`
+	Comments  string     `json:"comments,omitempty" yaml:"comments,omitempty"`
+
+	// Attachments The filenames of the attachments, in the directory of
+	// the page.
+	Attachments []string `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+
+	// Text The text of the page, after the front matter.
+	Text string `json:"-" yaml:"-"`
+
+	// Dir The directory of the page, relative to the root of the tree and
+	// separated by slashes.
+	Dir string `json:"dir" yaml:"-"`
+}
+
+const frontMatterDelimiter = "---\n"
+
+// Marshal returns the page as a file: its front matter in YAML followed by
+// its text.
+func (p *Page) Marshal() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString(frontMatterDelimiter)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString(frontMatterDelimiter)
+	buf.WriteString("\n")
+	buf.WriteString(p.Text)
+	return buf.Bytes(), nil
+}
+
+// ParsePage parses a page file. A file without front matter is a page with
+// text only.
+func ParsePage(buf []byte) (*Page, error) {
+	s := strings.ReplaceAll(string(buf), "\r\n", "\n")
+	p := &Page{}
+	if !strings.HasPrefix(s, frontMatterDelimiter) {
+		p.Text = s
+		return p, nil
+	}
+
+	// The newline before the closing delimiter ends the last line of the
+	// front matter, if any.
+	s = "\n" + s[len(frontMatterDelimiter):]
+	end := strings.Index(s, "\n"+frontMatterDelimiter)
+	if end < 0 {
+		return nil, errors.New("front matter not terminated")
+	}
+	if err := yaml.Unmarshal([]byte(s[:end+1]), p); err != nil {
+		return nil, err
+	}
+	p.Text = strings.TrimPrefix(s[end+1+len(frontMatterDelimiter):], "\n")
+	return p, nil
+}
@@ -0,0 +1,311 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+)
+
+// ReadTree reads the pages of a directory tree as written by
+// Exporter.Export. A page without title in its front matter is titled
+// after its directory, and a page without parent is a child of the page
+// of the parent directory, if any.
+func ReadTree(dir string, f Format) ([]Page, error) {
+	pages := []Page{}
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != f.IndexFile() {
+			return err
+		}
+
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		p, err := ParsePage(buf)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(name))
+		if err != nil {
+			return err
+		}
+		p.Dir = filepath.ToSlash(rel)
+		if p.Dir == "." {
+			return fmt.Errorf("%s: page outside of a page directory", name)
+		}
+		if p.Title == "" {
+			p.Title = path.Base(p.Dir)
+		}
+		pages = append(pages, *p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	titles := map[string]string{}
+	for _, p := range pages {
+		titles[p.Dir] = p.Title
+	}
+	for i := range pages {
+		if pages[i].Parent == "" {
+			pages[i].Parent = titles[path.Dir(pages[i].Dir)]
+		}
+	}
+	return pages, nil
+}
+
+// parentFirst sorts pages so that every page comes after its parent.
+func parentFirst(pages []Page) {
+	parents := map[string]string{}
+	for _, p := range pages {
+		parents[titleKey(p.Title)] = titleKey(p.Parent)
+	}
+	depth := func(p Page) int {
+		n := 0
+		seen := map[string]bool{}
+		for parent := parents[titleKey(p.Title)]; parent != "" && !seen[parent]; parent = parents[parent] {
+			if _, ok := parents[parent]; !ok {
+				break
+			}
+			seen[parent] = true
+			n++
+		}
+		return n
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		if di, dj := depth(pages[i]), depth(pages[j]); di != dj {
+			return di < dj
+		}
+		return pages[i].Dir < pages[j].Dir
+	})
+}
+
+// Action is what publishing does to a page.
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+
+	// ActionConflict is a page changed on the server since the version of
+	// its front matter. It is left as is.
+	ActionConflict Action = "conflict"
+
+	// ActionMerged is a page changed on the server since the version of its
+	// front matter, whose changes are merged with the local ones.
+	ActionMerged Action = "merged"
+)
+
+// Result is the outcome of publishing a page.
+type Result struct {
+	Title  string `json:"title"`
+	Dir    string `json:"dir"`
+	Action Action `json:"action"`
+
+	// Version The version of the page on the server: the saved one, or
+	// the current one when the page is unchanged or in conflict.
+	Version int `json:"version,omitempty"`
+
+	// Uploads The filenames of the attachments uploaded.
+	Uploads []string `json:"uploads,omitempty"`
+
+	// Diff The changes of the text from the server to the local page, in
+	// the unified format.
+	Diff string `json:"diff,omitempty"`
+
+	// Conflicts The overlapping changes of a page which could not be
+	// merged.
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// Publisher saves a directory tree of pages to the wiki of a project.
+type Publisher struct {
+	// Client The client of the Redmine server.
+	Client redmine.ClientWithResponsesInterface
+
+	// Auth The request editors authenticating the requests.
+	Auth []redmine.RequestEditorFn
+
+	// Project The ID or identifier of the project.
+	Project string
+
+	// Format The text formatting of the wiki.
+	Format Format
+
+	// Comments The comment of the saved versions.
+	Comments string
+
+	// DryRun Whether to only report what would change.
+	DryRun bool
+
+	// Merge Whether to merge the changes made on the server since the
+	// version of a page with the local ones, rather than reporting a
+	// conflict. The merged text is saved to the server and to the file.
+	Merge bool
+}
+
+// normalizeText returns text with Unix line breaks and without trailing
+// line breaks, as compared with the text on the server.
+func normalizeText(text string) string {
+	return strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// Publish saves the pages of the tree in dir, parents first, with the
+// attachments of their front matter not yet on the server. A page whose
+// version is not the current one on the server is a conflict and is not
+// saved. The front matter of the saved pages is updated with their new
+// version, so that the next publish detects the changes made on the server
+// in between.
+func (p *Publisher) Publish(ctx context.Context, dir string) ([]Result, error) {
+	pages, err := ReadTree(dir, p.Format)
+	if err != nil {
+		return nil, err
+	}
+	parentFirst(pages)
+
+	titles := map[string]string{}
+	dirs := map[string]string{}
+	for _, page := range pages {
+		titles[page.Dir] = page.Title
+		dirs[titleKey(page.Title)] = page.Dir
+	}
+
+	results := []Result{}
+	for _, page := range pages {
+		r, err := p.publish(ctx, dir, page, titles, dirs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", page.Title, err)
+		}
+		results = append(results, *r)
+	}
+	return results, nil
+}
+
+func (p *Publisher) publish(ctx context.Context, dir string, page Page, titles, dirs map[string]string) (*Result, error) {
+	current, err := apiutil.WikiPage(ctx, p.Client, p.Project, page.Title, []string{"attachments"}, p.Auth...)
+	exists := err == nil
+	if apiutil.IsNotFound(err) {
+		current = &model.WikiPage{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	text := importLinks(page.Text, p.Format, page.Dir, titles)
+	r := &Result{Title: page.Title, Dir: page.Dir, Version: current.Version}
+	for _, name := range page.Attachments {
+		if !slices.ContainsFunc(current.Attachments, func(a model.Attachment) bool { return a.Filename == name }) {
+			r.Uploads = append(r.Uploads, name)
+		}
+	}
+
+	sameText := normalizeText(text) == normalizeText(current.Text)
+	sameParent := titleKey(page.Parent) == titleKey(current.ParentTitle())
+	switch {
+	case !exists:
+		r.Action = ActionCreate
+	case sameText && sameParent && len(r.Uploads) == 0:
+		r.Action = ActionUnchanged
+	case page.Version != current.Version:
+		r.Action = ActionConflict
+	default:
+		r.Action = ActionUpdate
+	}
+
+	version := page.Version
+	if r.Action == ActionConflict && p.Merge && page.Version != 0 {
+		m := &Merger{Client: p.Client, Auth: p.Auth, Project: p.Project}
+		merged, err := m.Merge(ctx, page.Title, page.Version, text)
+		if err != nil {
+			return nil, err
+		}
+		if len(merged.Conflicts) > 0 {
+			r.Conflicts = merged.Conflicts
+		} else {
+			r.Action = ActionMerged
+			text, version = merged.Text, merged.Version
+			sameText = normalizeText(text) == normalizeText(current.Text)
+		}
+	}
+	if !sameText {
+		r.Diff = unifiedDiff(page.Title+" (server)", page.Title+" (local)", normalizeText(current.Text)+"\n", normalizeText(text)+"\n")
+	}
+
+	if p.DryRun || r.Action == ActionConflict {
+		return r, nil
+	}
+	if r.Action == ActionUnchanged {
+		if page.Version == current.Version {
+			return r, nil
+		}
+		page.Version = current.Version
+		return r, p.savePage(dir, page)
+	}
+
+	pageDir := filepath.Join(dir, filepath.FromSlash(page.Dir))
+	fields := apiutil.WikiPageFields{Text: &text, ParentTitle: &page.Parent}
+	if p.Comments != "" {
+		fields.Comments = &p.Comments
+	}
+	if exists {
+		fields.Version = &version
+	}
+	for _, name := range r.Uploads {
+		upload, err := p.upload(ctx, filepath.Join(pageDir, name))
+		if err != nil {
+			return nil, err
+		}
+		fields.Uploads = append(fields.Uploads, *upload)
+	}
+
+	err = apiutil.SaveWikiPage(ctx, p.Client, p.Project, page.Title, fields, p.Auth...)
+	if apiutil.IsConflict(err) {
+		r.Action, r.Uploads = ActionConflict, nil
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := apiutil.WikiPage(ctx, p.Client, p.Project, page.Title, nil, p.Auth...)
+	if err != nil {
+		return nil, err
+	}
+	r.Version = saved.Version
+	page.Version = saved.Version
+	if r.Action == ActionMerged {
+		page.Text = exportLinks(text, p.Format, page.Dir, dirs)
+	}
+	return r, p.savePage(dir, page)
+}
+
+func (p *Publisher) upload(ctx context.Context, name string) (*apiutil.Upload, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return apiutil.UploadFile(ctx, p.Client, filepath.Base(name), mime.TypeByExtension(filepath.Ext(name)), f, p.Auth...)
+}
+
+// savePage writes the file of page.
+func (p *Publisher) savePage(dir string, page Page) error {
+	buf, err := page.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, filepath.FromSlash(page.Dir), p.Format.IndexFile()), buf, 0o644)
+}
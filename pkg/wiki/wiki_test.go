@@ -0,0 +1,344 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/9506hqwy/redmine-client-go/pkg/apiutil"
+	"github.com/9506hqwy/redmine-client-go/pkg/model"
+	"github.com/9506hqwy/redmine-client-go/pkg/redmine"
+	"github.com/9506hqwy/redmine-client-go/pkg/redminetest"
+)
+
+var auth = []redmine.RequestEditorFn{redminetest.Admin}
+
+func newTestClient(t *testing.T) *redmine.ClientWithResponses {
+	f := redminetest.DefaultFixtures()
+	f.Projects = []model.Project{{Id: 1, Name: "Docs", Identifier: "docs"}}
+	f.WikiPages = []redminetest.WikiPage{
+		{Project: "docs", WikiPage: model.WikiPage{Title: "Wiki", Text: "See [[Child]], [[Child#Usage|usage]], [[Missing]] and [[other:Wiki]]."}},
+		{Project: "docs", WikiPage: model.WikiPage{Title: "Child", Parent: &model.WikiParent{Title: "Wiki"}, Text: "Back to [[wiki]]. ![[Grand child]]", Comments: "first"}},
+		{Project: "docs", WikiPage: model.WikiPage{Title: "Grand_child", Parent: &model.WikiParent{Title: "Child"}, Text: "Up to [[Child]] and [[Grand child]]."}},
+	}
+	c := redminetest.Client(t, f)
+	attach(t, c, "Child", "diagram.txt", "boxes")
+	return c
+}
+
+// attach uploads content to the page title as the attachment name.
+func attach(t *testing.T, c *redmine.ClientWithResponses, title, name, content string) {
+	ctx := context.Background()
+	upload, err := apiutil.UploadFile(ctx, c, name, "text/plain", strings.NewReader(content), auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"wiki_page":{"uploads":[{"token":%q,"filename":%q}]}}`, upload.Token, name)
+	resp, err := c.WikiUpdatePutWithBodyWithResponse(ctx, "docs", title, &redmine.WikiUpdatePutParams{}, "application/json", strings.NewReader(body), auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiutil.Check(resp, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := newTestClient(t)
+	dir := t.TempDir()
+
+	for _, f := range []Format{FormatMarkdown, FormatTextile} {
+		e := &Exporter{Client: c, Auth: auth, Project: "docs", Format: f}
+		pages, err := e.Export(context.Background(), filepath.Join(dir, string(f)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for _, p := range pages {
+			got = append(got, p.Dir)
+		}
+		if strings.Join(got, " ") != "Wiki Wiki/Child Wiki/Child/Grand_child" {
+			t.Fatalf("%s: dirs = %v", f, got)
+		}
+		if p := pages[1]; p.Parent != "Wiki" || p.Version != 1 || p.Author != "Redmine Admin" || p.Comments != "first" || len(p.Attachments) != 1 {
+			t.Errorf("%s: page = %+v", f, p)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, string(f), "Wiki", "Child", "diagram.txt"))
+		if err != nil || string(content) != "boxes" {
+			t.Errorf("%s: attachment = %q %v", f, content, err)
+		}
+	}
+
+	want := map[string]string{
+		"markdown/Wiki/index.md":                       "See [Child](Child/index.md), [usage](Child/index.md#Usage), [[Missing]] and [[other:Wiki]].",
+		"markdown/Wiki/Child/index.md":                 "Back to [wiki](../index.md). ![[Grand child]]",
+		"markdown/Wiki/Child/Grand_child/index.md":     "Up to [Child](../index.md) and [Grand child](index.md).",
+		"textile/Wiki/index.textile":                   `See "Child":Child/index.textile, "usage":Child/index.textile#Usage, [[Missing]] and [[other:Wiki]].`,
+		"textile/Wiki/Child/Grand_child/index.textile": `Up to "Child":../index.textile and "Grand child":index.textile.`,
+	}
+	for name, text := range want {
+		buf, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := ParsePage(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Text != text {
+			t.Errorf("%s: text = %q", name, p.Text)
+		}
+	}
+}
+
+func TestExportAttachmentName(t *testing.T) {
+	c := newTestClient(t)
+	attach(t, c, "Grand_child", "index.md", "not a page")
+	dir := t.TempDir()
+
+	e := &Exporter{Client: c, Auth: auth, Project: "docs", Format: FormatMarkdown}
+	if _, err := e.Export(context.Background(), dir); !errors.Is(err, ErrAttachmentName) {
+		t.Errorf("markdown: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Wiki", "Child", "Grand_child", "index.md")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("markdown: page file = %v", err)
+	}
+
+	// The file of a Textile page has another name.
+	e.Format = FormatTextile
+	if _, err := e.Export(context.Background(), dir); err != nil {
+		t.Errorf("textile: %v", err)
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	p := &Page{Title: "Wiki", Version: 3, Attachments: []string{"a.png"}, Text: "---\nnot front matter\n"}
+	buf, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParsePage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Wiki" || got.Version != 3 || len(got.Attachments) != 1 || got.Text != p.Text {
+		t.Errorf("got %+v", got)
+	}
+
+	if got, err := ParsePage([]byte("---\n---\ntext")); err != nil || got.Text != "text" {
+		t.Errorf("empty front matter: %+v %v", got, err)
+	}
+	if _, err := ParsePage([]byte("---\ntitle: x\n")); err == nil {
+		t.Error("unterminated front matter parsed")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	e := &Exporter{Client: c, Auth: auth, Project: "docs", Format: FormatMarkdown}
+	if _, err := e.Export(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new page under Child, without front matter, an edit of Wiki and a
+	// concurrent edit of Grand_child on the server.
+	newDir := filepath.Join(dir, "Wiki", "Child", "New")
+	if err := os.MkdirAll(newDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	page := &Page{Text: "Link to [Wiki](../../index.md).\n", Attachments: []string{"notes.txt"}}
+	buf, _ := page.Marshal()
+	_ = os.WriteFile(filepath.Join(newDir, "index.md"), buf, 0o644)
+	_ = os.WriteFile(filepath.Join(newDir, "notes.txt"), []byte("notes"), 0o644)
+
+	name := filepath.Join(dir, "Wiki", "index.md")
+	buf, _ = os.ReadFile(name)
+	_ = os.WriteFile(name, []byte(strings.Replace(string(buf), "See ", "Read ", 1)), 0o644)
+
+	text := "changed on the server"
+	if err := apiutil.SaveWikiPage(ctx, c, "docs", "Grand_child", apiutil.WikiPageFields{Text: &text}, auth...); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Publisher{Client: c, Auth: auth, Project: "docs", Format: FormatMarkdown, DryRun: true}
+	results, err := p.Publish(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s:%s", r.Title, r.Action))
+	}
+	if strings.Join(got, " ") != "Wiki:update Child:unchanged Grand_child:conflict New:create" {
+		t.Fatalf("dry run = %v", got)
+	}
+	want := "--- Wiki (server)\n+++ Wiki (local)\n@@ -1,1 +1,1 @@\n-See [[Child]], [[Child#Usage|usage]], [[Missing]] and [[other:Wiki]].\n+Read [[Child]], [[Child#Usage|usage]], [[Missing]] and [[other:Wiki]].\n"
+	if results[0].Diff != want {
+		t.Errorf("diff = %q", results[0].Diff)
+	}
+	if w, _ := apiutil.WikiPage(ctx, c, "docs", "Wiki", nil, auth...); w.Version != 1 {
+		t.Errorf("dry run saved version %d", w.Version)
+	}
+
+	p.DryRun = false
+	if results, err = p.Publish(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Version != 2 || results[2].Action != ActionConflict || results[3].Uploads[0] != "notes.txt" {
+		t.Errorf("results = %+v", results)
+	}
+
+	w, err := apiutil.WikiPage(ctx, c, "docs", "New", []string{"attachments"}, auth...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Text != "Link to [[Wiki]].\n" || w.ParentTitle() != "Child" || len(w.Attachments) != 1 {
+		t.Errorf("new page = %+v", w)
+	}
+	if w, _ := apiutil.WikiPage(ctx, c, "docs", "Grand_child", nil, auth...); w.Text != text {
+		t.Errorf("conflict overwritten: %q", w.Text)
+	}
+
+	// The front matter has the new versions, so publishing again changes
+	// nothing.
+	if results, err = p.Publish(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Title != "Grand_child" && r.Action != ActionUnchanged {
+			t.Errorf("republish: %+v", r)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	want := "--- a\n+++ b\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("got\n%s", got)
+	}
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("same text: %q", got)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name, local, server, want string
+		conflicts                 int
+	}{
+		{"clean", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\nf\n", "a\nB\nc\nd\ne\nf\n", 0},
+		{"insert and delete", "a\nb\nb2\nc\nd\ne\n", "a\nb\nc\ne\n", "a\nb\nb2\nc\ne\n", 0},
+		{"conflict", "a\nlocal\nc\nd\ne\n", "a\nserver\nc\nd\ne\n", "a\n<<<<<<< local\nlocal\n||||||| base\nb\n=======\nserver\n>>>>>>> server\nc\nd\ne\n", 1},
+	}
+	for _, tt := range tests {
+		got, conflicts := Merge3(base, tt.local, tt.server)
+		if got != tt.want || len(conflicts) != tt.conflicts {
+			t.Errorf("%s: got %q %+v", tt.name, got, conflicts)
+		}
+	}
+
+	_, conflicts := Merge3(base, "a\nlocal\nc\nd\ne\n", "a\nserver\nc\nd\ne\n")
+	if c := conflicts[0]; c.BaseLine != 2 || c.Base[0] != "b" || c.Local[0] != "local" || c.Server[0] != "server" {
+		t.Errorf("conflict = %+v", c)
+	}
+}
+
+func TestMergerSave(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	m := &Merger{Client: c, Auth: auth, Project: "docs"}
+
+	base := "one\ntwo\nthree\nfour\nfive"
+	if err := apiutil.SaveWikiPage(ctx, c, "docs", "Merged", apiutil.WikiPageFields{Text: &base}, auth...); err != nil {
+		t.Fatal(err)
+	}
+	server := "one\ntwo\nthree\nfour\nfive, by a human"
+	if err := apiutil.SaveWikiPage(ctx, c, "docs", "Merged", apiutil.WikiPageFields{Text: &server}, auth...); err != nil {
+		t.Fatal(err)
+	}
+
+	local := "one, by a bot\ntwo\nthree\nfour\nfive"
+	r, err := m.Save(ctx, "Merged", 1, apiutil.WikiPageFields{Text: &local})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "one, by a bot\ntwo\nthree\nfour\nfive, by a human\n"
+	if r == nil || r.Text != want || r.Version != 2 {
+		t.Fatalf("merge = %+v", r)
+	}
+	w, _ := apiutil.WikiPage(ctx, c, "docs", "Merged", nil, auth...)
+	if normalizeText(w.Text) != normalizeText(want) || w.Version != 3 {
+		t.Errorf("page = %q version %d", w.Text, w.Version)
+	}
+
+	local = "one\ntwo\nthree\nfour\nfive, by a bot"
+	r, err = m.Save(ctx, "Merged", 1, apiutil.WikiPageFields{Text: &local})
+	if !errors.Is(err, ErrConflict) || len(r.Conflicts) != 1 || r.Conflicts[0].BaseLine != 5 {
+		t.Fatalf("conflict = %+v %v", r, err)
+	}
+	if w, _ := apiutil.WikiPage(ctx, c, "docs", "Merged", nil, auth...); w.Version != 3 {
+		t.Errorf("conflict saved version %d", w.Version)
+	}
+
+	// Saving from the current version does not merge.
+	if r, err = m.Save(ctx, "Merged", 3, apiutil.WikiPageFields{Text: &local}); r != nil || err != nil {
+		t.Errorf("save = %+v %v", r, err)
+	}
+}
+
+func TestPublishMerge(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	e := &Exporter{Client: c, Auth: auth, Project: "docs", Format: FormatMarkdown}
+	if _, err := e.Export(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(dir, "Wiki", "index.md")
+	buf, _ := os.ReadFile(name)
+	_ = os.WriteFile(name, append(buf, "\nLocal line.\n"...), 0o644)
+
+	text := "Server line.\n\nSee [[Child]], [[Child#Usage|usage]], [[Missing]] and [[other:Wiki]]."
+	if err := apiutil.SaveWikiPage(ctx, c, "docs", "Wiki", apiutil.WikiPageFields{Text: &text}, auth...); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Publisher{Client: c, Auth: auth, Project: "docs", Format: FormatMarkdown, Merge: true}
+	results, err := p.Publish(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Action != ActionMerged || r.Version != 3 {
+		t.Fatalf("result = %+v", r)
+	}
+
+	w, _ := apiutil.WikiPage(ctx, c, "docs", "Wiki", nil, auth...)
+	want := text + "\nLocal line.\n"
+	if w.Text != want {
+		t.Errorf("text = %q", w.Text)
+	}
+	buf, _ = os.ReadFile(name)
+	page, err := ParsePage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Version != 3 || !strings.HasPrefix(page.Text, "Server line.\n\nSee [Child](Child/index.md)") {
+		t.Errorf("page = %+v", page)
+	}
+}
@@ -9,6 +9,10 @@ curl -fsSL \
     -o "${SOURCE_FILE}" \
     "https://github.com/9506hqwy/openapi-spec-redmine/raw/refs/heads/main/openapi.yml"
 
+# The full specification, enums included, is committed with pkg/contract,
+# whose tests check the routes of the client against it.
+cp "${SOURCE_FILE}" "${BASE_DIR}/pkg/contract/openapi.yml"
+
 yq 'del(.. | select(. | key == "enum"))' "${SOURCE_FILE}" > "${DEST_FILE}"
 
 go tool oapi-codegen -config "${BASE_DIR}/cfg.yml" "${DEST_FILE}"